@@ -1,15 +1,30 @@
 package main
 
 import (
+	"context"
+	"errors"
 	"fmt"
+	nethttp "net/http"
 	"os"
+	"os/signal"
+	"runtime"
+	"syscall"
+	"time"
 
 	_ "github.com/CryptoGu1/books-rest-clean-arch/docs"
 	"github.com/CryptoGu1/books-rest-clean-arch/internal/config"
+	"github.com/CryptoGu1/books-rest-clean-arch/internal/domain"
 	"github.com/CryptoGu1/books-rest-clean-arch/internal/handler/http"
 	"github.com/CryptoGu1/books-rest-clean-arch/internal/repository"
 	"github.com/CryptoGu1/books-rest-clean-arch/internal/service"
+	"github.com/CryptoGu1/books-rest-clean-arch/pkg/auditq"
+	"github.com/CryptoGu1/books-rest-clean-arch/pkg/auditsink"
+	"github.com/CryptoGu1/books-rest-clean-arch/pkg/cursor"
+	"github.com/CryptoGu1/books-rest-clean-arch/pkg/jwtkeys"
 	"github.com/CryptoGu1/books-rest-clean-arch/pkg/log_grpc"
+	"github.com/CryptoGu1/books-rest-clean-arch/pkg/mailer"
+	"github.com/CryptoGu1/books-rest-clean-arch/pkg/oidc"
+	"github.com/CryptoGu1/books-rest-clean-arch/pkg/password"
 	"github.com/CryptoGu1/books-rest-clean-arch/pkg/postgres"
 	log "github.com/sirupsen/logrus"
 )
@@ -53,31 +68,308 @@ func main() {
 
 	jwtSecret := []byte(os.Getenv("JWT_SECRET"))
 
+	tokenKeys, err := newTokenKeys(cfg.JWT, jwtSecret)
+	if err != nil {
+		log.Fatal(err)
+	}
+	if len(jwtSecret) == 0 {
+		// секрет всё равно нужен: им подписываются ссылки подтверждения email
+		log.Fatal("JWT_SECRET is not set")
+	}
+	cursorSecret := []byte(os.Getenv("CURSOR_SECRET"))
+	if len(cursorSecret) == 0 {
+		log.Warn("CURSOR_SECRET is not set, deriving the cursor key from JWT_SECRET")
+		cursorSecret = cursor.DeriveSecret(jwtSecret)
+	}
+
 	//init DI
-	bookRepo := repository.NewBookPostgresRepo(db)
+	bookRepo := repository.NewBookPostgresRepo(db, cfg.Search.Language)
+	if err := bookRepo.SyncSearchConfig(context.Background()); err != nil {
+		log.Fatal("search.language: ", err)
+	}
 	userRepo := repository.NewUserPostgresRepo(db)
 	tokenRepo := repository.NewToken(db)
+	roleRepo := repository.NewRolePostgresRepo(db)
+	resetRepo := repository.NewPasswordResetPostgresRepo(db)
+	apiKeyRepo := repository.NewAPIKeyPostgresRepo(db)
+	mfaRepo := repository.NewMFAPostgresRepo(db)
+	identityRepo := repository.NewIdentityPostgresRepo(db)
+	auditOutboxRepo := repository.NewAuditOutboxPostgresRepo(db)
+	transactor := repository.NewTransactor(db)
+
+	var attemptRepo repository.LoginAttemptRepository = repository.NewLoginAttemptPostgresRepo(db)
+	if cfg.Auth.Lockout.Store == "memory" {
+		attemptRepo = repository.NewLoginAttemptMemoryRepo()
+	}
+
+	auditSink, err := newAuditSink(cfg.Audit.Sinks)
+	if err != nil {
+		log.Fatal("audit.sinks: ", err)
+	}
+	defer auditSink.Close()
+	// недоступный сервер логов — не повод не стартовать: события подождут в outbox
+	checkCtx, cancelCheck := context.WithTimeout(context.Background(), 5*time.Second)
+	if err := auditsink.Check(checkCtx, auditSink); err != nil {
+		log.Warn("audit sink is not healthy, events will be retried: ", err)
+	}
+	cancelCheck()
+
+	// запросы не ждут сервис логов: события уходят в фоне, недоставленные — в outbox
+	auditQueue := auditq.New(auditSink, auditq.NewFileOutbox(cfg.Audit.OutboxFile), auditq.Config{
+		QueueSize:      cfg.Audit.QueueSize,
+		Workers:        cfg.Audit.Workers,
+		BatchSize:      cfg.Audit.BatchSize,
+		MaxRetries:     cfg.Audit.MaxRetries,
+		BaseBackoff:    cfg.Audit.BaseBackoff,
+		MaxBackoff:     cfg.Audit.MaxBackoff,
+		ReplayInterval: cfg.Audit.ReplayInterval,
+	})
+	auditQueue.Start()
+
+	bookService := service.NewBookService(bookRepo, auditOutboxRepo, transactor, auditQueue, cursorSecret)
 
-	logHost := os.Getenv("LOG_GRPC_HOST")
-	if logHost == "" {
-		logHost = "localhost"
+	relay := service.NewAuditRelay(auditOutboxRepo, transactor, auditSink, service.AuditRelayConfig{
+		BatchSize:    cfg.Audit.Relay.BatchSize,
+		PollInterval: cfg.Audit.Relay.PollInterval,
+		BaseBackoff:  cfg.Audit.Relay.BaseBackoff,
+		MaxBackoff:   cfg.Audit.Relay.MaxBackoff,
+		Lease:        cfg.Audit.Relay.Lease,
+		Retention:    cfg.Audit.Relay.Retention,
+	})
+	relayCtx, stopRelay := context.WithCancel(context.Background())
+	relayDone := make(chan struct{})
+	go func() {
+		defer close(relayDone)
+		relay.Run(relayCtx)
+	}()
+
+	hasher, err := newPasswordHasher(cfg.Auth.Password)
+	if err != nil {
+		log.Fatal("auth.password: ", err)
+	}
+
+	unverifiedPolicy, err := domain.ParseUnverifiedPolicy(cfg.Auth.UnverifiedPolicy)
+	if err != nil {
+		log.Fatal("auth.unverified_policy: ", err)
 	}
-	LogPort := 9000
-	auditClient, err := log_grpc.NewClient(logHost, LogPort)
+
+	mailSender, err := newMailer(cfg.Mail)
 	if err != nil {
-		log.Error("Failed to connect to log", err)
+		log.Fatal("mail: ", err)
 	}
-	bookService := service.NewBookService(bookRepo, auditClient)
+	// письма уходят в фоне: время ответа не должно зависеть от того, отправлялось ли письмо
+	mail := mailer.NewAsync(mailSender, cfg.Mail.QueueSize, cfg.Mail.Workers)
 
-	userService := service.NewAuthService(userRepo, tokenRepo, auditClient, jwtSecret)
+	userService := service.NewAuthService(userRepo, roleRepo, tokenRepo, resetRepo, attemptRepo, apiKeyRepo, mfaRepo, identityRepo, transactor, mail, auditQueue, service.AuthConfig{
+		Secret:           jwtSecret,
+		Signer:           tokenKeys,
+		VerifyURL:        cfg.Auth.VerifyURL,
+		MFAIssuer:        cfg.Auth.MFA.Issuer,
+		Hasher:           hasher,
+		OIDCProviders:    newOIDCProviders(cfg.Auth.OIDC.Providers),
+		UnverifiedPolicy: unverifiedPolicy,
+		Lockout: domain.LockoutPolicy{
+			FreeAttempts:    cfg.Auth.Lockout.FreeAttempts,
+			MaxFailures:     cfg.Auth.Lockout.MaxFailures,
+			BaseDelay:       cfg.Auth.Lockout.BaseDelay,
+			MaxDelay:        cfg.Auth.Lockout.MaxDelay,
+			LockoutDuration: cfg.Auth.Lockout.LockoutDuration,
+			Window:          cfg.Auth.Lockout.Window,
+		},
+	})
 
-	handler := http.NewHandler(bookService, userService, jwtSecret)
+	sameSite, err := http.ParseSameSite(cfg.Auth.Cookie.SameSite)
+	if err != nil {
+		log.Fatal("auth.cookie.same_site: ", err)
+	}
+	trustedProxies, err := http.ParseTrustedProxies(cfg.Server.TrustedProxies)
+	if err != nil {
+		log.Fatal("server.trusted_proxies: ", err)
+	}
+	handler := http.NewHandler(bookService, userService, tokenKeys, http.Config{
+		Cookie: http.CookiePolicy{
+			Secure:   cfg.Auth.Cookie.Secure,
+			SameSite: sameSite,
+			Domain:   cfg.Auth.Cookie.Domain,
+		},
+		BodyTokens:      cfg.Auth.BodyTokens,
+		LegacyGetSignIn: cfg.Auth.LegacyGetSignIn,
+		TrustedProxies:  trustedProxies,
+	})
 
 	router := handler.InitRouter()
 
-	log.Info("SERVER STARTED")
-	if err := router.Start(fmt.Sprintf(":%d", cfg.Server.Port)); err != nil {
-		log.Fatal(err)
+	go func() {
+		log.Info("SERVER STARTED")
+		if err := router.Start(fmt.Sprintf(":%d", cfg.Server.Port)); err != nil && !errors.Is(err, nethttp.ErrServerClosed) {
+			log.Fatal(err)
+		}
+	}()
+
+	quit := make(chan os.Signal, 1)
+	signal.Notify(quit, syscall.SIGINT, syscall.SIGTERM)
+	<-quit
+
+	shutdownTimeout := cfg.Audit.ShutdownTimeout
+	if shutdownTimeout <= 0 {
+		shutdownTimeout = 10 * time.Second
 	}
+	ctx, cancel := context.WithTimeout(context.Background(), shutdownTimeout)
+	defer cancel()
 
+	if err := router.Shutdown(ctx); err != nil {
+		log.Error("failed to shutdown server", err)
+	}
+	// сначала дожидаемся запросов, потом отправляем то, что они положили в очередь аудита
+	if err := auditQueue.Close(ctx); err != nil {
+		log.Warn("audit queue was not drained, rest saved to outbox: ", err)
+	}
+	if err := mail.Close(ctx); err != nil {
+		log.Warn("mail queue was not drained: ", err)
+	}
+	// пачка, не отправленная до остановки, вернётся в работу после истечения аренды
+	stopRelay()
+	<-relayDone
+	log.Info("SERVER STOPPED")
+}
+
+func newAuditSink(cfg []config.AuditSink) (auditsink.Sink, error) {
+	sinks := make([]auditsink.Config, 0, len(cfg))
+	for _, c := range cfg {
+		sinks = append(sinks, auditsink.Config{
+			Type: c.Type,
+			GRPC: log_grpc.Config{
+				Host:    c.Host,
+				Port:    c.Port,
+				Timeout: c.Timeout,
+				TLS: log_grpc.TLSConfig{
+					Enabled:    c.TLS.Enabled,
+					CAFile:     c.TLS.CAFile,
+					CertFile:   c.TLS.CertFile,
+					KeyFile:    c.TLS.KeyFile,
+					ServerName: c.TLS.ServerName,
+				},
+				Retry: log_grpc.RetryConfig{
+					MaxAttempts:    c.Retry.MaxAttempts,
+					InitialBackoff: c.Retry.InitialBackoff,
+					MaxBackoff:     c.Retry.MaxBackoff,
+				},
+				KeepaliveTime:    c.Keepalive.Time,
+				KeepaliveTimeout: c.Keepalive.Timeout,
+				Breaker: log_grpc.BreakerConfig{
+					FailureThreshold: c.Breaker.FailureThreshold,
+					OpenTimeout:      c.Breaker.OpenTimeout,
+				},
+				HealthCheck: c.HealthCheck,
+			},
+			Path:       c.Path,
+			MaxSize:    c.MaxSize,
+			MaxBackups: c.MaxBackups,
+		})
+	}
+	return auditsink.New(sinks...)
+}
+
+// newMailer: драйвер обязателен — молча писать письма с токенами в лог на проде нельзя
+func newMailer(cfg config.Mail) (mailer.Sender, error) {
+	switch cfg.Driver {
+	case "smtp":
+		return mailer.NewSMTPMailer(mailer.SMTPConfig{
+			Host:     cfg.SMTP.Host,
+			Port:     cfg.SMTP.Port,
+			Username: cfg.SMTP.Username,
+			Password: cfg.SMTP.Password,
+			From:     cfg.From,
+		}), nil
+	case "file":
+		return mailer.NewFileMailer(cfg.File), nil
+	case "log":
+		return mailer.NewLogMailer(), nil
+	case "":
+		return nil, errors.New("driver is not set (smtp, file or log)")
+	default:
+		return nil, fmt.Errorf("unknown driver %q", cfg.Driver)
+	}
+}
+
+// newPasswordHasher: новые пароли — выбранным алгоритмом, второй остаётся для проверки старых хешей.
+// Незаданные параметры argon2 берутся по умолчанию, недопустимые — ошибка на старте
+func newPasswordHasher(cfg config.Password) (*password.Hasher, error) {
+	bcryptAlg := password.Bcrypt{Cost: cfg.BcryptCost}
+	if err := bcryptAlg.Validate(); err != nil {
+		return nil, err
+	}
+
+	argonAlg := password.DefaultArgon2id
+	if a := cfg.Argon2; a.Memory != 0 {
+		argonAlg.Memory = a.Memory
+	}
+	if a := cfg.Argon2; a.Iterations != 0 {
+		argonAlg.Iterations = a.Iterations
+	}
+	if a := cfg.Argon2; a.Parallelism != 0 {
+		argonAlg.Parallelism = a.Parallelism
+	}
+	if a := cfg.Argon2; a.SaltLength != 0 {
+		argonAlg.SaltLength = a.SaltLength
+	}
+	if a := cfg.Argon2; a.KeyLength != 0 {
+		argonAlg.KeyLength = a.KeyLength
+	}
+	if err := argonAlg.Validate(); err != nil {
+		return nil, err
+	}
+
+	limit := cfg.MaxConcurrent
+	if limit <= 0 {
+		limit = runtime.NumCPU()
+	}
+
+	switch cfg.Algorithm {
+	case "", "bcrypt":
+		return password.NewHasher(bcryptAlg, argonAlg).Limit(limit), nil
+	case "argon2id":
+		return password.NewHasher(argonAlg, bcryptAlg).Limit(limit), nil
+	default:
+		return nil, fmt.Errorf("unknown algorithm %q", cfg.Algorithm)
+	}
+}
+
+func newOIDCProviders(cfg []config.OIDCProvider) map[string]service.OIDCProvider {
+	providers := make(map[string]service.OIDCProvider, len(cfg))
+	for _, p := range cfg {
+		providers[p.Name] = oidc.NewProvider(oidc.Config{
+			Issuer:       p.Issuer,
+			ClientID:     p.ClientID,
+			ClientSecret: p.ClientSecret,
+			RedirectURL:  p.RedirectURL,
+			Scopes:       p.Scopes,
+		}, nil)
+	}
+	return providers
+}
+
+func newTokenKeys(cfg config.JWT, secret []byte) (*jwtkeys.KeySet, error) {
+	if cfg.SigningKey == "" {
+		log.Warn("jwt.signing_key is empty, falling back to HS256 with JWT_SECRET")
+		return jwtkeys.NewHMAC(secret), nil
+	}
+
+	keys := make([]*jwtkeys.Key, 0, len(cfg.Keys))
+	for _, k := range cfg.Keys {
+		var verifyUntil time.Time
+		if k.VerifyUntil != "" {
+			var err error
+			if verifyUntil, err = time.Parse(time.RFC3339, k.VerifyUntil); err != nil {
+				return nil, fmt.Errorf("jwt key %q: verify_until: %w", k.Kid, err)
+			}
+		}
+
+		key, err := jwtkeys.LoadKey(k.Kid, k.File, verifyUntil)
+		if err != nil {
+			return nil, err
+		}
+		keys = append(keys, key)
+	}
+	return jwtkeys.New(cfg.SigningKey, keys...)
 }
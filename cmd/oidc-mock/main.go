@@ -0,0 +1,33 @@
+// oidc-mock — локальный OpenID Connect провайдер для ручной проверки входа через внешний аккаунт.
+// Каждая авторизация сразу подтверждается от имени пользователя из флагов
+package main
+
+import (
+	"flag"
+	"net/http"
+
+	"github.com/CryptoGu1/books-rest-clean-arch/pkg/oidc/oidctest"
+	log "github.com/sirupsen/logrus"
+)
+
+func main() {
+	addr := flag.String("addr", "localhost:9999", "listen address")
+	issuer := flag.String("issuer", "http://localhost:9999", "issuer URL as seen by the app")
+	clientID := flag.String("client-id", "books", "client id")
+	clientSecret := flag.String("client-secret", "books-secret", "client secret")
+	email := flag.String("email", "reader@example.com", "email of the signed-in user")
+	name := flag.String("name", "Local Reader", "name of the signed-in user")
+	flag.Parse()
+
+	provider, err := oidctest.New(*issuer, *clientID, *clientSecret)
+	if err != nil {
+		log.Fatal(err)
+	}
+	provider.SetUser(oidctest.User{Subject: *email, Email: *email, EmailVerified: true, Name: *name})
+
+	log.WithFields(log.Fields{
+		"addr":   *addr,
+		"issuer": *issuer,
+	}).Info("mock OIDC provider started")
+	log.Fatal(http.ListenAndServe(*addr, provider))
+}
@@ -4,6 +4,7 @@ go 1.25.0
 
 require (
 	github.com/CryptoGu1/books-grpc-log v0.0.0-20251130103545-19483be1fcdf
+	github.com/DATA-DOG/go-sqlmock v1.5.2
 	github.com/go-playground/validator/v10 v10.27.0
 	github.com/golang-jwt/jwt/v5 v5.3.0
 	github.com/jmoiron/sqlx v1.4.0
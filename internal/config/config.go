@@ -1,6 +1,10 @@
 package config
 
 import (
+	"os"
+	"strings"
+	"time"
+
 	"github.com/joho/godotenv"
 	"github.com/kelseyhightower/envconfig"
 	"github.com/spf13/viper"
@@ -11,7 +15,179 @@ type Config struct {
 
 	Server struct {
 		Port int `mapstructure:"port"`
+		// TrustedProxies — CIDR обратных прокси, от которых принимается X-Forwarded-For
+		TrustedProxies []string `mapstructure:"trusted_proxies"`
 	} `mapstructure:"server"`
+
+	Search struct {
+		Language string `mapstructure:"language"`
+	} `mapstructure:"search"`
+
+	Mail Mail `mapstructure:"mail"`
+
+	Audit Audit `mapstructure:"audit"`
+
+	JWT JWT `mapstructure:"jwt"`
+
+	Auth struct {
+		// UnverifiedPolicy — allow, deny или read_only (см. domain.UnverifiedPolicy)
+		UnverifiedPolicy string  `mapstructure:"unverified_policy"`
+		VerifyURL        string  `mapstructure:"verify_url"`
+		Lockout          Lockout `mapstructure:"lockout"`
+		MFA              struct {
+			// Issuer — имя сервиса, которое увидит пользователь в приложении-аутентификаторе
+			Issuer string `mapstructure:"issuer"`
+		} `mapstructure:"mfa"`
+		OIDC struct {
+			Providers []OIDCProvider `mapstructure:"providers"`
+		} `mapstructure:"oidc"`
+		Cookie struct {
+			Secure bool `mapstructure:"secure"`
+			// SameSite — strict, lax или none (none требует secure)
+			SameSite string `mapstructure:"same_site"`
+			Domain   string `mapstructure:"domain"`
+		} `mapstructure:"cookie"`
+		Password Password `mapstructure:"password"`
+		// BodyTokens — refresh-токен в теле запроса/ответа для клиентов без cookie
+		BodyTokens bool `mapstructure:"body_tokens"`
+		// LegacyGetSignIn — оставить устаревший GET /auth/sign-in
+		LegacyGetSignIn bool `mapstructure:"legacy_get_sign_in"`
+	} `mapstructure:"auth"`
+}
+
+// JWT — ключи подписи access-токенов. Без ключей используется HS256 с JWT_SECRET
+type JWT struct {
+	// SigningKey — kid ключа, которым подписываются новые токены
+	SigningKey string   `mapstructure:"signing_key"`
+	Keys       []JWTKey `mapstructure:"keys"`
+}
+
+type JWTKey struct {
+	Kid  string `mapstructure:"kid"`
+	File string `mapstructure:"file"`
+	// VerifyUntil (RFC 3339) — конец окна ротации для выведенного из оборота ключа
+	VerifyUntil string `mapstructure:"verify_until"`
+}
+
+// OIDCProvider — внешний провайдер входа. ClientSecret можно не хранить в файле,
+// а передать через OIDC_<NAME>_CLIENT_SECRET
+type OIDCProvider struct {
+	Name         string   `mapstructure:"name"`
+	Issuer       string   `mapstructure:"issuer"`
+	ClientID     string   `mapstructure:"client_id"`
+	ClientSecret string   `mapstructure:"client_secret"`
+	RedirectURL  string   `mapstructure:"redirect_url"`
+	Scopes       []string `mapstructure:"scopes"`
+}
+
+// Password — хеширование паролей. Хеши прежнего алгоритма продолжают проверяться
+// и пересчитываются текущим при входе
+type Password struct {
+	// Algorithm — argon2id или bcrypt
+	Algorithm  string `mapstructure:"algorithm"`
+	BcryptCost int    `mapstructure:"bcrypt_cost"`
+	// MaxConcurrent — сколько хешей считается одновременно; 0 — по числу CPU
+	MaxConcurrent int `mapstructure:"max_concurrent"`
+	// нулевые поля Argon2 берутся из password.DefaultArgon2id
+	Argon2 struct {
+		// Memory — в KiB
+		Memory      uint32 `mapstructure:"memory"`
+		Iterations  uint32 `mapstructure:"iterations"`
+		Parallelism uint8  `mapstructure:"parallelism"`
+		SaltLength  uint32 `mapstructure:"salt_length"`
+		KeyLength   uint32 `mapstructure:"key_length"`
+	} `mapstructure:"argon2"`
+}
+
+// Audit — асинхронная отправка аудит-событий. Нулевые значения заменяются умолчаниями auditq
+type Audit struct {
+	// Sinks — куда отправлять события; несколько — в каждый. Пусто — сервис логов по gRPC
+	Sinks       []AuditSink   `mapstructure:"sinks"`
+	QueueSize   int           `mapstructure:"queue_size"`
+	Workers     int           `mapstructure:"workers"`
+	BatchSize   int           `mapstructure:"batch_size"`
+	MaxRetries  int           `mapstructure:"max_retries"`
+	BaseBackoff time.Duration `mapstructure:"base_backoff"`
+	MaxBackoff  time.Duration `mapstructure:"max_backoff"`
+	// OutboxFile — куда складываются события, которые не удалось отправить
+	OutboxFile     string        `mapstructure:"outbox_file"`
+	ReplayInterval time.Duration `mapstructure:"replay_interval"`
+	// ShutdownTimeout — сколько ждать отправки очереди при остановке
+	ShutdownTimeout time.Duration `mapstructure:"shutdown_timeout"`
+	// Relay — доставка событий из таблицы audit_outbox (изменения книг)
+	Relay struct {
+		BatchSize    int           `mapstructure:"batch_size"`
+		PollInterval time.Duration `mapstructure:"poll_interval"`
+		BaseBackoff  time.Duration `mapstructure:"base_backoff"`
+		MaxBackoff   time.Duration `mapstructure:"max_backoff"`
+		// Lease — сколько пачка закреплена за репликой; 0 — по размеру пачки
+		Lease     time.Duration `mapstructure:"lease"`
+		Retention time.Duration `mapstructure:"retention"`
+	} `mapstructure:"relay"`
+}
+
+// AuditSink — один получатель аудита (см. auditsink.Config)
+type AuditSink struct {
+	// Type — grpc, file, stdout или noop
+	Type string `mapstructure:"type"`
+	// Host для grpc можно задать через LOG_GRPC_HOST
+	Host string `mapstructure:"host"`
+	Port int    `mapstructure:"port"`
+	// Timeout — дедлайн одного вызова сервиса логов
+	Timeout time.Duration `mapstructure:"timeout"`
+	TLS     struct {
+		Enabled bool   `mapstructure:"enabled"`
+		CAFile  string `mapstructure:"ca_file"`
+		// CertFile и KeyFile — клиентский сертификат для mTLS
+		CertFile   string `mapstructure:"cert_file"`
+		KeyFile    string `mapstructure:"key_file"`
+		ServerName string `mapstructure:"server_name"`
+	} `mapstructure:"tls"`
+	Retry struct {
+		MaxAttempts    int           `mapstructure:"max_attempts"`
+		InitialBackoff time.Duration `mapstructure:"initial_backoff"`
+		MaxBackoff     time.Duration `mapstructure:"max_backoff"`
+	} `mapstructure:"retry"`
+	Keepalive struct {
+		Time    time.Duration `mapstructure:"time"`
+		Timeout time.Duration `mapstructure:"timeout"`
+	} `mapstructure:"keepalive"`
+	Breaker struct {
+		FailureThreshold int           `mapstructure:"failure_threshold"`
+		OpenTimeout      time.Duration `mapstructure:"open_timeout"`
+	} `mapstructure:"breaker"`
+	HealthCheck bool `mapstructure:"health_check"`
+
+	Path       string `mapstructure:"path"`
+	MaxSize    int64  `mapstructure:"max_size"`
+	MaxBackups int    `mapstructure:"max_backups"`
+}
+
+type Lockout struct {
+	// Store — где хранить счётчики: memory или postgres
+	Store           string        `mapstructure:"store"`
+	FreeAttempts    int           `mapstructure:"free_attempts"`
+	MaxFailures     int           `mapstructure:"max_failures"`
+	BaseDelay       time.Duration `mapstructure:"base_delay"`
+	MaxDelay        time.Duration `mapstructure:"max_delay"`
+	LockoutDuration time.Duration `mapstructure:"lockout_duration"`
+	Window          time.Duration `mapstructure:"window"`
+}
+
+type Mail struct {
+	// Driver — smtp, file или log; обязателен
+	Driver string `mapstructure:"driver"`
+	File   string `mapstructure:"file"`
+	From   string `mapstructure:"from"`
+	// QueueSize и Workers — фоновая отправка писем
+	QueueSize int `mapstructure:"queue_size"`
+	Workers   int `mapstructure:"workers"`
+	SMTP      struct {
+		Host     string `mapstructure:"host"`
+		Port     int    `mapstructure:"port"`
+		Username string `mapstructure:"username"`
+		Password string `envconfig:"SMTP_PASSWORD"`
+	} `mapstructure:"smtp"`
 }
 
 type Postgres struct {
@@ -43,5 +219,26 @@ func New(folder, filename string) (*Config, error) {
 		return nil, err
 	}
 
+	if err := envconfig.Process("smtp", &cfg.Mail.SMTP); err != nil {
+		return nil, err
+	}
+
+	for i, p := range cfg.Auth.OIDC.Providers {
+		if secret := os.Getenv("OIDC_" + strings.ToUpper(p.Name) + "_CLIENT_SECRET"); secret != "" {
+			cfg.Auth.OIDC.Providers[i].ClientSecret = secret
+		}
+	}
+
+	if len(cfg.Audit.Sinks) == 0 {
+		cfg.Audit.Sinks = []AuditSink{{Type: "grpc"}}
+	}
+	if host := os.Getenv("LOG_GRPC_HOST"); host != "" {
+		for i, sink := range cfg.Audit.Sinks {
+			if sink.Type == "grpc" {
+				cfg.Audit.Sinks[i].Host = host
+			}
+		}
+	}
+
 	return cfg, nil
 }
@@ -0,0 +1,54 @@
+package domain
+
+import (
+	"strings"
+	"time"
+)
+
+const (
+	// APIKeyPrefix помогает узнать ключ в логах и сканерах секретов
+	APIKeyPrefix = "bk_"
+	// apiKeyDisplayLen — сколько символов ключа (после APIKeyPrefix) показываем в списке
+	apiKeyDisplayLen = 8
+	// APIKeyTouchInterval — last_used_at обновляется не чаще, чтобы не писать в базу на каждый запрос
+	APIKeyTouchInterval = time.Minute
+)
+
+type APIKey struct {
+	ID         int64      `json:"id"`
+	UserID     int64      `json:"-"`
+	Name       string     `json:"name"`
+	Prefix     string     `json:"prefix"`
+	KeyHash    string     `json:"-"`
+	Scopes     []string   `json:"scopes"`
+	CreatedAt  time.Time  `json:"created_at"`
+	LastUsedAt *time.Time `json:"last_used_at,omitempty"`
+	ExpiresAt  *time.Time `json:"expires_at,omitempty"`
+	RevokedAt  *time.Time `json:"revoked_at,omitempty"`
+}
+
+func (k APIKey) Active(now time.Time) bool {
+	return k.RevokedAt == nil && (k.ExpiresAt == nil || now.Before(*k.ExpiresAt))
+}
+
+// CreatedAPIKey — ответ на создание ключа: сам ключ показывается один раз
+type CreatedAPIKey struct {
+	APIKey
+	Key string `json:"key"`
+}
+
+type CreateAPIKeyInput struct {
+	Name string `json:"name" validate:"required,lte=100"`
+	// Scopes — права ключа; берутся только те, что есть у владельца. Пустой список — только чтение
+	Scopes    []string   `json:"scopes"`
+	ExpiresAt *time.Time `json:"expires_at" validate:"omitempty,gt"`
+}
+
+// APIKeyDisplayPrefix — видимая часть ключа, например "bk_1a2b3c4d"
+func APIKeyDisplayPrefix(key string) string {
+	rest := strings.TrimPrefix(key, APIKeyPrefix)
+	if len(rest) > apiKeyDisplayLen {
+		rest = rest[:apiKeyDisplayLen]
+	}
+	return APIKeyPrefix + rest
+}
@@ -0,0 +1,17 @@
+package domain
+
+import "time"
+
+// AuditEvent — аудит-событие в transactional outbox. Записывается в одной транзакции
+// с изменением, которое описывает, и доставляется на сервер логов фоновым relay
+type AuditEvent struct {
+	// ID — ключ идемпотентности: при повторной доставке сервер логов узнаёт событие по нему
+	ID       string `db:"event_id"`
+	Entity   string `db:"entity"`
+	Action   string `db:"action"`
+	EntityID int64  `db:"entity_id"`
+	// Metadata — исходящая gRPC metadata запроса (изменённые поля и т.п.)
+	Metadata   map[string][]string `db:"-"`
+	OccurredAt time.Time           `db:"occurred_at"`
+	Attempts   int                 `db:"attempts"`
+}
@@ -42,6 +42,7 @@ type Book struct {
 	Author      string    `db:"author" json:"author"`
 	PublishDate time.Time `db:"publish_date" json:"publish_date"`
 	Rating      int       `db:"rating" json:"rating"`
+	CreatedBy   *int64    `db:"created_by" json:"created_by,omitempty"`
 }
 
 type CreateBookInput struct {
@@ -74,17 +75,17 @@ func (input *CreateBookInput) ToBook() *Book {
 	return book
 }
 
-func (input *UpdateBookInput) ToBook() *Book {
-	book := &Book{
-		Title:  input.Title,
-		Author: input.Author,
-		Rating: input.Rating,
+// ToPatch: без publish_date дата публикации остаётся прежней, а не сбрасывается
+func (input *UpdateBookInput) ToPatch() BookPatch {
+	patch := BookPatch{
+		Title:  &input.Title,
+		Author: &input.Author,
+		Rating: &input.Rating,
 	}
 
 	if input.PublishDate != nil {
-		book.PublishDate = input.PublishDate.ToTime()
-	} else {
-		book.PublishDate = time.Now().UTC()
+		date := input.PublishDate.ToTime()
+		patch.PublishDate = &date
 	}
-	return book
+	return patch
 }
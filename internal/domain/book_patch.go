@@ -0,0 +1,257 @@
+package domain
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"reflect"
+	"strings"
+	"time"
+)
+
+const dateLayout = "2006-01-02"
+
+// BookPatch — частичное обновление книги: nil означает «поле не трогать»
+type BookPatch struct {
+	Title       *string
+	Author      *string
+	PublishDate *time.Time
+	Rating      *int
+}
+
+// JSONPatchOp — операция RFC 6902
+type JSONPatchOp struct {
+	Op    string          `json:"op"`
+	Path  string          `json:"path"`
+	From  string          `json:"from,omitempty"`
+	Value json.RawMessage `json:"value,omitempty"`
+}
+
+// поля книги, доступные для изменения через PATCH
+var patchableBookFields = map[string]struct{}{
+	"title":        {},
+	"author":       {},
+	"publish_date": {},
+	"rating":       {},
+}
+
+func (p BookPatch) IsEmpty() bool {
+	return p.Title == nil && p.Author == nil && p.PublishDate == nil && p.Rating == nil
+}
+
+func (p BookPatch) Validate() error {
+	if p.Title != nil && strings.TrimSpace(*p.Title) == "" {
+		return fmt.Errorf("%w: title must not be empty", ErrInvalidPatch)
+	}
+	if p.Author != nil && strings.TrimSpace(*p.Author) == "" {
+		return fmt.Errorf("%w: author must not be empty", ErrInvalidPatch)
+	}
+	if p.Rating != nil && (*p.Rating < 1 || *p.Rating > 5) {
+		return fmt.Errorf("%w: rating must be between 1 and 5", ErrInvalidPatch)
+	}
+	return nil
+}
+
+// FieldChange — значение поля до и после изменения
+type FieldChange struct {
+	Before interface{} `json:"before"`
+	After  interface{} `json:"after"`
+}
+
+// поля книги в порядке, в котором они перечисляются в аудите
+var bookDiffOrder = []string{"title", "author", "publish_date", "rating"}
+
+// Diff возвращает поля патча, значения которых отличаются от прежней версии книги, с обоими значениями
+func (p BookPatch) Diff(old *Book) map[string]FieldChange {
+	diff := make(map[string]FieldChange)
+	if p.Title != nil && *p.Title != old.Title {
+		diff["title"] = FieldChange{Before: old.Title, After: *p.Title}
+	}
+	if p.Author != nil && *p.Author != old.Author {
+		diff["author"] = FieldChange{Before: old.Author, After: *p.Author}
+	}
+	if p.PublishDate != nil && !p.PublishDate.Equal(old.PublishDate) {
+		diff["publish_date"] = FieldChange{Before: old.PublishDate.Format(dateLayout), After: p.PublishDate.Format(dateLayout)}
+	}
+	if p.Rating != nil && *p.Rating != old.Rating {
+		diff["rating"] = FieldChange{Before: old.Rating, After: *p.Rating}
+	}
+	return diff
+}
+
+// ChangedFields возвращает поля патча, значения которых отличаются от прежней версии книги
+func (p BookPatch) ChangedFields(old *Book) []string {
+	diff := p.Diff(old)
+	var fields []string
+	for _, field := range bookDiffOrder {
+		if _, ok := diff[field]; ok {
+			fields = append(fields, field)
+		}
+	}
+	return fields
+}
+
+// ParseMergePatch разбирает тело RFC 7396. Все поля книги обязательные,
+// поэтому null (удаление поля) и неизвестные поля считаются ошибкой
+func ParseMergePatch(data []byte) (BookPatch, error) {
+	var patch BookPatch
+
+	var doc map[string]json.RawMessage
+	if err := json.Unmarshal(data, &doc); err != nil || doc == nil {
+		return patch, fmt.Errorf("%w: body must be a JSON object", ErrInvalidPatch)
+	}
+
+	for field, raw := range doc {
+		if _, ok := patchableBookFields[field]; !ok {
+			return patch, fmt.Errorf("%w: field %q cannot be patched", ErrInvalidPatch, field)
+		}
+		if bytes.Equal(bytes.TrimSpace(raw), []byte("null")) {
+			return patch, fmt.Errorf("%w: field %q cannot be removed", ErrInvalidPatch, field)
+		}
+
+		var err error
+		switch field {
+		case "title":
+			err = json.Unmarshal(raw, &patch.Title)
+		case "author":
+			err = json.Unmarshal(raw, &patch.Author)
+		case "rating":
+			err = json.Unmarshal(raw, &patch.Rating)
+		case "publish_date":
+			var s string
+			if err = json.Unmarshal(raw, &s); err == nil {
+				var t time.Time
+				t, err = time.Parse(dateLayout, s)
+				t = t.UTC()
+				patch.PublishDate = &t
+			}
+		}
+		if err != nil {
+			return patch, fmt.Errorf("%w: invalid value for %q", ErrInvalidPatch, field)
+		}
+	}
+
+	return patch, patch.Validate()
+}
+
+// ApplyJSONPatch применяет операции RFC 6902 к текущему состоянию книги
+// и возвращает BookPatch только по полям, которые в итоге изменились
+func ApplyJSONPatch(book *Book, ops []JSONPatchOp) (BookPatch, error) {
+	doc, err := bookDocument(book)
+	if err != nil {
+		return BookPatch{}, err
+	}
+	original := make(map[string]json.RawMessage, len(doc))
+	for k, v := range doc {
+		original[k] = v
+	}
+
+	for i, op := range ops {
+		if err := applyOp(doc, op); err != nil {
+			return BookPatch{}, fmt.Errorf("operation %d: %w", i, err)
+		}
+	}
+
+	changed := make(map[string]json.RawMessage)
+	for field := range patchableBookFields {
+		value, ok := doc[field]
+		if !ok {
+			return BookPatch{}, fmt.Errorf("%w: field %q cannot be removed", ErrInvalidPatch, field)
+		}
+		if !jsonEqual(value, original[field]) {
+			changed[field] = value
+		}
+	}
+
+	data, err := json.Marshal(changed)
+	if err != nil {
+		return BookPatch{}, err
+	}
+	return ParseMergePatch(data)
+}
+
+func applyOp(doc map[string]json.RawMessage, op JSONPatchOp) error {
+	field, err := patchPointer(op.Path)
+	if err != nil {
+		return err
+	}
+
+	switch op.Op {
+	case "add", "replace":
+		if op.Value == nil {
+			return fmt.Errorf("%w: %s requires a value", ErrInvalidPatch, op.Op)
+		}
+		if _, ok := doc[field]; !ok && op.Op == "replace" {
+			return fmt.Errorf("%w: path %q does not exist", ErrInvalidPatch, op.Path)
+		}
+		doc[field] = op.Value
+	case "remove":
+		if _, ok := doc[field]; !ok {
+			return fmt.Errorf("%w: path %q does not exist", ErrInvalidPatch, op.Path)
+		}
+		delete(doc, field)
+	case "test":
+		if value, ok := doc[field]; !ok || !jsonEqual(value, op.Value) {
+			return fmt.Errorf("%w: %s", ErrPatchTestFailed, op.Path)
+		}
+	case "move", "copy":
+		from, err := patchPointer(op.From)
+		if err != nil {
+			return err
+		}
+		value, ok := doc[from]
+		if !ok {
+			return fmt.Errorf("%w: path %q does not exist", ErrInvalidPatch, op.From)
+		}
+		if op.Op == "move" {
+			delete(doc, from)
+		}
+		doc[field] = value
+	default:
+		return fmt.Errorf("%w: unsupported op %q", ErrInvalidPatch, op.Op)
+	}
+	return nil
+}
+
+// patchPointer принимает только JSON Pointer на поле верхнего уровня, например "/title"
+func patchPointer(path string) (string, error) {
+	field, ok := strings.CutPrefix(path, "/")
+	if !ok || strings.Contains(field, "/") {
+		return "", fmt.Errorf("%w: invalid path %q", ErrInvalidPatch, path)
+	}
+	field = strings.NewReplacer("~1", "/", "~0", "~").Replace(field)
+
+	if _, ok := patchableBookFields[field]; !ok {
+		return "", fmt.Errorf("%w: field %q cannot be patched", ErrInvalidPatch, field)
+	}
+	return field, nil
+}
+
+// bookDocument — JSON-представление книги, к которому применяются операции;
+// publish_date в том же формате, что принимает API
+func bookDocument(book *Book) (map[string]json.RawMessage, error) {
+	values := map[string]interface{}{
+		"title":        book.Title,
+		"author":       book.Author,
+		"publish_date": book.PublishDate.Format(dateLayout),
+		"rating":       book.Rating,
+	}
+
+	doc := make(map[string]json.RawMessage, len(values))
+	for k, v := range values {
+		raw, err := json.Marshal(v)
+		if err != nil {
+			return nil, err
+		}
+		doc[k] = raw
+	}
+	return doc, nil
+}
+
+func jsonEqual(a, b json.RawMessage) bool {
+	var va, vb interface{}
+	if json.Unmarshal(a, &va) != nil || json.Unmarshal(b, &vb) != nil {
+		return false
+	}
+	return reflect.DeepEqual(va, vb)
+}
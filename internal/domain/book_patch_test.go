@@ -0,0 +1,109 @@
+package domain
+
+import (
+	"encoding/json"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestParseMergePatch(t *testing.T) {
+	testTable := []struct {
+		name          string
+		body          string
+		expectedError error
+		check         func(t *testing.T, p BookPatch)
+	}{
+		{
+			name: "partial",
+			body: `{"rating": 4}`,
+			check: func(t *testing.T, p BookPatch) {
+				require.NotNil(t, p.Rating)
+				assert.Equal(t, 4, *p.Rating)
+				assert.Nil(t, p.Title)
+				assert.Nil(t, p.PublishDate)
+			},
+		},
+		{
+			name: "date",
+			body: `{"publish_date": "1869-01-01"}`,
+			check: func(t *testing.T, p BookPatch) {
+				require.NotNil(t, p.PublishDate)
+				assert.Equal(t, time.Date(1869, 1, 1, 0, 0, 0, 0, time.UTC), *p.PublishDate)
+			},
+		},
+		{name: "null removes required field", body: `{"title": null}`, expectedError: ErrInvalidPatch},
+		{name: "unknown field", body: `{"id": 5}`, expectedError: ErrInvalidPatch},
+		{name: "wrong type", body: `{"rating": "five"}`, expectedError: ErrInvalidPatch},
+		{name: "rating out of range", body: `{"rating": 9}`, expectedError: ErrInvalidPatch},
+		{name: "empty title", body: `{"title": " "}`, expectedError: ErrInvalidPatch},
+		{name: "not an object", body: `[1]`, expectedError: ErrInvalidPatch},
+	}
+
+	for _, testCase := range testTable {
+		t.Run(testCase.name, func(t *testing.T) {
+			patch, err := ParseMergePatch([]byte(testCase.body))
+			if testCase.expectedError != nil {
+				assert.ErrorIs(t, err, testCase.expectedError)
+				return
+			}
+			require.NoError(t, err)
+			testCase.check(t, patch)
+		})
+	}
+}
+
+func TestApplyJSONPatch(t *testing.T) {
+	book := &Book{
+		ID:          1,
+		Title:       "War and Peace",
+		Author:      "Tolstoy",
+		PublishDate: time.Date(1869, 1, 1, 0, 0, 0, 0, time.UTC),
+		Rating:      4,
+	}
+
+	ops := func(s string) []JSONPatchOp {
+		var o []JSONPatchOp
+		require.NoError(t, json.Unmarshal([]byte(s), &o))
+		return o
+	}
+
+	t.Run("replace with passing test", func(t *testing.T) {
+		patch, err := ApplyJSONPatch(book, ops(`[
+			{"op": "test", "path": "/rating", "value": 4},
+			{"op": "replace", "path": "/rating", "value": 5},
+			{"op": "replace", "path": "/title", "value": "War and Peace"}
+		]`))
+		require.NoError(t, err)
+		require.NotNil(t, patch.Rating)
+		assert.Equal(t, 5, *patch.Rating)
+		// значение не поменялось — поля нет в патче
+		assert.Nil(t, patch.Title)
+		assert.Equal(t, []string{"rating"}, patch.ChangedFields(book))
+		assert.Equal(t, map[string]FieldChange{"rating": {Before: 4, After: 5}}, patch.Diff(book))
+	})
+
+	t.Run("failed test", func(t *testing.T) {
+		_, err := ApplyJSONPatch(book, ops(`[{"op": "test", "path": "/rating", "value": 1}]`))
+		assert.ErrorIs(t, err, ErrPatchTestFailed)
+	})
+
+	t.Run("copy", func(t *testing.T) {
+		patch, err := ApplyJSONPatch(book, ops(`[{"op": "copy", "from": "/author", "path": "/title"}]`))
+		require.NoError(t, err)
+		require.NotNil(t, patch.Title)
+		assert.Equal(t, "Tolstoy", *patch.Title)
+	})
+
+	t.Run("remove required field", func(t *testing.T) {
+		_, err := ApplyJSONPatch(book, ops(`[{"op": "remove", "path": "/author"}]`))
+		assert.ErrorIs(t, err, ErrInvalidPatch)
+	})
+
+	t.Run("id is read only", func(t *testing.T) {
+		_, err := ApplyJSONPatch(book, ops(`[{"op": "replace", "path": "/id", "value": 2}]`))
+		assert.ErrorIs(t, err, ErrInvalidPatch)
+	})
+}
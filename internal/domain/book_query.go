@@ -0,0 +1,188 @@
+package domain
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"strings"
+	"time"
+)
+
+const (
+	DefaultBooksLimit = 20
+	MaxBooksLimit     = 100
+)
+
+// поля, по которым разрешена сортировка списка книг
+var bookSortFields = map[string]struct{}{
+	"id":           {},
+	"title":        {},
+	"author":       {},
+	"publish_date": {},
+	"rating":       {},
+}
+
+type SortField struct {
+	Field string
+	Desc  bool
+}
+
+// BookQuery описывает пагинацию, фильтры и сортировку для списка книг
+type BookQuery struct {
+	Limit  int `validate:"min=1,max=100"`
+	Offset int `validate:"min=0"`
+
+	Author        string
+	Title         string
+	MinRating     *int `validate:"omitempty,min=0,max=5"`
+	MaxRating     *int `validate:"omitempty,min=0,max=5"`
+	PublishedFrom *time.Time
+	PublishedTo   *time.Time
+	CreatedBy     *int64
+
+	Sort []SortField
+}
+
+type BookPage struct {
+	Items  []*Book `json:"items"`
+	Total  int     `json:"total"`
+	Limit  int     `json:"limit"`
+	Offset int     `json:"offset"`
+}
+
+// BookKeyset — позиция последней отданной книги при keyset-пагинации:
+// значение колонки сортировки и id как тай-брейкер
+type BookKeyset struct {
+	Value interface{}
+	ID    int
+}
+
+type BookCursorPage struct {
+	Items      []*Book `json:"items"`
+	Limit      int     `json:"limit"`
+	NextCursor string  `json:"next_cursor,omitempty"`
+}
+
+type BookSearchQuery struct {
+	Query  string `validate:"required,max=200"`
+	Limit  int    `validate:"min=1,max=100"`
+	Offset int    `validate:"min=0"`
+}
+
+type BookHighlight struct {
+	Title  string `db:"title" json:"title"`
+	Author string `db:"author" json:"author"`
+}
+
+// BookSearchResult — книга с релевантностью и подсвеченными (<mark>) совпадениями.
+// Highlight — готовый HTML: текст экранирован, теги только <mark>
+type BookSearchResult struct {
+	Book
+	Rank      float64       `db:"rank" json:"rank"`
+	Highlight BookHighlight `db:"highlight" json:"highlight"`
+}
+
+type BookSearchPage struct {
+	Items  []*BookSearchResult `json:"items"`
+	Total  int                 `json:"total"`
+	Limit  int                 `json:"limit"`
+	Offset int                 `json:"offset"`
+}
+
+func (q BookSearchQuery) Validate() error {
+	return validate.Struct(q)
+}
+
+func (q BookQuery) Validate() error {
+	if err := validate.Struct(q); err != nil {
+		return err
+	}
+	if q.MinRating != nil && q.MaxRating != nil && *q.MinRating > *q.MaxRating {
+		return fmt.Errorf("min_rating must not exceed max_rating")
+	}
+	if q.PublishedFrom != nil && q.PublishedTo != nil && q.PublishedFrom.After(*q.PublishedTo) {
+		return fmt.Errorf("published_from must not be after published_to")
+	}
+	return nil
+}
+
+// FilterKey — отпечаток фильтров и сортировки без пагинации: курсор, выданный для одного
+// набора фильтров, нельзя продолжить с другим
+func (q BookQuery) FilterKey() string {
+	b, _ := json.Marshal(struct {
+		Author        string
+		Title         string
+		MinRating     *int
+		MaxRating     *int
+		PublishedFrom *time.Time
+		PublishedTo   *time.Time
+		CreatedBy     *int64
+		Sort          []SortField
+	}{q.Author, q.Title, q.MinRating, q.MaxRating, q.PublishedFrom, q.PublishedTo, q.CreatedBy, q.Sort})
+	sum := sha256.Sum256(b)
+	return hex.EncodeToString(sum[:8])
+}
+
+// ParseSort разбирает строку вида "title,-rating": минус означает сортировку по убыванию
+func ParseSort(s string) ([]SortField, error) {
+	if strings.TrimSpace(s) == "" {
+		return nil, nil
+	}
+
+	var fields []SortField
+	for _, part := range strings.Split(s, ",") {
+		part = strings.TrimSpace(part)
+		desc := strings.HasPrefix(part, "-")
+		name := strings.TrimPrefix(part, "-")
+
+		if _, ok := bookSortFields[name]; !ok {
+			return nil, fmt.Errorf("unknown sort field %q", name)
+		}
+		fields = append(fields, SortField{Field: name, Desc: desc})
+	}
+	return fields, nil
+}
+
+func (f SortField) String() string {
+	if f.Desc {
+		return "-" + f.Field
+	}
+	return f.Field
+}
+
+// SortValue возвращает значение поля книги, по которому идёт сортировка
+func (b *Book) SortValue(field string) interface{} {
+	switch field {
+	case "title":
+		return b.Title
+	case "author":
+		return b.Author
+	case "publish_date":
+		return b.PublishDate
+	case "rating":
+		return b.Rating
+	default:
+		return b.ID
+	}
+}
+
+// ParseSortValue восстанавливает типизированное значение сортировки из JSON курсора
+func ParseSortValue(field string, raw json.RawMessage) (interface{}, error) {
+	var err error
+	switch field {
+	case "title", "author":
+		var v string
+		err = json.Unmarshal(raw, &v)
+		return v, err
+	case "publish_date":
+		var v time.Time
+		err = json.Unmarshal(raw, &v)
+		return v, err
+	case "id", "rating":
+		var v int
+		err = json.Unmarshal(raw, &v)
+		return v, err
+	}
+	return nil, fmt.Errorf("unknown sort field %q", field)
+}
@@ -0,0 +1,22 @@
+package domain
+
+import "context"
+
+// ClientInfo — сведения о клиенте, от которого пришёл запрос
+type ClientInfo struct {
+	IP        string
+	UserAgent string
+	// RequestID — X-Request-ID запроса, связывает аудит-события с логами
+	RequestID string
+}
+
+type clientInfoKey struct{}
+
+func WithClientInfo(ctx context.Context, info ClientInfo) context.Context {
+	return context.WithValue(ctx, clientInfoKey{}, info)
+}
+
+func ClientInfoFromContext(ctx context.Context) ClientInfo {
+	info, _ := ctx.Value(clientInfoKey{}).(ClientInfo)
+	return info
+}
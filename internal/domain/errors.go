@@ -4,5 +4,27 @@ import "errors"
 
 var (
 	ErrBookNotFound         = errors.New("book not found")
+	ErrForbidden            = errors.New("forbidden")
+	ErrRoleNotFound         = errors.New("role not found")
+	ErrUserNotFound         = errors.New("user not found")
 	ErrRefreshTokenNotFound = errors.New("refresh token expired")
+	ErrRefreshTokenReused   = errors.New("refresh token reuse detected")
+	ErrSessionNotFound      = errors.New("session not found")
+	ErrInvalidResetToken    = errors.New("invalid or expired reset token")
+	ErrEmailNotVerified     = errors.New("email not verified")
+	ErrInvalidVerification  = errors.New("invalid or expired verification link")
+	ErrEmailTaken           = errors.New("email already in use")
+	ErrInvalidPassword      = errors.New("invalid password")
+	ErrAPIKeyNotFound       = errors.New("api key not found")
+	ErrInvalidAPIKey        = errors.New("invalid api key")
+	ErrMFANotEnabled        = errors.New("two-factor authentication is not enabled")
+	ErrMFAAlreadyEnabled    = errors.New("two-factor authentication is already enabled")
+	ErrInvalidMFACode       = errors.New("invalid two-factor code")
+	ErrInvalidMFAToken      = errors.New("invalid or expired mfa token")
+	ErrUnknownProvider      = errors.New("unknown identity provider")
+	ErrIdentityNotFound     = errors.New("identity not linked")
+	ErrOIDCFailed           = errors.New("external sign-in failed")
+	ErrInvalidCursor        = errors.New("invalid cursor")
+	ErrInvalidPatch         = errors.New("invalid patch")
+	ErrPatchTestFailed      = errors.New("patch test failed")
 )
@@ -0,0 +1,40 @@
+package domain
+
+import (
+	"time"
+
+	"github.com/golang-jwt/jwt/v5"
+)
+
+const (
+	// OIDCStateTTL — сколько у пользователя есть времени на вход у провайдера
+	OIDCStateTTL      = 10 * time.Minute
+	OIDCStateAudience = "oidc-state"
+)
+
+// UserIdentity — аккаунт пользователя у внешнего провайдера
+type UserIdentity struct {
+	Provider  string    `db:"provider"`
+	Subject   string    `db:"subject"`
+	UserID    int64     `db:"user_id"`
+	Email     string    `db:"email"`
+	CreatedAt time.Time `db:"created_at"`
+}
+
+// OIDCStateClaims — то, что нужно сохранить между редиректом к провайдеру и возвратом.
+// Хранится у клиента в подписанной cookie, поэтому сервер остаётся без состояния
+type OIDCStateClaims struct {
+	jwt.RegisteredClaims
+	Provider string `json:"provider"`
+	State    string `json:"state"`
+	Nonce    string `json:"nonce"`
+	Verifier string `json:"verifier"`
+}
+
+type OIDCCallbackInput struct {
+	Provider string
+	Code     string
+	State    string
+	// StateToken — значение cookie, выставленной при OIDCLogin
+	StateToken string
+}
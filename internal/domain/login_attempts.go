@@ -0,0 +1,96 @@
+package domain
+
+import (
+	"errors"
+	"fmt"
+	"math"
+	"time"
+)
+
+// LoginAttempts — счётчик неудачных входов по ключу (email или IP)
+type LoginAttempts struct {
+	Key            string    `db:"key"`
+	Failures       int       `db:"failures"`
+	FirstFailureAt time.Time `db:"first_failure_at"`
+	LastFailureAt  time.Time `db:"last_failure_at"`
+}
+
+// LockoutPolicy описывает защиту от перебора: первые FreeAttempts ошибок бесплатны,
+// дальше каждая следующая попытка ждёт BaseDelay * 2^n (не больше MaxDelay),
+// а после MaxFailures ключ блокируется на LockoutDuration. Счётчик сбрасывается,
+// если с первой ошибки прошло больше Window
+type LockoutPolicy struct {
+	FreeAttempts    int
+	MaxFailures     int
+	BaseDelay       time.Duration
+	MaxDelay        time.Duration
+	LockoutDuration time.Duration
+	Window          time.Duration
+}
+
+var DefaultLockoutPolicy = LockoutPolicy{
+	FreeAttempts:    3,
+	MaxFailures:     10,
+	BaseDelay:       time.Second,
+	MaxDelay:        5 * time.Minute,
+	LockoutDuration: 15 * time.Minute,
+	Window:          time.Hour,
+}
+
+// Distributed — политика счётчика по одному email со всех адресов: подбор пароля к аккаунту
+// с множества IP замедляется после 2*MaxFailures ошибок, но не блокируется полностью —
+// иначе кто угодно запер бы чужой аккаунт
+func (p LockoutPolicy) Distributed() LockoutPolicy {
+	return LockoutPolicy{
+		FreeAttempts: 2 * p.MaxFailures,
+		MaxFailures:  math.MaxInt,
+		BaseDelay:    p.BaseDelay,
+		MaxDelay:     p.MaxDelay,
+		Window:       p.Window,
+	}
+}
+
+// Locked — достигнут ли порог полной блокировки
+func (p LockoutPolicy) Locked(a LoginAttempts) bool {
+	return a.Failures >= p.MaxFailures
+}
+
+// RetryAfter возвращает, сколько ещё ждать до следующей попытки; 0 — можно пробовать
+func (p LockoutPolicy) RetryAfter(a LoginAttempts, now time.Time) time.Duration {
+	if a.Failures < p.FreeAttempts {
+		return 0
+	}
+
+	var delay time.Duration
+	if p.Locked(a) {
+		// блокировка отсчитывается от последней ошибки и не снимается вместе с окном
+		delay = p.LockoutDuration
+	} else if now.Sub(a.FirstFailureAt) > p.Window {
+		return 0
+	} else {
+		delay = p.BaseDelay << (a.Failures - p.FreeAttempts)
+		if delay <= 0 || delay > p.MaxDelay {
+			delay = p.MaxDelay
+		}
+	}
+
+	if wait := a.LastFailureAt.Add(delay).Sub(now); wait > 0 {
+		return wait
+	}
+	return 0
+}
+
+var ErrTooManyAttempts = errors.New("too many sign-in attempts")
+
+// TooManyAttemptsError — ErrTooManyAttempts с временем ожидания для заголовка Retry-After
+type TooManyAttemptsError struct {
+	RetryAfter time.Duration
+}
+
+func (e *TooManyAttemptsError) Error() string {
+	return fmt.Sprintf("%s, retry after %s", ErrTooManyAttempts, e.RetryAfter.Round(time.Second))
+}
+
+func (e *TooManyAttemptsError) Is(target error) bool {
+	return target == ErrTooManyAttempts
+}
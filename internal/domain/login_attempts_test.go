@@ -0,0 +1,77 @@
+package domain
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestLockoutPolicy_RetryAfter(t *testing.T) {
+	p := LockoutPolicy{
+		FreeAttempts:    3,
+		MaxFailures:     6,
+		BaseDelay:       time.Second,
+		MaxDelay:        4 * time.Second,
+		LockoutDuration: time.Minute,
+		Window:          time.Hour,
+	}
+	now := time.Now()
+	attempts := func(n int) LoginAttempts {
+		return LoginAttempts{Failures: n, FirstFailureAt: now.Add(-time.Minute), LastFailureAt: now}
+	}
+
+	testTable := []struct {
+		name     string
+		attempts LoginAttempts
+		expected time.Duration
+	}{
+		{name: "free attempts", attempts: attempts(2), expected: 0},
+		{name: "first delay", attempts: attempts(3), expected: time.Second},
+		{name: "exponential", attempts: attempts(4), expected: 2 * time.Second},
+		{name: "capped", attempts: attempts(5), expected: 4 * time.Second},
+		{name: "locked", attempts: attempts(6), expected: time.Minute},
+		{
+			name:     "window expired",
+			attempts: LoginAttempts{Failures: 5, FirstFailureAt: now.Add(-2 * time.Hour), LastFailureAt: now},
+			expected: 0,
+		},
+		{
+			name:     "lockout outlives window",
+			attempts: LoginAttempts{Failures: 6, FirstFailureAt: now.Add(-2 * time.Hour), LastFailureAt: now},
+			expected: time.Minute,
+		},
+		{
+			name:     "delay already passed",
+			attempts: LoginAttempts{Failures: 3, FirstFailureAt: now.Add(-time.Minute), LastFailureAt: now.Add(-time.Minute)},
+			expected: 0,
+		},
+	}
+
+	for _, testCase := range testTable {
+		t.Run(testCase.name, func(t *testing.T) {
+			assert.Equal(t, testCase.expected, p.RetryAfter(testCase.attempts, now))
+		})
+	}
+}
+
+func TestLockoutPolicy_Distributed(t *testing.T) {
+	p := LockoutPolicy{
+		FreeAttempts:    3,
+		MaxFailures:     6,
+		BaseDelay:       time.Second,
+		MaxDelay:        4 * time.Second,
+		LockoutDuration: time.Minute,
+		Window:          time.Hour,
+	}.Distributed()
+	now := time.Now()
+	attempts := func(n int) LoginAttempts {
+		return LoginAttempts{Failures: n, FirstFailureAt: now.Add(-time.Minute), LastFailureAt: now}
+	}
+
+	assert.Zero(t, p.RetryAfter(attempts(11), now))
+	assert.Equal(t, time.Second, p.RetryAfter(attempts(12), now))
+	// сколько бы ни было ошибок — только замедление, до блокировки не доходит
+	assert.False(t, p.Locked(attempts(1000)))
+	assert.Equal(t, 4*time.Second, p.RetryAfter(attempts(1000), now))
+}
@@ -0,0 +1,72 @@
+package domain
+
+import (
+	"strings"
+	"time"
+
+	"github.com/golang-jwt/jwt/v5"
+)
+
+const (
+	// MFAChallengeTTL — сколько есть времени ввести код после пароля
+	MFAChallengeTTL      = 5 * time.Minute
+	MFAChallengeAudience = "mfa-challenge"
+	// MFASkew — сколько соседних 30-секундных интервалов принимаем из-за рассинхронизации часов
+	MFASkew            = 1
+	RecoveryCodesCount = 10
+)
+
+type TOTP struct {
+	UserID       int64      `db:"user_id"`
+	Secret       string     `db:"secret"`
+	CreatedAt    time.Time  `db:"created_at"`
+	ConfirmedAt  *time.Time `db:"confirmed_at"`
+	LastUsedStep int64      `db:"last_used_step"`
+}
+
+func (t TOTP) Enabled() bool {
+	return t.ConfirmedAt != nil
+}
+
+// TOTPEnrollment — ответ на начало регистрации; секрет и резервные коды показываются один раз
+type TOTPEnrollment struct {
+	Secret        string   `json:"secret"`
+	URI           string   `json:"otpauth_uri"`
+	RecoveryCodes []string `json:"recovery_codes"`
+}
+
+// SignInResult — итог проверки пароля: либо пара токенов, либо MFAToken для второго шага
+type SignInResult struct {
+	AccessToken  string
+	RefreshToken string
+	MFAToken     string
+}
+
+func (r SignInResult) MFARequired() bool {
+	return r.MFAToken != ""
+}
+
+type MFAChallengeClaims struct {
+	jwt.RegisteredClaims
+}
+
+type MFACodeInput struct {
+	// Code — 6 цифр из приложения или резервный код
+	Code string `json:"code" validate:"required"`
+}
+
+type MFAVerifyInput struct {
+	MFAToken string `json:"mfa_token" validate:"required"`
+	Code     string `json:"code" validate:"required"`
+}
+
+// NormalizeRecoveryCode приводит резервный код к каноническому виду: без дефисов и пробелов, в верхнем регистре
+func NormalizeRecoveryCode(code string) string {
+	code = strings.ToUpper(code)
+	return strings.Map(func(r rune) rune {
+		if r == '-' || r == ' ' {
+			return -1
+		}
+		return r
+	}, code)
+}
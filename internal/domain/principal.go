@@ -0,0 +1,55 @@
+package domain
+
+import "context"
+
+// Principal — аутентифицированный пользователь, от имени которого выполняется запрос
+type Principal struct {
+	UserID      int64
+	Roles       []string
+	Permissions []string
+	// APIKeyID — не 0, если запрос аутентифицирован API-ключом, а не access-токеном
+	APIKeyID int64
+}
+
+type principalKey struct{}
+
+func WithPrincipal(ctx context.Context, p Principal) context.Context {
+	return context.WithValue(ctx, principalKey{}, p)
+}
+
+func PrincipalFromContext(ctx context.Context) (Principal, bool) {
+	p, ok := ctx.Value(principalKey{}).(Principal)
+	return p, ok
+}
+
+func (p Principal) HasRole(role string) bool {
+	for _, r := range p.Roles {
+		if r == role {
+			return true
+		}
+	}
+	return false
+}
+
+func (p Principal) HasPermission(perm string) bool {
+	for _, r := range p.Permissions {
+		if r == perm {
+			return true
+		}
+	}
+	return false
+}
+
+// IsAdmin — запрос от администратора. API-ключ администратором не бывает, даже если он
+// выпущен администратором: ключу достаются только его scopes
+func (p Principal) IsAdmin() bool {
+	return p.APIKeyID == 0 && p.HasRole(RoleAdmin)
+}
+
+// CanModify — книгу может менять её владелец или администратор
+func (p Principal) CanModify(book *Book) bool {
+	if p.IsAdmin() {
+		return true
+	}
+	return book.CreatedBy != nil && *book.CreatedBy == p.UserID
+}
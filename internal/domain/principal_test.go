@@ -0,0 +1,30 @@
+package domain
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestPrincipal_CanModify(t *testing.T) {
+	owner := int64(7)
+	book := &Book{CreatedBy: &owner}
+
+	testTable := []struct {
+		name      string
+		principal Principal
+		expected  bool
+	}{
+		{name: "owner", principal: Principal{UserID: 7}, expected: true},
+		{name: "other user", principal: Principal{UserID: 8}, expected: false},
+		{name: "admin", principal: Principal{UserID: 8, Roles: []string{RoleAdmin}}, expected: true},
+		{name: "owner api key", principal: Principal{UserID: 7, APIKeyID: 1}, expected: true},
+		{name: "admin api key", principal: Principal{UserID: 8, Roles: []string{RoleAdmin}, APIKeyID: 1}, expected: false},
+	}
+
+	for _, testCase := range testTable {
+		t.Run(testCase.name, func(t *testing.T) {
+			assert.Equal(t, testCase.expected, testCase.principal.CanModify(book))
+		})
+	}
+}
@@ -0,0 +1,39 @@
+package domain
+
+const (
+	RoleReader    = "reader"
+	RoleLibrarian = "librarian"
+	RoleAdmin     = "admin"
+
+	PermBooksWrite  = "books:write"
+	PermRolesManage = "roles:manage"
+)
+
+type Role struct {
+	Name        string   `json:"name"`
+	Permissions []string `json:"permissions"`
+}
+
+// RoleNames и PermissionNames сворачивают роли пользователя в плоские списки для JWT
+func RoleNames(roles []Role) []string {
+	names := make([]string, 0, len(roles))
+	for _, r := range roles {
+		names = append(names, r.Name)
+	}
+	return names
+}
+
+func PermissionNames(roles []Role) []string {
+	seen := make(map[string]struct{})
+	perms := make([]string, 0)
+	for _, r := range roles {
+		for _, p := range r.Permissions {
+			if _, ok := seen[p]; ok {
+				continue
+			}
+			seen[p] = struct{}{}
+			perms = append(perms, p)
+		}
+	}
+	return perms
+}
@@ -1,10 +1,49 @@
 package domain
 
-import "time"
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"time"
+
+	"github.com/golang-jwt/jwt/v5"
+)
 
 type RefreshSession struct {
-	ID        int64
-	UserID    int64
-	Token     string
-	ExpiresAt time.Time
+	ID         int64      `db:"id"`
+	UserID     int64      `db:"user_id"`
+	TokenHash  string     `db:"token_hash"`
+	FamilyID   string     `db:"family_id"`
+	UserAgent  string     `db:"user_agent"`
+	IP         string     `db:"ip"`
+	CreatedAt  time.Time  `db:"created_at"`
+	LastUsedAt time.Time  `db:"last_used_at"`
+	ExpiresAt  time.Time  `db:"expires_at"`
+	RevokedAt  *time.Time `db:"revoked_at"`
+}
+
+// HashToken возвращает SHA-256 непрозрачного токена (refresh, сброс пароля) в hex. В базе
+// хранится только хеш: токен — 32 случайных байта, поэтому соль и медленный KDF не нужны
+func HashToken(token string) string {
+	sum := sha256.Sum256([]byte(token))
+	return hex.EncodeToString(sum[:])
+}
+
+// Session — активная сессия пользователя в том виде, в котором её видит клиент.
+// ID — идентификатор семейства refresh-токенов, он не меняется при ротации
+type Session struct {
+	ID         string    `json:"id"`
+	UserAgent  string    `json:"user_agent"`
+	IP         string    `json:"ip"`
+	CreatedAt  time.Time `json:"created_at"`
+	LastUsedAt time.Time `json:"last_used_at"`
+	ExpiresAt  time.Time `json:"expires_at"`
+	Current    bool      `json:"current"`
+}
+
+// AccessClaims — claims access-токена: роли и права кладутся прямо в JWT,
+// чтобы middleware не ходила в базу на каждый запрос
+type AccessClaims struct {
+	jwt.RegisteredClaims
+	Roles       []string `json:"roles,omitempty"`
+	Permissions []string `json:"perms,omitempty"`
 }
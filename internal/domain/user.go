@@ -12,8 +12,14 @@ type User struct {
 	ID           int64     `json:"id"`
 	Name         string    `json:"name"`
 	Email        string    `json:"email"`
-	Password     string    `json:"password"`
+	Password     string    `json:"-"`
 	RegisteredAt time.Time `json:"registered_at"`
+	// EmailVerifiedAt — nil, пока пользователь не перешёл по ссылке из письма
+	EmailVerifiedAt *time.Time `json:"email_verified_at,omitempty"`
+}
+
+func (u User) EmailVerified() bool {
+	return u.EmailVerifiedAt != nil
 }
 
 type SingUpInput struct {
@@ -35,6 +41,28 @@ func (i SingInInput) Validate() error {
 	return validate.Struct(i)
 }
 
+// UpdateProfileInput — PATCH /users/me. Смена email требует текущий пароль и применяется
+// только после перехода по ссылке, отправленной на новый адрес
+type UpdateProfileInput struct {
+	Name            *string `json:"name" validate:"omitempty,gte=2,lte=20"`
+	Email           *string `json:"email" validate:"omitempty,email"`
+	CurrentPassword string  `json:"current_password" validate:"required_with=Email"`
+}
+
+func (i UpdateProfileInput) IsEmpty() bool {
+	return i.Name == nil && i.Email == nil
+}
+
+type ChangePasswordInput struct {
+	CurrentPassword string `json:"current_password" validate:"required"`
+	NewPassword     string `json:"new_password" validate:"required,gte=8"`
+}
+
+// DeleteAccountInput — DELETE /users/me; удаление подтверждается паролем
+type DeleteAccountInput struct {
+	Password string `json:"password" validate:"required"`
+}
+
 type ValidationError struct {
 	Field   string `json:"field"`
 	Message string `json:"message"`
@@ -0,0 +1,51 @@
+package domain
+
+import (
+	"fmt"
+	"time"
+
+	"github.com/golang-jwt/jwt/v5"
+)
+
+// UnverifiedPolicy — что разрешено пользователю, не подтвердившему email
+type UnverifiedPolicy string
+
+const (
+	// UnverifiedAllow — без ограничений
+	UnverifiedAllow UnverifiedPolicy = "allow"
+	// UnverifiedDeny — вход запрещён до подтверждения
+	UnverifiedDeny UnverifiedPolicy = "deny"
+	// UnverifiedReadOnly — вход разрешён, но access-токен выдаётся без прав (только чтение книг)
+	UnverifiedReadOnly UnverifiedPolicy = "read_only"
+)
+
+// ParseUnverifiedPolicy разбирает значение из конфига; пустое — UnverifiedAllow.
+// Опечатка — ошибка, а не молчаливое allow
+func ParseUnverifiedPolicy(s string) (UnverifiedPolicy, error) {
+	switch p := UnverifiedPolicy(s); p {
+	case "":
+		return UnverifiedAllow, nil
+	case UnverifiedAllow, UnverifiedDeny, UnverifiedReadOnly:
+		return p, nil
+	default:
+		return "", fmt.Errorf("unknown unverified policy %q (allow, deny or read_only)", s)
+	}
+}
+
+const (
+	EmailVerificationTTL = 24 * time.Hour
+	// EmailVerificationAudience — aud токена из письма
+	EmailVerificationAudience = "email-verification"
+)
+
+type VerificationClaims struct {
+	jwt.RegisteredClaims
+	// Email — адрес пользователя на момент выдачи ссылки
+	Email string `json:"email"`
+	// NewEmail — не пусто для смены адреса: ссылка уходит на него и переключает email
+	NewEmail string `json:"new_email,omitempty"`
+}
+
+type ResendVerificationInput struct {
+	Email string `json:"email" validate:"required,email"`
+}
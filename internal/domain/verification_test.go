@@ -0,0 +1,34 @@
+package domain
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestParseUnverifiedPolicy(t *testing.T) {
+	testTable := []struct {
+		input         string
+		expected      UnverifiedPolicy
+		expectedError bool
+	}{
+		{input: "", expected: UnverifiedAllow},
+		{input: "allow", expected: UnverifiedAllow},
+		{input: "deny", expected: UnverifiedDeny},
+		{input: "read_only", expected: UnverifiedReadOnly},
+		{input: "readonly", expectedError: true},
+		{input: "Deny", expectedError: true},
+	}
+
+	for _, testCase := range testTable {
+		t.Run(testCase.input, func(t *testing.T) {
+			policy, err := ParseUnverifiedPolicy(testCase.input)
+			if testCase.expectedError {
+				assert.Error(t, err)
+				return
+			}
+			assert.NoError(t, err)
+			assert.Equal(t, testCase.expected, policy)
+		})
+	}
+}
@@ -0,0 +1,88 @@
+package http
+
+import (
+	"net/http"
+	"strconv"
+
+	"github.com/labstack/echo/v4"
+)
+
+// getUserRoles godoc
+// @Summary      Get user roles
+// @Description  Роли и права пользователя (только для администраторов)
+// @Tags         admin
+// @Produce      json
+// @Param        id   path      int  true  "User ID"
+// @Success      200  {array}   domain.Role
+// @Failure      400  {object}  map[string]string
+// @Failure      403  {object}  map[string]string
+// @Router       /admin/users/{id}/roles [get]
+func (h *Handler) getUserRoles(c echo.Context) error {
+	userID, err := strconv.ParseInt(c.Param("id"), 10, 64)
+	if err != nil {
+		return respondErr(c, echo.NewHTTPError(http.StatusBadRequest, "invalid user id"))
+	}
+
+	ctx := c.Request().Context()
+	roles, err := h.UserService.GetUserRoles(ctx, userID)
+	if err != nil {
+		logError("get-user-roles", err)
+		return respondErr(c, err)
+	}
+
+	return respondJSON(c, http.StatusOK, roles)
+}
+
+// assignRole godoc
+// @Summary      Assign role
+// @Description  Назначает пользователю роль (reader, librarian, admin)
+// @Tags         admin
+// @Produce      json
+// @Param        id    path  int     true  "User ID"
+// @Param        role  path  string  true  "Role name"
+// @Success      204  "No Content"
+// @Failure      400  {object}  map[string]string
+// @Failure      403  {object}  map[string]string
+// @Failure      404  {object}  map[string]string
+// @Router       /admin/users/{id}/roles/{role} [put]
+func (h *Handler) assignRole(c echo.Context) error {
+	userID, err := strconv.ParseInt(c.Param("id"), 10, 64)
+	if err != nil {
+		return respondErr(c, echo.NewHTTPError(http.StatusBadRequest, "invalid user id"))
+	}
+
+	ctx := c.Request().Context()
+	if err := h.UserService.AssignRole(ctx, userID, c.Param("role")); err != nil {
+		logError("assign-role", err)
+		return respondErr(c, err)
+	}
+
+	return c.NoContent(http.StatusNoContent)
+}
+
+// revokeRole godoc
+// @Summary      Revoke role
+// @Description  Снимает с пользователя роль
+// @Tags         admin
+// @Produce      json
+// @Param        id    path  int     true  "User ID"
+// @Param        role  path  string  true  "Role name"
+// @Success      204  "No Content"
+// @Failure      400  {object}  map[string]string
+// @Failure      403  {object}  map[string]string
+// @Failure      404  {object}  map[string]string
+// @Router       /admin/users/{id}/roles/{role} [delete]
+func (h *Handler) revokeRole(c echo.Context) error {
+	userID, err := strconv.ParseInt(c.Param("id"), 10, 64)
+	if err != nil {
+		return respondErr(c, echo.NewHTTPError(http.StatusBadRequest, "invalid user id"))
+	}
+
+	ctx := c.Request().Context()
+	if err := h.UserService.RevokeRole(ctx, userID, c.Param("role")); err != nil {
+		logError("revoke-role", err)
+		return respondErr(c, err)
+	}
+
+	return c.NoContent(http.StatusNoContent)
+}
@@ -0,0 +1,95 @@
+package http
+
+import (
+	"net/http"
+	"strconv"
+
+	"github.com/CryptoGu1/books-rest-clean-arch/internal/domain"
+	"github.com/labstack/echo/v4"
+)
+
+// createAPIKey godoc
+// @Summary      Create API key
+// @Description  Выпускает персональный API-ключ. Ключ возвращается один раз, в базе хранится только хеш
+// @Tags         users
+// @Accept       json
+// @Produce      json
+// @Param        input  body      domain.CreateAPIKeyInput  true  "Name, scopes and expiry"
+// @Success      201    {object}  domain.CreatedAPIKey
+// @Failure      400    {object}  map[string]string
+// @Failure      401    {object}  map[string]string
+// @Failure      403    {object}  map[string]string
+// @Router       /users/me/api-keys [post]
+func (h *Handler) createAPIKey(c echo.Context) error {
+	ctx := c.Request().Context()
+	principal, ok := domain.PrincipalFromContext(ctx)
+	if !ok {
+		return respondErr(c, echo.NewHTTPError(http.StatusUnauthorized, "unauthorized"))
+	}
+
+	var input domain.CreateAPIKeyInput
+	if err := c.Bind(&input); err != nil {
+		return respondErr(c, echo.NewHTTPError(http.StatusBadRequest, "invalid request body"))
+	}
+	if err := h.validate.Struct(input); err != nil {
+		return respondErr(c, echo.NewHTTPError(http.StatusBadRequest, "invalid input body"))
+	}
+
+	key, err := h.UserService.CreateAPIKey(ctx, principal, input)
+	if err != nil {
+		logError("create-api-key", err)
+		return respondErr(c, err)
+	}
+	return respondJSON(c, http.StatusCreated, key)
+}
+
+// listAPIKeys godoc
+// @Summary      List API keys
+// @Description  Ключи текущего пользователя, включая отозванные и истёкшие
+// @Tags         users
+// @Produce      json
+// @Success      200  {array}   domain.APIKey
+// @Failure      401  {object}  map[string]string
+// @Router       /users/me/api-keys [get]
+func (h *Handler) listAPIKeys(c echo.Context) error {
+	ctx := c.Request().Context()
+	principal, ok := domain.PrincipalFromContext(ctx)
+	if !ok {
+		return respondErr(c, echo.NewHTTPError(http.StatusUnauthorized, "unauthorized"))
+	}
+
+	keys, err := h.UserService.ListAPIKeys(ctx, principal.UserID)
+	if err != nil {
+		logError("list-api-keys", err)
+		return respondErr(c, err)
+	}
+	return respondJSON(c, http.StatusOK, keys)
+}
+
+// revokeAPIKey godoc
+// @Summary      Revoke API key
+// @Tags         users
+// @Param        id  path  int  true  "API key ID"
+// @Success      204
+// @Failure      400  {object}  map[string]string
+// @Failure      401  {object}  map[string]string
+// @Failure      404  {object}  map[string]string
+// @Router       /users/me/api-keys/{id} [delete]
+func (h *Handler) revokeAPIKey(c echo.Context) error {
+	ctx := c.Request().Context()
+	principal, ok := domain.PrincipalFromContext(ctx)
+	if !ok {
+		return respondErr(c, echo.NewHTTPError(http.StatusUnauthorized, "unauthorized"))
+	}
+
+	keyID, err := strconv.ParseInt(c.Param("id"), 10, 64)
+	if err != nil {
+		return respondErr(c, echo.NewHTTPError(http.StatusBadRequest, "invalid api key id"))
+	}
+
+	if err := h.UserService.RevokeAPIKey(ctx, principal.UserID, keyID); err != nil {
+		logError("revoke-api-key", err)
+		return respondErr(c, err)
+	}
+	return c.NoContent(http.StatusNoContent)
+}
@@ -1,6 +1,9 @@
 package http
 
 import (
+	"encoding/json"
+	"io"
+	"mime"
 	"net/http"
 	"strconv"
 
@@ -9,6 +12,11 @@ import (
 	log "github.com/sirupsen/logrus"
 )
 
+const (
+	mimeMergePatch = "application/merge-patch+json"
+	mimeJSONPatch  = "application/json-patch+json"
+)
+
 // Create godoc
 //
 //	@Summary		Create new book
@@ -83,21 +91,109 @@ func (h *Handler) GetById(c echo.Context) error {
 
 // GetAllBooks godoc
 // @Summary      Get all books
-// @Description  Возвращает список всех книг
+// @Description  Возвращает страницу книг с фильтрами и сортировкой
 // @Tags         books
 // @Accept       json
 // @Produce      json
-// @Success      200  {array}   domain.Book
+// @Param        limit           query     int     false  "Page size (default 20, max 100)"
+// @Param        offset          query     int     false  "Offset"
+// @Param        author          query     string  false  "Exact author"
+// @Param        title           query     string  false  "Title substring"
+// @Param        min_rating      query     int     false  "Min rating"
+// @Param        max_rating      query     int     false  "Max rating"
+// @Param        published_from  query     string  false  "Publish date from (YYYY-MM-DD)"
+// @Param        published_to    query     string  false  "Publish date to (YYYY-MM-DD)"
+// @Param        sort            query     string  false  "Sort fields, e.g. title,-rating"
+// @Param        cursor          query     string  false  "Keyset mode: empty for the first page, then next_cursor with the same filters and sort (single sort field, no offset)"
+// @Success      200  {object}  bookListResponse
+// @Success      200  {object}  bookCursorResponse
+// @Failure      400  {object}  map[string]string
 // @Failure      500  {object}  map[string]string
 // @Router       /books [get]
 func (h *Handler) GetAll(c echo.Context) error {
+	query, err := parseBookQuery(c)
+	if err != nil {
+		return respondErr(c, err)
+	}
+
+	ctx := c.Request().Context()
+	if c.QueryParams().Has("cursor") {
+		if len(query.Sort) > 1 || c.QueryParam("offset") != "" {
+			return respondErr(c, echo.NewHTTPError(http.StatusBadRequest, "cursor pagination supports a single sort field and no offset"))
+		}
+
+		page, err := h.bookService.GetAllAfter(ctx, query, c.QueryParam("cursor"))
+		if err != nil {
+			return respondErr(c, err)
+		}
+		return respondJSON(c, http.StatusOK, newBookCursorResponse(c, page))
+	}
+
+	page, err := h.bookService.GetAll(ctx, query)
+	if err != nil {
+		return respondErr(c, err)
+
+	}
+	return respondJSON(c, http.StatusOK, newBookListResponse(c, page))
+}
+
+// GetMyBooks godoc
+// @Summary      Get my books
+// @Description  Возвращает книги, добавленные текущим пользователем (те же фильтры и сортировка, что у /books)
+// @Tags         books
+// @Produce      json
+// @Param        limit   query     int     false  "Page size (default 20, max 100)"
+// @Param        offset  query     int     false  "Offset"
+// @Param        sort    query     string  false  "Sort fields, e.g. title,-rating"
+// @Success      200  {object}  bookListResponse
+// @Failure      400  {object}  map[string]string
+// @Failure      401  {object}  map[string]string
+// @Router       /users/me/books [get]
+func (h *Handler) GetMyBooks(c echo.Context) error {
+	query, err := parseBookQuery(c)
+	if err != nil {
+		return respondErr(c, err)
+	}
+
 	ctx := c.Request().Context()
-	books, err := h.bookService.GetAll(ctx)
+	principal, ok := domain.PrincipalFromContext(ctx)
+	if !ok {
+		return respondErr(c, echo.NewHTTPError(http.StatusUnauthorized, "unauthorized"))
+	}
+	query.CreatedBy = &principal.UserID
+
+	page, err := h.bookService.GetAll(ctx, query)
+	if err != nil {
+		return respondErr(c, err)
+	}
+	return respondJSON(c, http.StatusOK, newBookListResponse(c, page))
+}
+
+// SearchBooks godoc
+// @Summary      Search books
+// @Description  Полнотекстовый поиск по названию и автору с ранжированием и подсветкой
+// @Tags         books
+// @Accept       json
+// @Produce      json
+// @Param        q       query     string  true   "Search query, words are matched by prefix"
+// @Param        limit   query     int     false  "Page size (default 20, max 100)"
+// @Param        offset  query     int     false  "Offset"
+// @Success      200  {object}  bookSearchResponse
+// @Failure      400  {object}  map[string]string
+// @Failure      500  {object}  map[string]string
+// @Router       /books/search [get]
+func (h *Handler) Search(c echo.Context) error {
+	query, err := parseBookSearchQuery(c)
 	if err != nil {
 		return respondErr(c, err)
+	}
 
+	ctx := c.Request().Context()
+	page, err := h.bookService.Search(ctx, query)
+	if err != nil {
+		return respondErr(c, err)
 	}
-	return respondJSON(c, http.StatusOK, books)
+	return respondJSON(c, http.StatusOK, newBookSearchResponse(c, page))
 }
 
 // UpdateBook godoc
@@ -110,6 +206,7 @@ func (h *Handler) GetAll(c echo.Context) error {
 // @Param        input  body      domain.UpdateBookInput  true  "Updated book data"
 // @Success      200    {object}  map[string]interface{}
 // @Failure      400    {object}  map[string]string
+// @Failure      403    {object}  map[string]string
 // @Failure      404    {object}  map[string]string
 // @Failure      500    {object}  map[string]string
 // @Router       /books/{id} [put]
@@ -129,9 +226,8 @@ func (h *Handler) Update(c echo.Context) error {
 
 	}
 
-	book := input.ToBook()
 	ctx := c.Request().Context()
-	if err := h.bookService.Update(ctx, id, book); err != nil {
+	if err := h.bookService.Update(ctx, id, input.ToPatch()); err != nil {
 		return respondErr(c, err)
 	}
 	return respondJSON(c, http.StatusOK, map[string]interface{}{
@@ -141,6 +237,70 @@ func (h *Handler) Update(c echo.Context) error {
 
 }
 
+// PatchBook godoc
+// @Summary      Patch book
+// @Description  Частично обновляет книгу: application/merge-patch+json (RFC 7396) или application/json-patch+json (RFC 6902)
+// @Tags         books
+// @Accept       json
+// @Accept       application/merge-patch+json
+// @Accept       application/json-patch+json
+// @Produce      json
+// @Param        id     path      int                  true  "Book ID"
+// @Param        input  body      object               true  "Merge patch object or JSON Patch operations"
+// @Success      200    {object}  map[string]interface{}
+// @Failure      400    {object}  map[string]string
+// @Failure      403    {object}  map[string]string
+// @Failure      404    {object}  map[string]string
+// @Failure      415    {object}  map[string]string
+// @Failure      422    {object}  map[string]string
+// @Failure      500    {object}  map[string]string
+// @Router       /books/{id} [patch]
+func (h *Handler) Patch(c echo.Context) error {
+	idParam := c.Param("id")
+	id, errConv := strconv.Atoi(idParam)
+	if errConv != nil {
+		return respondErr(c, echo.NewHTTPError(http.StatusBadRequest, "invalid book id"))
+	}
+
+	body, err := io.ReadAll(c.Request().Body)
+	if err != nil {
+		return respondErr(c, echo.NewHTTPError(http.StatusBadRequest, "invalid request body"))
+	}
+
+	mediaType := echo.MIMEApplicationJSON
+	if ct := c.Request().Header.Get(echo.HeaderContentType); ct != "" {
+		if mediaType, _, err = mime.ParseMediaType(ct); err != nil {
+			return respondErr(c, echo.NewHTTPError(http.StatusUnsupportedMediaType, "invalid content type"))
+		}
+	}
+
+	ctx := c.Request().Context()
+	switch mediaType {
+	case mimeJSONPatch:
+		var ops []domain.JSONPatchOp
+		if err := json.Unmarshal(body, &ops); err != nil {
+			return respondErr(c, echo.NewHTTPError(http.StatusBadRequest, "invalid request body"))
+		}
+		err = h.bookService.ApplyJSONPatch(ctx, id, ops)
+	case mimeMergePatch, echo.MIMEApplicationJSON:
+		patch, perr := domain.ParseMergePatch(body)
+		if perr != nil {
+			return respondErr(c, perr)
+		}
+		err = h.bookService.Update(ctx, id, patch)
+	default:
+		return respondErr(c, echo.NewHTTPError(http.StatusUnsupportedMediaType, "unsupported content type"))
+	}
+	if err != nil {
+		return respondErr(c, err)
+	}
+
+	return respondJSON(c, http.StatusOK, map[string]interface{}{
+		"message": "Book updated successfully",
+		"id":      id,
+	})
+}
+
 // DeleteBook godoc
 // @Summary      Delete book
 // @Description  Удаляет книгу по ID
@@ -150,6 +310,7 @@ func (h *Handler) Update(c echo.Context) error {
 // @Param        id   path      int  true  "Book ID"
 // @Success      204  "No Content"
 // @Failure      400  {object}  map[string]string
+// @Failure      403  {object}  map[string]string
 // @Failure      404  {object}  map[string]string
 // @Failure      500  {object}  map[string]string
 // @Router       /books/{id} [delete]
@@ -0,0 +1,211 @@
+package http
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/CryptoGu1/books-rest-clean-arch/internal/domain"
+	"github.com/CryptoGu1/books-rest-clean-arch/mocks"
+	"github.com/CryptoGu1/books-rest-clean-arch/pkg/jwtkeys"
+	"github.com/labstack/echo/v4"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+)
+
+func intPtr(v int) *int { return &v }
+
+func TestHandler_GetAll(t *testing.T) {
+	type mockBehavior func(s *mocks.BookService, query domain.BookQuery)
+
+	testTable := []struct {
+		name               string
+		url                string
+		query              domain.BookQuery
+		mockBehavior       mockBehavior
+		expectedStatusCode int
+		expectedNext       string
+		expectedPrev       string
+	}{
+		{
+			name:  "defaults",
+			url:   "/books",
+			query: domain.BookQuery{Limit: domain.DefaultBooksLimit},
+			mockBehavior: func(s *mocks.BookService, query domain.BookQuery) {
+				s.On("GetAll", mock.Anything, query).Return(&domain.BookPage{
+					Items: []*domain.Book{{ID: 1}},
+					Total: 1,
+					Limit: query.Limit,
+				}, nil)
+			},
+			expectedStatusCode: 200,
+		},
+		{
+			name: "filters and links",
+			url:  "/books?limit=2&offset=2&author=Tolstoy&min_rating=3&sort=-rating,title",
+			query: domain.BookQuery{
+				Limit:     2,
+				Offset:    2,
+				Author:    "Tolstoy",
+				MinRating: intPtr(3),
+				Sort:      []domain.SortField{{Field: "rating", Desc: true}, {Field: "title"}},
+			},
+			mockBehavior: func(s *mocks.BookService, query domain.BookQuery) {
+				s.On("GetAll", mock.Anything, query).Return(&domain.BookPage{
+					Items:  []*domain.Book{{ID: 3}, {ID: 4}},
+					Total:  10,
+					Limit:  query.Limit,
+					Offset: query.Offset,
+				}, nil)
+			},
+			expectedStatusCode: 200,
+			expectedNext:       "/books?author=Tolstoy&limit=2&min_rating=3&offset=4&sort=-rating%2Ctitle",
+			expectedPrev:       "/books?author=Tolstoy&limit=2&min_rating=3&offset=0&sort=-rating%2Ctitle",
+		},
+		{
+			name:               "unknown sort field",
+			url:                "/books?sort=password",
+			mockBehavior:       func(s *mocks.BookService, query domain.BookQuery) {},
+			expectedStatusCode: 400,
+		},
+		{
+			name:               "limit too large",
+			url:                "/books?limit=1000",
+			mockBehavior:       func(s *mocks.BookService, query domain.BookQuery) {},
+			expectedStatusCode: 400,
+		},
+		{
+			name:               "bad date",
+			url:                "/books?published_from=yesterday",
+			mockBehavior:       func(s *mocks.BookService, query domain.BookQuery) {},
+			expectedStatusCode: 400,
+		},
+		{
+			name:  "service fail",
+			url:   "/books",
+			query: domain.BookQuery{Limit: domain.DefaultBooksLimit},
+			mockBehavior: func(s *mocks.BookService, query domain.BookQuery) {
+				s.On("GetAll", mock.Anything, query).Return(nil, errors.New("service error"))
+			},
+			expectedStatusCode: 500,
+		},
+	}
+
+	for _, testCase := range testTable {
+		t.Run(testCase.name, func(t *testing.T) {
+			bookService := mocks.NewBookService(t)
+			testCase.mockBehavior(bookService, testCase.query)
+
+			handler := NewHandler(bookService, nil, jwtkeys.NewHMAC([]byte("secret")), Config{})
+			e := echo.New()
+
+			req := httptest.NewRequest(http.MethodGet, testCase.url, nil)
+			rec := httptest.NewRecorder()
+			c := e.NewContext(req, rec)
+
+			err := handler.GetAll(c)
+
+			assert.NoError(t, err)
+			assert.Equal(t, testCase.expectedStatusCode, rec.Code)
+
+			if rec.Code == http.StatusOK {
+				var resp bookListResponse
+				assert.NoError(t, json.Unmarshal(rec.Body.Bytes(), &resp))
+				assert.Equal(t, testCase.expectedNext, resp.Next)
+				assert.Equal(t, testCase.expectedPrev, resp.Prev)
+			}
+		})
+	}
+}
+
+func TestHandler_Patch(t *testing.T) {
+	type mockBehavior func(s *mocks.BookService)
+
+	testTable := []struct {
+		name               string
+		contentType        string
+		body               string
+		mockBehavior       mockBehavior
+		expectedStatusCode int
+	}{
+		{
+			name:        "json patch",
+			contentType: mimeJSONPatch,
+			body:        `[{"op": "replace", "path": "/title", "value": "War and Peace"}]`,
+			mockBehavior: func(s *mocks.BookService) {
+				s.On("ApplyJSONPatch", mock.Anything, 1, mock.MatchedBy(func(ops []domain.JSONPatchOp) bool {
+					return len(ops) == 1 && ops[0].Op == "replace" && ops[0].Path == "/title"
+				})).Return(nil)
+			},
+			expectedStatusCode: http.StatusOK,
+		},
+		{
+			name:        "merge patch",
+			contentType: mimeMergePatch + "; charset=utf-8",
+			body:        `{"title": "War and Peace"}`,
+			mockBehavior: func(s *mocks.BookService) {
+				s.On("Update", mock.Anything, 1, mock.MatchedBy(func(p domain.BookPatch) bool {
+					return p.Title != nil && *p.Title == "War and Peace"
+				})).Return(nil)
+			},
+			expectedStatusCode: http.StatusOK,
+		},
+		{
+			name:               "unsupported content type",
+			contentType:        "text/plain",
+			body:               `{"title": "War and Peace"}`,
+			mockBehavior:       func(s *mocks.BookService) {},
+			expectedStatusCode: http.StatusUnsupportedMediaType,
+		},
+		{
+			name:               "invalid json patch",
+			contentType:        mimeJSONPatch,
+			body:               `{"op": "replace"}`,
+			mockBehavior:       func(s *mocks.BookService) {},
+			expectedStatusCode: http.StatusBadRequest,
+		},
+		{
+			name:        "failed test op",
+			contentType: mimeJSONPatch,
+			body:        `[{"op": "test", "path": "/title", "value": "Anna Karenina"}]`,
+			mockBehavior: func(s *mocks.BookService) {
+				s.On("ApplyJSONPatch", mock.Anything, 1, mock.Anything).Return(domain.ErrPatchTestFailed)
+			},
+			expectedStatusCode: http.StatusUnprocessableEntity,
+		},
+		{
+			name:        "not found",
+			contentType: mimeJSONPatch,
+			body:        `[{"op": "replace", "path": "/title", "value": "War and Peace"}]`,
+			mockBehavior: func(s *mocks.BookService) {
+				s.On("ApplyJSONPatch", mock.Anything, 1, mock.Anything).Return(domain.ErrBookNotFound)
+			},
+			expectedStatusCode: http.StatusNotFound,
+		},
+	}
+
+	for _, testCase := range testTable {
+		t.Run(testCase.name, func(t *testing.T) {
+			bookService := mocks.NewBookService(t)
+			testCase.mockBehavior(bookService)
+
+			handler := NewHandler(bookService, nil, jwtkeys.NewHMAC([]byte("secret")), Config{})
+			e := echo.New()
+
+			req := httptest.NewRequest(http.MethodPatch, "/books/1", bytes.NewBufferString(testCase.body))
+			req.Header.Set(echo.HeaderContentType, testCase.contentType)
+			rec := httptest.NewRecorder()
+			c := e.NewContext(req, rec)
+			c.SetParamNames("id")
+			c.SetParamValues("1")
+
+			err := handler.Patch(c)
+
+			assert.NoError(t, err)
+			assert.Equal(t, testCase.expectedStatusCode, rec.Code)
+		})
+	}
+}
@@ -0,0 +1,151 @@
+package http
+
+import (
+	"fmt"
+	"net"
+	"net/http"
+	"strings"
+	"time"
+
+	"github.com/labstack/echo/v4"
+)
+
+const (
+	refreshCookieName = "refresh-token"
+	refreshCookieTTL  = 30 * 24 * time.Hour
+	// tokenDeliveryHeader: body — клиент без cookie (мобильное приложение, CLI) получает
+	// refresh-токен в теле ответа и сам присылает его в /auth/refresh и /auth/logout
+	tokenDeliveryHeader = "X-Token-Delivery"
+)
+
+// Config — настройки HTTP-слоя аутентификации
+type Config struct {
+	Cookie CookiePolicy
+	// BodyTokens разрешает передачу refresh-токена в теле запроса и ответа вместо cookie
+	BodyTokens bool
+	// LegacyGetSignIn оставляет устаревший GET /auth/sign-in для старых клиентов
+	LegacyGetSignIn bool
+	// TrustedProxies — адреса обратных прокси, которым можно верить в X-Forwarded-For.
+	// Пусто — IP клиента берётся только из соединения
+	TrustedProxies []*net.IPNet
+}
+
+// CookiePolicy — атрибуты cookie с refresh-токеном
+type CookiePolicy struct {
+	Secure bool
+	// SameSite; нулевое значение — Lax
+	SameSite http.SameSite
+	Domain   string
+}
+
+// ParseTrustedProxies разбирает список CIDR (или отдельных IP) доверенных прокси
+func ParseTrustedProxies(list []string) ([]*net.IPNet, error) {
+	nets := make([]*net.IPNet, 0, len(list))
+	for _, item := range list {
+		if !strings.Contains(item, "/") {
+			ip := net.ParseIP(item)
+			if ip == nil {
+				return nil, fmt.Errorf("invalid proxy address %q", item)
+			}
+			bits := 8 * len(ip.To16())
+			if ip.To4() != nil {
+				ip, bits = ip.To4(), 32
+			}
+			nets = append(nets, &net.IPNet{IP: ip, Mask: net.CIDRMask(bits, bits)})
+			continue
+		}
+		_, ipNet, err := net.ParseCIDR(item)
+		if err != nil {
+			return nil, fmt.Errorf("invalid proxy range %q: %w", item, err)
+		}
+		nets = append(nets, ipNet)
+	}
+	return nets, nil
+}
+
+// ParseSameSite разбирает значение из конфига: strict, lax или none
+func ParseSameSite(s string) (http.SameSite, error) {
+	switch strings.ToLower(s) {
+	case "", "lax":
+		return http.SameSiteLaxMode, nil
+	case "strict":
+		return http.SameSiteStrictMode, nil
+	case "none":
+		return http.SameSiteNoneMode, nil
+	default:
+		return 0, fmt.Errorf("unknown SameSite mode %q", s)
+	}
+}
+
+func (p CookiePolicy) cookie(value string, maxAge int) *http.Cookie {
+	sameSite := p.SameSite
+	if sameSite == 0 {
+		sameSite = http.SameSiteLaxMode
+	}
+	return &http.Cookie{
+		Name:     refreshCookieName,
+		Value:    value,
+		Path:     "/",
+		Domain:   p.Domain,
+		HttpOnly: true,
+		// браузеры отбрасывают SameSite=None без Secure
+		Secure:   p.Secure || sameSite == http.SameSiteNoneMode,
+		SameSite: sameSite,
+		MaxAge:   maxAge,
+	}
+}
+
+type refreshInput struct {
+	RefreshToken string `json:"refresh_token"`
+}
+
+// wantsBodyTokens — клиент попросил refresh-токен в теле ответа и это разрешено конфигом.
+// Браузерам токен в теле не отдаётся: иначе его прочитает любой скрипт на странице
+func (h *Handler) wantsBodyTokens(c echo.Context) bool {
+	return h.cfg.BodyTokens && !fromBrowser(c.Request()) &&
+		strings.EqualFold(c.Request().Header.Get(tokenDeliveryHeader), "body")
+}
+
+// fromBrowser: fetch/XHR из браузера всегда приходят с Origin или Sec-Fetch-*, и скрипт их не уберёт
+func fromBrowser(r *http.Request) bool {
+	return r.Header.Get(echo.HeaderOrigin) != "" || r.Header.Get("Sec-Fetch-Mode") != "" || r.Header.Get("Sec-Fetch-Site") != ""
+}
+
+// issueTokens отдаёт пару токенов: refresh — в cookie или, по запросу клиента, в теле
+func (h *Handler) issueTokens(c echo.Context, access, refresh string) error {
+	if h.wantsBodyTokens(c) {
+		return respondJSON(c, http.StatusOK, map[string]interface{}{
+			"token":         access,
+			"refresh_token": refresh,
+		})
+	}
+
+	c.SetCookie(h.cfg.Cookie.cookie(refresh, int(refreshCookieTTL/time.Second)))
+	return respondJSON(c, http.StatusOK, map[string]interface{}{
+		"token": access,
+	})
+}
+
+// refreshTokenFrom достаёт refresh-токен из cookie, а если её нет и это разрешено — из тела запроса
+func (h *Handler) refreshTokenFrom(c echo.Context) string {
+	if cookie, err := c.Cookie(refreshCookieName); err == nil && cookie.Value != "" {
+		return cookie.Value
+	}
+	if !h.cfg.BodyTokens || fromBrowser(c.Request()) {
+		return ""
+	}
+
+	var input refreshInput
+	if err := c.Bind(&input); err != nil {
+		return ""
+	}
+	// ответ с новым токеном пойдёт тем же путём, что и запрос
+	if input.RefreshToken != "" {
+		c.Request().Header.Set(tokenDeliveryHeader, "body")
+	}
+	return input.RefreshToken
+}
+
+func (h *Handler) clearRefreshCookie(c echo.Context) {
+	c.SetCookie(h.cfg.Cookie.cookie("", -1))
+}
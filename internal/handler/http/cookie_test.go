@@ -0,0 +1,158 @@
+package http
+
+import (
+	"bytes"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/CryptoGu1/books-rest-clean-arch/internal/domain"
+	"github.com/CryptoGu1/books-rest-clean-arch/mocks"
+	"github.com/CryptoGu1/books-rest-clean-arch/pkg/jwtkeys"
+	"github.com/labstack/echo/v4"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+)
+
+func TestHandler_refresh(t *testing.T) {
+	type mockBehavior func(s *mocks.AuthService)
+
+	testTable := []struct {
+		name               string
+		cfg                Config
+		cookie             string
+		body               string
+		headers            map[string]string
+		mockBehavior       mockBehavior
+		expectedStatusCode int
+		expectedBody       string
+		expectCookie       bool
+	}{
+		{
+			name:   "cookie",
+			cfg:    Config{Cookie: CookiePolicy{Secure: true, SameSite: http.SameSiteStrictMode, Domain: "example.com"}},
+			cookie: "old",
+			mockBehavior: func(s *mocks.AuthService) {
+				s.On("RefreshTokens", mock.Anything, "old").Return("access", "new", nil)
+			},
+			expectedStatusCode: http.StatusOK,
+			expectedBody:       `{"token":"access"}`,
+			expectCookie:       true,
+		},
+		{
+			name: "body",
+			cfg:  Config{BodyTokens: true},
+			body: `{"refresh_token": "old"}`,
+			mockBehavior: func(s *mocks.AuthService) {
+				s.On("RefreshTokens", mock.Anything, "old").Return("access", "new", nil)
+			},
+			expectedStatusCode: http.StatusOK,
+			expectedBody:       `{"token":"access","refresh_token":"new"}`,
+		},
+		{
+			name:               "body tokens are not for browsers",
+			cfg:                Config{BodyTokens: true},
+			body:               `{"refresh_token": "old"}`,
+			headers:            map[string]string{echo.HeaderOrigin: "https://evil.example"},
+			mockBehavior:       func(s *mocks.AuthService) {},
+			expectedStatusCode: http.StatusUnauthorized,
+		},
+		{
+			name:               "body tokens disabled",
+			body:               `{"refresh_token": "old"}`,
+			mockBehavior:       func(s *mocks.AuthService) {},
+			expectedStatusCode: http.StatusUnauthorized,
+		},
+		{
+			name:               "missing token",
+			cfg:                Config{BodyTokens: true},
+			mockBehavior:       func(s *mocks.AuthService) {},
+			expectedStatusCode: http.StatusUnauthorized,
+		},
+		{
+			name:   "service fail",
+			cookie: "old",
+			mockBehavior: func(s *mocks.AuthService) {
+				s.On("RefreshTokens", mock.Anything, "old").Return("", "", domain.ErrRefreshTokenReused)
+			},
+			expectedStatusCode: http.StatusUnauthorized,
+		},
+	}
+
+	for _, testCase := range testTable {
+		t.Run(testCase.name, func(t *testing.T) {
+			s := mocks.NewAuthService(t)
+			testCase.mockBehavior(s)
+
+			handler := NewHandler(nil, s, jwtkeys.NewHMAC([]byte("secret")), testCase.cfg)
+			e := echo.New()
+			e.POST("/auth/refresh", handler.refresh)
+
+			req := httptest.NewRequest(http.MethodPost, "/auth/refresh", bytes.NewBufferString(testCase.body))
+			req.Header.Set(echo.HeaderContentType, echo.MIMEApplicationJSON)
+			for k, v := range testCase.headers {
+				req.Header.Set(k, v)
+			}
+			if testCase.cookie != "" {
+				req.AddCookie(&http.Cookie{Name: refreshCookieName, Value: testCase.cookie})
+			}
+			rec := httptest.NewRecorder()
+			e.ServeHTTP(rec, req)
+
+			assert.Equal(t, testCase.expectedStatusCode, rec.Code)
+			if testCase.expectedBody != "" {
+				assert.JSONEq(t, testCase.expectedBody, rec.Body.String())
+			}
+
+			cookies := rec.Result().Cookies()
+			if !testCase.expectCookie {
+				assert.Empty(t, cookies)
+				return
+			}
+			if assert.Len(t, cookies, 1) {
+				assert.Equal(t, "new", cookies[0].Value)
+				assert.True(t, cookies[0].HttpOnly)
+				assert.True(t, cookies[0].Secure)
+				assert.Equal(t, http.SameSiteStrictMode, cookies[0].SameSite)
+				assert.Equal(t, "example.com", cookies[0].Domain)
+			}
+		})
+	}
+}
+
+func TestCookiePolicy_SameSiteNoneRequiresSecure(t *testing.T) {
+	cookie := CookiePolicy{SameSite: http.SameSiteNoneMode}.cookie("token", 60)
+	assert.True(t, cookie.Secure)
+
+	cookie = CookiePolicy{}.cookie("token", 60)
+	assert.Equal(t, http.SameSiteLaxMode, cookie.SameSite)
+	assert.False(t, cookie.Secure)
+}
+
+func TestHandler_LegacyGetSignIn(t *testing.T) {
+	body := `{"email": "test@test.kz", "password": "test1234"}`
+
+	for _, legacy := range []bool{false, true} {
+		s := mocks.NewAuthService(t)
+		s.On("SignIn", mock.Anything, mock.Anything).Return(domain.SignInResult{AccessToken: "access", RefreshToken: "refresh"}, nil)
+
+		e := NewHandler(nil, s, jwtkeys.NewHMAC([]byte("secret")), Config{LegacyGetSignIn: legacy}).InitRouter()
+
+		req := httptest.NewRequest(http.MethodPost, "/auth/sign-in", bytes.NewBufferString(body))
+		req.Header.Set(echo.HeaderContentType, echo.MIMEApplicationJSON)
+		rec := httptest.NewRecorder()
+		e.ServeHTTP(rec, req)
+		assert.Equal(t, http.StatusOK, rec.Code)
+
+		req = httptest.NewRequest(http.MethodGet, "/auth/sign-in", bytes.NewBufferString(body))
+		req.Header.Set(echo.HeaderContentType, echo.MIMEApplicationJSON)
+		rec = httptest.NewRecorder()
+		e.ServeHTTP(rec, req)
+		if legacy {
+			assert.Equal(t, http.StatusOK, rec.Code)
+			assert.Equal(t, "true", rec.Header().Get("Deprecation"))
+		} else {
+			assert.Equal(t, http.StatusMethodNotAllowed, rec.Code)
+		}
+	}
+}
@@ -4,6 +4,7 @@ import (
 	"context"
 
 	"github.com/CryptoGu1/books-rest-clean-arch/internal/domain"
+	"github.com/CryptoGu1/books-rest-clean-arch/pkg/jwtkeys"
 	"github.com/go-playground/validator/v10"
 	"github.com/labstack/echo/v4"
 	"github.com/labstack/echo/v4/middleware"
@@ -13,61 +14,155 @@ import (
 type BookService interface {
 	Create(ctx context.Context, input *domain.CreateBookInput) (int, error)
 	GetById(ctx context.Context, id int) (*domain.Book, error)
-	GetAll(ctx context.Context) ([]*domain.Book, error)
-	Update(ctx context.Context, id int, book *domain.Book) error
+	GetAll(ctx context.Context, query domain.BookQuery) (*domain.BookPage, error)
+	GetAllAfter(ctx context.Context, query domain.BookQuery, cursor string) (*domain.BookCursorPage, error)
+	Search(ctx context.Context, query domain.BookSearchQuery) (*domain.BookSearchPage, error)
+	Update(ctx context.Context, id int, patch domain.BookPatch) error
+	ApplyJSONPatch(ctx context.Context, id int, ops []domain.JSONPatchOp) error
 	Delete(ctx context.Context, id int) error
 }
 
 type AuthService interface {
 	SignUp(ctx context.Context, input domain.SingUpInput) (int, error)
-	SignIn(ctx context.Context, input domain.SingInInput) (string, string, error)
+	SignIn(ctx context.Context, input domain.SingInInput) (domain.SignInResult, error)
+	VerifyMFA(ctx context.Context, input domain.MFAVerifyInput) (string, string, error)
 	RefreshTokens(ctx context.Context, refreshToken string) (string, string, error)
+	Logout(ctx context.Context, refreshToken string) error
+	LogoutAll(ctx context.Context, userID int64) error
+	ListSessions(ctx context.Context, userID int64, currentToken string) ([]domain.Session, error)
+	RevokeSession(ctx context.Context, userID int64, sessionID string) error
+	ForgotPassword(ctx context.Context, input domain.ForgotPasswordInput) error
+	ResetPassword(ctx context.Context, input domain.ResetPasswordInput) error
+	VerifyEmail(ctx context.Context, token string) error
+	ResendVerification(ctx context.Context, input domain.ResendVerificationInput) error
+	GetProfile(ctx context.Context, userID int64) (domain.User, error)
+	UpdateProfile(ctx context.Context, userID int64, input domain.UpdateProfileInput) (domain.User, error)
+	ChangePassword(ctx context.Context, userID int64, input domain.ChangePasswordInput) error
+	DeleteAccount(ctx context.Context, userID int64, input domain.DeleteAccountInput) error
+	CreateAPIKey(ctx context.Context, principal domain.Principal, input domain.CreateAPIKeyInput) (domain.CreatedAPIKey, error)
+	ListAPIKeys(ctx context.Context, userID int64) ([]domain.APIKey, error)
+	RevokeAPIKey(ctx context.Context, userID, keyID int64) error
+	AuthenticateAPIKey(ctx context.Context, rawKey string) (domain.Principal, error)
+	OIDCLogin(ctx context.Context, provider string) (string, string, error)
+	OIDCCallback(ctx context.Context, input domain.OIDCCallbackInput) (domain.SignInResult, error)
+	EnrollTOTP(ctx context.Context, userID int64) (domain.TOTPEnrollment, error)
+	ConfirmTOTP(ctx context.Context, userID int64, code string) error
+	DisableTOTP(ctx context.Context, userID int64, code string) error
+	GetUserRoles(ctx context.Context, userID int64) ([]domain.Role, error)
+	AssignRole(ctx context.Context, userID int64, role string) error
+	RevokeRole(ctx context.Context, userID int64, role string) error
 }
 
 type Handler struct {
 	bookService BookService
 	UserService AuthService
 	validate    *validator.Validate
-	jwtSecret   []byte
+	tokenKeys   *jwtkeys.KeySet
+	cfg         Config
 }
 
-func NewHandler(bookService BookService, userService AuthService, jwtSecret []byte) *Handler {
+func NewHandler(bookService BookService, userService AuthService, tokenKeys *jwtkeys.KeySet, cfg Config) *Handler {
 	return &Handler{
 		bookService: bookService,
 		UserService: userService,
 		validate:    validator.New(),
-		jwtSecret:   jwtSecret,
+		tokenKeys:   tokenKeys,
+		cfg:         cfg,
 	}
 }
 
 func (h *Handler) InitRouter() *echo.Echo {
 	e := echo.New()
+	e.IPExtractor = h.ipExtractor()
 
 	//Middlewares
 	e.Use(LoggingMiddleware)
+	e.Use(middleware.RequestID())
+	e.Use(ClientInfoMiddleware)
 	e.Use(middleware.Recover())
 	e.Use(middleware.CORS())
 
 	//Swager
 	e.GET("/swagger/*", echoSwagger.WrapHandler)
 
+	e.GET("/.well-known/jwks.json", h.jwks)
+
 	auth := e.Group("/auth")
 	{
 		auth.POST("/sign-up", h.signUp)
-		auth.GET("/sign-in", h.signIn)
+		auth.POST("/sign-in", h.signIn)
+		if h.cfg.LegacyGetSignIn {
+			auth.GET("/sign-in", h.legacySignIn)
+		}
 		auth.POST("/refresh", h.refresh)
+		auth.POST("/logout", h.logout)
+		auth.POST("/logout-all", h.logoutAll, h.JWTMiddleware, RequireInteractive)
+		auth.GET("/sessions", h.listSessions, h.JWTMiddleware, RequireInteractive)
+		auth.DELETE("/sessions/:id", h.revokeSession, h.JWTMiddleware, RequireInteractive)
+		auth.POST("/password/forgot", h.forgotPassword)
+		auth.POST("/password/reset", h.resetPassword)
+		auth.GET("/verify", h.verifyEmail)
+		auth.POST("/verify/resend", h.resendVerification)
+		auth.GET("/oidc/:provider/login", h.oidcLogin)
+		auth.GET("/oidc/:provider/callback", h.oidcCallback)
+		auth.POST("/mfa/verify", h.verifyMFA)
+		auth.POST("/mfa/totp", h.enrollTOTP, h.JWTMiddleware, RequireInteractive)
+		auth.POST("/mfa/totp/confirm", h.confirmTOTP, h.JWTMiddleware, RequireInteractive)
+		auth.DELETE("/mfa/totp", h.disableTOTP, h.JWTMiddleware, RequireInteractive)
 
 	}
 
 	booksGroup := e.Group("/books")
 	booksGroup.Use(h.JWTMiddleware)
 	{
-		booksGroup.POST("", h.Create)
+		canWrite := RequirePermission(domain.PermBooksWrite)
+
+		booksGroup.POST("", h.Create, canWrite)
+		booksGroup.GET("/search", h.Search)
 		booksGroup.GET("/:id", h.GetById)
 		booksGroup.GET("", h.GetAll)
-		booksGroup.PUT("/:id", h.Update)
-		booksGroup.DELETE("/:id", h.Delete)
+		booksGroup.PUT("/:id", h.Update, canWrite)
+		booksGroup.PATCH("/:id", h.Patch, canWrite)
+		booksGroup.DELETE("/:id", h.Delete, canWrite)
+	}
+
+	usersGroup := e.Group("/users")
+	usersGroup.Use(h.JWTMiddleware)
+	{
+		usersGroup.GET("/me", h.getProfile)
+		usersGroup.PATCH("/me", h.updateProfile, RequireInteractive)
+		usersGroup.DELETE("/me", h.deleteAccount, RequireInteractive)
+		usersGroup.POST("/me/password", h.changePassword, RequireInteractive)
+		usersGroup.GET("/me/api-keys", h.listAPIKeys, RequireInteractive)
+		usersGroup.POST("/me/api-keys", h.createAPIKey, RequireInteractive)
+		usersGroup.DELETE("/me/api-keys/:id", h.revokeAPIKey, RequireInteractive)
+		usersGroup.GET("/me/books", h.GetMyBooks)
+	}
+
+	adminGroup := e.Group("/admin")
+	adminGroup.Use(h.JWTMiddleware, RequirePermission(domain.PermRolesManage))
+	{
+		adminGroup.GET("/users/:id/roles", h.getUserRoles)
+		adminGroup.PUT("/users/:id/roles/:role", h.assignRole)
+		adminGroup.DELETE("/users/:id/roles/:role", h.revokeRole)
 	}
 
 	return e
 }
+
+// ipExtractor: X-Forwarded-For учитывается, только если запрос пришёл от доверенного прокси.
+// Иначе клиент сам выбирал бы себе IP и обходил блокировку входа по IP
+func (h *Handler) ipExtractor() echo.IPExtractor {
+	if len(h.cfg.TrustedProxies) == 0 {
+		return echo.ExtractIPDirect()
+	}
+	opts := []echo.TrustOption{
+		echo.TrustLoopback(false),
+		echo.TrustLinkLocal(false),
+		echo.TrustPrivateNet(false),
+	}
+	for _, ipNet := range h.cfg.TrustedProxies {
+		opts = append(opts, echo.TrustIPRange(ipNet))
+	}
+	return echo.ExtractIPFromXFFHeader(opts...)
+}
@@ -0,0 +1,20 @@
+package http
+
+import (
+	"net/http"
+
+	"github.com/labstack/echo/v4"
+)
+
+// jwks godoc
+// @Summary      JSON Web Key Set
+// @Description  Публичные ключи для проверки access-токенов другими сервисами (RFC 7517)
+// @Tags         auth
+// @Produce      json
+// @Success      200  {object}  jwtkeys.JWKS
+// @Router       /.well-known/jwks.json [get]
+func (h *Handler) jwks(c echo.Context) error {
+	// ключи меняются только при ротации, клиентам можно их кешировать
+	c.Response().Header().Set("Cache-Control", "public, max-age=300")
+	return respondJSON(c, http.StatusOK, h.tokenKeys.JWKS())
+}
@@ -0,0 +1,116 @@
+package http
+
+import (
+	"context"
+	"net/http"
+
+	"github.com/CryptoGu1/books-rest-clean-arch/internal/domain"
+	"github.com/labstack/echo/v4"
+)
+
+// verifyMFA godoc
+// @Summary      Complete sign-in with a second factor
+// @Description  Обменивает mfa_token из /auth/sign-in и код из приложения (или резервный код) на пару токенов
+// @Tags         auth
+// @Accept       json
+// @Produce      json
+// @Param        input  body      domain.MFAVerifyInput  true  "MFA token and code"
+// @Success      200    {object}  map[string]string
+// @Failure      400    {object}  map[string]string
+// @Failure      401    {object}  map[string]string
+// @Failure      429    {object}  map[string]string
+// @Router       /auth/mfa/verify [post]
+func (h *Handler) verifyMFA(c echo.Context) error {
+	var input domain.MFAVerifyInput
+	if err := c.Bind(&input); err != nil {
+		return respondErr(c, echo.NewHTTPError(http.StatusBadRequest, "invalid request body"))
+	}
+	if err := h.validate.Struct(input); err != nil {
+		return respondErr(c, echo.NewHTTPError(http.StatusBadRequest, "invalid input body"))
+	}
+
+	token, refresh, err := h.UserService.VerifyMFA(c.Request().Context(), input)
+	if err != nil {
+		logError("verify-mfa", err)
+		setRetryAfter(c, err)
+		return respondErr(c, err)
+	}
+
+	return h.issueTokens(c, token, refresh)
+}
+
+// enrollTOTP godoc
+// @Summary      Start TOTP enrollment
+// @Description  Генерирует секрет, otpauth-ссылку и резервные коды. Они показываются один раз; 2FA включится после подтверждения кодом
+// @Tags         auth
+// @Produce      json
+// @Success      201  {object}  domain.TOTPEnrollment
+// @Failure      401  {object}  map[string]string
+// @Failure      409  {object}  map[string]string
+// @Router       /auth/mfa/totp [post]
+func (h *Handler) enrollTOTP(c echo.Context) error {
+	ctx := c.Request().Context()
+	principal, ok := domain.PrincipalFromContext(ctx)
+	if !ok {
+		return respondErr(c, echo.NewHTTPError(http.StatusUnauthorized, "unauthorized"))
+	}
+
+	enrollment, err := h.UserService.EnrollTOTP(ctx, principal.UserID)
+	if err != nil {
+		logError("enroll-totp", err)
+		return respondErr(c, err)
+	}
+	return respondJSON(c, http.StatusCreated, enrollment)
+}
+
+// confirmTOTP godoc
+// @Summary      Confirm TOTP enrollment
+// @Description  Включает 2FA, если код из приложения верный
+// @Tags         auth
+// @Accept       json
+// @Param        input  body  domain.MFACodeInput  true  "Code from the authenticator app"
+// @Success      204
+// @Failure      400  {object}  map[string]string
+// @Failure      401  {object}  map[string]string
+// @Failure      409  {object}  map[string]string
+// @Router       /auth/mfa/totp/confirm [post]
+func (h *Handler) confirmTOTP(c echo.Context) error {
+	return h.withMFACode(c, "confirm-totp", h.UserService.ConfirmTOTP)
+}
+
+// disableTOTP godoc
+// @Summary      Disable TOTP
+// @Description  Отключает 2FA; нужен код из приложения или резервный код
+// @Tags         auth
+// @Accept       json
+// @Param        input  body  domain.MFACodeInput  true  "Code from the authenticator app or a recovery code"
+// @Success      204
+// @Failure      400  {object}  map[string]string
+// @Failure      401  {object}  map[string]string
+// @Failure      409  {object}  map[string]string
+// @Router       /auth/mfa/totp [delete]
+func (h *Handler) disableTOTP(c echo.Context) error {
+	return h.withMFACode(c, "disable-totp", h.UserService.DisableTOTP)
+}
+
+func (h *Handler) withMFACode(c echo.Context, name string, action func(ctx context.Context, userID int64, code string) error) error {
+	ctx := c.Request().Context()
+	principal, ok := domain.PrincipalFromContext(ctx)
+	if !ok {
+		return respondErr(c, echo.NewHTTPError(http.StatusUnauthorized, "unauthorized"))
+	}
+
+	var input domain.MFACodeInput
+	if err := c.Bind(&input); err != nil {
+		return respondErr(c, echo.NewHTTPError(http.StatusBadRequest, "invalid request body"))
+	}
+	if err := h.validate.Struct(input); err != nil {
+		return respondErr(c, echo.NewHTTPError(http.StatusBadRequest, "invalid input body"))
+	}
+
+	if err := action(ctx, principal.UserID, input.Code); err != nil {
+		logError(name, err)
+		return respondErr(c, err)
+	}
+	return c.NoContent(http.StatusNoContent)
+}
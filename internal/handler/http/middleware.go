@@ -1,11 +1,12 @@
 package http
 
 import (
+	"errors"
 	"net/http"
 	"strconv"
 	"strings"
 
-	"github.com/golang-jwt/jwt/v5"
+	"github.com/CryptoGu1/books-rest-clean-arch/internal/domain"
 	"github.com/labstack/echo/v4"
 	"github.com/sirupsen/logrus"
 )
@@ -24,8 +25,29 @@ func LoggingMiddleware(next echo.HandlerFunc) echo.HandlerFunc {
 	}
 }
 
+// ClientInfoMiddleware кладёт IP, User-Agent и ID запроса в контекст запроса для сервисного слоя.
+// ID запроса выставляет middleware.RequestID, поэтому он должен стоять раньше
+func ClientInfoMiddleware(next echo.HandlerFunc) echo.HandlerFunc {
+	return func(c echo.Context) error {
+		req := c.Request()
+		ctx := domain.WithClientInfo(req.Context(), domain.ClientInfo{
+			IP:        c.RealIP(),
+			UserAgent: req.UserAgent(),
+			RequestID: c.Response().Header().Get(echo.HeaderXRequestID),
+		})
+		c.SetRequest(req.WithContext(ctx))
+		return next(c)
+	}
+}
+
+// JWTMiddleware аутентифицирует запрос access-токеном (Authorization: Bearer) или
+// API-ключом (Authorization: ApiKey <key> либо X-API-Key) и кладёт Principal в контекст
 func (h *Handler) JWTMiddleware(next echo.HandlerFunc) echo.HandlerFunc {
 	return func(c echo.Context) error {
+		if apiKey, ok := apiKeyFromRequest(c.Request()); ok {
+			return h.authenticateAPIKey(c, next, apiKey)
+		}
+
 		authHeader := c.Request().Header.Get("Authorization")
 		if authHeader == "" {
 			return echo.NewHTTPError(http.StatusUnauthorized, "Missing Authorization header")
@@ -38,14 +60,12 @@ func (h *Handler) JWTMiddleware(next echo.HandlerFunc) echo.HandlerFunc {
 		}
 		tokenStr := parts[1]
 
-		token, err := jwt.ParseWithClaims(tokenStr, &jwt.RegisteredClaims{}, func(token *jwt.Token) (interface{}, error) {
-			return h.jwtSecret, nil
-		})
+		token, err := h.tokenKeys.Parse(tokenStr, &domain.AccessClaims{})
 		if err != nil || !token.Valid {
 			return echo.NewHTTPError(http.StatusUnauthorized, "Invalid token")
 		}
 
-		claims, ok := token.Claims.(*jwt.RegisteredClaims)
+		claims, ok := token.Claims.(*domain.AccessClaims)
 		if !ok {
 			return echo.NewHTTPError(http.StatusUnauthorized, "Invalid claims")
 
@@ -58,6 +78,91 @@ func (h *Handler) JWTMiddleware(next echo.HandlerFunc) echo.HandlerFunc {
 		}
 
 		c.Set("userID", userId)
+
+		ctx := domain.WithPrincipal(c.Request().Context(), domain.Principal{
+			UserID:      int64(userId),
+			Roles:       claims.Roles,
+			Permissions: claims.Permissions,
+		})
+		c.SetRequest(c.Request().WithContext(ctx))
 		return next(c)
 	}
 }
+
+func (h *Handler) authenticateAPIKey(c echo.Context, next echo.HandlerFunc, apiKey string) error {
+	principal, err := h.UserService.AuthenticateAPIKey(c.Request().Context(), apiKey)
+	if err != nil {
+		if errors.Is(err, domain.ErrInvalidAPIKey) {
+			return echo.NewHTTPError(http.StatusUnauthorized, "Invalid API key")
+		}
+		logError("api-key", err)
+		return echo.NewHTTPError(http.StatusInternalServerError, "internal error")
+	}
+
+	c.Set("userID", int(principal.UserID))
+	c.SetRequest(c.Request().WithContext(domain.WithPrincipal(c.Request().Context(), principal)))
+	return next(c)
+}
+
+func apiKeyFromRequest(req *http.Request) (string, bool) {
+	if key := req.Header.Get("X-API-Key"); key != "" {
+		return key, true
+	}
+	scheme, key, ok := strings.Cut(req.Header.Get("Authorization"), " ")
+	if ok && strings.EqualFold(scheme, "ApiKey") && key != "" {
+		return key, true
+	}
+	return "", false
+}
+
+// RequireInteractive отклоняет запросы с API-ключом: ключами и сессиями управляет только
+// сам пользователь, иначе утёкший ключ мог бы выпустить себе замену
+func RequireInteractive(next echo.HandlerFunc) echo.HandlerFunc {
+	return func(c echo.Context) error {
+		principal, ok := domain.PrincipalFromContext(c.Request().Context())
+		if !ok {
+			return echo.NewHTTPError(http.StatusUnauthorized, "Unauthorized")
+		}
+		if principal.APIKeyID != 0 {
+			return echo.NewHTTPError(http.StatusForbidden, "Not allowed with an API key")
+		}
+		return next(c)
+	}
+}
+
+// RequireRole пропускает запрос, если у пользователя есть хотя бы одна из ролей.
+// Ставится после JWTMiddleware
+func RequireRole(roles ...string) echo.MiddlewareFunc {
+	return func(next echo.HandlerFunc) echo.HandlerFunc {
+		return func(c echo.Context) error {
+			principal, ok := domain.PrincipalFromContext(c.Request().Context())
+			if !ok {
+				return echo.NewHTTPError(http.StatusUnauthorized, "Unauthorized")
+			}
+			for _, role := range roles {
+				if principal.HasRole(role) {
+					return next(c)
+				}
+			}
+			return echo.NewHTTPError(http.StatusForbidden, "Insufficient role")
+		}
+	}
+}
+
+// RequirePermission пропускает запрос, если у пользователя есть все перечисленные права
+func RequirePermission(perms ...string) echo.MiddlewareFunc {
+	return func(next echo.HandlerFunc) echo.HandlerFunc {
+		return func(c echo.Context) error {
+			principal, ok := domain.PrincipalFromContext(c.Request().Context())
+			if !ok {
+				return echo.NewHTTPError(http.StatusUnauthorized, "Unauthorized")
+			}
+			for _, perm := range perms {
+				if !principal.HasPermission(perm) {
+					return echo.NewHTTPError(http.StatusForbidden, "Insufficient permissions")
+				}
+			}
+			return next(c)
+		}
+	}
+}
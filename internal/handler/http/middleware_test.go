@@ -0,0 +1,265 @@
+package http
+
+import (
+	"crypto/ed25519"
+	"crypto/rand"
+	"encoding/base64"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/CryptoGu1/books-rest-clean-arch/internal/domain"
+	"github.com/CryptoGu1/books-rest-clean-arch/mocks"
+	"github.com/CryptoGu1/books-rest-clean-arch/pkg/jwtkeys"
+	"github.com/golang-jwt/jwt/v5"
+	"github.com/labstack/echo/v4"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+	"github.com/stretchr/testify/require"
+)
+
+func signTestToken(t *testing.T, secret []byte, roles, perms []string) string {
+	claims := &domain.AccessClaims{
+		RegisteredClaims: jwt.RegisteredClaims{
+			Subject:   "7",
+			ExpiresAt: jwt.NewNumericDate(time.Now().Add(time.Hour)),
+		},
+		Roles:       roles,
+		Permissions: perms,
+	}
+	token, err := jwt.NewWithClaims(jwt.SigningMethodHS256, claims).SignedString(secret)
+	require.NoError(t, err)
+	return token
+}
+
+func TestRequirePermission(t *testing.T) {
+	secret := []byte("secret")
+
+	testTable := []struct {
+		name               string
+		authHeader         string
+		expectedStatusCode int
+	}{
+		{
+			name:               "librarian",
+			authHeader:         "Bearer " + signTestToken(t, secret, []string{domain.RoleLibrarian}, []string{domain.PermBooksWrite}),
+			expectedStatusCode: http.StatusOK,
+		},
+		{
+			name:               "reader",
+			authHeader:         "Bearer " + signTestToken(t, secret, []string{domain.RoleReader}, nil),
+			expectedStatusCode: http.StatusForbidden,
+		},
+		{
+			name:               "foreign secret",
+			authHeader:         "Bearer " + signTestToken(t, []byte("other"), nil, []string{domain.PermBooksWrite}),
+			expectedStatusCode: http.StatusUnauthorized,
+		},
+		{
+			name:               "no token",
+			expectedStatusCode: http.StatusUnauthorized,
+		},
+	}
+
+	for _, testCase := range testTable {
+		t.Run(testCase.name, func(t *testing.T) {
+			handler := NewHandler(nil, nil, jwtkeys.NewHMAC(secret), Config{})
+			e := echo.New()
+
+			var principal domain.Principal
+			e.POST("/books", func(c echo.Context) error {
+				principal, _ = domain.PrincipalFromContext(c.Request().Context())
+				return c.NoContent(http.StatusOK)
+			}, handler.JWTMiddleware, RequirePermission(domain.PermBooksWrite))
+
+			req := httptest.NewRequest(http.MethodPost, "/books", nil)
+			if testCase.authHeader != "" {
+				req.Header.Set(echo.HeaderAuthorization, testCase.authHeader)
+			}
+			rec := httptest.NewRecorder()
+			e.ServeHTTP(rec, req)
+
+			assert.Equal(t, testCase.expectedStatusCode, rec.Code)
+			if rec.Code == http.StatusOK {
+				assert.Equal(t, int64(7), principal.UserID)
+				assert.True(t, principal.HasRole(domain.RoleLibrarian))
+			}
+		})
+	}
+}
+
+func TestJWTMiddleware_APIKey(t *testing.T) {
+	testTable := []struct {
+		name               string
+		headers            map[string]string
+		mockBehavior       func(s *mocks.AuthService)
+		interactiveOnly    bool
+		expectedStatusCode int
+	}{
+		{
+			name:    "x-api-key header",
+			headers: map[string]string{"X-API-Key": "bk_valid"},
+			mockBehavior: func(s *mocks.AuthService) {
+				s.On("AuthenticateAPIKey", mock.Anything, "bk_valid").
+					Return(domain.Principal{UserID: 7, Permissions: []string{domain.PermBooksWrite}, APIKeyID: 1}, nil)
+			},
+			expectedStatusCode: http.StatusOK,
+		},
+		{
+			name:    "apikey scheme",
+			headers: map[string]string{echo.HeaderAuthorization: "ApiKey bk_valid"},
+			mockBehavior: func(s *mocks.AuthService) {
+				s.On("AuthenticateAPIKey", mock.Anything, "bk_valid").
+					Return(domain.Principal{UserID: 7, Permissions: []string{domain.PermBooksWrite}, APIKeyID: 1}, nil)
+			},
+			expectedStatusCode: http.StatusOK,
+		},
+		{
+			name:    "revoked key",
+			headers: map[string]string{"X-API-Key": "bk_revoked"},
+			mockBehavior: func(s *mocks.AuthService) {
+				s.On("AuthenticateAPIKey", mock.Anything, "bk_revoked").Return(domain.Principal{}, domain.ErrInvalidAPIKey)
+			},
+			expectedStatusCode: http.StatusUnauthorized,
+		},
+		{
+			name:    "key cannot manage keys",
+			headers: map[string]string{"X-API-Key": "bk_valid"},
+			mockBehavior: func(s *mocks.AuthService) {
+				s.On("AuthenticateAPIKey", mock.Anything, "bk_valid").
+					Return(domain.Principal{UserID: 7, APIKeyID: 1}, nil)
+			},
+			interactiveOnly:    true,
+			expectedStatusCode: http.StatusForbidden,
+		},
+	}
+
+	for _, testCase := range testTable {
+		t.Run(testCase.name, func(t *testing.T) {
+			s := mocks.NewAuthService(t)
+			testCase.mockBehavior(s)
+
+			handler := NewHandler(nil, s, jwtkeys.NewHMAC([]byte("secret")), Config{})
+			e := echo.New()
+
+			var principal domain.Principal
+			ok := func(c echo.Context) error {
+				principal, _ = domain.PrincipalFromContext(c.Request().Context())
+				return c.NoContent(http.StatusOK)
+			}
+			if testCase.interactiveOnly {
+				e.POST("/users/me/api-keys", ok, handler.JWTMiddleware, RequireInteractive)
+			} else {
+				e.POST("/users/me/api-keys", ok, handler.JWTMiddleware, RequirePermission(domain.PermBooksWrite))
+			}
+
+			req := httptest.NewRequest(http.MethodPost, "/users/me/api-keys", nil)
+			for k, v := range testCase.headers {
+				req.Header.Set(k, v)
+			}
+			rec := httptest.NewRecorder()
+			e.ServeHTTP(rec, req)
+
+			assert.Equal(t, testCase.expectedStatusCode, rec.Code)
+			if rec.Code == http.StatusOK {
+				assert.Equal(t, int64(7), principal.UserID)
+				assert.Equal(t, int64(1), principal.APIKeyID)
+			}
+		})
+	}
+}
+
+func TestJWTMiddleware_AsymmetricKeys(t *testing.T) {
+	_, priv, err := ed25519.GenerateKey(rand.Reader)
+	require.NoError(t, err)
+	key, err := jwtkeys.NewKey("2026-10", priv, time.Time{})
+	require.NoError(t, err)
+	keys, err := jwtkeys.New("2026-10", key)
+	require.NoError(t, err)
+
+	signed, err := keys.Sign(&domain.AccessClaims{
+		RegisteredClaims: jwt.RegisteredClaims{
+			Subject:   "7",
+			ExpiresAt: jwt.NewNumericDate(time.Now().Add(time.Hour)),
+		},
+	})
+	require.NoError(t, err)
+
+	handler := NewHandler(nil, nil, keys, Config{})
+	e := handler.InitRouter()
+	e.GET("/ping", func(c echo.Context) error { return c.NoContent(http.StatusOK) }, handler.JWTMiddleware)
+
+	testTable := []struct {
+		name               string
+		token              string
+		expectedStatusCode int
+	}{
+		{name: "signed with current key", token: signed, expectedStatusCode: http.StatusOK},
+		{name: "legacy hs256 token", token: signTestToken(t, []byte("secret"), nil, nil), expectedStatusCode: http.StatusUnauthorized},
+	}
+
+	for _, testCase := range testTable {
+		t.Run(testCase.name, func(t *testing.T) {
+			req := httptest.NewRequest(http.MethodGet, "/ping", nil)
+			req.Header.Set(echo.HeaderAuthorization, "Bearer "+testCase.token)
+			rec := httptest.NewRecorder()
+			e.ServeHTTP(rec, req)
+
+			assert.Equal(t, testCase.expectedStatusCode, rec.Code)
+		})
+	}
+
+	t.Run("jwks", func(t *testing.T) {
+		req := httptest.NewRequest(http.MethodGet, "/.well-known/jwks.json", nil)
+		rec := httptest.NewRecorder()
+		e.ServeHTTP(rec, req)
+
+		require.Equal(t, http.StatusOK, rec.Code)
+		var set jwtkeys.JWKS
+		require.NoError(t, json.Unmarshal(rec.Body.Bytes(), &set))
+		require.Len(t, set.Keys, 1)
+		assert.Equal(t, "2026-10", set.Keys[0].Kid)
+		assert.Equal(t, "EdDSA", set.Keys[0].Alg)
+		assert.Equal(t, base64.RawURLEncoding.EncodeToString(priv.Public().(ed25519.PublicKey)), set.Keys[0].X)
+	})
+}
+
+func TestClientInfoMiddleware_IP(t *testing.T) {
+	proxies, err := ParseTrustedProxies([]string{"10.0.0.0/8"})
+	require.NoError(t, err)
+
+	testTable := []struct {
+		name       string
+		cfg        Config
+		remoteAddr string
+		expectedIP string
+	}{
+		{name: "forwarded header is ignored without trusted proxies", remoteAddr: "203.0.113.5:1234", expectedIP: "203.0.113.5"},
+		{name: "untrusted proxy", cfg: Config{TrustedProxies: proxies}, remoteAddr: "203.0.113.5:1234", expectedIP: "203.0.113.5"},
+		{name: "trusted proxy", cfg: Config{TrustedProxies: proxies}, remoteAddr: "10.1.2.3:1234", expectedIP: "198.51.100.7"},
+	}
+
+	for _, testCase := range testTable {
+		t.Run(testCase.name, func(t *testing.T) {
+			h := &Handler{cfg: testCase.cfg}
+			e := echo.New()
+			e.IPExtractor = h.ipExtractor()
+
+			var ip string
+			e.GET("/ip", func(c echo.Context) error {
+				ip = domain.ClientInfoFromContext(c.Request().Context()).IP
+				return c.NoContent(http.StatusOK)
+			}, ClientInfoMiddleware)
+
+			req := httptest.NewRequest(http.MethodGet, "/ip", nil)
+			req.RemoteAddr = testCase.remoteAddr
+			req.Header.Set(echo.HeaderXForwardedFor, "198.51.100.7")
+			req.Header.Set(echo.HeaderXRealIP, "198.51.100.7")
+			e.ServeHTTP(httptest.NewRecorder(), req)
+
+			assert.Equal(t, testCase.expectedIP, ip)
+		})
+	}
+}
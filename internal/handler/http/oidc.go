@@ -0,0 +1,84 @@
+package http
+
+import (
+	"net/http"
+	"time"
+
+	"github.com/CryptoGu1/books-rest-clean-arch/internal/domain"
+	"github.com/labstack/echo/v4"
+)
+
+const oidcStateCookie = "oidc-state"
+
+// oidcLogin godoc
+// @Summary      Sign in with an external provider
+// @Description  Перенаправляет к OpenID Connect провайдеру (authorization code + PKCE)
+// @Tags         auth
+// @Param        provider  path  string  true  "Provider name from config"
+// @Success      302
+// @Failure      404  {object}  map[string]string
+// @Router       /auth/oidc/{provider}/login [get]
+func (h *Handler) oidcLogin(c echo.Context) error {
+	authURL, stateToken, err := h.UserService.OIDCLogin(c.Request().Context(), c.Param("provider"))
+	if err != nil {
+		logError("oidc-login", err)
+		return respondErr(c, err)
+	}
+
+	// Lax, а не Strict: возврат от провайдера — переход с чужого сайта
+	c.SetCookie(&http.Cookie{
+		Name:     oidcStateCookie,
+		Value:    stateToken,
+		Path:     "/auth/oidc",
+		HttpOnly: true,
+		SameSite: http.SameSiteLaxMode,
+		MaxAge:   int(domain.OIDCStateTTL / time.Second),
+	})
+	return c.Redirect(http.StatusFound, authURL)
+}
+
+// oidcCallback godoc
+// @Summary      External provider callback
+// @Description  Завершает вход через провайдера. Ответ такой же, как у /auth/sign-in, включая запрос второго фактора
+// @Tags         auth
+// @Produce      json
+// @Param        provider  path   string  true  "Provider name from config"
+// @Param        code      query  string  true  "Authorization code"
+// @Param        state     query  string  true  "State"
+// @Success      200  {object}  map[string]interface{}
+// @Failure      401  {object}  map[string]string
+// @Failure      403  {object}  map[string]string
+// @Failure      409  {object}  map[string]string
+// @Router       /auth/oidc/{provider}/callback [get]
+func (h *Handler) oidcCallback(c echo.Context) error {
+	// state одноразовый — cookie удаляем при любом исходе
+	c.SetCookie(&http.Cookie{
+		Name:     oidcStateCookie,
+		Value:    "",
+		Path:     "/auth/oidc",
+		HttpOnly: true,
+		SameSite: http.SameSiteLaxMode,
+		MaxAge:   -1,
+	})
+
+	if reason := c.QueryParam("error"); reason != "" {
+		return respondErr(c, echo.NewHTTPError(http.StatusUnauthorized, "sign-in rejected by provider: "+reason))
+	}
+
+	cookie, err := c.Cookie(oidcStateCookie)
+	if err != nil || cookie.Value == "" || c.QueryParam("code") == "" {
+		return respondErr(c, domain.ErrOIDCFailed)
+	}
+
+	result, err := h.UserService.OIDCCallback(c.Request().Context(), domain.OIDCCallbackInput{
+		Provider:   c.Param("provider"),
+		Code:       c.QueryParam("code"),
+		State:      c.QueryParam("state"),
+		StateToken: cookie.Value,
+	})
+	if err != nil {
+		logError("oidc-callback", err)
+		return respondErr(c, err)
+	}
+	return h.respondSignIn(c, result)
+}
@@ -0,0 +1,83 @@
+package http
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/CryptoGu1/books-rest-clean-arch/internal/domain"
+	"github.com/CryptoGu1/books-rest-clean-arch/mocks"
+	"github.com/CryptoGu1/books-rest-clean-arch/pkg/jwtkeys"
+	"github.com/labstack/echo/v4"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+)
+
+func TestHandler_oidcCallback(t *testing.T) {
+	type mockBehavior func(s *mocks.AuthService)
+
+	testTable := []struct {
+		name               string
+		query              string
+		cookie             string
+		mockBehavior       mockBehavior
+		expectedStatusCode int
+	}{
+		{
+			name:   "ok",
+			query:  "?code=code&state=state",
+			cookie: "state-token",
+			mockBehavior: func(s *mocks.AuthService) {
+				s.On("OIDCCallback", mock.Anything, domain.OIDCCallbackInput{
+					Provider: "local", Code: "code", State: "state", StateToken: "state-token",
+				}).Return(domain.SignInResult{AccessToken: "access", RefreshToken: "refresh"}, nil)
+			},
+			expectedStatusCode: http.StatusOK,
+		},
+		{
+			name:               "rejected by provider",
+			query:              "?error=access_denied&state=state",
+			cookie:             "state-token",
+			mockBehavior:       func(s *mocks.AuthService) {},
+			expectedStatusCode: http.StatusUnauthorized,
+		},
+		{
+			name:               "missing state cookie",
+			query:              "?code=code&state=state",
+			mockBehavior:       func(s *mocks.AuthService) {},
+			expectedStatusCode: http.StatusUnauthorized,
+		},
+	}
+
+	for _, testCase := range testTable {
+		t.Run(testCase.name, func(t *testing.T) {
+			s := mocks.NewAuthService(t)
+			testCase.mockBehavior(s)
+
+			handler := NewHandler(nil, s, jwtkeys.NewHMAC([]byte("secret")), Config{})
+			e := echo.New()
+			e.GET("/auth/oidc/:provider/callback", handler.oidcCallback)
+
+			req := httptest.NewRequest(http.MethodGet, "/auth/oidc/local/callback"+testCase.query, nil)
+			if testCase.cookie != "" {
+				req.AddCookie(&http.Cookie{Name: oidcStateCookie, Value: testCase.cookie})
+			}
+			rec := httptest.NewRecorder()
+			e.ServeHTTP(rec, req)
+
+			assert.Equal(t, testCase.expectedStatusCode, rec.Code)
+
+			cookies := map[string]*http.Cookie{}
+			for _, c := range rec.Result().Cookies() {
+				cookies[c.Name] = c
+			}
+			// state одноразовый при любом исходе
+			if assert.Contains(t, cookies, oidcStateCookie) {
+				assert.Negative(t, cookies[oidcStateCookie].MaxAge)
+			}
+			if testCase.expectedStatusCode == http.StatusOK {
+				assert.Equal(t, "refresh", cookies["refresh-token"].Value)
+			}
+		})
+	}
+}
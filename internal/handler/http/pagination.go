@@ -0,0 +1,186 @@
+package http
+
+import (
+	"fmt"
+	"net/http"
+	"net/url"
+	"strconv"
+	"time"
+
+	"github.com/CryptoGu1/books-rest-clean-arch/internal/domain"
+	"github.com/labstack/echo/v4"
+)
+
+type bookListResponse struct {
+	Items  []*domain.Book `json:"items"`
+	Total  int            `json:"total"`
+	Limit  int            `json:"limit"`
+	Offset int            `json:"offset"`
+	Next   string         `json:"next,omitempty"`
+	Prev   string         `json:"prev,omitempty"`
+}
+
+type bookCursorResponse struct {
+	Items      []*domain.Book `json:"items"`
+	Limit      int            `json:"limit"`
+	NextCursor string         `json:"next_cursor,omitempty"`
+	Next       string         `json:"next,omitempty"`
+}
+
+type bookSearchResponse struct {
+	Items  []*domain.BookSearchResult `json:"items"`
+	Total  int                        `json:"total"`
+	Limit  int                        `json:"limit"`
+	Offset int                        `json:"offset"`
+	Next   string                     `json:"next,omitempty"`
+	Prev   string                     `json:"prev,omitempty"`
+}
+
+func parseBookSearchQuery(c echo.Context) (domain.BookSearchQuery, error) {
+	q := domain.BookSearchQuery{Query: c.QueryParam("q")}
+
+	var err error
+	if q.Limit, err = intParam(c, "limit", domain.DefaultBooksLimit); err != nil {
+		return q, err
+	}
+	if q.Offset, err = intParam(c, "offset", 0); err != nil {
+		return q, err
+	}
+
+	if err := q.Validate(); err != nil {
+		return q, echo.NewHTTPError(http.StatusBadRequest, err.Error())
+	}
+	return q, nil
+}
+
+func parseBookQuery(c echo.Context) (domain.BookQuery, error) {
+	q := domain.BookQuery{
+		Limit:  domain.DefaultBooksLimit,
+		Author: c.QueryParam("author"),
+		Title:  c.QueryParam("title"),
+	}
+
+	var err error
+	if q.Limit, err = intParam(c, "limit", q.Limit); err != nil {
+		return q, err
+	}
+	if q.Offset, err = intParam(c, "offset", 0); err != nil {
+		return q, err
+	}
+	if q.MinRating, err = optIntParam(c, "min_rating"); err != nil {
+		return q, err
+	}
+	if q.MaxRating, err = optIntParam(c, "max_rating"); err != nil {
+		return q, err
+	}
+	if q.PublishedFrom, err = dateParam(c, "published_from"); err != nil {
+		return q, err
+	}
+	if q.PublishedTo, err = dateParam(c, "published_to"); err != nil {
+		return q, err
+	}
+	if q.Sort, err = domain.ParseSort(c.QueryParam("sort")); err != nil {
+		return q, echo.NewHTTPError(http.StatusBadRequest, err.Error())
+	}
+
+	if err := q.Validate(); err != nil {
+		return q, echo.NewHTTPError(http.StatusBadRequest, err.Error())
+	}
+	return q, nil
+}
+
+func intParam(c echo.Context, name string, def int) (int, error) {
+	raw := c.QueryParam(name)
+	if raw == "" {
+		return def, nil
+	}
+	v, err := strconv.Atoi(raw)
+	if err != nil {
+		return 0, echo.NewHTTPError(http.StatusBadRequest, fmt.Sprintf("invalid %s", name))
+	}
+	return v, nil
+}
+
+func optIntParam(c echo.Context, name string) (*int, error) {
+	if c.QueryParam(name) == "" {
+		return nil, nil
+	}
+	v, err := intParam(c, name, 0)
+	if err != nil {
+		return nil, err
+	}
+	return &v, nil
+}
+
+func dateParam(c echo.Context, name string) (*time.Time, error) {
+	raw := c.QueryParam(name)
+	if raw == "" {
+		return nil, nil
+	}
+	t, err := time.Parse("2006-01-02", raw)
+	if err != nil {
+		return nil, echo.NewHTTPError(http.StatusBadRequest, fmt.Sprintf("invalid %s, expected YYYY-MM-DD", name))
+	}
+	t = t.UTC()
+	return &t, nil
+}
+
+func newBookListResponse(c echo.Context, page *domain.BookPage) bookListResponse {
+	resp := bookListResponse{
+		Items:  page.Items,
+		Total:  page.Total,
+		Limit:  page.Limit,
+		Offset: page.Offset,
+	}
+
+	if page.Offset+page.Limit < page.Total {
+		resp.Next = pageLink(c.Request().URL, page.Limit, page.Offset+page.Limit)
+	}
+	if page.Offset > 0 {
+		resp.Prev = pageLink(c.Request().URL, page.Limit, max(page.Offset-page.Limit, 0))
+	}
+	return resp
+}
+
+func newBookSearchResponse(c echo.Context, page *domain.BookSearchPage) bookSearchResponse {
+	resp := bookSearchResponse{
+		Items:  page.Items,
+		Total:  page.Total,
+		Limit:  page.Limit,
+		Offset: page.Offset,
+	}
+
+	if page.Offset+page.Limit < page.Total {
+		resp.Next = pageLink(c.Request().URL, page.Limit, page.Offset+page.Limit)
+	}
+	if page.Offset > 0 {
+		resp.Prev = pageLink(c.Request().URL, page.Limit, max(page.Offset-page.Limit, 0))
+	}
+	return resp
+}
+
+func newBookCursorResponse(c echo.Context, page *domain.BookCursorPage) bookCursorResponse {
+	resp := bookCursorResponse{
+		Items:      page.Items,
+		Limit:      page.Limit,
+		NextCursor: page.NextCursor,
+	}
+
+	if page.NextCursor != "" {
+		values := c.Request().URL.Query()
+		values.Set("cursor", page.NextCursor)
+		link := url.URL{Path: c.Request().URL.Path, RawQuery: values.Encode()}
+		resp.Next = link.String()
+	}
+	return resp
+}
+
+// pageLink сохраняет фильтры и сортировку из исходного запроса, меняя только limit/offset
+func pageLink(u *url.URL, limit, offset int) string {
+	values := u.Query()
+	values.Set("limit", strconv.Itoa(limit))
+	values.Set("offset", strconv.Itoa(offset))
+
+	link := url.URL{Path: u.Path, RawQuery: values.Encode()}
+	return link.String()
+}
@@ -0,0 +1,135 @@
+package http
+
+import (
+	"net/http"
+
+	"github.com/CryptoGu1/books-rest-clean-arch/internal/domain"
+	"github.com/labstack/echo/v4"
+)
+
+// getProfile godoc
+// @Summary      Get my profile
+// @Description  Профиль текущего пользователя
+// @Tags         users
+// @Produce      json
+// @Success      200  {object}  domain.User
+// @Failure      401  {object}  map[string]string
+// @Router       /users/me [get]
+func (h *Handler) getProfile(c echo.Context) error {
+	ctx := c.Request().Context()
+	principal, ok := domain.PrincipalFromContext(ctx)
+	if !ok {
+		return respondErr(c, echo.NewHTTPError(http.StatusUnauthorized, "unauthorized"))
+	}
+
+	user, err := h.UserService.GetProfile(ctx, principal.UserID)
+	if err != nil {
+		logError("get-profile", err)
+		return respondErr(c, err)
+	}
+	return respondJSON(c, http.StatusOK, user)
+}
+
+// updateProfile godoc
+// @Summary      Update my profile
+// @Description  Меняет имя и/или email. Для смены email нужен current_password; адрес меняется после перехода по ссылке из письма на новый адрес
+// @Tags         users
+// @Accept       json
+// @Produce      json
+// @Param        input  body      domain.UpdateProfileInput  true  "Profile fields"
+// @Success      200    {object}  domain.User
+// @Failure      400    {object}  map[string]string
+// @Failure      401    {object}  map[string]string
+// @Failure      409    {object}  map[string]string
+// @Router       /users/me [patch]
+func (h *Handler) updateProfile(c echo.Context) error {
+	ctx := c.Request().Context()
+	principal, ok := domain.PrincipalFromContext(ctx)
+	if !ok {
+		return respondErr(c, echo.NewHTTPError(http.StatusUnauthorized, "unauthorized"))
+	}
+
+	var input domain.UpdateProfileInput
+	if err := c.Bind(&input); err != nil {
+		return respondErr(c, echo.NewHTTPError(http.StatusBadRequest, "invalid request body"))
+	}
+	if err := h.validate.Struct(input); err != nil {
+		return respondErr(c, echo.NewHTTPError(http.StatusBadRequest, "invalid input body"))
+	}
+
+	user, err := h.UserService.UpdateProfile(ctx, principal.UserID, input)
+	if err != nil {
+		logError("update-profile", err)
+		return respondErr(c, err)
+	}
+	return respondJSON(c, http.StatusOK, user)
+}
+
+// changePassword godoc
+// @Summary      Change my password
+// @Description  Меняет пароль (нужен текущий) и завершает все сессии
+// @Tags         users
+// @Accept       json
+// @Param        input  body  domain.ChangePasswordInput  true  "Current and new password"
+// @Success      204
+// @Failure      400  {object}  map[string]string
+// @Failure      401  {object}  map[string]string
+// @Router       /users/me/password [post]
+func (h *Handler) changePassword(c echo.Context) error {
+	ctx := c.Request().Context()
+	principal, ok := domain.PrincipalFromContext(ctx)
+	if !ok {
+		return respondErr(c, echo.NewHTTPError(http.StatusUnauthorized, "unauthorized"))
+	}
+
+	var input domain.ChangePasswordInput
+	if err := c.Bind(&input); err != nil {
+		return respondErr(c, echo.NewHTTPError(http.StatusBadRequest, "invalid request body"))
+	}
+	if err := h.validate.Struct(input); err != nil {
+		return respondErr(c, echo.NewHTTPError(http.StatusBadRequest, "invalid input body"))
+	}
+
+	if err := h.UserService.ChangePassword(ctx, principal.UserID, input); err != nil {
+		logError("change-password", err)
+		return respondErr(c, err)
+	}
+
+	h.clearRefreshCookie(c)
+	return c.NoContent(http.StatusNoContent)
+}
+
+// deleteAccount godoc
+// @Summary      Delete my account
+// @Description  Удаляет аккаунт и все сессии (нужен пароль); добавленные книги остаются без владельца
+// @Tags         users
+// @Accept       json
+// @Param        input  body  domain.DeleteAccountInput  true  "Password"
+// @Success      204
+// @Failure      400  {object}  map[string]string
+// @Failure      401  {object}  map[string]string
+// @Failure      429  {object}  map[string]string
+// @Router       /users/me [delete]
+func (h *Handler) deleteAccount(c echo.Context) error {
+	ctx := c.Request().Context()
+	principal, ok := domain.PrincipalFromContext(ctx)
+	if !ok {
+		return respondErr(c, echo.NewHTTPError(http.StatusUnauthorized, "unauthorized"))
+	}
+
+	var input domain.DeleteAccountInput
+	if err := c.Bind(&input); err != nil {
+		return respondErr(c, echo.NewHTTPError(http.StatusBadRequest, "invalid request body"))
+	}
+	if err := h.validate.Struct(input); err != nil {
+		return respondErr(c, echo.NewHTTPError(http.StatusBadRequest, "invalid input body"))
+	}
+
+	if err := h.UserService.DeleteAccount(ctx, principal.UserID, input); err != nil {
+		logError("delete-account", err)
+		return respondErr(c, err)
+	}
+
+	h.clearRefreshCookie(c)
+	return c.NoContent(http.StatusNoContent)
+}
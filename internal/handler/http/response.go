@@ -29,6 +29,65 @@ func mapErrorToStatus(err error) int {
 	if errors.Is(err, domain.ErrBookNotFound) {
 		return http.StatusNotFound
 	}
+	// domain.ErrForbidden -> 403
+	if errors.Is(err, domain.ErrForbidden) {
+		return http.StatusForbidden
+	}
+	// domain.ErrInvalidCursor -> 400
+	if errors.Is(err, domain.ErrInvalidCursor) {
+		return http.StatusBadRequest
+	}
+	// domain.ErrInvalidPatch -> 400
+	if errors.Is(err, domain.ErrInvalidPatch) {
+		return http.StatusBadRequest
+	}
+	// domain.ErrInvalidResetToken, domain.ErrInvalidVerification -> 400
+	if errors.Is(err, domain.ErrInvalidResetToken) || errors.Is(err, domain.ErrInvalidVerification) {
+		return http.StatusBadRequest
+	}
+	// domain.ErrInvalidAPIKey -> 401
+	if errors.Is(err, domain.ErrInvalidAPIKey) {
+		return http.StatusUnauthorized
+	}
+	// domain.ErrInvalidMFACode, domain.ErrInvalidMFAToken -> 401
+	if errors.Is(err, domain.ErrInvalidMFACode) || errors.Is(err, domain.ErrInvalidMFAToken) {
+		return http.StatusUnauthorized
+	}
+	// domain.ErrMFAAlreadyEnabled, domain.ErrMFANotEnabled -> 409
+	if errors.Is(err, domain.ErrMFAAlreadyEnabled) || errors.Is(err, domain.ErrMFANotEnabled) {
+		return http.StatusConflict
+	}
+	// domain.ErrOIDCFailed -> 401
+	if errors.Is(err, domain.ErrOIDCFailed) {
+		return http.StatusUnauthorized
+	}
+	// domain.ErrInvalidPassword -> 400
+	if errors.Is(err, domain.ErrInvalidPassword) {
+		return http.StatusBadRequest
+	}
+	// domain.ErrEmailTaken -> 409
+	if errors.Is(err, domain.ErrEmailTaken) {
+		return http.StatusConflict
+	}
+	// domain.ErrTooManyAttempts -> 429
+	if errors.Is(err, domain.ErrTooManyAttempts) {
+		return http.StatusTooManyRequests
+	}
+	// domain.ErrEmailNotVerified -> 403
+	if errors.Is(err, domain.ErrEmailNotVerified) {
+		return http.StatusForbidden
+	}
+	// domain.ErrPatchTestFailed -> 422
+	if errors.Is(err, domain.ErrPatchTestFailed) {
+		return http.StatusUnprocessableEntity
+	}
+	// domain.ErrRoleNotFound, domain.ErrUserNotFound, domain.ErrSessionNotFound, domain.ErrAPIKeyNotFound,
+	// domain.ErrUnknownProvider -> 404
+	if errors.Is(err, domain.ErrRoleNotFound) || errors.Is(err, domain.ErrUserNotFound) ||
+		errors.Is(err, domain.ErrSessionNotFound) || errors.Is(err, domain.ErrAPIKeyNotFound) ||
+		errors.Is(err, domain.ErrUnknownProvider) {
+		return http.StatusNotFound
+	}
 	// sql.ErrNoRows -> 404
 	if errors.Is(err, sql.ErrNoRows) {
 		return http.StatusNotFound
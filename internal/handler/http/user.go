@@ -1,8 +1,10 @@
 package http
 
 import (
+	"errors"
+	"math"
 	"net/http"
-	"time"
+	"strconv"
 
 	"github.com/CryptoGu1/books-rest-clean-arch/internal/domain"
 	"github.com/labstack/echo/v4"
@@ -42,6 +44,19 @@ func (h *Handler) signUp(c echo.Context) error {
 
 }
 
+// signIn godoc
+// @Summary      Sign in
+// @Description  Проверяет пароль. Возвращает access-токен (refresh — в cookie или, с X-Token-Delivery: body, в теле)
+// @Description  либо mfa_token, если у пользователя включена 2FA
+// @Tags         auth
+// @Accept       json
+// @Produce      json
+// @Param        input  body      domain.SingInInput  true  "Credentials"
+// @Success      200    {object}  map[string]interface{}
+// @Failure      400    {object}  map[string]string
+// @Failure      403    {object}  map[string]string
+// @Failure      429    {object}  map[string]string
+// @Router       /auth/sign-in [post]
 func (h *Handler) signIn(c echo.Context) error {
 	var input domain.SingInInput
 
@@ -59,93 +74,199 @@ func (h *Handler) signIn(c echo.Context) error {
 	}
 
 	ctx := c.Request().Context()
-	token, refresh, err := h.UserService.SignIn(ctx, input)
+	result, err := h.UserService.SignIn(ctx, input)
 	if err != nil {
 		log.WithFields(log.Fields{
 			"handler": "sign-in",
 			"problem": "service error",
 		}).Error(err)
+
+		setRetryAfter(c, err)
 		return respondErr(c, err)
 	}
 
-	cookie := new(http.Cookie)
-	cookie.Name = "refresh-token"
-	cookie.Value = refresh
-	cookie.Path = "/"
-	cookie.HttpOnly = true
-	cookie.SameSite = http.SameSiteStrictMode
-	cookie.Expires = time.Now().Add(time.Hour * 24 * 30)
+	return h.respondSignIn(c, result)
+}
 
-	c.SetCookie(cookie)
+// legacySignIn — GET /auth/sign-in с телом запроса: прокси и HTTP-клиенты часто отбрасывают тело у GET
+func (h *Handler) legacySignIn(c echo.Context) error {
+	c.Response().Header().Set("Deprecation", "true")
+	c.Response().Header().Set("Link", `</auth/sign-in>; rel="successor-version"`)
+	log.WithFields(log.Fields{
+		"handler": "sign-in",
+		"remote":  c.RealIP(),
+	}).Warn("deprecated GET /auth/sign-in used")
+	return h.signIn(c)
+}
 
-	return respondJSON(c, http.StatusOK, map[string]interface{}{
-		"token": token,
-	})
+// respondSignIn отдаёт результат первого шага входа (по паролю или через провайдера)
+func (h *Handler) respondSignIn(c echo.Context, result domain.SignInResult) error {
+	// нужен второй фактор: cookie выставит /auth/mfa/verify
+	if result.MFARequired() {
+		return respondJSON(c, http.StatusOK, map[string]interface{}{
+			"mfa_required": true,
+			"mfa_token":    result.MFAToken,
+		})
+	}
 
+	return h.issueTokens(c, result.AccessToken, result.RefreshToken)
 }
 
-func (h *Handler) refresh(c echo.Context) error {
-	// 1. Достаем cookie с refresh токеном
-	cookie, err := c.Cookie("refresh-token")
-	if err != nil {
-		return respondErr(c, echo.NewHTTPError(http.StatusUnauthorized, "missing refresh token"))
+// setRetryAfter выставляет Retry-After, если вход временно заблокирован
+func setRetryAfter(c echo.Context, err error) {
+	var tooMany *domain.TooManyAttemptsError
+	if errors.As(err, &tooMany) {
+		seconds := int(math.Ceil(tooMany.RetryAfter.Seconds()))
+		c.Response().Header().Set("Retry-After", strconv.Itoa(seconds))
 	}
+}
 
-	refreshToken := cookie.Value
+// refresh godoc
+// @Summary      Refresh tokens
+// @Description  Ротирует refresh-токен из cookie. Клиенты без cookie (при auth.body_tokens) передают его
+// @Description  в теле как refresh_token и получают новый там же
+// @Tags         auth
+// @Accept       json
+// @Produce      json
+// @Success      200  {object}  map[string]string
+// @Failure      401  {object}  map[string]string
+// @Router       /auth/refresh [post]
+func (h *Handler) refresh(c echo.Context) error {
+	refreshToken := h.refreshTokenFrom(c)
 	if refreshToken == "" {
-		return respondErr(c, echo.NewHTTPError(http.StatusUnauthorized, "empty refresh token"))
+		return respondErr(c, echo.NewHTTPError(http.StatusUnauthorized, "missing refresh token"))
 	}
 
-	// 2. Обновляем токены через сервис
-	ctx := c.Request().Context()
-	accessToken, newRefreshToken, err := h.UserService.RefreshTokens(ctx, refreshToken)
+	accessToken, newRefreshToken, err := h.UserService.RefreshTokens(c.Request().Context(), refreshToken)
 	if err != nil {
 		return respondErr(c, echo.NewHTTPError(http.StatusUnauthorized, err.Error()))
 	}
+	return h.issueTokens(c, accessToken, newRefreshToken)
+}
 
-	// 3. Перезаписываем cookie с новым refresh токеном
-	newCookie := &http.Cookie{
-		Name:     "refresh-token",
-		Value:    newRefreshToken,
-		Path:     "/",
-		HttpOnly: true,
-		Secure:   false, // в продакшене поставить true
-		SameSite: http.SameSiteLaxMode,
-		Expires:  time.Now().Add(30 * 24 * time.Hour),
+func (h *Handler) logout(c echo.Context) error {
+	if refreshToken := h.refreshTokenFrom(c); refreshToken != "" {
+		ctx := c.Request().Context()
+		if err := h.UserService.Logout(ctx, refreshToken); err != nil {
+			logError("logout", err)
+			return respondErr(c, err)
+		}
 	}
-	c.SetCookie(newCookie)
 
-	// 4. Возвращаем новый access токен
-	return respondJSON(c, http.StatusOK, map[string]string{
-		"access_token": accessToken,
-	})
+	h.clearRefreshCookie(c)
+	return c.NoContent(http.StatusNoContent)
 }
 
-func (h *Handler) RefreshToken(c echo.Context) error {
-	cookie, err := c.Cookie("refresh-token")
-	if err != nil {
-		return respondErr(c, echo.NewHTTPError(http.StatusUnauthorized, "missing refresh token"))
+func (h *Handler) logoutAll(c echo.Context) error {
+	ctx := c.Request().Context()
+	principal, ok := domain.PrincipalFromContext(ctx)
+	if !ok {
+		return respondErr(c, echo.NewHTTPError(http.StatusUnauthorized, "unauthorized"))
 	}
 
+	if err := h.UserService.LogoutAll(ctx, principal.UserID); err != nil {
+		logError("logout-all", err)
+		return respondErr(c, err)
+	}
+
+	h.clearRefreshCookie(c)
+	return c.NoContent(http.StatusNoContent)
+}
+
+func (h *Handler) listSessions(c echo.Context) error {
 	ctx := c.Request().Context()
-	accessToken, refreshToken, err := h.UserService.RefreshTokens(ctx, cookie.Value)
+	principal, ok := domain.PrincipalFromContext(ctx)
+	if !ok {
+		return respondErr(c, echo.NewHTTPError(http.StatusUnauthorized, "unauthorized"))
+	}
+
+	var current string
+	if cookie, err := c.Cookie(refreshCookieName); err == nil {
+		current = cookie.Value
+	}
+
+	sessions, err := h.UserService.ListSessions(ctx, principal.UserID, current)
 	if err != nil {
-		return respondErr(c, echo.NewHTTPError(http.StatusUnauthorized, err.Error()))
+		logError("list-sessions", err)
+		return respondErr(c, err)
+	}
+	return respondJSON(c, http.StatusOK, sessions)
+}
+
+func (h *Handler) revokeSession(c echo.Context) error {
+	ctx := c.Request().Context()
+	principal, ok := domain.PrincipalFromContext(ctx)
+	if !ok {
+		return respondErr(c, echo.NewHTTPError(http.StatusUnauthorized, "unauthorized"))
+	}
+
+	if err := h.UserService.RevokeSession(ctx, principal.UserID, c.Param("id")); err != nil {
+		logError("revoke-session", err)
+		return respondErr(c, err)
+	}
+	return c.NoContent(http.StatusNoContent)
+}
+
+func (h *Handler) forgotPassword(c echo.Context) error {
+	var input domain.ForgotPasswordInput
+	if err := c.Bind(&input); err != nil {
+		return respondErr(c, echo.NewHTTPError(http.StatusBadRequest, "invalid request body"))
+	}
+	if err := h.validate.Struct(input); err != nil {
+		return respondErr(c, echo.NewHTTPError(http.StatusBadRequest, "invalid input body"))
 	}
 
-	cookieSet := &http.Cookie{
-		Name:     "refresh-token",
-		Value:    refreshToken,
-		Path:     "/",
-		HttpOnly: true,
-		Secure:   false, // true в продакшене (если HTTPS)
-		SameSite: http.SameSiteLaxMode,
-		Expires:  time.Now().Add(30 * 24 * time.Hour),
+	// ответ одинаковый при любом исходе, чтобы по нему нельзя было перебирать адреса
+	if err := h.UserService.ForgotPassword(c.Request().Context(), input); err != nil {
+		logError("forgot-password", err)
 	}
-	c.SetCookie(cookieSet)
+	return c.NoContent(http.StatusAccepted)
+}
 
+func (h *Handler) resetPassword(c echo.Context) error {
+	var input domain.ResetPasswordInput
+	if err := c.Bind(&input); err != nil {
+		return respondErr(c, echo.NewHTTPError(http.StatusBadRequest, "invalid request body"))
+	}
+	if err := h.validate.Struct(input); err != nil {
+		return respondErr(c, echo.NewHTTPError(http.StatusBadRequest, "invalid input body"))
+	}
+
+	if err := h.UserService.ResetPassword(c.Request().Context(), input); err != nil {
+		logError("reset-password", err)
+		return respondErr(c, err)
+	}
+
+	h.clearRefreshCookie(c)
+	return c.NoContent(http.StatusNoContent)
+}
+
+func (h *Handler) verifyEmail(c echo.Context) error {
+	token := c.QueryParam("token")
+	if token == "" {
+		return respondErr(c, echo.NewHTTPError(http.StatusBadRequest, "missing token"))
+	}
+
+	if err := h.UserService.VerifyEmail(c.Request().Context(), token); err != nil {
+		logError("verify-email", err)
+		return respondErr(c, err)
+	}
 	return respondJSON(c, http.StatusOK, map[string]interface{}{
-		"token": accessToken,
+		"verified": true,
 	})
+}
 
+func (h *Handler) resendVerification(c echo.Context) error {
+	var input domain.ResendVerificationInput
+	if err := c.Bind(&input); err != nil {
+		return respondErr(c, echo.NewHTTPError(http.StatusBadRequest, "invalid request body"))
+	}
+	if err := h.validate.Struct(input); err != nil {
+		return respondErr(c, echo.NewHTTPError(http.StatusBadRequest, "invalid input body"))
+	}
+
+	if err := h.UserService.ResendVerification(c.Request().Context(), input); err != nil {
+		logError("resend-verification", err)
+	}
+	return c.NoContent(http.StatusAccepted)
 }
@@ -6,9 +6,11 @@ import (
 	"net/http"
 	"net/http/httptest"
 	"testing"
+	"time"
 
 	"github.com/CryptoGu1/books-rest-clean-arch/internal/domain"
 	"github.com/CryptoGu1/books-rest-clean-arch/mocks"
+	"github.com/CryptoGu1/books-rest-clean-arch/pkg/jwtkeys"
 	"github.com/labstack/echo/v4"
 	"github.com/stretchr/testify/assert"
 	"github.com/stretchr/testify/mock"
@@ -69,7 +71,7 @@ func TestHandler_signup(t *testing.T) {
 			testCase.mockBehavior(authService, testCase.inputUser)
 
 			var bookService BookService
-			handler := NewHandler(bookService, authService, []byte("secret"))
+			handler := NewHandler(bookService, authService, jwtkeys.NewHMAC([]byte("secret")), Config{})
 			e := echo.New()
 
 			req := httptest.NewRequest(http.MethodPost, "/auth/sign-up", bytes.NewBufferString(testCase.inputBody))
@@ -88,3 +90,231 @@ func TestHandler_signup(t *testing.T) {
 	}
 
 }
+
+func TestHandler_logout(t *testing.T) {
+	type mockBehavior func(s *mocks.AuthService)
+
+	testTable := []struct {
+		name               string
+		cookie             string
+		mockBehavior       mockBehavior
+		expectedStatusCode int
+	}{
+		{
+			name:   "ok",
+			cookie: "refresh",
+			mockBehavior: func(s *mocks.AuthService) {
+				s.On("Logout", mock.Anything, "refresh").Return(nil)
+			},
+			expectedStatusCode: 204,
+		},
+		{
+			name:               "no cookie",
+			mockBehavior:       func(s *mocks.AuthService) {},
+			expectedStatusCode: 204,
+		},
+		{
+			name:   "service fail",
+			cookie: "refresh",
+			mockBehavior: func(s *mocks.AuthService) {
+				s.On("Logout", mock.Anything, "refresh").Return(errors.New("service error"))
+			},
+			expectedStatusCode: 500,
+		},
+	}
+
+	for _, testCase := range testTable {
+		t.Run(testCase.name, func(t *testing.T) {
+			authService := mocks.NewAuthService(t)
+			testCase.mockBehavior(authService)
+
+			handler := NewHandler(nil, authService, jwtkeys.NewHMAC([]byte("secret")), Config{})
+			e := echo.New()
+
+			req := httptest.NewRequest(http.MethodPost, "/auth/logout", nil)
+			if testCase.cookie != "" {
+				req.AddCookie(&http.Cookie{Name: "refresh-token", Value: testCase.cookie})
+			}
+			rec := httptest.NewRecorder()
+			c := e.NewContext(req, rec)
+
+			err := handler.logout(c)
+
+			assert.NoError(t, err)
+			assert.Equal(t, testCase.expectedStatusCode, rec.Code)
+			if rec.Code == http.StatusNoContent {
+				cookies := rec.Result().Cookies()
+				if assert.Len(t, cookies, 1) {
+					assert.Equal(t, "refresh-token", cookies[0].Name)
+					assert.Empty(t, cookies[0].Value)
+					assert.Negative(t, cookies[0].MaxAge)
+				}
+			}
+		})
+	}
+}
+
+func TestHandler_logoutAll(t *testing.T) {
+	authService := mocks.NewAuthService(t)
+	authService.On("LogoutAll", mock.Anything, int64(7)).Return(nil)
+
+	handler := NewHandler(nil, authService, jwtkeys.NewHMAC([]byte("secret")), Config{})
+	e := echo.New()
+
+	req := httptest.NewRequest(http.MethodPost, "/auth/logout-all", nil)
+	req = req.WithContext(domain.WithPrincipal(req.Context(), domain.Principal{UserID: 7}))
+	rec := httptest.NewRecorder()
+	c := e.NewContext(req, rec)
+
+	assert.NoError(t, handler.logoutAll(c))
+	assert.Equal(t, http.StatusNoContent, rec.Code)
+}
+
+func TestHandler_signIn_TooManyAttempts(t *testing.T) {
+	s := mocks.NewAuthService(t)
+	s.On("SignIn", mock.Anything, mock.Anything).
+		Return(domain.SignInResult{}, &domain.TooManyAttemptsError{RetryAfter: 1500 * time.Millisecond})
+
+	handler := NewHandler(nil, s, jwtkeys.NewHMAC([]byte("secret")), Config{})
+	e := echo.New()
+	e.POST("/auth/sign-in", handler.signIn)
+
+	req := httptest.NewRequest(http.MethodPost, "/auth/sign-in",
+		bytes.NewBufferString(`{"email": "test@test.kz", "password": "test1234"}`))
+	req.Header.Set(echo.HeaderContentType, echo.MIMEApplicationJSON)
+	rec := httptest.NewRecorder()
+	e.ServeHTTP(rec, req)
+
+	assert.Equal(t, http.StatusTooManyRequests, rec.Code)
+	assert.Equal(t, "2", rec.Header().Get("Retry-After"))
+}
+
+func TestHandler_getProfile_HidesPassword(t *testing.T) {
+	s := mocks.NewAuthService(t)
+	s.On("GetProfile", mock.Anything, int64(7)).
+		Return(domain.User{ID: 7, Name: "Leo", Email: "leo@example.com", Password: "$2a$10$hash"}, nil)
+
+	handler := NewHandler(nil, s, jwtkeys.NewHMAC([]byte("secret")), Config{})
+	e := echo.New()
+	e.GET("/users/me", handler.getProfile, handler.JWTMiddleware)
+
+	req := httptest.NewRequest(http.MethodGet, "/users/me", nil)
+	req.Header.Set(echo.HeaderAuthorization, "Bearer "+signTestToken(t, []byte("secret"), nil, nil))
+	rec := httptest.NewRecorder()
+	e.ServeHTTP(rec, req)
+
+	assert.Equal(t, http.StatusOK, rec.Code)
+	assert.Contains(t, rec.Body.String(), `"email":"leo@example.com"`)
+	assert.NotContains(t, rec.Body.String(), "password")
+	assert.NotContains(t, rec.Body.String(), "$2a$")
+}
+
+func TestHandler_deleteAccount(t *testing.T) {
+	type mockBehavior func(s *mocks.AuthService)
+
+	testTable := []struct {
+		name               string
+		body               string
+		mockBehavior       mockBehavior
+		expectedStatusCode int
+	}{
+		{
+			name: "ok",
+			body: `{"password": "password"}`,
+			mockBehavior: func(s *mocks.AuthService) {
+				s.On("DeleteAccount", mock.Anything, int64(7), domain.DeleteAccountInput{Password: "password"}).Return(nil)
+			},
+			expectedStatusCode: http.StatusNoContent,
+		},
+		{
+			name:               "password required",
+			body:               `{}`,
+			mockBehavior:       func(s *mocks.AuthService) {},
+			expectedStatusCode: http.StatusBadRequest,
+		},
+		{
+			name: "wrong password",
+			body: `{"password": "wrong"}`,
+			mockBehavior: func(s *mocks.AuthService) {
+				s.On("DeleteAccount", mock.Anything, int64(7), domain.DeleteAccountInput{Password: "wrong"}).Return(domain.ErrInvalidPassword)
+			},
+			expectedStatusCode: http.StatusBadRequest,
+		},
+	}
+
+	for _, testCase := range testTable {
+		t.Run(testCase.name, func(t *testing.T) {
+			s := mocks.NewAuthService(t)
+			testCase.mockBehavior(s)
+
+			handler := NewHandler(nil, s, jwtkeys.NewHMAC([]byte("secret")), Config{})
+			e := echo.New()
+			e.DELETE("/users/me", handler.deleteAccount, handler.JWTMiddleware)
+
+			req := httptest.NewRequest(http.MethodDelete, "/users/me", bytes.NewBufferString(testCase.body))
+			req.Header.Set(echo.HeaderContentType, echo.MIMEApplicationJSON)
+			req.Header.Set(echo.HeaderAuthorization, "Bearer "+signTestToken(t, []byte("secret"), nil, nil))
+			rec := httptest.NewRecorder()
+			e.ServeHTTP(rec, req)
+
+			assert.Equal(t, testCase.expectedStatusCode, rec.Code)
+		})
+	}
+}
+
+func TestHandler_signIn_MFARequired(t *testing.T) {
+	s := mocks.NewAuthService(t)
+	s.On("SignIn", mock.Anything, mock.Anything).Return(domain.SignInResult{MFAToken: "challenge"}, nil)
+
+	handler := NewHandler(nil, s, jwtkeys.NewHMAC([]byte("secret")), Config{})
+	e := echo.New()
+	e.POST("/auth/sign-in", handler.signIn)
+
+	req := httptest.NewRequest(http.MethodPost, "/auth/sign-in",
+		bytes.NewBufferString(`{"email": "test@test.kz", "password": "test1234"}`))
+	req.Header.Set(echo.HeaderContentType, echo.MIMEApplicationJSON)
+	rec := httptest.NewRecorder()
+	e.ServeHTTP(rec, req)
+
+	assert.Equal(t, http.StatusOK, rec.Code)
+	assert.JSONEq(t, `{"mfa_required":true,"mfa_token":"challenge"}`, rec.Body.String())
+	assert.Empty(t, rec.Result().Cookies())
+}
+
+func TestHandler_verifyMFA(t *testing.T) {
+	testTable := []struct {
+		name               string
+		err                error
+		expectedStatusCode int
+	}{
+		{name: "ok", expectedStatusCode: http.StatusOK},
+		{name: "wrong code", err: domain.ErrInvalidMFACode, expectedStatusCode: http.StatusUnauthorized},
+		{name: "expired token", err: domain.ErrInvalidMFAToken, expectedStatusCode: http.StatusUnauthorized},
+	}
+
+	for _, testCase := range testTable {
+		t.Run(testCase.name, func(t *testing.T) {
+			s := mocks.NewAuthService(t)
+			s.On("VerifyMFA", mock.Anything, domain.MFAVerifyInput{MFAToken: "challenge", Code: "123456"}).
+				Return("access", "refresh", testCase.err)
+
+			handler := NewHandler(nil, s, jwtkeys.NewHMAC([]byte("secret")), Config{})
+			e := echo.New()
+			e.POST("/auth/mfa/verify", handler.verifyMFA)
+
+			req := httptest.NewRequest(http.MethodPost, "/auth/mfa/verify",
+				bytes.NewBufferString(`{"mfa_token": "challenge", "code": "123456"}`))
+			req.Header.Set(echo.HeaderContentType, echo.MIMEApplicationJSON)
+			rec := httptest.NewRecorder()
+			e.ServeHTTP(rec, req)
+
+			assert.Equal(t, testCase.expectedStatusCode, rec.Code)
+			if testCase.err == nil {
+				cookies := rec.Result().Cookies()
+				if assert.Len(t, cookies, 1) {
+					assert.Equal(t, "refresh", cookies[0].Value)
+				}
+			}
+		})
+	}
+}
@@ -0,0 +1,162 @@
+package repository
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/CryptoGu1/books-rest-clean-arch/internal/domain"
+	"github.com/jmoiron/sqlx"
+	"github.com/lib/pq"
+)
+
+const apiKeyColumns = "id, user_id, name, prefix, key_hash, scopes, created_at, last_used_at, expires_at, revoked_at"
+
+type APIKeyRepository interface {
+	Create(ctx context.Context, key domain.APIKey) (domain.APIKey, error)
+	ListByUser(ctx context.Context, userID int64) ([]domain.APIKey, error)
+	GetByHash(ctx context.Context, keyHash string) (domain.APIKey, error)
+	Revoke(ctx context.Context, userID, id int64) error
+	Touch(ctx context.Context, id int64, at time.Time) error
+}
+
+type APIKeyPostgresRepo struct {
+	db *sqlx.DB
+}
+
+func NewAPIKeyPostgresRepo(db *sqlx.DB) *APIKeyPostgresRepo {
+	return &APIKeyPostgresRepo{db: db}
+}
+
+// apiKeyRow — строка api_keys; TEXT[] сканируется через pq.StringArray, чтобы не тащить pq в domain
+type apiKeyRow struct {
+	ID         int64          `db:"id"`
+	UserID     int64          `db:"user_id"`
+	Name       string         `db:"name"`
+	Prefix     string         `db:"prefix"`
+	KeyHash    string         `db:"key_hash"`
+	Scopes     pq.StringArray `db:"scopes"`
+	CreatedAt  time.Time      `db:"created_at"`
+	LastUsedAt *time.Time     `db:"last_used_at"`
+	ExpiresAt  *time.Time     `db:"expires_at"`
+	RevokedAt  *time.Time     `db:"revoked_at"`
+}
+
+func (r apiKeyRow) toDomain() domain.APIKey {
+	scopes := []string(r.Scopes)
+	if scopes == nil {
+		scopes = []string{}
+	}
+	return domain.APIKey{
+		ID:         r.ID,
+		UserID:     r.UserID,
+		Name:       r.Name,
+		Prefix:     r.Prefix,
+		KeyHash:    r.KeyHash,
+		Scopes:     scopes,
+		CreatedAt:  r.CreatedAt,
+		LastUsedAt: r.LastUsedAt,
+		ExpiresAt:  r.ExpiresAt,
+		RevokedAt:  r.RevokedAt,
+	}
+}
+
+func (r *APIKeyPostgresRepo) Create(ctx context.Context, key domain.APIKey) (domain.APIKey, error) {
+	query := `
+	INSERT INTO api_keys (user_id, name, prefix, key_hash, scopes, expires_at)
+	VALUES ($1, $2, $3, $4, $5, $6)
+	RETURNING ` + apiKeyColumns
+
+	if _, ok := ctx.Deadline(); !ok {
+		var cancel context.CancelFunc
+		ctx, cancel = context.WithTimeout(ctx, 5*time.Second)
+		defer cancel()
+	}
+
+	var row apiKeyRow
+	err := r.db.GetContext(ctx, &row, query,
+		key.UserID, key.Name, key.Prefix, key.KeyHash, pq.Array(key.Scopes), key.ExpiresAt)
+	if err != nil {
+		return domain.APIKey{}, fmt.Errorf("repo: create api key: %w", err)
+	}
+	return row.toDomain(), nil
+}
+
+func (r *APIKeyPostgresRepo) ListByUser(ctx context.Context, userID int64) ([]domain.APIKey, error) {
+	query := `SELECT ` + apiKeyColumns + ` FROM api_keys WHERE user_id = $1 ORDER BY created_at DESC, id DESC`
+
+	if _, ok := ctx.Deadline(); !ok {
+		var cancel context.CancelFunc
+		ctx, cancel = context.WithTimeout(ctx, 5*time.Second)
+		defer cancel()
+	}
+
+	var rows []apiKeyRow
+	if err := r.db.SelectContext(ctx, &rows, query, userID); err != nil {
+		return nil, fmt.Errorf("repo: list api keys: %w", err)
+	}
+
+	keys := make([]domain.APIKey, 0, len(rows))
+	for _, row := range rows {
+		keys = append(keys, row.toDomain())
+	}
+	return keys, nil
+}
+
+func (r *APIKeyPostgresRepo) GetByHash(ctx context.Context, keyHash string) (domain.APIKey, error) {
+	query := `SELECT ` + apiKeyColumns + ` FROM api_keys WHERE key_hash = $1`
+
+	if _, ok := ctx.Deadline(); !ok {
+		var cancel context.CancelFunc
+		ctx, cancel = context.WithTimeout(ctx, 5*time.Second)
+		defer cancel()
+	}
+
+	var row apiKeyRow
+	if err := r.db.GetContext(ctx, &row, query, keyHash); err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return domain.APIKey{}, domain.ErrAPIKeyNotFound
+		}
+		return domain.APIKey{}, fmt.Errorf("repo: get api key: %w", err)
+	}
+	return row.toDomain(), nil
+}
+
+// Revoke отзывает ключ пользователя; чужой или уже отозванный ключ — ErrAPIKeyNotFound
+func (r *APIKeyPostgresRepo) Revoke(ctx context.Context, userID, id int64) error {
+	query := `UPDATE api_keys SET revoked_at = now() WHERE id = $1 AND user_id = $2 AND revoked_at IS NULL`
+
+	if _, ok := ctx.Deadline(); !ok {
+		var cancel context.CancelFunc
+		ctx, cancel = context.WithTimeout(ctx, 5*time.Second)
+		defer cancel()
+	}
+
+	res, err := r.db.ExecContext(ctx, query, id, userID)
+	if err != nil {
+		return fmt.Errorf("repo: revoke api key: %w", err)
+	}
+	aff, err := res.RowsAffected()
+	if err != nil {
+		return fmt.Errorf("repo: revoke api key rows affected: %w", err)
+	}
+	if aff == 0 {
+		return domain.ErrAPIKeyNotFound
+	}
+	return nil
+}
+
+func (r *APIKeyPostgresRepo) Touch(ctx context.Context, id int64, at time.Time) error {
+	if _, ok := ctx.Deadline(); !ok {
+		var cancel context.CancelFunc
+		ctx, cancel = context.WithTimeout(ctx, 5*time.Second)
+		defer cancel()
+	}
+
+	if _, err := r.db.ExecContext(ctx, `UPDATE api_keys SET last_used_at = $2 WHERE id = $1`, id, at); err != nil {
+		return fmt.Errorf("repo: touch api key: %w", err)
+	}
+	return nil
+}
@@ -0,0 +1,148 @@
+package repository
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"time"
+
+	"github.com/CryptoGu1/books-rest-clean-arch/internal/domain"
+	"github.com/jmoiron/sqlx"
+)
+
+type AuditOutboxRepository interface {
+	// Add пишет событие; вызывается внутри Transactor.WithinTx вместе с изменением, которое оно описывает
+	Add(ctx context.Context, event domain.AuditEvent) error
+	// ClaimPending забирает до limit событий, готовых к отправке, и сдвигает их next_attempt_at
+	// на leaseUntil: пока аренда не истекла, другие relay эти события не возьмут.
+	// Одна команда — блокировки строк не переживают её коммит
+	ClaimPending(ctx context.Context, limit int, leaseUntil time.Time) ([]domain.AuditEvent, error)
+	MarkDelivered(ctx context.Context, eventID string) error
+	// MarkFailed откладывает следующую попытку до nextAttempt
+	MarkFailed(ctx context.Context, eventID string, nextAttempt time.Time, reason string) error
+	// PurgeDelivered удаляет события, доставленные раньше before
+	PurgeDelivered(ctx context.Context, before time.Time) (int64, error)
+}
+
+type AuditOutboxPostgresRepo struct {
+	db *sqlx.DB
+}
+
+func NewAuditOutboxPostgresRepo(db *sqlx.DB) *AuditOutboxPostgresRepo {
+	return &AuditOutboxPostgresRepo{db: db}
+}
+
+func (r *AuditOutboxPostgresRepo) Add(ctx context.Context, event domain.AuditEvent) error {
+	metadata, err := json.Marshal(event.Metadata)
+	if err != nil {
+		return fmt.Errorf("repo: add audit event: %w", err)
+	}
+	if event.Metadata == nil {
+		metadata = []byte("{}")
+	}
+
+	if _, ok := ctx.Deadline(); !ok {
+		var cancel context.CancelFunc
+		ctx, cancel = context.WithTimeout(ctx, 5*time.Second)
+		defer cancel()
+	}
+
+	query := `
+	INSERT INTO audit_outbox (entity, action, entity_id, metadata, occurred_at)
+	VALUES ($1, $2, $3, $4, $5)`
+	if _, err := conn(ctx, r.db).ExecContext(ctx, query,
+		event.Entity, event.Action, event.EntityID, metadata, event.OccurredAt); err != nil {
+		return fmt.Errorf("repo: add audit event: %w", err)
+	}
+	return nil
+}
+
+func (r *AuditOutboxPostgresRepo) ClaimPending(ctx context.Context, limit int, leaseUntil time.Time) ([]domain.AuditEvent, error) {
+	query := `
+	WITH claimed AS (
+		SELECT id FROM audit_outbox
+		WHERE delivered_at IS NULL AND next_attempt_at <= now()
+		ORDER BY id
+		LIMIT $1
+		FOR UPDATE SKIP LOCKED
+	)
+	UPDATE audit_outbox o SET next_attempt_at = $2
+	FROM claimed WHERE o.id = claimed.id
+	RETURNING o.event_id, o.entity, o.action, o.entity_id, o.metadata, o.occurred_at, o.attempts`
+
+	if _, ok := ctx.Deadline(); !ok {
+		var cancel context.CancelFunc
+		ctx, cancel = context.WithTimeout(ctx, 5*time.Second)
+		defer cancel()
+	}
+
+	var rows []struct {
+		domain.AuditEvent
+		RawMetadata []byte `db:"metadata"`
+	}
+	if err := sqlx.SelectContext(ctx, conn(ctx, r.db), &rows, query, limit, leaseUntil); err != nil {
+		return nil, fmt.Errorf("repo: claim audit events: %w", err)
+	}
+
+	events := make([]domain.AuditEvent, 0, len(rows))
+	for _, row := range rows {
+		event := row.AuditEvent
+		if err := json.Unmarshal(row.RawMetadata, &event.Metadata); err != nil {
+			return nil, fmt.Errorf("repo: decode audit metadata: %w", err)
+		}
+		events = append(events, event)
+	}
+	return events, nil
+}
+
+func (r *AuditOutboxPostgresRepo) MarkDelivered(ctx context.Context, eventID string) error {
+	query := `UPDATE audit_outbox SET delivered_at = now(), attempts = attempts + 1, last_error = NULL WHERE event_id = $1`
+
+	if _, ok := ctx.Deadline(); !ok {
+		var cancel context.CancelFunc
+		ctx, cancel = context.WithTimeout(ctx, 5*time.Second)
+		defer cancel()
+	}
+
+	if _, err := conn(ctx, r.db).ExecContext(ctx, query, eventID); err != nil {
+		return fmt.Errorf("repo: mark audit event delivered: %w", err)
+	}
+	return nil
+}
+
+func (r *AuditOutboxPostgresRepo) MarkFailed(ctx context.Context, eventID string, nextAttempt time.Time, reason string) error {
+	query := `
+	UPDATE audit_outbox SET attempts = attempts + 1, next_attempt_at = $2, last_error = $3
+	WHERE event_id = $1`
+
+	if _, ok := ctx.Deadline(); !ok {
+		var cancel context.CancelFunc
+		ctx, cancel = context.WithTimeout(ctx, 5*time.Second)
+		defer cancel()
+	}
+
+	if _, err := conn(ctx, r.db).ExecContext(ctx, query, eventID, nextAttempt, reason); err != nil {
+		return fmt.Errorf("repo: mark audit event failed: %w", err)
+	}
+	return nil
+}
+
+func (r *AuditOutboxPostgresRepo) PurgeDelivered(ctx context.Context, before time.Time) (int64, error) {
+	query := `DELETE FROM audit_outbox WHERE delivered_at < $1`
+
+	if _, ok := ctx.Deadline(); !ok {
+		var cancel context.CancelFunc
+		ctx, cancel = context.WithTimeout(ctx, 5*time.Second)
+		defer cancel()
+	}
+
+	res, err := conn(ctx, r.db).ExecContext(ctx, query, before)
+	if err != nil {
+		return 0, fmt.Errorf("repo: purge audit outbox: %w", err)
+	}
+	n, err := res.RowsAffected()
+	if err != nil {
+		return 0, fmt.Errorf("repo: purge audit outbox rows affected: %w", err)
+	}
+	return n, nil
+}
@@ -0,0 +1,87 @@
+package repository
+
+import (
+	"context"
+	"errors"
+	"regexp"
+	"testing"
+	"time"
+
+	"github.com/CryptoGu1/books-rest-clean-arch/internal/domain"
+	"github.com/DATA-DOG/go-sqlmock"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestTransactor_BookAndAuditEvent(t *testing.T) {
+	books, mock := newMockRepo(t, sqlmock.QueryMatcherRegexp)
+	outbox := NewAuditOutboxPostgresRepo(books.db)
+	tx := NewTransactor(books.db)
+	event := domain.AuditEvent{Entity: "BOOK", Action: "CREATE", OccurredAt: time.Now()}
+
+	testTable := []struct {
+		name      string
+		outboxErr error
+	}{
+		{name: "commit"},
+		{name: "outbox failure rolls back the book", outboxErr: errors.New("disk full")},
+	}
+
+	for _, testCase := range testTable {
+		t.Run(testCase.name, func(t *testing.T) {
+			mock.ExpectBegin()
+			mock.ExpectQuery(regexp.QuoteMeta("INSERT INTO books")).
+				WillReturnRows(sqlmock.NewRows([]string{"id"}).AddRow(5))
+			insert := mock.ExpectExec(regexp.QuoteMeta("INSERT INTO audit_outbox")).
+				WithArgs("BOOK", "CREATE", int64(5), []byte("{}"), sqlmock.AnyArg())
+			if testCase.outboxErr != nil {
+				insert.WillReturnError(testCase.outboxErr)
+				mock.ExpectRollback()
+			} else {
+				insert.WillReturnResult(sqlmock.NewResult(1, 1))
+				mock.ExpectCommit()
+			}
+
+			err := tx.WithinTx(context.Background(), func(ctx context.Context) error {
+				id, err := books.Create(ctx, &domain.Book{Title: "War and Peace"})
+				if err != nil {
+					return err
+				}
+				e := event
+				e.EntityID = int64(id)
+				return outbox.Add(ctx, e)
+			})
+
+			if testCase.outboxErr != nil {
+				assert.ErrorIs(t, err, testCase.outboxErr)
+			} else {
+				assert.NoError(t, err)
+			}
+			require.NoError(t, mock.ExpectationsWereMet())
+		})
+	}
+}
+
+func TestAuditOutboxPostgresRepo_ClaimPending(t *testing.T) {
+	books, mock := newMockRepo(t, sqlmock.QueryMatcherRegexp)
+	outbox := NewAuditOutboxPostgresRepo(books.db)
+	occurred := time.Date(2026, 10, 1, 12, 0, 0, 0, time.UTC)
+
+	lease := occurred.Add(time.Hour)
+
+	mock.ExpectQuery(`(?s)FOR UPDATE SKIP LOCKED.*UPDATE audit_outbox o SET next_attempt_at = \$2.*RETURNING`).WithArgs(10, lease).
+		WillReturnRows(sqlmock.NewRows([]string{"event_id", "entity", "action", "entity_id", "metadata", "occurred_at", "attempts"}).
+			AddRow("0b8e", "BOOK", "UPDATE", 3, []byte(`{"x-audit-changed-fields":["title"]}`), occurred, 2))
+
+	events, err := outbox.ClaimPending(context.Background(), 10, lease)
+	require.NoError(t, err)
+	assert.Equal(t, []domain.AuditEvent{{
+		ID:         "0b8e",
+		Entity:     "BOOK",
+		Action:     "UPDATE",
+		EntityID:   3,
+		Metadata:   map[string][]string{"x-audit-changed-fields": {"title"}},
+		OccurredAt: occurred,
+		Attempts:   2,
+	}}, events)
+}
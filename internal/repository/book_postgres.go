@@ -5,7 +5,10 @@ import (
 	"database/sql"
 	"errors"
 	"fmt"
+	"html"
+	"strings"
 	"time"
+	"unicode"
 
 	"github.com/CryptoGu1/books-rest-clean-arch/internal/domain"
 	"github.com/jmoiron/sqlx"
@@ -14,18 +17,60 @@ import (
 type BookRepository interface {
 	Create(ctx context.Context, book *domain.Book) (int, error)
 	GetBook(ctx context.Context, id int) (*domain.Book, error)
-	GetAllBooks(ctx context.Context) ([]*domain.Book, error)
-	Update(ctx context.Context, id int, book *domain.Book) error
+	// GetBookForUpdate читает книгу и блокирует строку до конца транзакции из ctx
+	GetBookForUpdate(ctx context.Context, id int) (*domain.Book, error)
+	GetAllBooks(ctx context.Context, q domain.BookQuery) ([]*domain.Book, int, error)
+	GetBooksAfter(ctx context.Context, q domain.BookQuery, after *domain.BookKeyset) ([]*domain.Book, error)
+	Search(ctx context.Context, q domain.BookSearchQuery) ([]*domain.BookSearchResult, int, error)
+	Update(ctx context.Context, id int, patch domain.BookPatch) (*domain.Book, error)
 	Delete(ctx context.Context, id int) error
 }
 
+// явный список колонок: в books есть служебные поля (search_vector), которых нет в domain.Book
+const bookSelectColumns = "id, title, author, publish_date, rating, created_by"
+
+const defaultSearchConfig = "simple"
+
 type BookPostgresRepo struct {
 	db *sqlx.DB
+	// конфигурация полнотекстового поиска Postgres (simple, english, russian...);
+	// попадает в базу через SyncSearchConfig, запросы берут её из search_settings
+	searchConfig string
+}
+
+func NewBookPostgresRepo(db *sqlx.DB, searchConfig string) *BookPostgresRepo {
+	if searchConfig == "" {
+		searchConfig = defaultSearchConfig
+	}
+	return &BookPostgresRepo{db: db, searchConfig: searchConfig}
 }
 
-func NewBookPostgresRepo(db *sqlx.DB) BookRepository {
-	return &BookPostgresRepo{db: db}
+// SyncSearchConfig записывает конфигурацию поиска из настроек в search_settings и, если она
+// поменялась, пересчитывает векторы книг. Неизвестная конфигурация — ошибка
+func (r *BookPostgresRepo) SyncSearchConfig(ctx context.Context) error {
+	tx, err := r.db.BeginTxx(ctx, nil)
+	if err != nil {
+		return fmt.Errorf("repo: sync search config: %w", err)
+	}
+	defer tx.Rollback()
+
+	res, err := tx.ExecContext(ctx,
+		`UPDATE search_settings SET config = $1::regconfig WHERE config <> $1::regconfig`, r.searchConfig)
+	if err != nil {
+		return fmt.Errorf("repo: sync search config: %w", err)
+	}
+	n, err := res.RowsAffected()
+	if err != nil {
+		return fmt.Errorf("repo: sync search config rows affected: %w", err)
+	}
+	if n == 0 {
+		return nil
+	}
 
+	if _, err := tx.ExecContext(ctx, `UPDATE books SET search_vector = books_search_vector(title, author)`); err != nil {
+		return fmt.Errorf("repo: reindex books: %w", err)
+	}
+	return tx.Commit()
 }
 
 func (r *BookPostgresRepo) Delete(ctx context.Context, id int) error {
@@ -37,7 +82,7 @@ func (r *BookPostgresRepo) Delete(ctx context.Context, id int) error {
 		defer cancel()
 	}
 
-	res, err := r.db.ExecContext(ctx, query, id)
+	res, err := conn(ctx, r.db).ExecContext(ctx, query, id)
 	if err != nil {
 		return fmt.Errorf("repo: delete book: %w", err)
 	}
@@ -54,15 +99,15 @@ func (r *BookPostgresRepo) Delete(ctx context.Context, id int) error {
 func (r *BookPostgresRepo) Create(ctx context.Context, book *domain.Book) (int, error) {
 	var id int
 	query := `
-	INSERT INTO books (title, author, publish_date, rating)
-	values ($1, $2, $3, $4) RETURNING id`
+	INSERT INTO books (title, author, publish_date, rating, created_by)
+	values ($1, $2, $3, $4, $5) RETURNING id`
 	if _, ok := ctx.Deadline(); !ok {
 		var cancel context.CancelFunc
-		ctx, cancel = context.WithTimeout(context.Background(), 5*time.Second)
+		ctx, cancel = context.WithTimeout(ctx, 5*time.Second)
 		defer cancel()
 	}
 
-	err := r.db.QueryRowxContext(ctx, query, book.Title, book.Author, book.PublishDate, book.Rating).Scan(&id)
+	err := conn(ctx, r.db).QueryRowxContext(ctx, query, book.Title, book.Author, book.PublishDate, book.Rating, book.CreatedBy).Scan(&id)
 	if err != nil {
 		return 0, fmt.Errorf("repo: create book: %w", err)
 	}
@@ -74,7 +119,7 @@ func (r *BookPostgresRepo) Create(ctx context.Context, book *domain.Book) (int,
 func (r *BookPostgresRepo) GetBook(ctx context.Context, id int) (*domain.Book, error) {
 	var book domain.Book
 	query := `
-	SELECT * FROM books WHERE id = $1`
+	SELECT ` + bookSelectColumns + ` FROM books WHERE id = $1`
 
 	if _, ok := ctx.Deadline(); !ok {
 		var cancel context.CancelFunc
@@ -92,10 +137,30 @@ func (r *BookPostgresRepo) GetBook(ctx context.Context, id int) (*domain.Book, e
 	return &book, nil
 }
 
-func (r *BookPostgresRepo) GetAllBooks(ctx context.Context) ([]*domain.Book, error) {
-	var books []*domain.Book
+func (r *BookPostgresRepo) GetBookForUpdate(ctx context.Context, id int) (*domain.Book, error) {
+	var book domain.Book
 	query := `
-	SELECT * FROM books`
+	SELECT ` + bookSelectColumns + ` FROM books WHERE id = $1 FOR UPDATE`
+
+	if _, ok := ctx.Deadline(); !ok {
+		var cancel context.CancelFunc
+		ctx, cancel = context.WithTimeout(ctx, 5*time.Second)
+		defer cancel()
+	}
+
+	err := sqlx.GetContext(ctx, conn(ctx, r.db), &book, query, id)
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return nil, domain.ErrBookNotFound
+		}
+		return nil, fmt.Errorf("repo: get book for update: %w", err)
+	}
+	return &book, nil
+}
+
+func (r *BookPostgresRepo) GetAllBooks(ctx context.Context, q domain.BookQuery) ([]*domain.Book, int, error) {
+	books := make([]*domain.Book, 0)
+	where, args := bookFilter(q)
 
 	if _, ok := ctx.Deadline(); !ok {
 		var cancel context.CancelFunc
@@ -103,33 +168,276 @@ func (r *BookPostgresRepo) GetAllBooks(ctx context.Context) ([]*domain.Book, err
 		defer cancel()
 	}
 
-	if err := r.db.SelectContext(ctx, &books, query); err != nil {
-		return nil, fmt.Errorf("repo: get all books: %w", err)
+	var total int
+	countQuery := `SELECT COUNT(*) FROM books` + where
+	if err := r.db.GetContext(ctx, &total, countQuery, args...); err != nil {
+		return nil, 0, fmt.Errorf("repo: count books: %w", err)
+	}
+
+	query := fmt.Sprintf(`SELECT %s FROM books%s ORDER BY %s LIMIT $%d OFFSET $%d`,
+		bookSelectColumns, where, bookOrderBy(q.Sort), len(args)+1, len(args)+2)
+	args = append(args, q.Limit, q.Offset)
+
+	if err := r.db.SelectContext(ctx, &books, query, args...); err != nil {
+		return nil, 0, fmt.Errorf("repo: get all books: %w", err)
+	}
+
+	return books, total, nil
+}
+
+// GetBooksAfter — keyset-пагинация: вместо OFFSET продолжает выборку строго после
+// (значение сортировки, id) последней книги предыдущей страницы. Сортировка одна, id идёт в том же направлении
+func (r *BookPostgresRepo) GetBooksAfter(ctx context.Context, q domain.BookQuery, after *domain.BookKeyset) ([]*domain.Book, error) {
+	books := make([]*domain.Book, 0)
+
+	sort := domain.SortField{Field: "id"}
+	if len(q.Sort) > 0 {
+		sort = q.Sort[0]
+	}
+	col, ok := bookSortColumns[sort.Field]
+	if !ok {
+		return nil, fmt.Errorf("repo: get books after: unknown sort field %q", sort.Field)
+	}
+
+	dir, cmp := "ASC", ">"
+	if sort.Desc {
+		dir, cmp = "DESC", "<"
+	}
+
+	where, args := bookFilter(q)
+	if after != nil {
+		var cond string
+		if col == "id" {
+			args = append(args, after.ID)
+			cond = fmt.Sprintf("id %s $%d", cmp, len(args))
+		} else {
+			args = append(args, after.Value, after.ID)
+			cond = fmt.Sprintf("(%s, id) %s ($%d, $%d)", col, cmp, len(args)-1, len(args))
+		}
+		if where == "" {
+			where = " WHERE " + cond
+		} else {
+			where += " AND " + cond
+		}
+	}
+
+	orderBy := fmt.Sprintf("id %s", dir)
+	if col != "id" {
+		orderBy = fmt.Sprintf("%s %s, id %s", col, dir, dir)
+	}
+
+	args = append(args, q.Limit)
+	query := fmt.Sprintf(`SELECT %s FROM books%s ORDER BY %s LIMIT $%d`, bookSelectColumns, where, orderBy, len(args))
+
+	if _, ok := ctx.Deadline(); !ok {
+		var cancel context.CancelFunc
+		ctx, cancel = context.WithTimeout(ctx, 5*time.Second)
+		defer cancel()
+	}
+
+	if err := r.db.SelectContext(ctx, &books, query, args...); err != nil {
+		return nil, fmt.Errorf("repo: get books after: %w", err)
 	}
 
 	return books, nil
 }
 
-func (r *BookPostgresRepo) Update(ctx context.Context, id int, book *domain.Book) error {
-	query := `UPDATE books SET title=$1, author=$2, publish_date=$3, rating=$4 WHERE id=$5`
+func (r *BookPostgresRepo) Search(ctx context.Context, q domain.BookSearchQuery) ([]*domain.BookSearchResult, int, error) {
+	results := make([]*domain.BookSearchResult, 0)
+
+	tsQuery := prefixTSQuery(q.Query)
+	if tsQuery == "" {
+		return results, 0, nil
+	}
+
 	if _, ok := ctx.Deadline(); !ok {
 		var cancel context.CancelFunc
 		ctx, cancel = context.WithTimeout(ctx, 5*time.Second)
 		defer cancel()
 	}
 
-	res, err := r.db.ExecContext(ctx, query, book.Title, book.Author, book.PublishDate, book.Rating, id)
-	if err != nil {
-		return fmt.Errorf("repo: update book: %w", err)
+	// запрос строится той же конфигурацией, что и search_vector (см. search_settings)
+	var total int
+	countQuery := `
+	SELECT COUNT(*) FROM books b, search_settings s
+	WHERE b.search_vector @@ to_tsquery(s.config, $1)`
+	if err := r.db.GetContext(ctx, &total, countQuery, tsQuery); err != nil {
+		return nil, 0, fmt.Errorf("repo: count search books: %w", err)
+	}
+
+	// маркеры подсветки вырезаются из исходного текста, чтобы их нельзя было подделать
+	query := `
+	SELECT b.id, b.title, b.author, b.publish_date, b.rating, b.created_by,
+		ts_rank(b.search_vector, q) AS rank,
+		ts_headline(s.config, translate(b.title, E'\x02\x03', ''), q, $2) AS "highlight.title",
+		ts_headline(s.config, translate(b.author, E'\x02\x03', ''), q, $2) AS "highlight.author"
+	FROM books b, search_settings s, to_tsquery(s.config, $1) AS q
+	WHERE b.search_vector @@ q
+	ORDER BY rank DESC, b.id
+	LIMIT $3 OFFSET $4`
 
+	if err := r.db.SelectContext(ctx, &results, query, tsQuery, headlineOptions, q.Limit, q.Offset); err != nil {
+		return nil, 0, fmt.Errorf("repo: search books: %w", err)
 	}
 
-	aff, err := res.RowsAffected()
-	if err != nil {
-		return fmt.Errorf("repo: update rows affected: %w", err)
+	for _, res := range results {
+		res.Highlight.Title = escapeHeadline(res.Highlight.Title)
+		res.Highlight.Author = escapeHeadline(res.Highlight.Author)
 	}
-	if aff == 0 {
-		return domain.ErrBookNotFound
+	return results, total, nil
+}
+
+// Update обновляет только переданные в патче колонки и возвращает книгу в состоянии до изменения.
+// Старые значения берутся из подзапроса с FOR UPDATE, поэтому чтение и запись атомарны
+func (r *BookPostgresRepo) Update(ctx context.Context, id int, patch domain.BookPatch) (*domain.Book, error) {
+	if patch.IsEmpty() {
+		return r.GetBook(ctx, id)
 	}
-	return nil
+
+	var (
+		sets []string
+		args []interface{}
+	)
+	set := func(col string, arg interface{}) {
+		args = append(args, arg)
+		sets = append(sets, fmt.Sprintf("%s = $%d", col, len(args)))
+	}
+	if patch.Title != nil {
+		set("title", *patch.Title)
+	}
+	if patch.Author != nil {
+		set("author", *patch.Author)
+	}
+	if patch.PublishDate != nil {
+		set("publish_date", *patch.PublishDate)
+	}
+	if patch.Rating != nil {
+		set("rating", *patch.Rating)
+	}
+	args = append(args, id)
+
+	query := fmt.Sprintf(`
+	UPDATE books AS b SET %s
+	FROM (SELECT %s FROM books WHERE id = $%d FOR UPDATE) AS old
+	WHERE b.id = old.id
+	RETURNING old.id, old.title, old.author, old.publish_date, old.rating, old.created_by`,
+		strings.Join(sets, ", "), bookSelectColumns, len(args))
+
+	if _, ok := ctx.Deadline(); !ok {
+		var cancel context.CancelFunc
+		ctx, cancel = context.WithTimeout(ctx, 5*time.Second)
+		defer cancel()
+	}
+
+	var old domain.Book
+	if err := sqlx.GetContext(ctx, conn(ctx, r.db), &old, query, args...); err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return nil, domain.ErrBookNotFound
+		}
+		return nil, fmt.Errorf("repo: update book: %w", err)
+	}
+	return &old, nil
+}
+
+// колонки для ORDER BY берутся только из этой таблицы, пользовательский ввод в SQL не попадает
+var bookSortColumns = map[string]string{
+	"id":           "id",
+	"title":        "title",
+	"author":       "author",
+	"publish_date": "publish_date",
+	"rating":       "rating",
+}
+
+func bookFilter(q domain.BookQuery) (string, []interface{}) {
+	var (
+		conds []string
+		args  []interface{}
+	)
+	add := func(cond string, arg interface{}) {
+		args = append(args, arg)
+		conds = append(conds, fmt.Sprintf(cond, len(args)))
+	}
+
+	if q.Author != "" {
+		add("author = $%d", q.Author)
+	}
+	if q.CreatedBy != nil {
+		add("created_by = $%d", *q.CreatedBy)
+	}
+	if q.Title != "" {
+		add("title ILIKE $%d", "%"+escapeLike(q.Title)+"%")
+	}
+	if q.MinRating != nil {
+		add("rating >= $%d", *q.MinRating)
+	}
+	if q.MaxRating != nil {
+		add("rating <= $%d", *q.MaxRating)
+	}
+	if q.PublishedFrom != nil {
+		add("publish_date >= $%d", *q.PublishedFrom)
+	}
+	if q.PublishedTo != nil {
+		// граница включительная: берём всё до начала следующего дня
+		add("publish_date < $%d", q.PublishedTo.AddDate(0, 0, 1))
+	}
+
+	if len(conds) == 0 {
+		return "", nil
+	}
+	return " WHERE " + strings.Join(conds, " AND "), args
+}
+
+func bookOrderBy(sort []domain.SortField) string {
+	parts := make([]string, 0, len(sort)+1)
+	hasID := false
+	for _, f := range sort {
+		col, ok := bookSortColumns[f.Field]
+		if !ok {
+			continue
+		}
+		if col == "id" {
+			hasID = true
+		}
+		if f.Desc {
+			col += " DESC"
+		}
+		parts = append(parts, col)
+	}
+	// id в конце делает порядок детерминированным между страницами
+	if !hasID {
+		parts = append(parts, "id")
+	}
+	return strings.Join(parts, ", ")
+}
+
+func escapeLike(s string) string {
+	return strings.NewReplacer(`\`, `\\`, "%", `\%`, "_", `\_`).Replace(s)
+}
+
+// ts_headline размечает совпадения управляющими символами, а не тегами: название и автора
+// вводят пользователи, поэтому текст сначала экранируется и только потом получает <mark>
+const (
+	headlineStart   = "\x02"
+	headlineStop    = "\x03"
+	headlineOptions = "StartSel=" + headlineStart + ", StopSel=" + headlineStop + ", HighlightAll=true"
+)
+
+var headlineMarks = strings.NewReplacer(headlineStart, "<mark>", headlineStop, "</mark>")
+
+func escapeHeadline(s string) string {
+	return headlineMarks.Replace(html.EscapeString(s))
+}
+
+// prefixTSQuery превращает пользовательский ввод в безопасный tsquery:
+// оставляет только буквы и цифры, каждое слово ищется по префиксу, слова объединяются через И
+func prefixTSQuery(input string) string {
+	words := strings.FieldsFunc(input, func(r rune) bool {
+		return !unicode.IsLetter(r) && !unicode.IsDigit(r)
+	})
+
+	terms := make([]string, 0, len(words))
+	for _, w := range words {
+		terms = append(terms, w+":*")
+	}
+	return strings.Join(terms, " & ")
 }
@@ -0,0 +1,274 @@
+package repository
+
+import (
+	"context"
+	"database/sql/driver"
+	"errors"
+	"regexp"
+	"testing"
+	"time"
+
+	"github.com/CryptoGu1/books-rest-clean-arch/internal/domain"
+	"github.com/DATA-DOG/go-sqlmock"
+	"github.com/jmoiron/sqlx"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+var bookColumns = []string{"id", "title", "author", "publish_date", "rating", "created_by"}
+
+func newMockRepo(t *testing.T, matcher ...sqlmock.QueryMatcher) (*BookPostgresRepo, sqlmock.Sqlmock) {
+	m := sqlmock.QueryMatcherEqual
+	if len(matcher) > 0 {
+		m = matcher[0]
+	}
+	db, mock, err := sqlmock.New(sqlmock.QueryMatcherOption(m))
+	require.NoError(t, err)
+	t.Cleanup(func() { db.Close() })
+
+	return &BookPostgresRepo{db: sqlx.NewDb(db, "sqlmock"), searchConfig: defaultSearchConfig}, mock
+}
+
+func TestBookPostgresRepo_GetBooksAfter(t *testing.T) {
+	date := time.Date(2020, 1, 2, 0, 0, 0, 0, time.UTC)
+
+	testTable := []struct {
+		name          string
+		query         domain.BookQuery
+		after         *domain.BookKeyset
+		expectedSQL   string
+		expectedArgs  []driver.Value
+		rows          *sqlmock.Rows
+		expectedIDs   []int
+		expectedError bool
+	}{
+		{
+			name:         "first page by id",
+			query:        domain.BookQuery{Limit: 3},
+			expectedSQL:  `SELECT id, title, author, publish_date, rating, created_by FROM books ORDER BY id ASC LIMIT $1`,
+			expectedArgs: []driver.Value{3},
+			rows: sqlmock.NewRows(bookColumns).
+				AddRow(1, "A", "X", date, 5, nil).
+				AddRow(2, "B", "Y", date, 4, nil),
+			expectedIDs: []int{1, 2},
+		},
+		{
+			name:         "after id",
+			query:        domain.BookQuery{Limit: 2},
+			after:        &domain.BookKeyset{Value: 2, ID: 2},
+			expectedSQL:  `SELECT id, title, author, publish_date, rating, created_by FROM books WHERE id > $1 ORDER BY id ASC LIMIT $2`,
+			expectedArgs: []driver.Value{2, 2},
+			rows:         sqlmock.NewRows(bookColumns).AddRow(3, "C", "Z", date, 3, nil),
+			expectedIDs:  []int{3},
+		},
+		{
+			name: "after rating desc with filter",
+			query: domain.BookQuery{
+				Limit:  2,
+				Author: "Tolstoy",
+				Sort:   []domain.SortField{{Field: "rating", Desc: true}},
+			},
+			after:        &domain.BookKeyset{Value: 4, ID: 7},
+			expectedSQL:  `SELECT id, title, author, publish_date, rating, created_by FROM books WHERE author = $1 AND (rating, id) < ($2, $3) ORDER BY rating DESC, id DESC LIMIT $4`,
+			expectedArgs: []driver.Value{"Tolstoy", 4, 7, 2},
+			rows: sqlmock.NewRows(bookColumns).
+				AddRow(5, "War", "Tolstoy", date, 4, nil).
+				AddRow(9, "Peace", "Tolstoy", date, 3, nil),
+			expectedIDs: []int{5, 9},
+		},
+		{
+			name: "after publish_date asc",
+			query: domain.BookQuery{
+				Limit: 1,
+				Sort:  []domain.SortField{{Field: "publish_date"}},
+			},
+			after:        &domain.BookKeyset{Value: date, ID: 1},
+			expectedSQL:  `SELECT id, title, author, publish_date, rating, created_by FROM books WHERE (publish_date, id) > ($1, $2) ORDER BY publish_date ASC, id ASC LIMIT $3`,
+			expectedArgs: []driver.Value{date, 1, 1},
+			rows:         sqlmock.NewRows(bookColumns),
+			expectedIDs:  []int{},
+		},
+		{
+			name:          "unknown sort field",
+			query:         domain.BookQuery{Limit: 1, Sort: []domain.SortField{{Field: "password"}}},
+			expectedError: true,
+		},
+	}
+
+	for _, testCase := range testTable {
+		t.Run(testCase.name, func(t *testing.T) {
+			repo, mock := newMockRepo(t)
+
+			if testCase.expectedSQL != "" {
+				mock.ExpectQuery(testCase.expectedSQL).WithArgs(testCase.expectedArgs...).WillReturnRows(testCase.rows)
+			}
+
+			books, err := repo.GetBooksAfter(context.Background(), testCase.query, testCase.after)
+			if testCase.expectedError {
+				assert.Error(t, err)
+				return
+			}
+
+			require.NoError(t, err)
+			ids := make([]int, 0, len(books))
+			for _, b := range books {
+				ids = append(ids, b.ID)
+			}
+			assert.Equal(t, testCase.expectedIDs, ids)
+			assert.NoError(t, mock.ExpectationsWereMet())
+		})
+	}
+}
+
+func TestBookPostgresRepo_GetBooksAfter_DBError(t *testing.T) {
+	repo, mock := newMockRepo(t)
+	mock.ExpectQuery(`SELECT id, title, author, publish_date, rating, created_by FROM books ORDER BY id ASC LIMIT $1`).WillReturnError(errors.New("db down"))
+
+	_, err := repo.GetBooksAfter(context.Background(), domain.BookQuery{Limit: 1}, nil)
+	assert.Error(t, err)
+}
+
+func TestBookPostgresRepo_GetAllBooks(t *testing.T) {
+	repo, mock := newMockRepo(t)
+	minRating := 3
+
+	mock.ExpectQuery(`SELECT COUNT(*) FROM books WHERE title ILIKE $1 AND rating >= $2`).
+		WithArgs(`%50\%%`, 3).
+		WillReturnRows(sqlmock.NewRows([]string{"count"}).AddRow(4))
+	mock.ExpectQuery(`SELECT id, title, author, publish_date, rating, created_by FROM books WHERE title ILIKE $1 AND rating >= $2 ORDER BY title DESC, id LIMIT $3 OFFSET $4`).
+		WithArgs(`%50\%%`, 3, 2, 2).
+		WillReturnRows(sqlmock.NewRows(bookColumns).AddRow(1, "50% off", "X", time.Now(), 3, nil))
+
+	books, total, err := repo.GetAllBooks(context.Background(), domain.BookQuery{
+		Limit:     2,
+		Offset:    2,
+		Title:     "50%",
+		MinRating: &minRating,
+		Sort:      []domain.SortField{{Field: "title", Desc: true}},
+	})
+
+	require.NoError(t, err)
+	assert.Equal(t, 4, total)
+	assert.Len(t, books, 1)
+	assert.NoError(t, mock.ExpectationsWereMet())
+}
+
+func TestPrefixTSQuery(t *testing.T) {
+	assert.Equal(t, "war:* & peace:*", prefixTSQuery("war peace"))
+	assert.Equal(t, "Толстой:* & 1869:*", prefixTSQuery(" Толстой, 1869! "))
+	assert.Equal(t, "a:* & b:*", prefixTSQuery("a:* & !b | ('"))
+	assert.Equal(t, "", prefixTSQuery("&|!():*"))
+}
+
+func TestBookPostgresRepo_Search(t *testing.T) {
+	repo, mock := newMockRepo(t, sqlmock.QueryMatcherRegexp)
+
+	mock.ExpectQuery(`(?s)SELECT COUNT\(\*\) FROM books b, search_settings s.*to_tsquery\(s.config, \$1\)`).
+		WithArgs("tols:*").
+		WillReturnRows(sqlmock.NewRows([]string{"count"}).AddRow(1))
+	mock.ExpectQuery(`(?s)FROM books b, search_settings s, to_tsquery\(s.config, \$1\).*ORDER BY rank DESC, b.id`).
+		WithArgs("tols:*", headlineOptions, 10, 0).
+		WillReturnRows(sqlmock.NewRows([]string{
+			"id", "title", "author", "publish_date", "rating", "created_by", "rank", "highlight.title", "highlight.author",
+		}).AddRow(1, "<script>alert(1)</script>", "Tolstoy", time.Now(), 5, 3, 0.6,
+			"<script>alert(1)</script>", headlineStart+"Tolstoy"+headlineStop))
+
+	results, total, err := repo.Search(context.Background(), domain.BookSearchQuery{Query: "tols", Limit: 10})
+
+	require.NoError(t, err)
+	assert.Equal(t, 1, total)
+	require.Len(t, results, 1)
+	assert.Equal(t, "Tolstoy", results[0].Author)
+	assert.Equal(t, 0.6, results[0].Rank)
+	assert.Equal(t, "<mark>Tolstoy</mark>", results[0].Highlight.Author)
+	// разметка из названия доходит до клиента только экранированной
+	assert.Equal(t, "&lt;script&gt;alert(1)&lt;/script&gt;", results[0].Highlight.Title)
+	assert.NoError(t, mock.ExpectationsWereMet())
+}
+
+func TestBookPostgresRepo_SyncSearchConfig(t *testing.T) {
+	t.Run("unchanged", func(t *testing.T) {
+		repo, mock := newMockRepo(t, sqlmock.QueryMatcherRegexp)
+		repo.searchConfig = "english"
+
+		mock.ExpectBegin()
+		mock.ExpectExec(`UPDATE search_settings SET config = \$1::regconfig`).WithArgs("english").
+			WillReturnResult(sqlmock.NewResult(0, 0))
+		mock.ExpectRollback()
+
+		require.NoError(t, repo.SyncSearchConfig(context.Background()))
+		assert.NoError(t, mock.ExpectationsWereMet())
+	})
+
+	t.Run("changed", func(t *testing.T) {
+		repo, mock := newMockRepo(t, sqlmock.QueryMatcherRegexp)
+		repo.searchConfig = "english"
+
+		mock.ExpectBegin()
+		mock.ExpectExec(`UPDATE search_settings SET config = \$1::regconfig`).WithArgs("english").
+			WillReturnResult(sqlmock.NewResult(0, 1))
+		// векторы, посчитанные старой конфигурацией, пересчитываются
+		mock.ExpectExec(`UPDATE books SET search_vector = books_search_vector\(title, author\)`).
+			WillReturnResult(sqlmock.NewResult(0, 3))
+		mock.ExpectCommit()
+
+		require.NoError(t, repo.SyncSearchConfig(context.Background()))
+		assert.NoError(t, mock.ExpectationsWereMet())
+	})
+}
+
+func TestBookPostgresRepo_Search_EmptyQuery(t *testing.T) {
+	repo, mock := newMockRepo(t)
+
+	results, total, err := repo.Search(context.Background(), domain.BookSearchQuery{Query: "&&", Limit: 10})
+
+	require.NoError(t, err)
+	assert.Zero(t, total)
+	assert.Empty(t, results)
+	assert.NoError(t, mock.ExpectationsWereMet())
+}
+
+func TestBookPostgresRepo_GetBookForUpdate(t *testing.T) {
+	repo, mock := newMockRepo(t, sqlmock.QueryMatcherRegexp)
+	date := time.Date(2020, 1, 2, 0, 0, 0, 0, time.UTC)
+
+	mock.ExpectQuery(`FROM books WHERE id = \$1 FOR UPDATE`).WithArgs(1).
+		WillReturnRows(sqlmock.NewRows(bookColumns).AddRow(1, "A", "X", date, 5, 10))
+	mock.ExpectQuery(`FROM books WHERE id = \$1 FOR UPDATE`).WithArgs(2).
+		WillReturnRows(sqlmock.NewRows(bookColumns))
+
+	book, err := repo.GetBookForUpdate(context.Background(), 1)
+	require.NoError(t, err)
+	assert.Equal(t, int64(10), *book.CreatedBy)
+
+	_, err = repo.GetBookForUpdate(context.Background(), 2)
+	assert.ErrorIs(t, err, domain.ErrBookNotFound)
+}
+
+func TestBookPostgresRepo_Update(t *testing.T) {
+	repo, mock := newMockRepo(t, sqlmock.QueryMatcherRegexp)
+	title, rating := "Anna Karenina", 5
+	old := time.Date(1878, 1, 1, 0, 0, 0, 0, time.UTC)
+
+	mock.ExpectQuery(regexp.QuoteMeta(`UPDATE books AS b SET title = $1, rating = $2`)+
+		`.*`+regexp.QuoteMeta(`WHERE id = $3 FOR UPDATE`)).
+		WithArgs(title, rating, 7).
+		WillReturnRows(sqlmock.NewRows(bookColumns).AddRow(7, "Anna", "Tolstoy", old, 4, nil))
+
+	prev, err := repo.Update(context.Background(), 7, domain.BookPatch{Title: &title, Rating: &rating})
+
+	require.NoError(t, err)
+	assert.Equal(t, "Anna", prev.Title)
+	assert.Equal(t, old, prev.PublishDate)
+	assert.NoError(t, mock.ExpectationsWereMet())
+}
+
+func TestBookPostgresRepo_Update_NotFound(t *testing.T) {
+	repo, mock := newMockRepo(t, sqlmock.QueryMatcherRegexp)
+	rating := 5
+
+	mock.ExpectQuery(`UPDATE books`).WithArgs(rating, 7).WillReturnRows(sqlmock.NewRows(bookColumns))
+
+	_, err := repo.Update(context.Background(), 7, domain.BookPatch{Rating: &rating})
+	assert.ErrorIs(t, err, domain.ErrBookNotFound)
+}
@@ -0,0 +1,60 @@
+package repository
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/CryptoGu1/books-rest-clean-arch/internal/domain"
+	"github.com/jmoiron/sqlx"
+)
+
+type IdentityRepository interface {
+	// GetUserID — владелец внешнего аккаунта; ErrIdentityNotFound, если он ещё не привязан
+	GetUserID(ctx context.Context, provider, subject string) (int64, error)
+	Link(ctx context.Context, identity domain.UserIdentity) error
+}
+
+type IdentityPostgresRepo struct {
+	db *sqlx.DB
+}
+
+func NewIdentityPostgresRepo(db *sqlx.DB) *IdentityPostgresRepo {
+	return &IdentityPostgresRepo{db: db}
+}
+
+func (r *IdentityPostgresRepo) GetUserID(ctx context.Context, provider, subject string) (int64, error) {
+	query := `SELECT user_id FROM user_identities WHERE provider = $1 AND subject = $2`
+
+	if _, ok := ctx.Deadline(); !ok {
+		var cancel context.CancelFunc
+		ctx, cancel = context.WithTimeout(ctx, 5*time.Second)
+		defer cancel()
+	}
+
+	var userID int64
+	if err := r.db.GetContext(ctx, &userID, query, provider, subject); err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return 0, domain.ErrIdentityNotFound
+		}
+		return 0, fmt.Errorf("repo: get identity: %w", err)
+	}
+	return userID, nil
+}
+
+func (r *IdentityPostgresRepo) Link(ctx context.Context, identity domain.UserIdentity) error {
+	query := `INSERT INTO user_identities (provider, subject, user_id, email) VALUES ($1, $2, $3, $4)`
+
+	if _, ok := ctx.Deadline(); !ok {
+		var cancel context.CancelFunc
+		ctx, cancel = context.WithTimeout(ctx, 5*time.Second)
+		defer cancel()
+	}
+
+	if _, err := r.db.ExecContext(ctx, query, identity.Provider, identity.Subject, identity.UserID, identity.Email); err != nil {
+		return fmt.Errorf("repo: link identity: %w", err)
+	}
+	return nil
+}
@@ -0,0 +1,143 @@
+package repository
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"sync"
+	"time"
+
+	"github.com/CryptoGu1/books-rest-clean-arch/internal/domain"
+	"github.com/jmoiron/sqlx"
+)
+
+// LoginAttemptRepository хранит счётчики неудачных входов. Для одного инстанса хватит
+// LoginAttemptMemoryRepo, при нескольких репликах счётчики должны быть общими — LoginAttemptPostgresRepo
+type LoginAttemptRepository interface {
+	// Get возвращает счётчик; для неизвестного ключа — нулевой без ошибки
+	Get(ctx context.Context, key string) (domain.LoginAttempts, error)
+	// RegisterFailure атомарно увеличивает счётчик. Если первая ошибка старше window, счёт начинается заново
+	RegisterFailure(ctx context.Context, key string, now time.Time, window time.Duration) (domain.LoginAttempts, error)
+	Reset(ctx context.Context, key string) error
+}
+
+type LoginAttemptPostgresRepo struct {
+	db *sqlx.DB
+}
+
+func NewLoginAttemptPostgresRepo(db *sqlx.DB) *LoginAttemptPostgresRepo {
+	return &LoginAttemptPostgresRepo{db: db}
+}
+
+func (r *LoginAttemptPostgresRepo) Get(ctx context.Context, key string) (domain.LoginAttempts, error) {
+	query := `SELECT key, failures, first_failure_at, last_failure_at FROM login_attempts WHERE key = $1`
+
+	if _, ok := ctx.Deadline(); !ok {
+		var cancel context.CancelFunc
+		ctx, cancel = context.WithTimeout(ctx, 5*time.Second)
+		defer cancel()
+	}
+
+	var a domain.LoginAttempts
+	if err := r.db.GetContext(ctx, &a, query, key); err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return domain.LoginAttempts{Key: key}, nil
+		}
+		return domain.LoginAttempts{}, fmt.Errorf("repo: get login attempts: %w", err)
+	}
+	return a, nil
+}
+
+func (r *LoginAttemptPostgresRepo) RegisterFailure(ctx context.Context, key string, now time.Time, window time.Duration) (domain.LoginAttempts, error) {
+	query := `
+	INSERT INTO login_attempts AS la (key, failures, first_failure_at, last_failure_at)
+	VALUES ($1, 1, $2, $2)
+	ON CONFLICT (key) DO UPDATE SET
+		failures         = CASE WHEN la.first_failure_at < $3 THEN 1 ELSE la.failures + 1 END,
+		first_failure_at = CASE WHEN la.first_failure_at < $3 THEN $2 ELSE la.first_failure_at END,
+		last_failure_at  = $2
+	RETURNING key, failures, first_failure_at, last_failure_at`
+
+	if _, ok := ctx.Deadline(); !ok {
+		var cancel context.CancelFunc
+		ctx, cancel = context.WithTimeout(ctx, 5*time.Second)
+		defer cancel()
+	}
+
+	var a domain.LoginAttempts
+	if err := r.db.GetContext(ctx, &a, query, key, now, now.Add(-window)); err != nil {
+		return domain.LoginAttempts{}, fmt.Errorf("repo: register login failure: %w", err)
+	}
+	return a, nil
+}
+
+func (r *LoginAttemptPostgresRepo) Reset(ctx context.Context, key string) error {
+	if _, ok := ctx.Deadline(); !ok {
+		var cancel context.CancelFunc
+		ctx, cancel = context.WithTimeout(ctx, 5*time.Second)
+		defer cancel()
+	}
+
+	if _, err := r.db.ExecContext(ctx, `DELETE FROM login_attempts WHERE key = $1`, key); err != nil {
+		return fmt.Errorf("repo: reset login attempts: %w", err)
+	}
+	return nil
+}
+
+// memorySweepThreshold — при таком числе ключей устаревшие записи вычищаются,
+// чтобы перебор случайных email не раздувал память
+const memorySweepThreshold = 10000
+
+type LoginAttemptMemoryRepo struct {
+	mu       sync.Mutex
+	attempts map[string]domain.LoginAttempts
+}
+
+func NewLoginAttemptMemoryRepo() *LoginAttemptMemoryRepo {
+	return &LoginAttemptMemoryRepo{attempts: make(map[string]domain.LoginAttempts)}
+}
+
+func (r *LoginAttemptMemoryRepo) Get(_ context.Context, key string) (domain.LoginAttempts, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	if a, ok := r.attempts[key]; ok {
+		return a, nil
+	}
+	return domain.LoginAttempts{Key: key}, nil
+}
+
+func (r *LoginAttemptMemoryRepo) RegisterFailure(_ context.Context, key string, now time.Time, window time.Duration) (domain.LoginAttempts, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	if len(r.attempts) >= memorySweepThreshold {
+		r.sweep(now.Add(-window))
+	}
+
+	a, ok := r.attempts[key]
+	if !ok || a.FirstFailureAt.Before(now.Add(-window)) {
+		a = domain.LoginAttempts{Key: key, FirstFailureAt: now}
+	}
+	a.Failures++
+	a.LastFailureAt = now
+	r.attempts[key] = a
+	return a, nil
+}
+
+func (r *LoginAttemptMemoryRepo) Reset(_ context.Context, key string) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	delete(r.attempts, key)
+	return nil
+}
+
+func (r *LoginAttemptMemoryRepo) sweep(before time.Time) {
+	for key, a := range r.attempts {
+		if a.LastFailureAt.Before(before) {
+			delete(r.attempts, key)
+		}
+	}
+}
@@ -0,0 +1,185 @@
+package repository
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/CryptoGu1/books-rest-clean-arch/internal/domain"
+	"github.com/jmoiron/sqlx"
+)
+
+type MFARepository interface {
+	GetTOTP(ctx context.Context, userID int64) (domain.TOTP, error)
+	// StartTOTP сохраняет новый неподтверждённый секрет и резервные коды, заменяя прежнюю попытку
+	StartTOTP(ctx context.Context, userID int64, secret string, recoveryHashes []string) error
+	ConfirmTOTP(ctx context.Context, userID int64, step int64) error
+	// UseTOTPStep фиксирует принятый интервал; если он не новее последнего — ErrInvalidMFACode
+	UseTOTPStep(ctx context.Context, userID int64, step int64) error
+	UseRecoveryCode(ctx context.Context, userID int64, codeHash string) error
+	DeleteTOTP(ctx context.Context, userID int64) error
+}
+
+type MFAPostgresRepo struct {
+	db *sqlx.DB
+}
+
+func NewMFAPostgresRepo(db *sqlx.DB) *MFAPostgresRepo {
+	return &MFAPostgresRepo{db: db}
+}
+
+func (r *MFAPostgresRepo) GetTOTP(ctx context.Context, userID int64) (domain.TOTP, error) {
+	query := `SELECT user_id, secret, created_at, confirmed_at, last_used_step FROM user_totp WHERE user_id = $1`
+
+	if _, ok := ctx.Deadline(); !ok {
+		var cancel context.CancelFunc
+		ctx, cancel = context.WithTimeout(ctx, 5*time.Second)
+		defer cancel()
+	}
+
+	var t domain.TOTP
+	if err := r.db.GetContext(ctx, &t, query, userID); err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return domain.TOTP{}, domain.ErrMFANotEnabled
+		}
+		return domain.TOTP{}, fmt.Errorf("repo: get totp: %w", err)
+	}
+	return t, nil
+}
+
+func (r *MFAPostgresRepo) StartTOTP(ctx context.Context, userID int64, secret string, recoveryHashes []string) error {
+	if _, ok := ctx.Deadline(); !ok {
+		var cancel context.CancelFunc
+		ctx, cancel = context.WithTimeout(ctx, 5*time.Second)
+		defer cancel()
+	}
+
+	tx, err := r.db.BeginTxx(ctx, nil)
+	if err != nil {
+		return fmt.Errorf("repo: start totp: %w", err)
+	}
+	defer tx.Rollback()
+
+	// подтверждённый секрет не перезаписываем: сначала 2FA нужно отключить
+	res, err := tx.ExecContext(ctx, `
+		INSERT INTO user_totp (user_id, secret) VALUES ($1, $2)
+		ON CONFLICT (user_id) DO UPDATE SET secret = EXCLUDED.secret, created_at = now(), last_used_step = 0
+		WHERE user_totp.confirmed_at IS NULL`, userID, secret)
+	if err != nil {
+		return fmt.Errorf("repo: start totp: %w", err)
+	}
+	if aff, err := res.RowsAffected(); err == nil && aff == 0 {
+		return domain.ErrMFAAlreadyEnabled
+	}
+
+	if err := replaceRecoveryCodes(ctx, tx, userID, recoveryHashes); err != nil {
+		return err
+	}
+
+	if err := tx.Commit(); err != nil {
+		return fmt.Errorf("repo: start totp: %w", err)
+	}
+	return nil
+}
+
+func replaceRecoveryCodes(ctx context.Context, tx *sqlx.Tx, userID int64, hashes []string) error {
+	if _, err := tx.ExecContext(ctx, `DELETE FROM mfa_recovery_codes WHERE user_id = $1`, userID); err != nil {
+		return fmt.Errorf("repo: delete recovery codes: %w", err)
+	}
+	for _, h := range hashes {
+		if _, err := tx.ExecContext(ctx,
+			`INSERT INTO mfa_recovery_codes (user_id, code_hash) VALUES ($1, $2)`, userID, h); err != nil {
+			return fmt.Errorf("repo: insert recovery code: %w", err)
+		}
+	}
+	return nil
+}
+
+func (r *MFAPostgresRepo) ConfirmTOTP(ctx context.Context, userID int64, step int64) error {
+	query := `
+	UPDATE user_totp SET confirmed_at = now(), last_used_step = $2
+	WHERE user_id = $1 AND confirmed_at IS NULL`
+
+	if _, ok := ctx.Deadline(); !ok {
+		var cancel context.CancelFunc
+		ctx, cancel = context.WithTimeout(ctx, 5*time.Second)
+		defer cancel()
+	}
+
+	res, err := r.db.ExecContext(ctx, query, userID, step)
+	if err != nil {
+		return fmt.Errorf("repo: confirm totp: %w", err)
+	}
+	if aff, err := res.RowsAffected(); err == nil && aff == 0 {
+		return domain.ErrMFAAlreadyEnabled
+	}
+	return nil
+}
+
+func (r *MFAPostgresRepo) UseTOTPStep(ctx context.Context, userID int64, step int64) error {
+	query := `UPDATE user_totp SET last_used_step = $2 WHERE user_id = $1 AND last_used_step < $2`
+
+	if _, ok := ctx.Deadline(); !ok {
+		var cancel context.CancelFunc
+		ctx, cancel = context.WithTimeout(ctx, 5*time.Second)
+		defer cancel()
+	}
+
+	res, err := r.db.ExecContext(ctx, query, userID, step)
+	if err != nil {
+		return fmt.Errorf("repo: use totp step: %w", err)
+	}
+	if aff, err := res.RowsAffected(); err == nil && aff == 0 {
+		return domain.ErrInvalidMFACode
+	}
+	return nil
+}
+
+func (r *MFAPostgresRepo) UseRecoveryCode(ctx context.Context, userID int64, codeHash string) error {
+	query := `
+	UPDATE mfa_recovery_codes SET used_at = now()
+	WHERE user_id = $1 AND code_hash = $2 AND used_at IS NULL`
+
+	if _, ok := ctx.Deadline(); !ok {
+		var cancel context.CancelFunc
+		ctx, cancel = context.WithTimeout(ctx, 5*time.Second)
+		defer cancel()
+	}
+
+	res, err := r.db.ExecContext(ctx, query, userID, codeHash)
+	if err != nil {
+		return fmt.Errorf("repo: use recovery code: %w", err)
+	}
+	if aff, err := res.RowsAffected(); err == nil && aff == 0 {
+		return domain.ErrInvalidMFACode
+	}
+	return nil
+}
+
+func (r *MFAPostgresRepo) DeleteTOTP(ctx context.Context, userID int64) error {
+	if _, ok := ctx.Deadline(); !ok {
+		var cancel context.CancelFunc
+		ctx, cancel = context.WithTimeout(ctx, 5*time.Second)
+		defer cancel()
+	}
+
+	tx, err := r.db.BeginTxx(ctx, nil)
+	if err != nil {
+		return fmt.Errorf("repo: delete totp: %w", err)
+	}
+	defer tx.Rollback()
+
+	if _, err := tx.ExecContext(ctx, `DELETE FROM user_totp WHERE user_id = $1`, userID); err != nil {
+		return fmt.Errorf("repo: delete totp: %w", err)
+	}
+	if err := replaceRecoveryCodes(ctx, tx, userID, nil); err != nil {
+		return err
+	}
+
+	if err := tx.Commit(); err != nil {
+		return fmt.Errorf("repo: delete totp: %w", err)
+	}
+	return nil
+}
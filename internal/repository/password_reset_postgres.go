@@ -0,0 +1,81 @@
+package repository
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/CryptoGu1/books-rest-clean-arch/internal/domain"
+	"github.com/jmoiron/sqlx"
+)
+
+type PasswordResetRepository interface {
+	Create(ctx context.Context, reset domain.PasswordReset) error
+	Consume(ctx context.Context, tokenHash string) (int64, error)
+	// RevokeByUser гасит все неиспользованные токены пользователя
+	RevokeByUser(ctx context.Context, userID int64) error
+}
+
+type PasswordResetPostgresRepo struct {
+	db *sqlx.DB
+}
+
+func NewPasswordResetPostgresRepo(db *sqlx.DB) *PasswordResetPostgresRepo {
+	return &PasswordResetPostgresRepo{db: db}
+}
+
+func (r *PasswordResetPostgresRepo) Create(ctx context.Context, reset domain.PasswordReset) error {
+	query := `INSERT INTO password_resets (user_id, token_hash, expires_at) VALUES ($1, $2, $3)`
+
+	if _, ok := ctx.Deadline(); !ok {
+		var cancel context.CancelFunc
+		ctx, cancel = context.WithTimeout(ctx, 5*time.Second)
+		defer cancel()
+	}
+
+	if _, err := r.db.ExecContext(ctx, query, reset.UserID, reset.TokenHash, reset.ExpiresAt); err != nil {
+		return fmt.Errorf("repo: create password reset: %w", err)
+	}
+	return nil
+}
+
+// Consume помечает токен использованным и возвращает id пользователя. Проверка срока и
+// used_at IS NULL в одном UPDATE делает токен одноразовым даже при параллельных запросах
+func (r *PasswordResetPostgresRepo) Consume(ctx context.Context, tokenHash string) (int64, error) {
+	query := `
+	UPDATE password_resets SET used_at = now()
+	WHERE token_hash = $1 AND used_at IS NULL AND expires_at > now()
+	RETURNING user_id`
+
+	if _, ok := ctx.Deadline(); !ok {
+		var cancel context.CancelFunc
+		ctx, cancel = context.WithTimeout(ctx, 5*time.Second)
+		defer cancel()
+	}
+
+	var userID int64
+	if err := sqlx.GetContext(ctx, conn(ctx, r.db), &userID, query, tokenHash); err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return 0, domain.ErrInvalidResetToken
+		}
+		return 0, fmt.Errorf("repo: consume password reset: %w", err)
+	}
+	return userID, nil
+}
+
+func (r *PasswordResetPostgresRepo) RevokeByUser(ctx context.Context, userID int64) error {
+	query := `UPDATE password_resets SET used_at = now() WHERE user_id = $1 AND used_at IS NULL`
+
+	if _, ok := ctx.Deadline(); !ok {
+		var cancel context.CancelFunc
+		ctx, cancel = context.WithTimeout(ctx, 5*time.Second)
+		defer cancel()
+	}
+
+	if _, err := conn(ctx, r.db).ExecContext(ctx, query, userID); err != nil {
+		return fmt.Errorf("repo: revoke password resets: %w", err)
+	}
+	return nil
+}
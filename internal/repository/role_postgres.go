@@ -0,0 +1,122 @@
+package repository
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/CryptoGu1/books-rest-clean-arch/internal/domain"
+	"github.com/jmoiron/sqlx"
+	"github.com/lib/pq"
+)
+
+type RoleRepository interface {
+	GetUserRoles(ctx context.Context, userID int64) ([]domain.Role, error)
+	AssignRole(ctx context.Context, userID int64, role string) error
+	RevokeRole(ctx context.Context, userID int64, role string) error
+}
+
+type RolePostgresRepo struct {
+	db *sqlx.DB
+}
+
+func NewRolePostgresRepo(db *sqlx.DB) *RolePostgresRepo {
+	return &RolePostgresRepo{db: db}
+}
+
+func (r *RolePostgresRepo) GetUserRoles(ctx context.Context, userID int64) ([]domain.Role, error) {
+	query := `
+	SELECT r.name AS role, COALESCE(p.name, '') AS permission
+	FROM user_roles ur
+	JOIN roles r ON r.id = ur.role_id
+	LEFT JOIN role_permissions rp ON rp.role_id = r.id
+	LEFT JOIN permissions p ON p.id = rp.permission_id
+	WHERE ur.user_id = $1
+	ORDER BY r.name, p.name`
+
+	if _, ok := ctx.Deadline(); !ok {
+		var cancel context.CancelFunc
+		ctx, cancel = context.WithTimeout(ctx, 5*time.Second)
+		defer cancel()
+	}
+
+	var rows []struct {
+		Role       string `db:"role"`
+		Permission string `db:"permission"`
+	}
+	if err := r.db.SelectContext(ctx, &rows, query, userID); err != nil {
+		return nil, fmt.Errorf("repo: get user roles: %w", err)
+	}
+
+	roles := make([]domain.Role, 0)
+	for _, row := range rows {
+		if len(roles) == 0 || roles[len(roles)-1].Name != row.Role {
+			roles = append(roles, domain.Role{Name: row.Role, Permissions: []string{}})
+		}
+		if row.Permission != "" {
+			last := &roles[len(roles)-1]
+			last.Permissions = append(last.Permissions, row.Permission)
+		}
+	}
+	return roles, nil
+}
+
+func (r *RolePostgresRepo) AssignRole(ctx context.Context, userID int64, role string) error {
+	query := `
+	INSERT INTO user_roles (user_id, role_id)
+	SELECT $1, id FROM roles WHERE name = $2
+	ON CONFLICT DO NOTHING`
+
+	if _, ok := ctx.Deadline(); !ok {
+		var cancel context.CancelFunc
+		ctx, cancel = context.WithTimeout(ctx, 5*time.Second)
+		defer cancel()
+	}
+
+	if err := r.ensureRole(ctx, role); err != nil {
+		return err
+	}
+
+	if _, err := conn(ctx, r.db).ExecContext(ctx, query, userID, role); err != nil {
+		var pqErr *pq.Error
+		// 23503 foreign_key_violation — такого пользователя нет
+		if errors.As(err, &pqErr) && pqErr.Code == "23503" {
+			return domain.ErrUserNotFound
+		}
+		return fmt.Errorf("repo: assign role: %w", err)
+	}
+	return nil
+}
+
+func (r *RolePostgresRepo) RevokeRole(ctx context.Context, userID int64, role string) error {
+	query := `
+	DELETE FROM user_roles
+	WHERE user_id = $1 AND role_id = (SELECT id FROM roles WHERE name = $2)`
+
+	if _, ok := ctx.Deadline(); !ok {
+		var cancel context.CancelFunc
+		ctx, cancel = context.WithTimeout(ctx, 5*time.Second)
+		defer cancel()
+	}
+
+	if err := r.ensureRole(ctx, role); err != nil {
+		return err
+	}
+
+	if _, err := r.db.ExecContext(ctx, query, userID, role); err != nil {
+		return fmt.Errorf("repo: revoke role: %w", err)
+	}
+	return nil
+}
+
+func (r *RolePostgresRepo) ensureRole(ctx context.Context, role string) error {
+	var exists bool
+	if err := sqlx.GetContext(ctx, conn(ctx, r.db), &exists, `SELECT EXISTS (SELECT 1 FROM roles WHERE name = $1)`, role); err != nil {
+		return fmt.Errorf("repo: check role: %w", err)
+	}
+	if !exists {
+		return domain.ErrRoleNotFound
+	}
+	return nil
+}
@@ -2,11 +2,16 @@ package repository
 
 import (
 	"context"
+	"database/sql"
+	"errors"
+	"fmt"
 
 	"github.com/CryptoGu1/books-rest-clean-arch/internal/domain"
 	"github.com/jmoiron/sqlx"
 )
 
+const sessionColumns = "id, user_id, token_hash, family_id, user_agent, ip, created_at, last_used_at, expires_at, revoked_at"
+
 type Tokens struct {
 	db *sqlx.DB
 }
@@ -16,16 +21,77 @@ func NewToken(db *sqlx.DB) *Tokens {
 }
 
 func (r *Tokens) Create(ctx context.Context, token domain.RefreshSession) error {
-	_, err := r.db.ExecContext(ctx,
-		`INSERT INTO refresh_tokens (user_id, token, expires_at)
-		 VALUES ($1, $2, $3)`,
-		token.UserID, token.Token, token.ExpiresAt)
+	_, err := conn(ctx, r.db).ExecContext(ctx,
+		`INSERT INTO refresh_tokens (user_id, token_hash, family_id, user_agent, ip, created_at, last_used_at, expires_at)
+		 VALUES ($1, $2, $3, $4, $5, $6, $7, $8)`,
+		token.UserID, token.TokenHash, token.FamilyID, token.UserAgent, token.IP, token.CreatedAt, token.LastUsedAt, token.ExpiresAt)
 	return err
 }
 
-func (r *Tokens) Get(ctx context.Context, token string) (domain.RefreshSession, error) {
+// Get ищет сессию по хешу токена (domain.HashToken); сырой токен в базу не попадает
+func (r *Tokens) Get(ctx context.Context, tokenHash string) (domain.RefreshSession, error) {
 	var t domain.RefreshSession
-	query := `select user_id, token, expires_at from refresh_tokens where token = $1`
-	err := r.db.GetContext(ctx, &t, query, token)
+	query := `select ` + sessionColumns + ` from refresh_tokens where token_hash = $1`
+	err := r.db.GetContext(ctx, &t, query, tokenHash)
+	if errors.Is(err, sql.ErrNoRows) {
+		return t, domain.ErrRefreshTokenNotFound
+	}
 	return t, err
 }
+
+// ListActive возвращает действующие токены пользователя — по одному на семейство (сессию)
+func (r *Tokens) ListActive(ctx context.Context, userID int64) ([]domain.RefreshSession, error) {
+	sessions := make([]domain.RefreshSession, 0)
+	query := `select ` + sessionColumns + ` from refresh_tokens
+		where user_id = $1 and revoked_at is null and expires_at > now()
+		order by last_used_at desc`
+	if err := r.db.SelectContext(ctx, &sessions, query, userID); err != nil {
+		return nil, fmt.Errorf("repo: list sessions: %w", err)
+	}
+	return sessions, nil
+}
+
+// Revoke помечает токен использованным. Условие revoked_at IS NULL делает операцию атомарной:
+// если два запроса ротируют один и тот же токен, второй получит ErrRefreshTokenReused
+func (r *Tokens) Revoke(ctx context.Context, id int64) error {
+	res, err := conn(ctx, r.db).ExecContext(ctx,
+		`UPDATE refresh_tokens SET revoked_at = now() WHERE id = $1 AND revoked_at IS NULL`, id)
+	if err != nil {
+		return fmt.Errorf("repo: revoke refresh token: %w", err)
+	}
+	aff, err := res.RowsAffected()
+	if err != nil {
+		return fmt.Errorf("repo: revoke rows affected: %w", err)
+	}
+	if aff == 0 {
+		return domain.ErrRefreshTokenReused
+	}
+	return nil
+}
+
+func (r *Tokens) Delete(ctx context.Context, tokenHash string) error {
+	_, err := r.db.ExecContext(ctx, `DELETE FROM refresh_tokens WHERE token_hash = $1`, tokenHash)
+	if err != nil {
+		return fmt.Errorf("repo: delete refresh token: %w", err)
+	}
+	return nil
+}
+
+func (r *Tokens) DeleteByUser(ctx context.Context, userID int64) error {
+	_, err := conn(ctx, r.db).ExecContext(ctx, `DELETE FROM refresh_tokens WHERE user_id = $1`, userID)
+	if err != nil {
+		return fmt.Errorf("repo: delete user refresh tokens: %w", err)
+	}
+	return nil
+}
+
+func (r *Tokens) RevokeFamily(ctx context.Context, userID int64, familyID string) error {
+	_, err := r.db.ExecContext(ctx,
+		`UPDATE refresh_tokens SET revoked_at = now()
+		 WHERE user_id = $1 AND family_id = $2 AND revoked_at IS NULL`,
+		userID, familyID)
+	if err != nil {
+		return fmt.Errorf("repo: revoke token family: %w", err)
+	}
+	return nil
+}
@@ -2,16 +2,28 @@ package repository
 
 import (
 	"context"
+	"database/sql"
+	"errors"
 	"fmt"
 	"time"
 
 	"github.com/CryptoGu1/books-rest-clean-arch/internal/domain"
 	"github.com/jmoiron/sqlx"
+	"github.com/lib/pq"
 )
 
+const userColumns = "id, name, email, password_hash, registered_at, email_verified_at"
+
 type UserRepository interface {
 	CreateUser(ctx context.Context, input domain.User) (int, error)
 	GetByCredentials(ctx context.Context, email string) (domain.User, error)
+	GetByID(ctx context.Context, id int64) (domain.User, error)
+	UpdatePassword(ctx context.Context, userID int64, passwordHash string) error
+	MarkEmailVerified(ctx context.Context, userID int64) error
+	UpdateName(ctx context.Context, userID int64, name string) (domain.User, error)
+	// ChangeEmail меняет адрес, только если текущий всё ещё oldEmail; новый считается подтверждённым
+	ChangeEmail(ctx context.Context, userID int64, oldEmail, newEmail string) error
+	DeleteUser(ctx context.Context, userID int64) error
 }
 type UserPostgresRepo struct {
 	db *sqlx.DB
@@ -31,7 +43,7 @@ func (r *UserPostgresRepo) CreateUser(ctx context.Context, input domain.User) (i
 		defer cancel()
 	}
 
-	err := r.db.QueryRowxContext(ctx, query, input.Name, input.Email, input.Password, input.RegisteredAt).Scan(&id)
+	err := conn(ctx, r.db).QueryRowxContext(ctx, query, input.Name, input.Email, input.Password, input.RegisteredAt).Scan(&id)
 	if err != nil {
 		return 0, fmt.Errorf("repo:error creating user: %w", err)
 	}
@@ -40,7 +52,7 @@ func (r *UserPostgresRepo) CreateUser(ctx context.Context, input domain.User) (i
 
 func (r *UserPostgresRepo) GetByCredentials(ctx context.Context, email string) (domain.User, error) {
 	var user domain.User
-	query := `select * from users where email = $1 `
+	query := `select ` + userColumns + ` from users where email = $1 `
 
 	if _, ok := ctx.Deadline(); !ok {
 		var cancel context.CancelFunc
@@ -48,10 +60,139 @@ func (r *UserPostgresRepo) GetByCredentials(ctx context.Context, email string) (
 		defer cancel()
 	}
 
-	err := r.db.QueryRowxContext(ctx, query, email).Scan(&user.ID, &user.Name, &user.Email, &user.Password, &user.RegisteredAt)
+	err := r.db.QueryRowxContext(ctx, query, email).Scan(&user.ID, &user.Name, &user.Email, &user.Password, &user.RegisteredAt, &user.EmailVerifiedAt)
 	if err != nil {
 		return domain.User{}, fmt.Errorf("repo:error getting user by credentials: %w", err)
 	}
 
 	return user, err
 }
+
+func (r *UserPostgresRepo) GetByID(ctx context.Context, id int64) (domain.User, error) {
+	var user domain.User
+	query := `select ` + userColumns + ` from users where id = $1`
+
+	if _, ok := ctx.Deadline(); !ok {
+		var cancel context.CancelFunc
+		ctx, cancel = context.WithTimeout(ctx, 5*time.Second)
+		defer cancel()
+	}
+
+	err := r.db.QueryRowxContext(ctx, query, id).Scan(&user.ID, &user.Name, &user.Email, &user.Password, &user.RegisteredAt, &user.EmailVerifiedAt)
+	if errors.Is(err, sql.ErrNoRows) {
+		return domain.User{}, domain.ErrUserNotFound
+	}
+	if err != nil {
+		return domain.User{}, fmt.Errorf("repo:error getting user by id: %w", err)
+	}
+	return user, nil
+}
+
+func (r *UserPostgresRepo) UpdatePassword(ctx context.Context, userID int64, passwordHash string) error {
+	query := `update users set password_hash = $1 where id = $2`
+
+	if _, ok := ctx.Deadline(); !ok {
+		var cancel context.CancelFunc
+		ctx, cancel = context.WithTimeout(ctx, 5*time.Second)
+		defer cancel()
+	}
+
+	res, err := conn(ctx, r.db).ExecContext(ctx, query, passwordHash, userID)
+	if err != nil {
+		return fmt.Errorf("repo:error updating password: %w", err)
+	}
+	if aff, err := res.RowsAffected(); err == nil && aff == 0 {
+		return domain.ErrUserNotFound
+	}
+	return nil
+}
+
+// MarkEmailVerified подтверждает адрес; повторное подтверждение не меняет исходную дату
+func (r *UserPostgresRepo) MarkEmailVerified(ctx context.Context, userID int64) error {
+	query := `update users set email_verified_at = COALESCE(email_verified_at, now()) where id = $1`
+
+	if _, ok := ctx.Deadline(); !ok {
+		var cancel context.CancelFunc
+		ctx, cancel = context.WithTimeout(ctx, 5*time.Second)
+		defer cancel()
+	}
+
+	res, err := conn(ctx, r.db).ExecContext(ctx, query, userID)
+	if err != nil {
+		return fmt.Errorf("repo:error verifying email: %w", err)
+	}
+	if aff, err := res.RowsAffected(); err == nil && aff == 0 {
+		return domain.ErrUserNotFound
+	}
+	return nil
+}
+
+func (r *UserPostgresRepo) UpdateName(ctx context.Context, userID int64, name string) (domain.User, error) {
+	query := `update users set name = $2 where id = $1 returning ` + userColumns
+
+	if _, ok := ctx.Deadline(); !ok {
+		var cancel context.CancelFunc
+		ctx, cancel = context.WithTimeout(ctx, 5*time.Second)
+		defer cancel()
+	}
+
+	var user domain.User
+	err := r.db.QueryRowxContext(ctx, query, userID, name).
+		Scan(&user.ID, &user.Name, &user.Email, &user.Password, &user.RegisteredAt, &user.EmailVerifiedAt)
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return domain.User{}, domain.ErrUserNotFound
+		}
+		return domain.User{}, fmt.Errorf("repo:error updating name: %w", err)
+	}
+	return user, nil
+}
+
+func (r *UserPostgresRepo) ChangeEmail(ctx context.Context, userID int64, oldEmail, newEmail string) error {
+	query := `update users set email = $3, email_verified_at = now() where id = $1 and email = $2`
+
+	if _, ok := ctx.Deadline(); !ok {
+		var cancel context.CancelFunc
+		ctx, cancel = context.WithTimeout(ctx, 5*time.Second)
+		defer cancel()
+	}
+
+	res, err := r.db.ExecContext(ctx, query, userID, oldEmail, newEmail)
+	if err != nil {
+		var pqErr *pq.Error
+		// 23505 unique_violation — адрес занят другим пользователем
+		if errors.As(err, &pqErr) && pqErr.Code == "23505" {
+			return domain.ErrEmailTaken
+		}
+		return fmt.Errorf("repo:error changing email: %w", err)
+	}
+	if aff, err := res.RowsAffected(); err == nil && aff == 0 {
+		return domain.ErrUserNotFound
+	}
+	return nil
+}
+
+// DeleteUser удаляет пользователя; его книги остаются, но теряют владельца. Два запроса:
+// вызывать внутри Transactor.WithinTx вместе с удалением сессий
+func (r *UserPostgresRepo) DeleteUser(ctx context.Context, userID int64) error {
+	if _, ok := ctx.Deadline(); !ok {
+		var cancel context.CancelFunc
+		ctx, cancel = context.WithTimeout(ctx, 5*time.Second)
+		defer cancel()
+	}
+
+	db := conn(ctx, r.db)
+	// внешний ключ и так делает SET NULL, но явный запрос не зависит от схемы
+	if _, err := db.ExecContext(ctx, `update books set created_by = NULL where created_by = $1`, userID); err != nil {
+		return fmt.Errorf("repo:error anonymizing books: %w", err)
+	}
+
+	res, err := db.ExecContext(ctx, `delete from users where id = $1`, userID)
+	if err != nil {
+		return fmt.Errorf("repo:error deleting user: %w", err)
+	}
+	if aff, err := res.RowsAffected(); err == nil && aff == 0 {
+		return domain.ErrUserNotFound
+	}
+	return nil
+}
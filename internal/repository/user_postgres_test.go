@@ -0,0 +1,81 @@
+package repository
+
+import (
+	"context"
+	"errors"
+	"regexp"
+	"testing"
+
+	"github.com/CryptoGu1/books-rest-clean-arch/internal/domain"
+	"github.com/DATA-DOG/go-sqlmock"
+	"github.com/jmoiron/sqlx"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestUserPostgresRepo_DeleteUser(t *testing.T) {
+	testTable := []struct {
+		name          string
+		mockBehavior  func(mock sqlmock.Sqlmock)
+		expectedError error
+		expectFail    bool
+	}{
+		{
+			name: "ok",
+			mockBehavior: func(mock sqlmock.Sqlmock) {
+				mock.ExpectBegin()
+				mock.ExpectExec(regexp.QuoteMeta(`update books set created_by = NULL where created_by = $1`)).
+					WithArgs(7).WillReturnResult(sqlmock.NewResult(0, 3))
+				mock.ExpectExec(regexp.QuoteMeta(`delete from users where id = $1`)).
+					WithArgs(7).WillReturnResult(sqlmock.NewResult(0, 1))
+				mock.ExpectCommit()
+			},
+		},
+		{
+			name: "unknown user",
+			mockBehavior: func(mock sqlmock.Sqlmock) {
+				mock.ExpectBegin()
+				mock.ExpectExec(regexp.QuoteMeta(`update books`)).WillReturnResult(sqlmock.NewResult(0, 0))
+				mock.ExpectExec(regexp.QuoteMeta(`delete from users`)).WillReturnResult(sqlmock.NewResult(0, 0))
+				mock.ExpectRollback()
+			},
+			expectedError: domain.ErrUserNotFound,
+		},
+		{
+			name: "rollback on failure",
+			mockBehavior: func(mock sqlmock.Sqlmock) {
+				mock.ExpectBegin()
+				mock.ExpectExec(regexp.QuoteMeta(`update books`)).WillReturnError(errors.New("db down"))
+				mock.ExpectRollback()
+			},
+			expectFail: true,
+		},
+	}
+
+	for _, testCase := range testTable {
+		t.Run(testCase.name, func(t *testing.T) {
+			db, mock, err := sqlmock.New(sqlmock.QueryMatcherOption(sqlmock.QueryMatcherRegexp))
+			require.NoError(t, err)
+			defer db.Close()
+
+			sqlxDB := sqlx.NewDb(db, "sqlmock")
+			repo := NewUserPostgresRepo(sqlxDB)
+			testCase.mockBehavior(mock)
+
+			// запросы идут в транзакцию вызывающего, своей репозиторий не открывает
+			err = NewTransactor(sqlxDB).WithinTx(context.Background(), func(ctx context.Context) error {
+				return repo.DeleteUser(ctx, 7)
+			})
+
+			switch {
+			case testCase.expectedError != nil:
+				assert.ErrorIs(t, err, testCase.expectedError)
+			case testCase.expectFail:
+				assert.Error(t, err)
+			default:
+				assert.NoError(t, err)
+			}
+			assert.NoError(t, mock.ExpectationsWereMet())
+		})
+	}
+}
@@ -0,0 +1,107 @@
+package service
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"time"
+
+	audit "github.com/CryptoGu1/books-grpc-log/pkg/domain"
+	"github.com/CryptoGu1/books-rest-clean-arch/internal/domain"
+	"github.com/sirupsen/logrus"
+)
+
+// CreateAPIKey выпускает ключ от имени principal. Ключу достаются только те из запрошенных прав,
+// что есть у самого пользователя в момент создания
+func (s *AuthService) CreateAPIKey(ctx context.Context, principal domain.Principal, input domain.CreateAPIKeyInput) (domain.CreatedAPIKey, error) {
+	scopes := make([]string, 0, len(input.Scopes))
+	for _, scope := range input.Scopes {
+		if !principal.HasPermission(scope) {
+			return domain.CreatedAPIKey{}, fmt.Errorf("service: scope %q: %w", scope, domain.ErrForbidden)
+		}
+		scopes = append(scopes, scope)
+	}
+
+	secret, err := s.NewRefreshToken()
+	if err != nil {
+		return domain.CreatedAPIKey{}, fmt.Errorf("service: api key: %w", err)
+	}
+	raw := domain.APIKeyPrefix + secret
+
+	key, err := s.apiKeyRepo.Create(ctx, domain.APIKey{
+		UserID:    principal.UserID,
+		Name:      input.Name,
+		Prefix:    domain.APIKeyDisplayPrefix(raw),
+		KeyHash:   domain.HashToken(raw),
+		Scopes:    scopes,
+		ExpiresAt: input.ExpiresAt,
+	})
+	if err != nil {
+		return domain.CreatedAPIKey{}, fmt.Errorf("service: create api key: %w", err)
+	}
+
+	s.auditUser(ctx, "CreateAPIKey", audit.ACTION_UPDATE, principal.UserID)
+	return domain.CreatedAPIKey{APIKey: key, Key: raw}, nil
+}
+
+func (s *AuthService) ListAPIKeys(ctx context.Context, userID int64) ([]domain.APIKey, error) {
+	keys, err := s.apiKeyRepo.ListByUser(ctx, userID)
+	if err != nil {
+		return nil, fmt.Errorf("service: list api keys: %w", err)
+	}
+	return keys, nil
+}
+
+func (s *AuthService) RevokeAPIKey(ctx context.Context, userID, keyID int64) error {
+	if err := s.apiKeyRepo.Revoke(ctx, userID, keyID); err != nil {
+		return fmt.Errorf("service: revoke api key: %w", err)
+	}
+
+	s.auditUser(ctx, "RevokeAPIKey", audit.ACTION_UPDATE, userID)
+	return nil
+}
+
+// AuthenticateAPIKey проверяет ключ и строит Principal. Права — пересечение scopes ключа
+// с текущими правами владельца: отзыв роли сразу урезает и его ключи. Роли владельца ключу
+// не передаются — иначе ключ с узким scope у администратора правил бы чужие книги
+func (s *AuthService) AuthenticateAPIKey(ctx context.Context, rawKey string) (domain.Principal, error) {
+	key, err := s.apiKeyRepo.GetByHash(ctx, domain.HashToken(rawKey))
+	if err != nil {
+		if errors.Is(err, domain.ErrAPIKeyNotFound) {
+			return domain.Principal{}, domain.ErrInvalidAPIKey
+		}
+		return domain.Principal{}, fmt.Errorf("service: authenticate api key: %w", err)
+	}
+
+	now := time.Now()
+	if !key.Active(now) {
+		return domain.Principal{}, domain.ErrInvalidAPIKey
+	}
+
+	roles, err := s.roleRepo.GetUserRoles(ctx, key.UserID)
+	if err != nil {
+		return domain.Principal{}, fmt.Errorf("service: get user roles: %w", err)
+	}
+	owner := domain.Principal{Permissions: domain.PermissionNames(roles)}
+
+	permissions := make([]string, 0, len(key.Scopes))
+	for _, scope := range key.Scopes {
+		if owner.HasPermission(scope) {
+			permissions = append(permissions, scope)
+		}
+	}
+
+	if key.LastUsedAt == nil || now.Sub(*key.LastUsedAt) > domain.APIKeyTouchInterval {
+		if err := s.apiKeyRepo.Touch(ctx, key.ID, now); err != nil {
+			logrus.WithFields(logrus.Fields{
+				"method": "AuthenticateAPIKey",
+			}).Error("failed to update api key usage", err)
+		}
+	}
+
+	return domain.Principal{
+		UserID:      key.UserID,
+		Permissions: permissions,
+		APIKeyID:    key.ID,
+	}, nil
+}
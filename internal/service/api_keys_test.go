@@ -0,0 +1,118 @@
+package service
+
+import (
+	"context"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/CryptoGu1/books-rest-clean-arch/internal/domain"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+	"github.com/stretchr/testify/require"
+)
+
+func TestAuthService_CreateAPIKey(t *testing.T) {
+	librarian := domain.Principal{UserID: 7, Permissions: []string{domain.PermBooksWrite}}
+
+	t.Run("stores only the hash", func(t *testing.T) {
+		s, m := newTestAuthService(t)
+		var stored domain.APIKey
+		m.apiKeys.On("Create", mock.Anything, mock.MatchedBy(func(k domain.APIKey) bool {
+			stored = k
+			return true
+		})).Return(func(_ context.Context, k domain.APIKey) domain.APIKey { return k }, nil)
+		m.audit.On("SendLogRequest", mock.Anything, mock.Anything).Return(nil)
+
+		created, err := s.CreateAPIKey(context.Background(), librarian, domain.CreateAPIKeyInput{
+			Name:   "ci",
+			Scopes: []string{domain.PermBooksWrite},
+		})
+
+		require.NoError(t, err)
+		assert.True(t, strings.HasPrefix(created.Key, domain.APIKeyPrefix))
+		assert.Equal(t, domain.HashToken(created.Key), stored.KeyHash)
+		assert.True(t, strings.HasPrefix(created.Key, stored.Prefix))
+	})
+
+	t.Run("scope the user does not have", func(t *testing.T) {
+		s, _ := newTestAuthService(t)
+
+		_, err := s.CreateAPIKey(context.Background(), librarian, domain.CreateAPIKeyInput{
+			Name:   "ci",
+			Scopes: []string{domain.PermRolesManage},
+		})
+		assert.ErrorIs(t, err, domain.ErrForbidden)
+	})
+}
+
+func TestAuthService_AuthenticateAPIKey(t *testing.T) {
+	past := time.Now().Add(-time.Hour)
+	active := domain.APIKey{
+		ID:         3,
+		UserID:     7,
+		Scopes:     []string{domain.PermBooksWrite, domain.PermRolesManage},
+		LastUsedAt: &past,
+	}
+
+	testTable := []struct {
+		name          string
+		key           func() domain.APIKey
+		roles         []domain.Role
+		expectedPerms []string
+		expectedError error
+	}{
+		{
+			name:          "scopes limited by current roles",
+			key:           func() domain.APIKey { return active },
+			roles:         []domain.Role{{Name: domain.RoleLibrarian, Permissions: []string{domain.PermBooksWrite}}},
+			expectedPerms: []string{domain.PermBooksWrite},
+		},
+		{
+			name:          "admin roles are not inherited",
+			key:           func() domain.APIKey { return active },
+			roles:         []domain.Role{{Name: domain.RoleAdmin, Permissions: []string{domain.PermBooksWrite, domain.PermRolesManage}}},
+			expectedPerms: []string{domain.PermBooksWrite, domain.PermRolesManage},
+		},
+		{
+			name: "revoked",
+			key: func() domain.APIKey {
+				k := active
+				k.RevokedAt = &past
+				return k
+			},
+			expectedError: domain.ErrInvalidAPIKey,
+		},
+		{
+			name: "expired",
+			key: func() domain.APIKey {
+				k := active
+				k.ExpiresAt = &past
+				return k
+			},
+			expectedError: domain.ErrInvalidAPIKey,
+		},
+	}
+
+	for _, testCase := range testTable {
+		t.Run(testCase.name, func(t *testing.T) {
+			s, m := newTestAuthService(t)
+			m.apiKeys.On("GetByHash", mock.Anything, domain.HashToken("bk_key")).Return(testCase.key(), nil)
+			if testCase.expectedError == nil {
+				m.roles.On("GetUserRoles", mock.Anything, int64(7)).Return(testCase.roles, nil)
+				m.apiKeys.On("Touch", mock.Anything, int64(3), mock.Anything).Return(nil)
+			}
+
+			principal, err := s.AuthenticateAPIKey(context.Background(), "bk_key")
+			if testCase.expectedError != nil {
+				assert.ErrorIs(t, err, testCase.expectedError)
+				return
+			}
+			require.NoError(t, err)
+			assert.Equal(t, int64(7), principal.UserID)
+			assert.Equal(t, int64(3), principal.APIKeyID)
+			assert.Equal(t, testCase.expectedPerms, principal.Permissions)
+			assert.Empty(t, principal.Roles)
+		})
+	}
+}
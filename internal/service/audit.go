@@ -0,0 +1,49 @@
+package service
+
+import (
+	"context"
+	"time"
+
+	audit "github.com/CryptoGu1/books-grpc-log/pkg/domain"
+	"github.com/CryptoGu1/books-rest-clean-arch/internal/domain"
+	"github.com/CryptoGu1/books-rest-clean-arch/pkg/log_grpc"
+	"google.golang.org/grpc/metadata"
+)
+
+// requestAudit прикладывает к каждому событию актора и сведения о запросе из ctx,
+// чтобы не передавать их в каждом месте, где пишется аудит
+type requestAudit struct {
+	next AuditClient
+}
+
+func (a requestAudit) SendLogRequest(ctx context.Context, req audit.LogItem) error {
+	return a.next.SendLogRequest(withRequestDetails(ctx), req)
+}
+
+// withRequestDetails: актор — аутентифицированный пользователь; при входе и регистрации его ещё нет
+func withRequestDetails(ctx context.Context) context.Context {
+	info := domain.ClientInfoFromContext(ctx)
+	details := log_grpc.RequestDetails{
+		RequestID: info.RequestID,
+		ClientIP:  info.IP,
+		UserAgent: info.UserAgent,
+	}
+	if principal, ok := domain.PrincipalFromContext(ctx); ok {
+		details.ActorID = principal.UserID
+	}
+	return log_grpc.WithRequestDetails(ctx, details)
+}
+
+// newAuditEvent собирает событие для outbox; metadata запроса (актор, изменённые поля) сохраняется вместе с ним
+func newAuditEvent(ctx context.Context, entity, action string, entityID int64) domain.AuditEvent {
+	event := domain.AuditEvent{
+		Entity:     entity,
+		Action:     action,
+		EntityID:   entityID,
+		OccurredAt: time.Now(),
+	}
+	if md, ok := metadata.FromOutgoingContext(withRequestDetails(ctx)); ok {
+		event.Metadata = md.Copy()
+	}
+	return event
+}
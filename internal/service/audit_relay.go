@@ -0,0 +1,169 @@
+package service
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"time"
+
+	audit "github.com/CryptoGu1/books-grpc-log/pkg/domain"
+	"github.com/CryptoGu1/books-rest-clean-arch/internal/domain"
+	"github.com/CryptoGu1/books-rest-clean-arch/internal/repository"
+	"github.com/CryptoGu1/books-rest-clean-arch/pkg/auditq"
+	"github.com/CryptoGu1/books-rest-clean-arch/pkg/log_grpc"
+	"github.com/sirupsen/logrus"
+	"google.golang.org/grpc/metadata"
+)
+
+type AuditRelayConfig struct {
+	BatchSize    int
+	PollInterval time.Duration
+	BaseBackoff  time.Duration
+	MaxBackoff   time.Duration
+	SendTimeout  time.Duration
+	// Lease — на сколько пачка закрепляется за relay. Должна перекрывать отправку всей пачки,
+	// иначе другая реплика заберёт события повторно
+	Lease time.Duration
+	// Retention — сколько хранить доставленные события
+	Retention time.Duration
+}
+
+// AuditRelay пересылает события из audit_outbox на сервер логов. Пачка забирается в аренду
+// короткой командой, отправляется вне транзакции, а результат пишется второй короткой транзакцией.
+// Доставка at-least-once: если процесс упадёт после отправки, но до записи результата, событие
+// уйдёт повторно после истечения аренды с тем же ключом идемпотентности
+type AuditRelay struct {
+	outbox repository.AuditOutboxRepository
+	tx     repository.Transactor
+	client AuditClient
+	cfg    AuditRelayConfig
+}
+
+func NewAuditRelay(outbox repository.AuditOutboxRepository, tx repository.Transactor, client AuditClient, cfg AuditRelayConfig) *AuditRelay {
+	if cfg.BatchSize <= 0 {
+		cfg.BatchSize = 100
+	}
+	if cfg.PollInterval <= 0 {
+		cfg.PollInterval = time.Second
+	}
+	if cfg.BaseBackoff <= 0 {
+		cfg.BaseBackoff = time.Second
+	}
+	if cfg.MaxBackoff <= 0 {
+		cfg.MaxBackoff = 5 * time.Minute
+	}
+	if cfg.SendTimeout <= 0 {
+		cfg.SendTimeout = 5 * time.Second
+	}
+	if cfg.Lease <= 0 {
+		cfg.Lease = time.Duration(cfg.BatchSize)*cfg.SendTimeout + time.Minute
+	}
+	if cfg.Retention <= 0 {
+		cfg.Retention = 7 * 24 * time.Hour
+	}
+	return &AuditRelay{outbox: outbox, tx: tx, client: client, cfg: cfg}
+}
+
+// Run пересылает события до отмены ctx
+func (r *AuditRelay) Run(ctx context.Context) {
+	poll := time.NewTicker(r.cfg.PollInterval)
+	defer poll.Stop()
+	purge := time.NewTicker(time.Hour)
+	defer purge.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-poll.C:
+			// полная пачка — в outbox, скорее всего, есть ещё: не ждём следующего тика
+			for {
+				n, err := r.RelayOnce(ctx)
+				if err != nil {
+					if !errors.Is(err, context.Canceled) {
+						logrus.WithField("method", "AuditRelay").Error("failed to relay audit events", err)
+					}
+					break
+				}
+				if n < r.cfg.BatchSize {
+					break
+				}
+			}
+		case <-purge.C:
+			if _, err := r.outbox.PurgeDelivered(ctx, time.Now().Add(-r.cfg.Retention)); err != nil {
+				logrus.WithField("method", "AuditRelay").Error("failed to purge audit outbox", err)
+			}
+		}
+	}
+}
+
+// relayResult — итог отправки одного события
+type relayResult struct {
+	event domain.AuditEvent
+	err   error
+}
+
+// RelayOnce отправляет одну пачку событий и возвращает, сколько из них было взято в работу
+func (r *AuditRelay) RelayOnce(ctx context.Context) (int, error) {
+	events, err := r.outbox.ClaimPending(ctx, r.cfg.BatchSize, time.Now().Add(r.cfg.Lease))
+	if err != nil {
+		return 0, fmt.Errorf("service: relay audit events: %w", err)
+	}
+
+	// отправка — без транзакции: медленный сервер логов не держит соединение с базой
+	results := make([]relayResult, 0, len(events))
+	for _, event := range events {
+		if ctx.Err() != nil {
+			// неотправленные вернутся в работу, когда истечёт аренда
+			break
+		}
+		results = append(results, relayResult{event: event, err: r.send(ctx, event)})
+	}
+
+	// результат записываем и при остановке, иначе доставленное уйдёт повторно
+	markCtx := context.WithoutCancel(ctx)
+	err = r.tx.WithinTx(markCtx, func(ctx context.Context) error {
+		for _, res := range results {
+			if res.err == nil {
+				if err := r.outbox.MarkDelivered(ctx, res.event.ID); err != nil {
+					return err
+				}
+				continue
+			}
+
+			event := res.event
+			next := time.Now().Add(auditq.Backoff(r.cfg.BaseBackoff, r.cfg.MaxBackoff, event.Attempts+1))
+			logrus.WithFields(logrus.Fields{
+				"method":   "AuditRelay",
+				"event_id": event.ID,
+				"attempts": event.Attempts + 1,
+			}).Warn("audit event not delivered, will retry: ", res.err)
+
+			if err := r.outbox.MarkFailed(ctx, event.ID, next, res.err.Error()); err != nil {
+				return err
+			}
+		}
+		return nil
+	})
+	if err != nil {
+		return 0, fmt.Errorf("service: relay audit events: %w", err)
+	}
+	return len(events), nil
+}
+
+func (r *AuditRelay) send(ctx context.Context, event domain.AuditEvent) error {
+	ctx, cancel := context.WithTimeout(ctx, r.cfg.SendTimeout)
+	defer cancel()
+
+	if len(event.Metadata) > 0 {
+		ctx = metadata.NewOutgoingContext(ctx, metadata.MD(event.Metadata))
+	}
+	ctx = log_grpc.WithEventID(ctx, event.ID)
+
+	return r.client.SendLogRequest(ctx, audit.LogItem{
+		Entity:    event.Entity,
+		Action:    event.Action,
+		EntityID:  event.EntityID,
+		Timestamp: event.OccurredAt,
+	})
+}
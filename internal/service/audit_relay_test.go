@@ -0,0 +1,75 @@
+package service
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	audit "github.com/CryptoGu1/books-grpc-log/pkg/domain"
+	"github.com/CryptoGu1/books-rest-clean-arch/internal/domain"
+	"github.com/CryptoGu1/books-rest-clean-arch/mocks"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+	"github.com/stretchr/testify/require"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/metadata"
+	"google.golang.org/grpc/status"
+)
+
+func TestAuditRelay_RelayOnce(t *testing.T) {
+	outbox := mocks.NewAuditOutboxRepository(t)
+	client := mocks.NewAuditClient(t)
+	relay := NewAuditRelay(outbox, inTx(t), client, AuditRelayConfig{BatchSize: 10, BaseBackoff: time.Minute, Lease: time.Hour})
+
+	delivered := domain.AuditEvent{ID: "e1", Entity: audit.ENTITY_BOOK, Action: audit.ACTION_CREATE, EntityID: 1}
+	failed := domain.AuditEvent{ID: "e2", Entity: audit.ENTITY_BOOK, Action: audit.ACTION_DELETE, EntityID: 2, Attempts: 1}
+	outbox.On("ClaimPending", mock.Anything, 10, mock.MatchedBy(func(lease time.Time) bool {
+		return lease.After(time.Now().Add(59 * time.Minute))
+	})).Return([]domain.AuditEvent{delivered, failed}, nil)
+
+	// ключ идемпотентности уходит на сервер логов вместе с событием
+	client.On("SendLogRequest", mock.MatchedBy(func(ctx context.Context) bool {
+		md, _ := metadata.FromOutgoingContext(ctx)
+		return assert.ObjectsAreEqual([]string{"e1"}, md.Get("x-audit-event-id"))
+	}), mock.MatchedBy(func(item audit.LogItem) bool { return item.EntityID == 1 })).Return(nil)
+	client.On("SendLogRequest", mock.Anything, mock.MatchedBy(func(item audit.LogItem) bool { return item.EntityID == 2 })).
+		Return(status.Error(codes.Unavailable, "connection refused"))
+
+	outbox.On("MarkDelivered", mock.Anything, "e1").Return(nil)
+	// вторая неудачная попытка — задержка удваивается
+	outbox.On("MarkFailed", mock.Anything, "e2", mock.MatchedBy(func(next time.Time) bool {
+		return next.After(time.Now().Add(time.Minute)) && next.Before(time.Now().Add(2*time.Minute+time.Second))
+	}), mock.Anything).Return(nil)
+
+	n, err := relay.RelayOnce(context.Background())
+	require.NoError(t, err)
+	assert.Equal(t, 2, n)
+}
+
+func TestAuditRelay_RelayOnce_SendsOutsideTx(t *testing.T) {
+	outbox := mocks.NewAuditOutboxRepository(t)
+	client := mocks.NewAuditClient(t)
+
+	// сервер логов не должен вызываться, пока открыта транзакция
+	inside := false
+	tx := mocks.NewTransactor(t)
+	tx.On("WithinTx", mock.Anything, mock.Anything).Return(func(ctx context.Context, fn func(context.Context) error) error {
+		inside = true
+		defer func() { inside = false }()
+		return fn(ctx)
+	})
+	relay := NewAuditRelay(outbox, tx, client, AuditRelayConfig{BatchSize: 10})
+
+	outbox.On("ClaimPending", mock.Anything, 10, mock.Anything).
+		Return([]domain.AuditEvent{{ID: "e1", Entity: audit.ENTITY_BOOK, Action: audit.ACTION_CREATE, EntityID: 1}}, nil)
+	client.On("SendLogRequest", mock.Anything, mock.Anything).Run(func(mock.Arguments) {
+		assert.False(t, inside)
+	}).Return(nil)
+	outbox.On("MarkDelivered", mock.Anything, "e1").Run(func(mock.Arguments) {
+		assert.True(t, inside)
+	}).Return(nil)
+
+	n, err := relay.RelayOnce(context.Background())
+	require.NoError(t, err)
+	assert.Equal(t, 1, n)
+}
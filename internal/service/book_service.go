@@ -2,59 +2,82 @@ package service
 
 import (
 	"context"
+	"encoding/json"
 	"fmt"
 	"time"
 
 	audit "github.com/CryptoGu1/books-grpc-log/pkg/domain"
 	"github.com/CryptoGu1/books-rest-clean-arch/internal/domain"
 	"github.com/CryptoGu1/books-rest-clean-arch/internal/repository"
+	"github.com/CryptoGu1/books-rest-clean-arch/pkg/cursor"
+	"github.com/CryptoGu1/books-rest-clean-arch/pkg/log_grpc"
 	"github.com/sirupsen/logrus"
 )
 
+// BookService: изменения книг пишут аудит в audit_outbox в той же транзакции (его досылает AuditRelay),
+// чтения отправляются напрямую через audit
 type BookService struct {
-	repo  repository.BookRepository
-	audit AuditClient
+	repo   repository.BookRepository
+	outbox repository.AuditOutboxRepository
+	tx     repository.Transactor
+	audit  AuditClient
+	cursor *cursor.Codec
 }
 
-func NewBookService(repo repository.BookRepository, audit AuditClient) *BookService {
+func NewBookService(repo repository.BookRepository, outbox repository.AuditOutboxRepository, tx repository.Transactor,
+	audit AuditClient, cursorSecret []byte) *BookService {
 	return &BookService{repo,
-		audit}
+		outbox,
+		tx,
+		requestAudit{audit},
+		cursor.NewCodec(cursorSecret)}
+}
+
+// bookCursor — содержимое непрозрачного курсора; сортировка и отпечаток фильтров
+// (BookQuery.FilterKey) зашиты в курсор, чтобы клиент не мог сменить их посреди обхода
+type bookCursor struct {
+	Sort    string          `json:"s"`
+	Filters string          `json:"f"`
+	Value   json.RawMessage `json:"v"`
+	ID      int             `json:"id"`
 }
 
 func (s *BookService) Delete(ctx context.Context, id int) error {
-	if err := s.repo.Delete(ctx, id); err != nil {
+	err := s.tx.WithinTx(ctx, func(ctx context.Context) error {
+		if _, err := s.modifiableBook(ctx, id); err != nil {
+			return err
+		}
+		if err := s.repo.Delete(ctx, id); err != nil {
+			return err
+		}
+		return s.outbox.Add(ctx, newAuditEvent(ctx, audit.ENTITY_BOOK, audit.ACTION_DELETE, int64(id)))
+	})
+	if err != nil {
 		return fmt.Errorf("service: delete book : %w", err)
 	}
-
-	if err := s.audit.SendLogRequest(ctx, audit.LogItem{
-		Action:    audit.ACTION_DELETE,
-		Entity:    audit.ENTITY_BOOK,
-		EntityID:  int64(id),
-		Timestamp: time.Now(),
-	}); err != nil {
-		logrus.WithFields(logrus.Fields{
-			"method": "Delete Book",
-		}).Error("failed to send log request", err)
-	}
 	return nil
 }
 
 func (s *BookService) Create(ctx context.Context, input *domain.CreateBookInput) (int, error) {
+	principal, ok := domain.PrincipalFromContext(ctx)
+	if !ok {
+		return 0, fmt.Errorf("service: create book: %w", domain.ErrForbidden)
+	}
+
 	book := input.ToBook()
-	id, err := s.repo.Create(ctx, book)
+	book.CreatedBy = &principal.UserID
+
+	var id int
+	err := s.tx.WithinTx(ctx, func(ctx context.Context) error {
+		var err error
+		if id, err = s.repo.Create(ctx, book); err != nil {
+			return err
+		}
+		return s.outbox.Add(ctx, newAuditEvent(ctx, audit.ENTITY_BOOK, audit.ACTION_CREATE, int64(id)))
+	})
 	if err != nil {
 		return 0, fmt.Errorf("service: create book: %w", err)
 	}
-	if err := s.audit.SendLogRequest(ctx, audit.LogItem{
-		Action:    audit.ACTION_CREATE,
-		Entity:    audit.ENTITY_BOOK,
-		EntityID:  int64(id),
-		Timestamp: time.Now(),
-	}); err != nil {
-		logrus.WithFields(logrus.Fields{
-			"method": "Create Book",
-		}).Error("failed to send log request", err)
-	}
 	return id, nil
 
 }
@@ -80,8 +103,8 @@ func (s *BookService) GetById(ctx context.Context, id int) (*domain.Book, error)
 	return book, nil
 }
 
-func (s *BookService) GetAll(ctx context.Context) ([]*domain.Book, error) {
-	books, err := s.repo.GetAllBooks(ctx)
+func (s *BookService) GetAll(ctx context.Context, query domain.BookQuery) (*domain.BookPage, error) {
+	books, total, err := s.repo.GetAllBooks(ctx, query)
 	if err != nil {
 		return nil, fmt.Errorf("service: get all books: %w", err)
 	}
@@ -95,26 +118,177 @@ func (s *BookService) GetAll(ctx context.Context) ([]*domain.Book, error) {
 			"method": "Get All Books",
 		}).Error("failed to send log request", err)
 	}
-	return books, nil
+	return &domain.BookPage{
+		Items:  books,
+		Total:  total,
+		Limit:  query.Limit,
+		Offset: query.Offset,
+	}, nil
 }
 
-func (s *BookService) Update(ctx context.Context, id int, book *domain.Book) error {
-	err := s.repo.Update(ctx, id, book)
+func (s *BookService) GetAllAfter(ctx context.Context, query domain.BookQuery, after string) (*domain.BookCursorPage, error) {
+	sort := domain.SortField{Field: "id"}
+	if len(query.Sort) > 0 {
+		sort = query.Sort[0]
+	}
+
+	var keyset *domain.BookKeyset
+	if after != "" {
+		var c bookCursor
+		if err := s.cursor.Decode(after, &c); err != nil {
+			return nil, domain.ErrInvalidCursor
+		}
+		if c.Sort != sort.String() {
+			return nil, fmt.Errorf("%w: sort does not match cursor", domain.ErrInvalidCursor)
+		}
+		if c.Filters != query.FilterKey() {
+			return nil, fmt.Errorf("%w: filters do not match cursor", domain.ErrInvalidCursor)
+		}
+		value, err := domain.ParseSortValue(sort.Field, c.Value)
+		if err != nil {
+			return nil, domain.ErrInvalidCursor
+		}
+		keyset = &domain.BookKeyset{Value: value, ID: c.ID}
+	}
+
+	// берём на одну запись больше, чтобы понять, есть ли следующая страница
+	limit := query.Limit
+	query.Limit++
+	books, err := s.repo.GetBooksAfter(ctx, query, keyset)
 	if err != nil {
-		return fmt.Errorf("service: update book: %w", err)
+		return nil, fmt.Errorf("service: get books after: %w", err)
 	}
+
+	page := &domain.BookCursorPage{Items: books, Limit: limit}
+	if len(books) > limit {
+		page.Items = books[:limit]
+		last := page.Items[limit-1]
+
+		value, err := json.Marshal(last.SortValue(sort.Field))
+		if err != nil {
+			return nil, fmt.Errorf("service: encode cursor: %w", err)
+		}
+		page.NextCursor, err = s.cursor.Encode(bookCursor{
+			Sort:    sort.String(),
+			Filters: query.FilterKey(),
+			Value:   value,
+			ID:      last.ID,
+		})
+		if err != nil {
+			return nil, fmt.Errorf("service: encode cursor: %w", err)
+		}
+	}
+
 	if err := s.audit.SendLogRequest(ctx, audit.LogItem{
-		Action:    audit.ACTION_UPDATE,
+		Action:    audit.ACTION_GET,
 		Entity:    audit.ENTITY_BOOK,
-		EntityID:  int64(id),
+		EntityID:  0,
 		Timestamp: time.Now(),
 	}); err != nil {
 		logrus.WithFields(logrus.Fields{
-			"method": "Update Book",
+			"method": "Get Books After",
 		}).Error("failed to send log request", err)
 	}
+	return page, nil
+}
+
+func (s *BookService) Search(ctx context.Context, query domain.BookSearchQuery) (*domain.BookSearchPage, error) {
+	results, total, err := s.repo.Search(ctx, query)
+	if err != nil {
+		return nil, fmt.Errorf("service: search books: %w", err)
+	}
+	if err := s.audit.SendLogRequest(ctx, audit.LogItem{
+		Action:    audit.ACTION_GET,
+		Entity:    audit.ENTITY_BOOK,
+		EntityID:  0,
+		Timestamp: time.Now(),
+	}); err != nil {
+		logrus.WithFields(logrus.Fields{
+			"method": "Search Books",
+		}).Error("failed to send log request", err)
+	}
+	return &domain.BookSearchPage{
+		Items:  results,
+		Total:  total,
+		Limit:  query.Limit,
+		Offset: query.Offset,
+	}, nil
+}
+
+// Update применяет частичное обновление (его же использует PUT) и пишет в аудит реально изменённые поля
+func (s *BookService) Update(ctx context.Context, id int, patch domain.BookPatch) error {
+	if err := patch.Validate(); err != nil {
+		return fmt.Errorf("service: update book: %w", err)
+	}
+
+	err := s.tx.WithinTx(ctx, func(ctx context.Context) error {
+		if _, err := s.modifiableBook(ctx, id); err != nil {
+			return err
+		}
+		return s.update(ctx, id, patch)
+	})
+	if err != nil {
+		return fmt.Errorf("service: update book: %w", err)
+	}
 	return nil
+}
 
+// update пишет изменение и событие аудита; вызывается внутри s.tx.WithinTx
+func (s *BookService) update(ctx context.Context, id int, patch domain.BookPatch) error {
+	old, err := s.repo.Update(ctx, id, patch)
+	if err != nil {
+		return err
+	}
+
+	auditCtx := log_grpc.WithChangedFields(ctx, patch.ChangedFields(old))
+	if diff := patch.Diff(old); len(diff) > 0 {
+		raw, err := json.Marshal(diff)
+		if err != nil {
+			return err
+		}
+		auditCtx = log_grpc.WithDiff(auditCtx, raw)
+	}
+	return s.outbox.Add(ctx, newAuditEvent(auditCtx, audit.ENTITY_BOOK, audit.ACTION_UPDATE, int64(id)))
+}
+
+// ApplyJSONPatch применяет RFC 6902 к текущей версии книги и сохраняет результат через Update.
+// Чтение, проверка test-операций и запись — в одной транзакции на заблокированной строке:
+// параллельное изменение не потеряется и не проскочит мимо test
+func (s *BookService) ApplyJSONPatch(ctx context.Context, id int, ops []domain.JSONPatchOp) error {
+	err := s.tx.WithinTx(ctx, func(ctx context.Context) error {
+		book, err := s.modifiableBook(ctx, id)
+		if err != nil {
+			return err
+		}
+
+		patch, err := domain.ApplyJSONPatch(book, ops)
+		if err != nil {
+			return err
+		}
+		return s.update(ctx, id, patch)
+	})
+	if err != nil {
+		return fmt.Errorf("service: patch book: %w", err)
+	}
+	return nil
+}
+
+// modifiableBook возвращает книгу, если текущий пользователь — её владелец или администратор.
+// Вызывается внутри s.tx.WithinTx: строка блокируется, и владелец не сменится до записи
+func (s *BookService) modifiableBook(ctx context.Context, id int) (*domain.Book, error) {
+	principal, ok := domain.PrincipalFromContext(ctx)
+	if !ok {
+		return nil, domain.ErrForbidden
+	}
+
+	book, err := s.repo.GetBookForUpdate(ctx, id)
+	if err != nil {
+		return nil, err
+	}
+	if !principal.CanModify(book) {
+		return nil, domain.ErrForbidden
+	}
+	return book, nil
 }
 
 //func (s *BookService) validateCreateInput(input *domain.CreateBookInput) error {
@@ -0,0 +1,241 @@
+package service
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"testing"
+
+	audit "github.com/CryptoGu1/books-grpc-log/pkg/domain"
+	"github.com/CryptoGu1/books-rest-clean-arch/internal/domain"
+	"github.com/CryptoGu1/books-rest-clean-arch/mocks"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+	"github.com/stretchr/testify/require"
+)
+
+func int64Ptr(v int64) *int64 { return &v }
+
+// inTx — Transactor, который просто выполняет fn: транзакция проверяется на уровне репозитория
+func inTx(t *testing.T) *mocks.Transactor {
+	tx := mocks.NewTransactor(t)
+	tx.On("WithinTx", mock.Anything, mock.Anything).Return(func(ctx context.Context, fn func(context.Context) error) error {
+		return fn(ctx)
+	}).Maybe()
+	return tx
+}
+
+func TestBookService_Delete_Ownership(t *testing.T) {
+	book := &domain.Book{ID: 1, Title: "War and Peace", CreatedBy: int64Ptr(10)}
+
+	testTable := []struct {
+		name          string
+		ctx           context.Context
+		book          *domain.Book
+		expectDelete  bool
+		expectedError error
+	}{
+		{
+			name:         "owner",
+			ctx:          domain.WithPrincipal(context.Background(), domain.Principal{UserID: 10}),
+			book:         book,
+			expectDelete: true,
+		},
+		{
+			name:         "admin",
+			ctx:          domain.WithPrincipal(context.Background(), domain.Principal{UserID: 99, Roles: []string{domain.RoleAdmin}}),
+			book:         book,
+			expectDelete: true,
+		},
+		{
+			name:          "other user",
+			ctx:           domain.WithPrincipal(context.Background(), domain.Principal{UserID: 11}),
+			book:          book,
+			expectedError: domain.ErrForbidden,
+		},
+		{
+			name:          "book without owner",
+			ctx:           domain.WithPrincipal(context.Background(), domain.Principal{UserID: 10}),
+			book:          &domain.Book{ID: 1},
+			expectedError: domain.ErrForbidden,
+		},
+		{
+			name:          "anonymous",
+			ctx:           context.Background(),
+			expectedError: domain.ErrForbidden,
+		},
+	}
+
+	for _, testCase := range testTable {
+		t.Run(testCase.name, func(t *testing.T) {
+			repo := mocks.NewBookRepository(t)
+			outbox := mocks.NewAuditOutboxRepository(t)
+
+			if testCase.book != nil {
+				repo.On("GetBookForUpdate", mock.Anything, 1).Return(testCase.book, nil)
+			}
+			if testCase.expectDelete {
+				repo.On("Delete", mock.Anything, 1).Return(nil)
+				outbox.On("Add", mock.Anything, mock.MatchedBy(func(e domain.AuditEvent) bool {
+					return e.Action == audit.ACTION_DELETE && e.EntityID == 1
+				})).Return(nil)
+			}
+
+			s := NewBookService(repo, outbox, inTx(t), mocks.NewAuditClient(t), []byte("secret"))
+			err := s.Delete(testCase.ctx, 1)
+
+			if testCase.expectedError != nil {
+				assert.ErrorIs(t, err, testCase.expectedError)
+				return
+			}
+			assert.NoError(t, err)
+		})
+	}
+}
+
+func TestBookService_Create_SetsOwner(t *testing.T) {
+	repo := mocks.NewBookRepository(t)
+	outbox := mocks.NewAuditOutboxRepository(t)
+
+	repo.On("Create", mock.Anything, mock.MatchedBy(func(b *domain.Book) bool {
+		return b.CreatedBy != nil && *b.CreatedBy == 10
+	})).Return(5, nil)
+	outbox.On("Add", mock.Anything, mock.MatchedBy(func(e domain.AuditEvent) bool {
+		return e.Action == audit.ACTION_CREATE && e.EntityID == 5
+	})).Return(nil)
+
+	s := NewBookService(repo, outbox, inTx(t), mocks.NewAuditClient(t), []byte("secret"))
+	ctx := domain.WithPrincipal(context.Background(), domain.Principal{UserID: 10})
+
+	id, err := s.Create(ctx, &domain.CreateBookInput{Title: "Anna Karenina", Author: "Tolstoy", Rating: 5})
+
+	assert.NoError(t, err)
+	assert.Equal(t, 5, id)
+}
+
+func TestBookService_Update_AuditInTx(t *testing.T) {
+	repo := mocks.NewBookRepository(t)
+	outbox := mocks.NewAuditOutboxRepository(t)
+	tx := mocks.NewTransactor(t)
+
+	txErr := errors.New("outbox is unavailable")
+	// ошибка записи в outbox откатывает транзакцию вместе с изменением книги
+	tx.On("WithinTx", mock.Anything, mock.Anything).Return(func(ctx context.Context, fn func(context.Context) error) error {
+		return fn(ctx)
+	})
+	repo.On("GetBookForUpdate", mock.Anything, 1).Return(&domain.Book{ID: 1, Title: "War", CreatedBy: int64Ptr(10)}, nil)
+	repo.On("Update", mock.Anything, 1, mock.Anything).Return(&domain.Book{ID: 1, Title: "War"}, nil)
+	outbox.On("Add", mock.Anything, mock.MatchedBy(func(e domain.AuditEvent) bool {
+		return e.Action == audit.ACTION_UPDATE &&
+			assert.ObjectsAreEqual([]string{"title"}, e.Metadata["x-audit-changed-fields"]) &&
+			assert.ObjectsAreEqual([]string{`{"title":{"before":"War","after":"War and Peace"}}`}, e.Metadata["x-audit-diff-bin"]) &&
+			assert.ObjectsAreEqual([]string{"10"}, e.Metadata["x-audit-actor-id"])
+	})).Return(txErr)
+
+	s := NewBookService(repo, outbox, tx, mocks.NewAuditClient(t), []byte("secret"))
+	ctx := domain.WithPrincipal(context.Background(), domain.Principal{UserID: 10})
+	title := "War and Peace"
+
+	err := s.Update(ctx, 1, domain.BookPatch{Title: &title})
+	assert.ErrorIs(t, err, txErr)
+}
+
+func TestBookService_Update_OwnershipCheckedInTx(t *testing.T) {
+	repo := mocks.NewBookRepository(t)
+	outbox := mocks.NewAuditOutboxRepository(t)
+
+	// проверка владельца и запись — в одной транзакции, иначе владелец мог смениться между ними
+	inside := false
+	tx := mocks.NewTransactor(t)
+	tx.On("WithinTx", mock.Anything, mock.Anything).Return(func(ctx context.Context, fn func(context.Context) error) error {
+		inside = true
+		defer func() { inside = false }()
+		return fn(ctx)
+	})
+	repo.On("GetBookForUpdate", mock.Anything, 1).Run(func(mock.Arguments) {
+		assert.True(t, inside)
+	}).Return(&domain.Book{ID: 1, Title: "War", CreatedBy: int64Ptr(11)}, nil)
+
+	s := NewBookService(repo, outbox, tx, mocks.NewAuditClient(t), []byte("secret"))
+	ctx := domain.WithPrincipal(context.Background(), domain.Principal{UserID: 10})
+	title := "War and Peace"
+
+	err := s.Update(ctx, 1, domain.BookPatch{Title: &title})
+	assert.ErrorIs(t, err, domain.ErrForbidden)
+}
+
+func TestBookService_ApplyJSONPatch(t *testing.T) {
+	book := &domain.Book{ID: 1, Title: "War", Author: "Tolstoy", Rating: 4, CreatedBy: int64Ptr(10)}
+	ctx := domain.WithPrincipal(context.Background(), domain.Principal{UserID: 10})
+
+	t.Run("test op sees the locked row", func(t *testing.T) {
+		repo := mocks.NewBookRepository(t)
+		outbox := mocks.NewAuditOutboxRepository(t)
+
+		inside := false
+		tx := mocks.NewTransactor(t)
+		tx.On("WithinTx", mock.Anything, mock.Anything).Return(func(ctx context.Context, fn func(context.Context) error) error {
+			inside = true
+			defer func() { inside = false }()
+			return fn(ctx)
+		})
+		repo.On("GetBookForUpdate", mock.Anything, 1).Run(func(mock.Arguments) {
+			assert.True(t, inside)
+		}).Return(book, nil)
+		repo.On("Update", mock.Anything, 1, mock.MatchedBy(func(p domain.BookPatch) bool {
+			return p.Title != nil && *p.Title == "War and Peace"
+		})).Run(func(mock.Arguments) {
+			assert.True(t, inside)
+		}).Return(book, nil)
+		outbox.On("Add", mock.Anything, mock.Anything).Return(nil)
+
+		s := NewBookService(repo, outbox, tx, mocks.NewAuditClient(t), []byte("secret"))
+		err := s.ApplyJSONPatch(ctx, 1, []domain.JSONPatchOp{
+			{Op: "test", Path: "/title", Value: json.RawMessage(`"War"`)},
+			{Op: "replace", Path: "/title", Value: json.RawMessage(`"War and Peace"`)},
+		})
+		assert.NoError(t, err)
+	})
+
+	t.Run("failed test op writes nothing", func(t *testing.T) {
+		repo := mocks.NewBookRepository(t)
+		repo.On("GetBookForUpdate", mock.Anything, 1).Return(book, nil)
+
+		s := NewBookService(repo, mocks.NewAuditOutboxRepository(t), inTx(t), mocks.NewAuditClient(t), []byte("secret"))
+		err := s.ApplyJSONPatch(ctx, 1, []domain.JSONPatchOp{
+			{Op: "test", Path: "/title", Value: json.RawMessage(`"Anna Karenina"`)},
+			{Op: "replace", Path: "/title", Value: json.RawMessage(`"War and Peace"`)},
+		})
+		assert.ErrorIs(t, err, domain.ErrPatchTestFailed)
+	})
+}
+
+func TestBookService_GetAllAfter_CursorFilters(t *testing.T) {
+	books := []*domain.Book{{ID: 1, Author: "Tolstoy"}, {ID: 2, Author: "Tolstoy"}}
+	query := domain.BookQuery{Limit: 1, Author: "Tolstoy"}
+
+	repo := mocks.NewBookRepository(t)
+	repo.On("GetBooksAfter", mock.Anything, mock.Anything, mock.Anything).Return(books, nil)
+	auditClient := mocks.NewAuditClient(t)
+	auditClient.On("SendLogRequest", mock.Anything, mock.Anything).Return(nil)
+
+	s := NewBookService(repo, mocks.NewAuditOutboxRepository(t), inTx(t), auditClient, []byte("secret"))
+	page, err := s.GetAllAfter(context.Background(), query, "")
+	require.NoError(t, err)
+	require.NotEmpty(t, page.NextCursor)
+
+	_, err = s.GetAllAfter(context.Background(), query, page.NextCursor)
+	assert.NoError(t, err)
+
+	// продолжить обход с другими фильтрами нельзя: страницы не сложатся в один список
+	other := query
+	other.Author = "Dostoevsky"
+	_, err = s.GetAllAfter(context.Background(), other, page.NextCursor)
+	assert.ErrorIs(t, err, domain.ErrInvalidCursor)
+
+	minRating := 4
+	other = query
+	other.MinRating = &minRating
+	_, err = s.GetAllAfter(context.Background(), other, page.NextCursor)
+	assert.ErrorIs(t, err, domain.ErrInvalidCursor)
+}
@@ -0,0 +1,138 @@
+package service
+
+import (
+	"context"
+	"strings"
+	"time"
+
+	audit "github.com/CryptoGu1/books-grpc-log/pkg/domain"
+	"github.com/CryptoGu1/books-rest-clean-arch/internal/domain"
+	"github.com/CryptoGu1/books-rest-clean-arch/pkg/log_grpc"
+	"github.com/sirupsen/logrus"
+)
+
+type loginKeys struct {
+	// account — счётчик попыток входа в один аккаунт
+	account string
+	ip      string
+	// email — счётчик по аккаунту со всех адресов; только замедляет (LockoutPolicy.Distributed)
+	email string
+}
+
+// loginAttemptKeys — ключи счётчиков: по паре email+IP ловим подбор пароля к одному аккаунту,
+// по IP — перебор разных аккаунтов с одного адреса, по email — подбор к одному аккаунту
+// с множества адресов. Последний не блокирует полностью: иначе кто угодно запер бы чужой
+// аккаунт десятком неверных паролей
+func loginAttemptKeys(ctx context.Context, email string) loginKeys {
+	email = strings.ToLower(strings.TrimSpace(email))
+	keys := loginKeys{account: "email:" + email, email: "account:" + email}
+	if ip := domain.ClientInfoFromContext(ctx).IP; ip != "" {
+		keys.account += "|ip:" + ip
+		keys.ip = "ip:" + ip
+	}
+	return keys
+}
+
+func (k loginKeys) all() []string {
+	keys := []string{k.account}
+	for _, key := range []string{k.ip, k.email} {
+		if key != "" {
+			keys = append(keys, key)
+		}
+	}
+	return keys
+}
+
+// policy — политика для ключа: у счётчика по email только замедление, без блокировки
+func (s *AuthService) policy(keys loginKeys, key string) domain.LockoutPolicy {
+	if key == keys.email {
+		return s.cfg.Lockout.Distributed()
+	}
+	return s.cfg.Lockout
+}
+
+// checkLoginAttempts возвращает *domain.TooManyAttemptsError, если по одному из ключей надо подождать.
+// Ошибка хранилища не блокирует вход — лишь пишется в лог
+func (s *AuthService) checkLoginAttempts(ctx context.Context, keys loginKeys) error {
+	now := time.Now()
+	var wait time.Duration
+	for _, key := range keys.all() {
+		a, err := s.attempts.Get(ctx, key)
+		if err != nil {
+			logrus.WithFields(logrus.Fields{
+				"method": "SignIn",
+				"key":    key,
+			}).Error("failed to read login attempts", err)
+			continue
+		}
+		if d := s.policy(keys, key).RetryAfter(a, now); d > wait {
+			wait = d
+		}
+	}
+
+	if wait > 0 {
+		return &domain.TooManyAttemptsError{RetryAfter: wait}
+	}
+	return nil
+}
+
+// registerLoginFailure учитывает неудачную попытку; user — nil, если email не найден
+func (s *AuthService) registerLoginFailure(ctx context.Context, keys loginKeys, user *domain.User) {
+	now := time.Now()
+	for _, key := range keys.all() {
+		a, err := s.attempts.RegisterFailure(ctx, key, now, s.cfg.Lockout.Window)
+		if err != nil {
+			logrus.WithFields(logrus.Fields{
+				"method": "SignIn",
+				"key":    key,
+			}).Error("failed to register login failure", err)
+			continue
+		}
+
+		// событие пишем один раз — в момент достижения порога
+		if a.Failures != s.policy(keys, key).MaxFailures {
+			continue
+		}
+		logrus.WithFields(logrus.Fields{
+			"method":   "SignIn",
+			"key":      key,
+			"failures": a.Failures,
+		}).Warn("sign-in locked out")
+
+		// блокировка IP не относится к одному пользователю: id 0, адрес — в metadata события
+		var entityID int64
+		switch {
+		case key == keys.ip:
+		case user != nil:
+			entityID = user.ID
+		default:
+			continue
+		}
+		if err := s.auditClient.SendLogRequest(ctx, audit.LogItem{
+			Action:    log_grpc.ACTION_LOCKOUT,
+			Entity:    audit.ENTITY_USER,
+			EntityID:  entityID,
+			Timestamp: now,
+		}); err != nil {
+			logrus.WithFields(logrus.Fields{
+				"method": "SignIn",
+			}).Error("failed to send log request", err)
+		}
+	}
+}
+
+// resetLoginAttempts обнуляет счётчики аккаунта после успешного входа. Счётчик IP не трогаем:
+// иначе атакующий сбрасывал бы его, периодически входя в собственный аккаунт
+func (s *AuthService) resetLoginAttempts(ctx context.Context, keys loginKeys) {
+	for _, key := range []string{keys.account, keys.email} {
+		if key == "" {
+			continue
+		}
+		if err := s.attempts.Reset(ctx, key); err != nil {
+			logrus.WithFields(logrus.Fields{
+				"method": "SignIn",
+				"key":    key,
+			}).Error("failed to reset login attempts", err)
+		}
+	}
+}
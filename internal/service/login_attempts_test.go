@@ -0,0 +1,106 @@
+package service
+
+import (
+	"context"
+	"fmt"
+	"testing"
+	"time"
+
+	audit "github.com/CryptoGu1/books-grpc-log/pkg/domain"
+	"github.com/CryptoGu1/books-rest-clean-arch/internal/domain"
+	"github.com/CryptoGu1/books-rest-clean-arch/pkg/log_grpc"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+	"github.com/stretchr/testify/require"
+	"golang.org/x/crypto/bcrypt"
+	"google.golang.org/grpc/metadata"
+)
+
+func TestAuthService_SignIn_DistributedLockout(t *testing.T) {
+	s, m := newTestAuthService(t)
+	s.cfg.Lockout = domain.LockoutPolicy{
+		FreeAttempts:    2,
+		MaxFailures:     2,
+		BaseDelay:       time.Second,
+		MaxDelay:        10 * time.Second,
+		LockoutDuration: time.Hour,
+		Window:          time.Hour,
+	}
+
+	user := domain.User{ID: 7, Email: "leo@example.com", Password: "not-a-hash"}
+	m.users.On("GetByCredentials", mock.Anything, user.Email).Return(user, nil)
+
+	// по одной попытке с каждого адреса: счётчики email+IP и IP до порога не доходят
+	wrong := domain.SingInInput{Email: user.Email, Password: "wrong-password"}
+	for i := 0; i < 4; i++ {
+		ctx := domain.WithClientInfo(context.Background(), domain.ClientInfo{IP: fmt.Sprintf("10.0.0.%d", i+1)})
+		_, err := s.SignIn(ctx, wrong)
+		require.Error(t, err)
+		assert.NotErrorIs(t, err, domain.ErrTooManyAttempts)
+	}
+
+	// но аккаунт со всех адресов уже замедлен — и только замедлен, без часовой блокировки
+	ctx := domain.WithClientInfo(context.Background(), domain.ClientInfo{IP: "10.0.1.1"})
+	_, err := s.SignIn(ctx, wrong)
+	var tooMany *domain.TooManyAttemptsError
+	require.ErrorAs(t, err, &tooMany)
+	assert.LessOrEqual(t, tooMany.RetryAfter, s.cfg.Lockout.MaxDelay)
+}
+
+func TestAuthService_SignIn_Lockout(t *testing.T) {
+	s, m := newTestAuthService(t)
+	s.cfg.Lockout = domain.LockoutPolicy{
+		FreeAttempts:    2,
+		MaxFailures:     2,
+		BaseDelay:       time.Second,
+		MaxDelay:        time.Second,
+		LockoutDuration: time.Minute,
+		Window:          time.Hour,
+	}
+
+	hash, err := bcrypt.GenerateFromPassword([]byte("password"), bcrypt.MinCost)
+	require.NoError(t, err)
+	user := domain.User{ID: 7, Email: "leo@example.com", Password: string(hash)}
+
+	m.users.On("GetByCredentials", mock.Anything, user.Email).Return(user, nil).Twice()
+	m.audit.On("SendLogRequest", mock.Anything, mock.MatchedBy(func(item audit.LogItem) bool {
+		return item.Action == log_grpc.ACTION_LOCKOUT && item.EntityID == 7
+	})).Return(nil).Once()
+	// порог по IP — отдельное событие: не про пользователя, а про адрес из metadata
+	m.audit.On("SendLogRequest", mock.MatchedBy(func(ctx context.Context) bool {
+		md, _ := metadata.FromOutgoingContext(ctx)
+		return assert.ObjectsAreEqual([]string{"10.0.0.1"}, md.Get("x-audit-client-ip"))
+	}), mock.MatchedBy(func(item audit.LogItem) bool {
+		return item.Action == log_grpc.ACTION_LOCKOUT && item.EntityID == 0
+	})).Return(nil).Once()
+
+	ctx := domain.WithClientInfo(context.Background(), domain.ClientInfo{IP: "10.0.0.1"})
+	wrong := domain.SingInInput{Email: user.Email, Password: "wrong-password"}
+
+	for i := 0; i < 2; i++ {
+		_, err := s.SignIn(ctx, wrong)
+		require.Error(t, err)
+		assert.NotErrorIs(t, err, domain.ErrTooManyAttempts)
+	}
+
+	// даже верный пароль не проверяется, пока действует блокировка
+	_, err = s.SignIn(ctx, domain.SingInInput{Email: "LEO@example.com", Password: "password"})
+	var tooMany *domain.TooManyAttemptsError
+	require.ErrorAs(t, err, &tooMany)
+	assert.InDelta(t, time.Minute.Seconds(), tooMany.RetryAfter.Seconds(), 1)
+
+	// блокировка по IP распространяется и на другие аккаунты
+	_, err = s.SignIn(ctx, domain.SingInInput{Email: "other@example.com", Password: "password"})
+	assert.ErrorIs(t, err, domain.ErrTooManyAttempts)
+
+	// а сам аккаунт с другого адреса доступен: чужие неверные пароли не запирают владельца
+	m.users.On("GetByCredentials", mock.Anything, "LEO@example.com").Return(user, nil).Once()
+	m.mfa.On("GetTOTP", mock.Anything, int64(7)).Return(domain.TOTP{}, domain.ErrMFANotEnabled)
+	m.audit.On("SendLogRequest", mock.Anything, mock.Anything).Return(nil)
+	m.roles.On("GetUserRoles", mock.Anything, int64(7)).Return([]domain.Role{}, nil)
+	m.sessions.On("Create", mock.Anything, mock.Anything).Return(nil)
+
+	owner := domain.WithClientInfo(context.Background(), domain.ClientInfo{IP: "10.0.0.2"})
+	_, err = s.SignIn(owner, domain.SingInInput{Email: "LEO@example.com", Password: "password"})
+	assert.NoError(t, err)
+}
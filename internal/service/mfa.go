@@ -0,0 +1,217 @@
+package service
+
+import (
+	"context"
+	"crypto/rand"
+	"encoding/base32"
+	"errors"
+	"fmt"
+	"strconv"
+	"time"
+
+	audit "github.com/CryptoGu1/books-grpc-log/pkg/domain"
+	"github.com/CryptoGu1/books-rest-clean-arch/internal/domain"
+	"github.com/CryptoGu1/books-rest-clean-arch/pkg/totp"
+	"github.com/golang-jwt/jwt/v5"
+	"github.com/sirupsen/logrus"
+)
+
+// EnrollTOTP начинает подключение 2FA: генерирует секрет и резервные коды. До ConfirmTOTP
+// вход работает по-прежнему, повторный вызов заменяет незавершённую попытку
+func (s *AuthService) EnrollTOTP(ctx context.Context, userID int64) (domain.TOTPEnrollment, error) {
+	user, err := s.repo.GetByID(ctx, userID)
+	if err != nil {
+		return domain.TOTPEnrollment{}, fmt.Errorf("service: enroll totp: %w", err)
+	}
+
+	secret, err := totp.GenerateSecret()
+	if err != nil {
+		return domain.TOTPEnrollment{}, fmt.Errorf("service: enroll totp: %w", err)
+	}
+
+	codes := make([]string, 0, domain.RecoveryCodesCount)
+	hashes := make([]string, 0, domain.RecoveryCodesCount)
+	for i := 0; i < domain.RecoveryCodesCount; i++ {
+		code, err := newRecoveryCode()
+		if err != nil {
+			return domain.TOTPEnrollment{}, fmt.Errorf("service: recovery code: %w", err)
+		}
+		codes = append(codes, code)
+		hashes = append(hashes, domain.HashToken(domain.NormalizeRecoveryCode(code)))
+	}
+
+	if err := s.mfaRepo.StartTOTP(ctx, userID, secret, hashes); err != nil {
+		return domain.TOTPEnrollment{}, fmt.Errorf("service: enroll totp: %w", err)
+	}
+
+	return domain.TOTPEnrollment{
+		Secret:        secret,
+		URI:           totp.URI(s.cfg.MFAIssuer, user.Email, secret),
+		RecoveryCodes: codes,
+	}, nil
+}
+
+// ConfirmTOTP включает 2FA, если пользователь ввёл верный код из приложения
+func (s *AuthService) ConfirmTOTP(ctx context.Context, userID int64, code string) error {
+	t, err := s.mfaRepo.GetTOTP(ctx, userID)
+	if err != nil {
+		return fmt.Errorf("service: confirm totp: %w", err)
+	}
+	if t.Enabled() {
+		return domain.ErrMFAAlreadyEnabled
+	}
+
+	step, ok := totp.Validate(t.Secret, code, time.Now(), domain.MFASkew)
+	if !ok {
+		return domain.ErrInvalidMFACode
+	}
+	if err := s.mfaRepo.ConfirmTOTP(ctx, userID, step); err != nil {
+		return fmt.Errorf("service: confirm totp: %w", err)
+	}
+
+	s.auditUser(ctx, "ConfirmTOTP", audit.ACTION_UPDATE, userID)
+	return nil
+}
+
+// DisableTOTP отключает 2FA; подойдёт и код из приложения, и резервный код
+func (s *AuthService) DisableTOTP(ctx context.Context, userID int64, code string) error {
+	t, err := s.mfaRepo.GetTOTP(ctx, userID)
+	if err != nil {
+		return fmt.Errorf("service: disable totp: %w", err)
+	}
+	if !t.Enabled() {
+		return domain.ErrMFANotEnabled
+	}
+
+	if err := s.checkSecondFactorLimited(ctx, userID, t, code); err != nil {
+		return err
+	}
+	if err := s.mfaRepo.DeleteTOTP(ctx, userID); err != nil {
+		return fmt.Errorf("service: disable totp: %w", err)
+	}
+
+	s.auditUser(ctx, "DisableTOTP", audit.ACTION_UPDATE, userID)
+	return nil
+}
+
+// VerifyMFA — второй шаг входа: обменивает MFA-токен из SignIn и код на пару токенов
+func (s *AuthService) VerifyMFA(ctx context.Context, input domain.MFAVerifyInput) (string, string, error) {
+	var claims domain.MFAChallengeClaims
+	_, err := jwt.ParseWithClaims(input.MFAToken, &claims, func(t *jwt.Token) (interface{}, error) {
+		return s.purposeKey(domain.MFAChallengeAudience), nil
+	}, jwt.WithValidMethods([]string{jwt.SigningMethodHS256.Alg()}),
+		jwt.WithAudience(domain.MFAChallengeAudience),
+		jwt.WithExpirationRequired())
+	if err != nil {
+		return "", "", domain.ErrInvalidMFAToken
+	}
+
+	userID, err := strconv.ParseInt(claims.Subject, 10, 64)
+	if err != nil {
+		return "", "", domain.ErrInvalidMFAToken
+	}
+
+	if err := s.checkLoginAttempts(ctx, mfaAttemptKeys(userID)); err != nil {
+		return "", "", err
+	}
+
+	t, err := s.mfaRepo.GetTOTP(ctx, userID)
+	if err != nil {
+		if errors.Is(err, domain.ErrMFANotEnabled) {
+			return "", "", domain.ErrInvalidMFAToken
+		}
+		return "", "", fmt.Errorf("service: verify mfa: %w", err)
+	}
+	if !t.Enabled() {
+		return "", "", domain.ErrInvalidMFAToken
+	}
+
+	user, err := s.repo.GetByID(ctx, userID)
+	if err != nil {
+		return "", "", fmt.Errorf("service: verify mfa: %w", err)
+	}
+
+	if err := s.checkSecondFactorLimited(ctx, userID, t, input.Code); err != nil {
+		return "", "", err
+	}
+
+	return s.completeSignIn(ctx, user)
+}
+
+// mfaAttemptKeys — 6 цифр перебираются быстро, поэтому у второго фактора свой счётчик неудач,
+// общий для входа и отключения 2FA
+func mfaAttemptKeys(userID int64) loginKeys {
+	return loginKeys{account: "mfa:" + strconv.FormatInt(userID, 10)}
+}
+
+// checkSecondFactorLimited — checkSecondFactor со счётчиком неудач mfaAttemptKeys
+func (s *AuthService) checkSecondFactorLimited(ctx context.Context, userID int64, t domain.TOTP, code string) error {
+	keys := mfaAttemptKeys(userID)
+	if err := s.checkLoginAttempts(ctx, keys); err != nil {
+		return err
+	}
+	if err := s.checkSecondFactor(ctx, t, code); err != nil {
+		if errors.Is(err, domain.ErrInvalidMFACode) {
+			s.registerLoginFailure(ctx, keys, &domain.User{ID: userID})
+		}
+		return err
+	}
+	s.resetLoginAttempts(ctx, keys)
+	return nil
+}
+
+// checkSecondFactor принимает TOTP-код (каждый интервал — один раз) или неиспользованный резервный код
+func (s *AuthService) checkSecondFactor(ctx context.Context, t domain.TOTP, code string) error {
+	if step, ok := totp.Validate(t.Secret, code, time.Now(), domain.MFASkew); ok {
+		if err := s.mfaRepo.UseTOTPStep(ctx, t.UserID, step); err != nil {
+			if errors.Is(err, domain.ErrInvalidMFACode) {
+				return err
+			}
+			return fmt.Errorf("service: use totp code: %w", err)
+		}
+		return nil
+	}
+
+	normalized := domain.NormalizeRecoveryCode(code)
+	if normalized == "" {
+		return domain.ErrInvalidMFACode
+	}
+	if err := s.mfaRepo.UseRecoveryCode(ctx, t.UserID, domain.HashToken(normalized)); err != nil {
+		if errors.Is(err, domain.ErrInvalidMFACode) {
+			return err
+		}
+		return fmt.Errorf("service: use recovery code: %w", err)
+	}
+
+	logrus.WithFields(logrus.Fields{
+		"user_id": t.UserID,
+	}).Info("recovery code used")
+	return nil
+}
+
+// mfaChallenge выдаёт короткоживущий токен, подтверждающий, что пароль уже проверен
+func (s *AuthService) mfaChallenge(userID int64) (string, error) {
+	claims := &domain.MFAChallengeClaims{
+		RegisteredClaims: jwt.RegisteredClaims{
+			Subject:   strconv.FormatInt(userID, 10),
+			Audience:  jwt.ClaimStrings{domain.MFAChallengeAudience},
+			IssuedAt:  jwt.NewNumericDate(time.Now()),
+			ExpiresAt: jwt.NewNumericDate(time.Now().Add(domain.MFAChallengeTTL)),
+		},
+	}
+	token, err := jwt.NewWithClaims(jwt.SigningMethodHS256, claims).SignedString(s.purposeKey(domain.MFAChallengeAudience))
+	if err != nil {
+		return "", fmt.Errorf("service: sign mfa token: %w", err)
+	}
+	return token, nil
+}
+
+// newRecoveryCode — 40 случайных бит в виде XXXX-XXXX
+func newRecoveryCode() (string, error) {
+	b := make([]byte, 5)
+	if _, err := rand.Read(b); err != nil {
+		return "", err
+	}
+	code := base32.StdEncoding.EncodeToString(b)
+	return code[:4] + "-" + code[4:], nil
+}
@@ -0,0 +1,156 @@
+package service
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/CryptoGu1/books-rest-clean-arch/internal/domain"
+	"github.com/CryptoGu1/books-rest-clean-arch/pkg/totp"
+	"github.com/golang-jwt/jwt/v5"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+	"github.com/stretchr/testify/require"
+	"golang.org/x/crypto/bcrypt"
+)
+
+func TestAuthService_SignIn_MFA(t *testing.T) {
+	hash, err := bcrypt.GenerateFromPassword([]byte("password"), bcrypt.MinCost)
+	require.NoError(t, err)
+	user := domain.User{ID: 7, Email: "leo@example.com", Password: string(hash)}
+
+	secret, err := totp.GenerateSecret()
+	require.NoError(t, err)
+	confirmedAt := time.Now()
+	enabled := domain.TOTP{UserID: 7, Secret: secret, ConfirmedAt: &confirmedAt}
+
+	s, m := newTestAuthService(t)
+	m.users.On("GetByCredentials", mock.Anything, user.Email).Return(user, nil)
+	m.users.On("GetByID", mock.Anything, int64(7)).Return(user, nil)
+	m.mfa.On("GetTOTP", mock.Anything, int64(7)).Return(enabled, nil)
+
+	// без кода токены не выдаются — только challenge
+	result, err := s.SignIn(context.Background(), domain.SingInInput{Email: user.Email, Password: "password"})
+	require.NoError(t, err)
+	require.True(t, result.MFARequired())
+	assert.Empty(t, result.AccessToken)
+	assert.Empty(t, result.RefreshToken)
+
+	// challenge подписан отдельным ключом и не проходит как access-токен
+	_, err = jwt.ParseWithClaims(result.MFAToken, &domain.AccessClaims{}, func(*jwt.Token) (interface{}, error) { return s.hmacSecret, nil })
+	assert.Error(t, err)
+
+	t.Run("wrong code", func(t *testing.T) {
+		m.mfa.On("UseRecoveryCode", mock.Anything, int64(7), domain.HashToken("000000")).Return(domain.ErrInvalidMFACode).Once()
+
+		_, _, err := s.VerifyMFA(context.Background(), domain.MFAVerifyInput{MFAToken: result.MFAToken, Code: "000000"})
+		assert.ErrorIs(t, err, domain.ErrInvalidMFACode)
+	})
+
+	t.Run("invalid token", func(t *testing.T) {
+		_, _, err := s.VerifyMFA(context.Background(), domain.MFAVerifyInput{MFAToken: "garbage", Code: "123456"})
+		assert.ErrorIs(t, err, domain.ErrInvalidMFAToken)
+	})
+
+	t.Run("totp code", func(t *testing.T) {
+		code, err := totp.Code(secret, totp.Step(time.Now()))
+		require.NoError(t, err)
+
+		m.mfa.On("UseTOTPStep", mock.Anything, int64(7), mock.AnythingOfType("int64")).Return(nil).Once()
+		m.audit.On("SendLogRequest", mock.Anything, mock.Anything).Return(nil).Once()
+		m.roles.On("GetUserRoles", mock.Anything, int64(7)).Return([]domain.Role{}, nil).Once()
+		m.sessions.On("Create", mock.Anything, mock.Anything).Return(nil).Once()
+
+		access, refresh, err := s.VerifyMFA(context.Background(), domain.MFAVerifyInput{MFAToken: result.MFAToken, Code: code})
+		require.NoError(t, err)
+		assert.NotEmpty(t, access)
+		assert.NotEmpty(t, refresh)
+
+		// тот же интервал второй раз не принимается
+		m.mfa.On("UseTOTPStep", mock.Anything, int64(7), mock.AnythingOfType("int64")).Return(domain.ErrInvalidMFACode).Once()
+		_, _, err = s.VerifyMFA(context.Background(), domain.MFAVerifyInput{MFAToken: result.MFAToken, Code: code})
+		assert.ErrorIs(t, err, domain.ErrInvalidMFACode)
+	})
+
+	t.Run("recovery code", func(t *testing.T) {
+		m.mfa.On("UseRecoveryCode", mock.Anything, int64(7), domain.HashToken("ABCDEFGH")).Return(nil).Once()
+		m.audit.On("SendLogRequest", mock.Anything, mock.Anything).Return(nil).Once()
+		m.roles.On("GetUserRoles", mock.Anything, int64(7)).Return([]domain.Role{}, nil).Once()
+		m.sessions.On("Create", mock.Anything, mock.Anything).Return(nil).Once()
+
+		_, _, err := s.VerifyMFA(context.Background(), domain.MFAVerifyInput{MFAToken: result.MFAToken, Code: "abcd-efgh"})
+		require.NoError(t, err)
+	})
+}
+
+func TestAuthService_EnrollTOTP(t *testing.T) {
+	s, m := newTestAuthService(t)
+
+	var stored []string
+	m.users.On("GetByID", mock.Anything, int64(7)).Return(domain.User{ID: 7, Email: "leo@example.com"}, nil)
+	m.mfa.On("StartTOTP", mock.Anything, int64(7), mock.Anything, mock.Anything).
+		Run(func(args mock.Arguments) { stored = args.Get(3).([]string) }).
+		Return(nil)
+
+	enrollment, err := s.EnrollTOTP(context.Background(), 7)
+	require.NoError(t, err)
+	assert.Contains(t, enrollment.URI, "otpauth://totp/Books:leo@example.com?")
+	require.Len(t, enrollment.RecoveryCodes, domain.RecoveryCodesCount)
+
+	// в базу попадают только хеши
+	require.Len(t, stored, domain.RecoveryCodesCount)
+	for i, code := range enrollment.RecoveryCodes {
+		assert.NotContains(t, stored, code)
+		assert.Equal(t, domain.HashToken(domain.NormalizeRecoveryCode(code)), stored[i])
+	}
+}
+
+func TestAuthService_ConfirmTOTP(t *testing.T) {
+	secret, err := totp.GenerateSecret()
+	require.NoError(t, err)
+	code, err := totp.Code(secret, totp.Step(time.Now()))
+	require.NoError(t, err)
+
+	s, m := newTestAuthService(t)
+	m.mfa.On("GetTOTP", mock.Anything, int64(7)).Return(domain.TOTP{UserID: 7, Secret: secret}, nil)
+
+	assert.ErrorIs(t, s.ConfirmTOTP(context.Background(), 7, "not-a-code"), domain.ErrInvalidMFACode)
+
+	m.mfa.On("ConfirmTOTP", mock.Anything, int64(7), totp.Step(time.Now())).Return(nil)
+	m.audit.On("SendLogRequest", mock.Anything, mock.Anything).Return(nil)
+	assert.NoError(t, s.ConfirmTOTP(context.Background(), 7, code))
+}
+
+func TestAuthService_DisableTOTP_Lockout(t *testing.T) {
+	secret, err := totp.GenerateSecret()
+	require.NoError(t, err)
+	confirmedAt := time.Now()
+	enabled := domain.TOTP{UserID: 7, Secret: secret, ConfirmedAt: &confirmedAt}
+
+	s, m := newTestAuthService(t)
+	s.cfg.Lockout = domain.LockoutPolicy{
+		FreeAttempts:    2,
+		MaxFailures:     2,
+		BaseDelay:       time.Second,
+		MaxDelay:        time.Second,
+		LockoutDuration: time.Minute,
+		Window:          time.Hour,
+	}
+	m.mfa.On("GetTOTP", mock.Anything, int64(7)).Return(enabled, nil)
+	m.mfa.On("UseRecoveryCode", mock.Anything, int64(7), mock.Anything).Return(domain.ErrInvalidMFACode)
+	m.audit.On("SendLogRequest", mock.Anything, mock.Anything).Return(nil)
+
+	for i := 0; i < 2; i++ {
+		assert.ErrorIs(t, s.DisableTOTP(context.Background(), 7, "00000000"), domain.ErrInvalidMFACode)
+	}
+
+	// счётчик общий со вторым шагом входа: перебор через отключение 2FA тоже упирается в блокировку
+	code, err := totp.Code(secret, totp.Step(time.Now()))
+	require.NoError(t, err)
+	assert.ErrorIs(t, s.DisableTOTP(context.Background(), 7, code), domain.ErrTooManyAttempts)
+
+	challenge, err := s.mfaChallenge(7)
+	require.NoError(t, err)
+	_, _, err = s.VerifyMFA(context.Background(), domain.MFAVerifyInput{MFAToken: challenge, Code: code})
+	assert.ErrorIs(t, err, domain.ErrTooManyAttempts)
+}
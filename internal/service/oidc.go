@@ -0,0 +1,197 @@
+package service
+
+import (
+	"context"
+	"crypto/subtle"
+	"database/sql"
+	"errors"
+	"fmt"
+	"strings"
+	"time"
+
+	audit "github.com/CryptoGu1/books-grpc-log/pkg/domain"
+	"github.com/CryptoGu1/books-rest-clean-arch/internal/domain"
+	"github.com/CryptoGu1/books-rest-clean-arch/pkg/oidc"
+	"github.com/golang-jwt/jwt/v5"
+	"github.com/sirupsen/logrus"
+)
+
+// OIDCProvider — внешний провайдер OpenID Connect (см. oidc.Provider)
+type OIDCProvider interface {
+	AuthCodeURL(ctx context.Context, state, nonce, verifier string) (string, error)
+	Exchange(ctx context.Context, code, verifier, nonce string) (oidc.Claims, error)
+}
+
+// OIDCLogin готовит редирект к провайдеру. stateToken нужно вернуть в OIDCCallback —
+// хендлер хранит его в cookie
+func (s *AuthService) OIDCLogin(ctx context.Context, provider string) (authURL, stateToken string, err error) {
+	p, ok := s.cfg.OIDCProviders[provider]
+	if !ok {
+		return "", "", domain.ErrUnknownProvider
+	}
+
+	state, err := s.NewRefreshToken()
+	if err != nil {
+		return "", "", fmt.Errorf("service: oidc state: %w", err)
+	}
+	nonce, err := s.NewRefreshToken()
+	if err != nil {
+		return "", "", fmt.Errorf("service: oidc nonce: %w", err)
+	}
+	verifier, err := oidc.NewVerifier()
+	if err != nil {
+		return "", "", fmt.Errorf("service: oidc verifier: %w", err)
+	}
+
+	authURL, err = p.AuthCodeURL(ctx, state, nonce, verifier)
+	if err != nil {
+		return "", "", fmt.Errorf("service: oidc login: %w", err)
+	}
+
+	claims := &domain.OIDCStateClaims{
+		RegisteredClaims: jwt.RegisteredClaims{
+			Audience:  jwt.ClaimStrings{domain.OIDCStateAudience},
+			IssuedAt:  jwt.NewNumericDate(time.Now()),
+			ExpiresAt: jwt.NewNumericDate(time.Now().Add(domain.OIDCStateTTL)),
+		},
+		Provider: provider,
+		State:    state,
+		Nonce:    nonce,
+		Verifier: verifier,
+	}
+	stateToken, err = jwt.NewWithClaims(jwt.SigningMethodHS256, claims).SignedString(s.purposeKey(domain.OIDCStateAudience))
+	if err != nil {
+		return "", "", fmt.Errorf("service: sign oidc state: %w", err)
+	}
+	return authURL, stateToken, nil
+}
+
+// OIDCCallback завершает вход через провайдера. Внешний аккаунт связывается с пользователем
+// по подтверждённому email; если такого пользователя нет — он создаётся
+func (s *AuthService) OIDCCallback(ctx context.Context, input domain.OIDCCallbackInput) (domain.SignInResult, error) {
+	p, ok := s.cfg.OIDCProviders[input.Provider]
+	if !ok {
+		return domain.SignInResult{}, domain.ErrUnknownProvider
+	}
+
+	var state domain.OIDCStateClaims
+	_, err := jwt.ParseWithClaims(input.StateToken, &state, func(t *jwt.Token) (interface{}, error) {
+		return s.purposeKey(domain.OIDCStateAudience), nil
+	}, jwt.WithValidMethods([]string{jwt.SigningMethodHS256.Alg()}),
+		jwt.WithAudience(domain.OIDCStateAudience),
+		jwt.WithExpirationRequired())
+	// state из адреса должен совпасть с cookie этого же браузера — иначе это чужой ответ провайдера (CSRF)
+	if err != nil || state.Provider != input.Provider ||
+		subtle.ConstantTimeCompare([]byte(state.State), []byte(input.State)) != 1 {
+		return domain.SignInResult{}, domain.ErrOIDCFailed
+	}
+
+	claims, err := p.Exchange(ctx, input.Code, state.Verifier, state.Nonce)
+	if err != nil {
+		logrus.WithFields(logrus.Fields{
+			"method":   "OIDCCallback",
+			"provider": input.Provider,
+		}).Error("code exchange failed", err)
+		return domain.SignInResult{}, domain.ErrOIDCFailed
+	}
+
+	user, err := s.oidcUser(ctx, input.Provider, claims)
+	if err != nil {
+		return domain.SignInResult{}, err
+	}
+	return s.finishSignIn(ctx, user)
+}
+
+// oidcUser находит или создаёт пользователя для внешнего аккаунта
+func (s *AuthService) oidcUser(ctx context.Context, provider string, claims oidc.Claims) (domain.User, error) {
+	userID, err := s.identities.GetUserID(ctx, provider, claims.Subject)
+	if err == nil {
+		user, err := s.repo.GetByID(ctx, userID)
+		if err != nil {
+			return domain.User{}, fmt.Errorf("service: oidc user: %w", err)
+		}
+		return user, nil
+	}
+	if !errors.Is(err, domain.ErrIdentityNotFound) {
+		return domain.User{}, fmt.Errorf("service: oidc user: %w", err)
+	}
+
+	// без подтверждения у провайдера email мог указать кто угодно
+	if claims.Email == "" || !claims.EmailVerified {
+		return domain.User{}, domain.ErrEmailNotVerified
+	}
+
+	user, err := s.repo.GetByCredentials(ctx, claims.Email)
+	switch {
+	case err == nil:
+		// неподтверждённый локальный аккаунт мог завести кто-то другой, зная пароль;
+		// привязка отдала бы ему вход владельца адреса
+		if !user.EmailVerified() {
+			return domain.User{}, domain.ErrEmailTaken
+		}
+	case errors.Is(err, sql.ErrNoRows):
+		if user, err = s.createOIDCUser(ctx, claims); err != nil {
+			return domain.User{}, err
+		}
+	default:
+		return domain.User{}, fmt.Errorf("service: oidc user: %w", err)
+	}
+
+	if err := s.identities.Link(ctx, domain.UserIdentity{
+		Provider: provider,
+		Subject:  claims.Subject,
+		UserID:   user.ID,
+		Email:    claims.Email,
+	}); err != nil {
+		return domain.User{}, fmt.Errorf("service: oidc user: %w", err)
+	}
+	s.auditUser(ctx, "OIDCCallback", audit.ACTION_UPDATE, user.ID)
+	return user, nil
+}
+
+// createOIDCUser регистрирует пользователя без известного ему пароля; задать пароль можно через восстановление
+func (s *AuthService) createOIDCUser(ctx context.Context, claims oidc.Claims) (domain.User, error) {
+	random, err := s.NewRefreshToken()
+	if err != nil {
+		return domain.User{}, fmt.Errorf("service: oidc password: %w", err)
+	}
+	hashed, err := s.cfg.Hasher.Hash(random)
+	if err != nil {
+		return domain.User{}, fmt.Errorf("service: hash password: %w", err)
+	}
+
+	name := claims.Name
+	if name == "" {
+		name, _, _ = strings.Cut(claims.Email, "@")
+	}
+	user := domain.User{
+		Name:         name,
+		Email:        claims.Email,
+		Password:     hashed,
+		RegisteredAt: time.Now(),
+	}
+
+	err = s.tx.WithinTx(ctx, func(ctx context.Context) error {
+		id, err := s.repo.CreateUser(ctx, user)
+		if err != nil {
+			return fmt.Errorf("service: create user: %w", err)
+		}
+		user.ID = int64(id)
+
+		if err := s.roleRepo.AssignRole(ctx, user.ID, domain.RoleReader); err != nil {
+			return fmt.Errorf("service: assign default role: %w", err)
+		}
+		if err := s.repo.MarkEmailVerified(ctx, user.ID); err != nil {
+			return fmt.Errorf("service: verify email: %w", err)
+		}
+		return nil
+	})
+	if err != nil {
+		return domain.User{}, err
+	}
+	now := time.Now()
+	user.EmailVerifiedAt = &now
+
+	s.auditUser(ctx, "OIDCCallback", audit.ACTION_REGISTER, user.ID)
+	return user, nil
+}
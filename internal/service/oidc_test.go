@@ -0,0 +1,153 @@
+package service
+
+import (
+	"context"
+	"database/sql"
+	"net/http"
+	"net/url"
+	"testing"
+	"time"
+
+	"github.com/CryptoGu1/books-rest-clean-arch/internal/domain"
+	"github.com/CryptoGu1/books-rest-clean-arch/pkg/oidc"
+	"github.com/CryptoGu1/books-rest-clean-arch/pkg/oidc/oidctest"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+	"github.com/stretchr/testify/require"
+)
+
+// oidcRoundTrip проходит вход у тестового провайдера так, как это сделал бы браузер
+func oidcRoundTrip(t *testing.T, s *AuthService) domain.OIDCCallbackInput {
+	authURL, stateToken, err := s.OIDCLogin(context.Background(), "local")
+	require.NoError(t, err)
+
+	client := &http.Client{CheckRedirect: func(*http.Request, []*http.Request) error { return http.ErrUseLastResponse }}
+	resp, err := client.Get(authURL)
+	require.NoError(t, err)
+	resp.Body.Close()
+
+	back, err := url.Parse(resp.Header.Get("Location"))
+	require.NoError(t, err)
+	return domain.OIDCCallbackInput{
+		Provider:   "local",
+		Code:       back.Query().Get("code"),
+		State:      back.Query().Get("state"),
+		StateToken: stateToken,
+	}
+}
+
+func TestAuthService_OIDCCallback(t *testing.T) {
+	provider, srv, err := oidctest.NewServer("books", "secret")
+	require.NoError(t, err)
+	defer srv.Close()
+
+	verifiedAt := time.Now()
+	external := oidctest.User{Subject: "ext-1", Email: "leo@example.com", EmailVerified: true, Name: "Leo"}
+
+	type mockBehavior func(m authMocks)
+
+	testTable := []struct {
+		name          string
+		user          oidctest.User
+		mockBehavior  mockBehavior
+		expectedError error
+	}{
+		{
+			name: "already linked",
+			user: external,
+			mockBehavior: func(m authMocks) {
+				m.idents.On("GetUserID", mock.Anything, "local", "ext-1").Return(int64(7), nil)
+				m.users.On("GetByID", mock.Anything, int64(7)).Return(domain.User{ID: 7, EmailVerifiedAt: &verifiedAt}, nil)
+			},
+		},
+		{
+			name: "links existing user by verified email",
+			user: external,
+			mockBehavior: func(m authMocks) {
+				m.idents.On("GetUserID", mock.Anything, "local", "ext-1").Return(int64(0), domain.ErrIdentityNotFound)
+				m.users.On("GetByCredentials", mock.Anything, "leo@example.com").
+					Return(domain.User{ID: 7, Email: "leo@example.com", EmailVerifiedAt: &verifiedAt}, nil)
+				m.idents.On("Link", mock.Anything, domain.UserIdentity{Provider: "local", Subject: "ext-1", UserID: 7, Email: "leo@example.com"}).Return(nil)
+			},
+		},
+		{
+			name: "creates new user",
+			user: external,
+			mockBehavior: func(m authMocks) {
+				m.idents.On("GetUserID", mock.Anything, "local", "ext-1").Return(int64(0), domain.ErrIdentityNotFound)
+				m.users.On("GetByCredentials", mock.Anything, "leo@example.com").Return(domain.User{}, sql.ErrNoRows)
+				m.users.On("CreateUser", mock.Anything, mock.MatchedBy(func(u domain.User) bool {
+					return u.Email == "leo@example.com" && u.Name == "Leo" && u.Password != ""
+				})).Return(7, nil)
+				m.roles.On("AssignRole", mock.Anything, int64(7), domain.RoleReader).Return(nil)
+				m.users.On("MarkEmailVerified", mock.Anything, int64(7)).Return(nil)
+				m.idents.On("Link", mock.Anything, mock.MatchedBy(func(i domain.UserIdentity) bool { return i.UserID == 7 })).Return(nil)
+			},
+		},
+		{
+			name: "unverified email at provider",
+			user: oidctest.User{Subject: "ext-1", Email: "leo@example.com", Name: "Leo"},
+			mockBehavior: func(m authMocks) {
+				m.idents.On("GetUserID", mock.Anything, "local", "ext-1").Return(int64(0), domain.ErrIdentityNotFound)
+			},
+			expectedError: domain.ErrEmailNotVerified,
+		},
+		{
+			name: "local account with unverified email is not linked",
+			user: external,
+			mockBehavior: func(m authMocks) {
+				m.idents.On("GetUserID", mock.Anything, "local", "ext-1").Return(int64(0), domain.ErrIdentityNotFound)
+				m.users.On("GetByCredentials", mock.Anything, "leo@example.com").Return(domain.User{ID: 7, Email: "leo@example.com"}, nil)
+			},
+			expectedError: domain.ErrEmailTaken,
+		},
+	}
+
+	for _, testCase := range testTable {
+		t.Run(testCase.name, func(t *testing.T) {
+			s, m := newTestAuthService(t)
+			s.cfg.OIDCProviders = map[string]OIDCProvider{
+				"local": oidc.NewProvider(oidc.Config{Issuer: srv.URL, ClientID: "books", ClientSecret: "secret", RedirectURL: "http://app.local/cb"}, nil),
+			}
+			provider.SetUser(testCase.user)
+			testCase.mockBehavior(m)
+			if testCase.expectedError == nil {
+				m.mfa.On("GetTOTP", mock.Anything, int64(7)).Return(domain.TOTP{}, domain.ErrMFANotEnabled)
+				m.audit.On("SendLogRequest", mock.Anything, mock.Anything).Return(nil)
+				m.roles.On("GetUserRoles", mock.Anything, int64(7)).Return([]domain.Role{}, nil)
+				m.sessions.On("Create", mock.Anything, mock.MatchedBy(func(s domain.RefreshSession) bool { return s.UserID == 7 })).Return(nil)
+			}
+
+			result, err := s.OIDCCallback(context.Background(), oidcRoundTrip(t, s))
+			if testCase.expectedError != nil {
+				assert.ErrorIs(t, err, testCase.expectedError)
+				return
+			}
+			require.NoError(t, err)
+			assert.NotEmpty(t, result.AccessToken)
+			assert.NotEmpty(t, result.RefreshToken)
+		})
+	}
+}
+
+func TestAuthService_OIDCCallback_StateMismatch(t *testing.T) {
+	_, srv, err := oidctest.NewServer("books", "secret")
+	require.NoError(t, err)
+	defer srv.Close()
+
+	s, _ := newTestAuthService(t)
+	s.cfg.OIDCProviders = map[string]OIDCProvider{
+		"local": oidc.NewProvider(oidc.Config{Issuer: srv.URL, ClientID: "books", ClientSecret: "secret", RedirectURL: "http://app.local/cb"}, nil),
+	}
+
+	// ответ провайдера, подсунутый в чужой браузер, не совпадёт с его cookie
+	victim := oidcRoundTrip(t, s)
+	attacker := oidcRoundTrip(t, s)
+	victim.Code, victim.State = attacker.Code, attacker.State
+
+	_, err = s.OIDCCallback(context.Background(), victim)
+	assert.ErrorIs(t, err, domain.ErrOIDCFailed)
+
+	_, err = s.OIDCCallback(context.Background(), domain.OIDCCallbackInput{Provider: "unknown"})
+	assert.ErrorIs(t, err, domain.ErrUnknownProvider)
+}
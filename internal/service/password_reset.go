@@ -0,0 +1,95 @@
+package service
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"time"
+
+	audit "github.com/CryptoGu1/books-grpc-log/pkg/domain"
+	"github.com/CryptoGu1/books-rest-clean-arch/internal/domain"
+	"github.com/CryptoGu1/books-rest-clean-arch/pkg/log_grpc"
+	"github.com/CryptoGu1/books-rest-clean-arch/pkg/mailer"
+	"github.com/sirupsen/logrus"
+)
+
+// ForgotPassword отправляет письмо с токеном сброса. Для неизвестного email ничего не делает
+// и ошибку не возвращает — по ответу нельзя узнать, зарегистрирован ли адрес
+func (s *AuthService) ForgotPassword(ctx context.Context, input domain.ForgotPasswordInput) error {
+	user, err := s.repo.GetByCredentials(ctx, input.Email)
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return nil
+		}
+		return fmt.Errorf("service: forgot password: %w", err)
+	}
+
+	token, err := s.NewRefreshToken()
+	if err != nil {
+		return fmt.Errorf("service: reset token: %w", err)
+	}
+
+	if err := s.resetRepo.Create(ctx, domain.PasswordReset{
+		UserID:    user.ID,
+		TokenHash: domain.HashToken(token),
+		ExpiresAt: time.Now().Add(domain.PasswordResetTTL),
+	}); err != nil {
+		return fmt.Errorf("service: forgot password: %w", err)
+	}
+
+	if err := s.mailer.Send(ctx, mailer.Message{
+		To:      user.Email,
+		Subject: "Password reset",
+		Body: fmt.Sprintf("Hello, %s!\n\nUse this token to reset your password: %s\n"+
+			"It expires in %s. If you did not request a reset, ignore this email.\n",
+			user.Name, token, domain.PasswordResetTTL),
+	}); err != nil {
+		return fmt.Errorf("service: send reset email: %w", err)
+	}
+	return nil
+}
+
+// ResetPassword меняет пароль по одноразовому токену и завершает все сессии пользователя.
+// Токен, пароль, остальные токены сброса и сессии меняются в одной транзакции: новый пароль
+// не вступит в силу, пока старые сессии живы
+func (s *AuthService) ResetPassword(ctx context.Context, input domain.ResetPasswordInput) error {
+	// хеш считаем до транзакции, чтобы не держать её открытой на время bcrypt
+	hashed, err := s.cfg.Hasher.Hash(input.Password)
+	if err != nil {
+		return fmt.Errorf("service: hash password: %w", err)
+	}
+
+	var userID int64
+	err = s.tx.WithinTx(ctx, func(ctx context.Context) error {
+		var err error
+		if userID, err = s.resetRepo.Consume(ctx, domain.HashToken(input.Token)); err != nil {
+			return fmt.Errorf("service: reset password: %w", err)
+		}
+		if err := s.repo.UpdatePassword(ctx, userID, hashed); err != nil {
+			return fmt.Errorf("service: reset password: %w", err)
+		}
+		if err := s.resetRepo.RevokeByUser(ctx, userID); err != nil {
+			return fmt.Errorf("service: revoke reset tokens: %w", err)
+		}
+		if err := s.sessionRepo.DeleteByUser(ctx, userID); err != nil {
+			return fmt.Errorf("service: revoke sessions: %w", err)
+		}
+		return nil
+	})
+	if err != nil {
+		return err
+	}
+
+	if err := s.auditClient.SendLogRequest(ctx, audit.LogItem{
+		Action:    log_grpc.ACTION_PASSWORD_RESET,
+		Entity:    audit.ENTITY_USER,
+		EntityID:  userID,
+		Timestamp: time.Now(),
+	}); err != nil {
+		logrus.WithFields(logrus.Fields{
+			"method": "ResetPassword",
+		}).Error("failed to send log request", err)
+	}
+	return nil
+}
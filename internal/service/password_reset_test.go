@@ -0,0 +1,92 @@
+package service
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"path/filepath"
+	"regexp"
+	"testing"
+
+	"github.com/CryptoGu1/books-rest-clean-arch/internal/domain"
+	"github.com/CryptoGu1/books-rest-clean-arch/mocks"
+	"github.com/CryptoGu1/books-rest-clean-arch/pkg/mailer"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+	"github.com/stretchr/testify/require"
+)
+
+func TestAuthService_PasswordReset(t *testing.T) {
+	s, m := newTestAuthService(t)
+	mailPath := filepath.Join(t.TempDir(), "mail.jsonl")
+	s.mailer = mailer.NewFileMailer(mailPath)
+
+	user := domain.User{ID: 7, Name: "Leo", Email: "leo@example.com"}
+	var stored domain.PasswordReset
+
+	m.users.On("GetByCredentials", mock.Anything, user.Email).Return(user, nil)
+	m.resets.On("Create", mock.Anything, mock.MatchedBy(func(r domain.PasswordReset) bool {
+		stored = r
+		return r.UserID == 7 && len(r.TokenHash) == 64
+	})).Return(nil)
+
+	require.NoError(t, s.ForgotPassword(context.Background(), domain.ForgotPasswordInput{Email: user.Email}))
+
+	messages, err := mailer.ReadMessages(mailPath)
+	require.NoError(t, err)
+	require.Len(t, messages, 1)
+	assert.Equal(t, user.Email, messages[0].To)
+
+	token := regexp.MustCompile(`[0-9a-f]{64}`).FindString(messages[0].Body)
+	require.NotEmpty(t, token)
+	// в базу уходит только хеш, в письмо — сам токен
+	assert.Equal(t, stored.TokenHash, domain.HashToken(token))
+
+	m.resets.On("Consume", mock.Anything, stored.TokenHash).Return(int64(7), nil)
+	m.users.On("UpdatePassword", mock.Anything, int64(7), mock.AnythingOfType("string")).Return(nil)
+	m.resets.On("RevokeByUser", mock.Anything, int64(7)).Return(nil)
+	m.sessions.On("DeleteByUser", mock.Anything, int64(7)).Return(nil)
+	m.audit.On("SendLogRequest", mock.Anything, mock.Anything).Return(nil)
+
+	require.NoError(t, s.ResetPassword(context.Background(), domain.ResetPasswordInput{Token: token, Password: "new-password"}))
+}
+
+func TestAuthService_ForgotPassword_UnknownEmail(t *testing.T) {
+	s, m := newTestAuthService(t)
+	m.users.On("GetByCredentials", mock.Anything, "nobody@example.com").
+		Return(domain.User{}, fmt.Errorf("repo: %w", sql.ErrNoRows))
+
+	assert.NoError(t, s.ForgotPassword(context.Background(), domain.ForgotPasswordInput{Email: "nobody@example.com"}))
+}
+
+func TestAuthService_ResetPassword_InvalidToken(t *testing.T) {
+	s, m := newTestAuthService(t)
+	m.resets.On("Consume", mock.Anything, domain.HashToken("used")).Return(int64(0), domain.ErrInvalidResetToken)
+
+	err := s.ResetPassword(context.Background(), domain.ResetPasswordInput{Token: "used", Password: "new-password"})
+	assert.ErrorIs(t, err, domain.ErrInvalidResetToken)
+}
+
+func TestAuthService_ResetPassword_InTx(t *testing.T) {
+	s, m := newTestAuthService(t)
+
+	inside := false
+	tx := mocks.NewTransactor(t)
+	tx.On("WithinTx", mock.Anything, mock.Anything).Return(func(ctx context.Context, fn func(context.Context) error) error {
+		inside = true
+		defer func() { inside = false }()
+		return fn(ctx)
+	}).Once()
+	s.tx = tx
+
+	inTx := func(mock.Arguments) { assert.True(t, inside) }
+	m.resets.On("Consume", mock.Anything, domain.HashToken("token")).Run(inTx).Return(int64(7), nil)
+	m.users.On("UpdatePassword", mock.Anything, int64(7), mock.AnythingOfType("string")).Run(inTx).Return(nil)
+	m.resets.On("RevokeByUser", mock.Anything, int64(7)).Run(inTx).Return(nil)
+	m.sessions.On("DeleteByUser", mock.Anything, int64(7)).Run(inTx).Return(errors.New("db down"))
+
+	// сессии не удалились — откатываются и пароль, и токен: сброс можно повторить тем же письмом
+	err := s.ResetPassword(context.Background(), domain.ResetPasswordInput{Token: "token", Password: "new-password"})
+	assert.Error(t, err)
+}
@@ -0,0 +1,149 @@
+package service
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"strconv"
+	"time"
+
+	audit "github.com/CryptoGu1/books-grpc-log/pkg/domain"
+	"github.com/CryptoGu1/books-rest-clean-arch/internal/domain"
+	"github.com/CryptoGu1/books-rest-clean-arch/pkg/password"
+	"github.com/sirupsen/logrus"
+)
+
+func (s *AuthService) GetProfile(ctx context.Context, userID int64) (domain.User, error) {
+	user, err := s.repo.GetByID(ctx, userID)
+	if err != nil {
+		return domain.User{}, fmt.Errorf("service: get profile: %w", err)
+	}
+	return user, nil
+}
+
+// UpdateProfile меняет имя сразу, а email — только после перехода по ссылке, отправленной
+// на новый адрес. Для смены email нужен текущий пароль
+func (s *AuthService) UpdateProfile(ctx context.Context, userID int64, input domain.UpdateProfileInput) (domain.User, error) {
+	if input.IsEmpty() {
+		return s.GetProfile(ctx, userID)
+	}
+
+	user, err := s.repo.GetByID(ctx, userID)
+	if err != nil {
+		return domain.User{}, fmt.Errorf("service: update profile: %w", err)
+	}
+
+	if input.Email != nil && *input.Email != user.Email {
+		if err := s.reauthenticate(ctx, user, input.CurrentPassword); err != nil {
+			return domain.User{}, err
+		}
+		if err := s.sendEmailChangeLink(ctx, user, *input.Email); err != nil {
+			return domain.User{}, err
+		}
+		s.auditUser(ctx, "RequestEmailChange", audit.ACTION_UPDATE, userID)
+	}
+
+	if input.Name != nil {
+		user, err = s.repo.UpdateName(ctx, userID, *input.Name)
+		if err != nil {
+			return domain.User{}, fmt.Errorf("service: update profile: %w", err)
+		}
+		s.auditUser(ctx, "UpdateProfile", audit.ACTION_UPDATE, userID)
+	}
+	return user, nil
+}
+
+// ChangePassword меняет пароль после проверки текущего и завершает все сессии пользователя;
+// пароль и сессии меняются в одной транзакции
+func (s *AuthService) ChangePassword(ctx context.Context, userID int64, input domain.ChangePasswordInput) error {
+	user, err := s.repo.GetByID(ctx, userID)
+	if err != nil {
+		return fmt.Errorf("service: change password: %w", err)
+	}
+
+	if err := s.reauthenticate(ctx, user, input.CurrentPassword); err != nil {
+		return err
+	}
+
+	hashed, err := s.cfg.Hasher.Hash(input.NewPassword)
+	if err != nil {
+		return fmt.Errorf("service: hash password: %w", err)
+	}
+
+	err = s.tx.WithinTx(ctx, func(ctx context.Context) error {
+		if err := s.repo.UpdatePassword(ctx, userID, hashed); err != nil {
+			return fmt.Errorf("service: change password: %w", err)
+		}
+		if err := s.sessionRepo.DeleteByUser(ctx, userID); err != nil {
+			return fmt.Errorf("service: revoke sessions: %w", err)
+		}
+		return nil
+	})
+	if err != nil {
+		return err
+	}
+
+	s.auditUser(ctx, "ChangePassword", audit.ACTION_UPDATE, userID)
+	return nil
+}
+
+// DeleteAccount удаляет пользователя и его сессии после проверки пароля;
+// добавленные им книги остаются без владельца
+func (s *AuthService) DeleteAccount(ctx context.Context, userID int64, input domain.DeleteAccountInput) error {
+	user, err := s.repo.GetByID(ctx, userID)
+	if err != nil {
+		return fmt.Errorf("service: delete account: %w", err)
+	}
+
+	if err := s.reauthenticate(ctx, user, input.Password); err != nil {
+		return err
+	}
+
+	err = s.tx.WithinTx(ctx, func(ctx context.Context) error {
+		if err := s.sessionRepo.DeleteByUser(ctx, userID); err != nil {
+			return fmt.Errorf("service: revoke sessions: %w", err)
+		}
+		if err := s.repo.DeleteUser(ctx, userID); err != nil {
+			return fmt.Errorf("service: delete account: %w", err)
+		}
+		return nil
+	})
+	if err != nil {
+		return err
+	}
+
+	s.auditUser(ctx, "DeleteAccount", audit.ACTION_DELETE, userID)
+	return nil
+}
+
+// reauthenticate проверяет текущий пароль перед опасным действием. У проверки свой счётчик
+// неудач: с украденным access-токеном пароль не подобрать
+func (s *AuthService) reauthenticate(ctx context.Context, user domain.User, pass string) error {
+	keys := loginKeys{account: "reauth:" + strconv.FormatInt(user.ID, 10)}
+	if err := s.checkLoginAttempts(ctx, keys); err != nil {
+		return err
+	}
+
+	if _, err := s.cfg.Hasher.Verify(user.Password, pass); err != nil {
+		if errors.Is(err, password.ErrMismatch) {
+			s.registerLoginFailure(ctx, keys, &user)
+			return domain.ErrInvalidPassword
+		}
+		return fmt.Errorf("service: verify password: %w", err)
+	}
+	s.resetLoginAttempts(ctx, keys)
+	return nil
+}
+
+func (s *AuthService) auditUser(ctx context.Context, method, action string, userID int64) {
+	if err := s.auditClient.SendLogRequest(ctx, audit.LogItem{
+		Action:    action,
+		Entity:    audit.ENTITY_USER,
+		EntityID:  userID,
+		Timestamp: time.Now(),
+	}); err != nil {
+		logrus.WithFields(logrus.Fields{
+			"method": method,
+		}).Error("failed to send log request", err)
+	}
+}
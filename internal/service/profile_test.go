@@ -0,0 +1,122 @@
+package service
+
+import (
+	"context"
+	"errors"
+	"net/url"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/CryptoGu1/books-rest-clean-arch/internal/domain"
+	"github.com/CryptoGu1/books-rest-clean-arch/mocks"
+	"github.com/CryptoGu1/books-rest-clean-arch/pkg/mailer"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+	"github.com/stretchr/testify/require"
+	"golang.org/x/crypto/bcrypt"
+)
+
+func profileUser(t *testing.T) domain.User {
+	hash, err := bcrypt.GenerateFromPassword([]byte("password"), bcrypt.MinCost)
+	require.NoError(t, err)
+	return domain.User{ID: 7, Name: "Leo", Email: "leo@example.com", Password: string(hash)}
+}
+
+func TestAuthService_UpdateProfile_EmailChange(t *testing.T) {
+	s, m := newTestAuthService(t)
+	mailPath := filepath.Join(t.TempDir(), "mail.jsonl")
+	s.mailer = mailer.NewFileMailer(mailPath)
+	user := profileUser(t)
+	newEmail := "tolstoy@example.com"
+
+	m.users.On("GetByID", mock.Anything, int64(7)).Return(user, nil)
+	m.audit.On("SendLogRequest", mock.Anything, mock.Anything).Return(nil)
+
+	// без текущего пароля адрес не меняется и письмо не уходит
+	_, err := s.UpdateProfile(context.Background(), 7, domain.UpdateProfileInput{Email: &newEmail, CurrentPassword: "wrong"})
+	assert.ErrorIs(t, err, domain.ErrInvalidPassword)
+
+	// с паролем email остаётся прежним до перехода по ссылке
+	got, err := s.UpdateProfile(context.Background(), 7, domain.UpdateProfileInput{Email: &newEmail, CurrentPassword: "password"})
+	require.NoError(t, err)
+	assert.Equal(t, user.Email, got.Email)
+
+	messages, err := mailer.ReadMessages(mailPath)
+	require.NoError(t, err)
+	require.Len(t, messages, 1)
+	assert.Equal(t, newEmail, messages[0].To)
+
+	_, rawLink, ok := strings.Cut(messages[0].Body, "?")
+	require.True(t, ok)
+	query, err := url.ParseQuery(strings.Fields(rawLink)[0])
+	require.NoError(t, err)
+
+	m.users.On("ChangeEmail", mock.Anything, int64(7), user.Email, newEmail).Return(nil).Once()
+	assert.NoError(t, s.VerifyEmail(context.Background(), query.Get("token")))
+}
+
+func TestAuthService_ChangePassword_Lockout(t *testing.T) {
+	s, m := newTestAuthService(t)
+	s.cfg.Lockout = domain.LockoutPolicy{
+		FreeAttempts:    2,
+		MaxFailures:     2,
+		BaseDelay:       time.Second,
+		MaxDelay:        time.Second,
+		LockoutDuration: time.Minute,
+		Window:          time.Hour,
+	}
+	user := profileUser(t)
+
+	m.users.On("GetByID", mock.Anything, int64(7)).Return(user, nil)
+	m.audit.On("SendLogRequest", mock.Anything, mock.Anything).Return(nil)
+
+	wrong := domain.ChangePasswordInput{CurrentPassword: "wrong", NewPassword: "new-password"}
+	for i := 0; i < 2; i++ {
+		assert.ErrorIs(t, s.ChangePassword(context.Background(), 7, wrong), domain.ErrInvalidPassword)
+	}
+
+	// пока действует блокировка, не проверяется даже верный пароль — и удаление аккаунта тоже
+	right := domain.ChangePasswordInput{CurrentPassword: "password", NewPassword: "new-password"}
+	assert.ErrorIs(t, s.ChangePassword(context.Background(), 7, right), domain.ErrTooManyAttempts)
+	assert.ErrorIs(t, s.DeleteAccount(context.Background(), 7, domain.DeleteAccountInput{Password: "password"}), domain.ErrTooManyAttempts)
+}
+
+func TestAuthService_DeleteAccount(t *testing.T) {
+	s, m := newTestAuthService(t)
+	user := profileUser(t)
+
+	m.users.On("GetByID", mock.Anything, int64(7)).Return(user, nil)
+	assert.ErrorIs(t, s.DeleteAccount(context.Background(), 7, domain.DeleteAccountInput{Password: "wrong"}), domain.ErrInvalidPassword)
+
+	m.sessions.On("DeleteByUser", mock.Anything, int64(7)).Return(nil).Once()
+	m.users.On("DeleteUser", mock.Anything, int64(7)).Return(nil).Once()
+	m.audit.On("SendLogRequest", mock.Anything, mock.Anything).Return(nil)
+	assert.NoError(t, s.DeleteAccount(context.Background(), 7, domain.DeleteAccountInput{Password: "password"}))
+}
+
+func TestAuthService_ChangePassword_InTx(t *testing.T) {
+	s, m := newTestAuthService(t)
+
+	inside := false
+	tx := mocks.NewTransactor(t)
+	tx.On("WithinTx", mock.Anything, mock.Anything).Return(func(ctx context.Context, fn func(context.Context) error) error {
+		inside = true
+		defer func() { inside = false }()
+		return fn(ctx)
+	}).Once()
+	s.tx = tx
+
+	m.users.On("GetByID", mock.Anything, int64(7)).Return(profileUser(t), nil)
+	m.users.On("UpdatePassword", mock.Anything, int64(7), mock.AnythingOfType("string")).Run(func(mock.Arguments) {
+		assert.True(t, inside)
+	}).Return(nil)
+	m.sessions.On("DeleteByUser", mock.Anything, int64(7)).Run(func(mock.Arguments) {
+		assert.True(t, inside)
+	}).Return(errors.New("db down"))
+
+	// сессии не завершились — новый пароль откатывается вместе с ними, аудита нет
+	err := s.ChangePassword(context.Background(), 7, domain.ChangePasswordInput{CurrentPassword: "password", NewPassword: "new-password"})
+	assert.Error(t, err)
+}
@@ -3,6 +3,8 @@ package service
 import (
 	"context"
 	"crypto/rand"
+	"database/sql"
+	"errors"
 	"fmt"
 	"strconv"
 	"time"
@@ -10,37 +12,119 @@ import (
 	audit "github.com/CryptoGu1/books-grpc-log/pkg/domain"
 	"github.com/CryptoGu1/books-rest-clean-arch/internal/domain"
 	"github.com/CryptoGu1/books-rest-clean-arch/internal/repository"
+	"github.com/CryptoGu1/books-rest-clean-arch/pkg/jwtkeys"
+	"github.com/CryptoGu1/books-rest-clean-arch/pkg/log_grpc"
+	"github.com/CryptoGu1/books-rest-clean-arch/pkg/mailer"
+	"github.com/CryptoGu1/books-rest-clean-arch/pkg/password"
 	"github.com/golang-jwt/jwt/v5"
 	"github.com/sirupsen/logrus"
-	"golang.org/x/crypto/bcrypt"
 )
 
 type SessionRepository interface {
 	Create(ctx context.Context, token domain.RefreshSession) error
-	Get(ctx context.Context, token string) (domain.RefreshSession, error)
+	Get(ctx context.Context, tokenHash string) (domain.RefreshSession, error)
+	Revoke(ctx context.Context, id int64) error
+	Delete(ctx context.Context, tokenHash string) error
+	DeleteByUser(ctx context.Context, userID int64) error
+	RevokeFamily(ctx context.Context, userID int64, familyID string) error
+	ListActive(ctx context.Context, userID int64) ([]domain.RefreshSession, error)
 }
 
 type AuditClient interface {
 	SendLogRequest(ctx context.Context, req audit.LogItem) error
 }
+
+type Mailer interface {
+	Send(ctx context.Context, msg mailer.Message) error
+}
+
+// PasswordHasher хеширует и проверяет пароли (см. password.Hasher)
+type PasswordHasher interface {
+	Hash(password string) (string, error)
+	// Verify: rehash — пароль верный, но хеш стоит пересчитать текущим алгоритмом
+	Verify(encoded, password string) (rehash bool, err error)
+}
+
+// TokenSigner подписывает access-токены (см. jwtkeys.KeySet)
+type TokenSigner interface {
+	Sign(claims jwt.Claims) (string, error)
+}
+
+// AuthConfig — настройки AuthService, не являющиеся зависимостями
+type AuthConfig struct {
+	// Secret — HMAC-ключ для ссылок подтверждения email, а без Signer — и для access-токенов (HS256)
+	Secret []byte
+	Signer TokenSigner
+	// VerifyURL — адрес, на который ведёт ссылка подтверждения email; токен добавляется в ?token=
+	VerifyURL        string
+	UnverifiedPolicy domain.UnverifiedPolicy
+	// Lockout — защита от перебора паролей; нулевое значение заменяется domain.DefaultLockoutPolicy
+	Lockout domain.LockoutPolicy
+	// MFAIssuer — имя сервиса в приложении-аутентификаторе
+	MFAIssuer string
+	// Hasher — по умолчанию bcrypt с bcrypt.DefaultCost
+	Hasher PasswordHasher
+	// OIDCProviders — внешние провайдеры входа по имени из маршрута /auth/oidc/:provider
+	OIDCProviders map[string]OIDCProvider
+}
+
 type AuthService struct {
 	repo        repository.UserRepository
+	roleRepo    repository.RoleRepository
 	sessionRepo SessionRepository
+	resetRepo   repository.PasswordResetRepository
+	attempts    repository.LoginAttemptRepository
+	apiKeyRepo  repository.APIKeyRepository
+	mfaRepo     repository.MFARepository
+	identities  repository.IdentityRepository
+	tx          repository.Transactor
+	mailer      Mailer
 	auditClient AuditClient
 	hmacSecret  []byte
+	cfg         AuthConfig
 }
 
-func NewAuthService(repo repository.UserRepository, sessionRepo SessionRepository, auditClient AuditClient, secret []byte) *AuthService {
+func NewAuthService(repo repository.UserRepository, roleRepo repository.RoleRepository, sessionRepo SessionRepository,
+	resetRepo repository.PasswordResetRepository, attempts repository.LoginAttemptRepository,
+	apiKeyRepo repository.APIKeyRepository, mfaRepo repository.MFARepository,
+	identities repository.IdentityRepository, tx repository.Transactor, mailSender Mailer, auditClient AuditClient, cfg AuthConfig) *AuthService {
+	if cfg.UnverifiedPolicy == "" {
+		cfg.UnverifiedPolicy = domain.UnverifiedAllow
+	}
+	if cfg.VerifyURL == "" {
+		cfg.VerifyURL = "/auth/verify"
+	}
+	if cfg.Signer == nil {
+		cfg.Signer = jwtkeys.NewHMAC(cfg.Secret)
+	}
+	if cfg.Lockout == (domain.LockoutPolicy{}) {
+		cfg.Lockout = domain.DefaultLockoutPolicy
+	}
+	if cfg.Hasher == nil {
+		cfg.Hasher = password.NewHasher(password.Bcrypt{})
+	}
+	if cfg.MFAIssuer == "" {
+		cfg.MFAIssuer = "Books"
+	}
 	return &AuthService{
 		repo:        repo,
+		roleRepo:    roleRepo,
 		sessionRepo: sessionRepo,
-		auditClient: auditClient,
-		hmacSecret:  secret,
+		resetRepo:   resetRepo,
+		attempts:    attempts,
+		apiKeyRepo:  apiKeyRepo,
+		mfaRepo:     mfaRepo,
+		identities:  identities,
+		tx:          tx,
+		mailer:      mailSender,
+		auditClient: requestAudit{auditClient},
+		hmacSecret:  cfg.Secret,
+		cfg:         cfg,
 	}
 }
 
 func (s *AuthService) SignUp(ctx context.Context, input domain.SingUpInput) (int, error) {
-	hashed, err := bcrypt.GenerateFromPassword([]byte(input.Password), bcrypt.DefaultCost)
+	hashed, err := s.cfg.Hasher.Hash(input.Password)
 	if err != nil {
 		return 0, fmt.Errorf("service: hash password: %w", err)
 	}
@@ -48,15 +132,27 @@ func (s *AuthService) SignUp(ctx context.Context, input domain.SingUpInput) (int
 	user := &domain.User{
 		Email:        input.Email,
 		Name:         input.Name,
-		Password:     string(hashed),
+		Password:     hashed,
 		RegisteredAt: time.Now(),
 	}
 
-	id, err := s.repo.CreateUser(ctx, *user)
+	// пользователь без роли не прошёл бы авторизацию: создаём и назначаем роль вместе
+	var id int
+	err = s.tx.WithinTx(ctx, func(ctx context.Context) error {
+		var err error
+		if id, err = s.repo.CreateUser(ctx, *user); err != nil {
+			return fmt.Errorf("service: create user: %w", err)
+		}
+		if err := s.roleRepo.AssignRole(ctx, int64(id), domain.RoleReader); err != nil {
+			return fmt.Errorf("service: assign default role: %w", err)
+		}
+		return nil
+	})
 	if err != nil {
-		return 0, fmt.Errorf("service: create user: %w", err)
+		return 0, err
 	}
 
+	user.ID = int64(id)
 	if err = s.auditClient.SendLogRequest(ctx, audit.LogItem{
 		Action:    audit.ACTION_REGISTER,
 		Entity:    audit.ENTITY_USER,
@@ -67,20 +163,88 @@ func (s *AuthService) SignUp(ctx context.Context, input domain.SingUpInput) (int
 			"method": "SignUp",
 		}).Error("failed to send log request", err)
 	}
+
+	// письмо не доставлено — аккаунт всё равно создан, ссылку можно запросить повторно (ResendVerification)
+	if err := s.sendVerificationEmail(ctx, *user); err != nil {
+		logrus.WithFields(logrus.Fields{
+			"method": "SignUp",
+		}).Error("failed to send verification email", err)
+	}
 	return id, nil
 }
 
-func (s *AuthService) SignIn(ctx context.Context, input domain.SingInInput) (string, string, error) {
+// SignIn проверяет пароль. Если у пользователя включена 2FA, вместо токенов возвращается
+// MFAToken, который вместе с кодом обменивается на пару токенов в VerifyMFA
+func (s *AuthService) SignIn(ctx context.Context, input domain.SingInInput) (domain.SignInResult, error) {
+	keys := loginAttemptKeys(ctx, input.Email)
+	if err := s.checkLoginAttempts(ctx, keys); err != nil {
+		return domain.SignInResult{}, err
+	}
 
 	user, err := s.repo.GetByCredentials(ctx, input.Email)
 	if err != nil {
-		return "", "", fmt.Errorf("service: get user by credentials: %w", err)
+		if errors.Is(err, sql.ErrNoRows) {
+			s.registerLoginFailure(ctx, keys, nil)
+		}
+		return domain.SignInResult{}, fmt.Errorf("service: get user by credentials: %w", err)
+	}
+
+	rehash, err := s.cfg.Hasher.Verify(user.Password, input.Password)
+	if err != nil {
+		s.registerLoginFailure(ctx, keys, &user)
+		return domain.SignInResult{}, fmt.Errorf("service: incorrect password: %w", err)
+	}
+	s.resetLoginAttempts(ctx, keys)
+	if rehash {
+		s.rehashPassword(ctx, user.ID, input.Password)
+	}
+
+	if !user.EmailVerified() && s.cfg.UnverifiedPolicy == domain.UnverifiedDeny {
+		return domain.SignInResult{}, domain.ErrEmailNotVerified
 	}
 
-	if err := bcrypt.CompareHashAndPassword([]byte(user.Password), []byte(input.Password)); err != nil {
-		return "", "", fmt.Errorf("service: incorrect password: %w", err)
+	return s.finishSignIn(ctx, user)
+}
+
+// rehashPassword пересчитывает хеш устаревшим алгоритмом или параметрами — пароль в открытом
+// виде есть только в момент входа. Ошибка не мешает входу, попробуем в следующий раз
+func (s *AuthService) rehashPassword(ctx context.Context, userID int64, plain string) {
+	hashed, err := s.cfg.Hasher.Hash(plain)
+	if err == nil {
+		err = s.repo.UpdatePassword(ctx, userID, hashed)
+	}
+	if err != nil {
+		logrus.WithFields(logrus.Fields{
+			"method":  "SignIn",
+			"user_id": userID,
+		}).Error("failed to rehash password", err)
 	}
+}
 
+// finishSignIn — общий хвост входа по паролю и через внешнего провайдера: второй фактор, если включён,
+// иначе сразу пара токенов
+func (s *AuthService) finishSignIn(ctx context.Context, user domain.User) (domain.SignInResult, error) {
+	t, err := s.mfaRepo.GetTOTP(ctx, user.ID)
+	if err != nil && !errors.Is(err, domain.ErrMFANotEnabled) {
+		return domain.SignInResult{}, fmt.Errorf("service: get totp: %w", err)
+	}
+	if err == nil && t.Enabled() {
+		challenge, err := s.mfaChallenge(user.ID)
+		if err != nil {
+			return domain.SignInResult{}, err
+		}
+		return domain.SignInResult{MFAToken: challenge}, nil
+	}
+
+	access, refresh, err := s.completeSignIn(ctx, user)
+	if err != nil {
+		return domain.SignInResult{}, err
+	}
+	return domain.SignInResult{AccessToken: access, RefreshToken: refresh}, nil
+}
+
+// completeSignIn пишет событие входа и открывает новую сессию
+func (s *AuthService) completeSignIn(ctx context.Context, user domain.User) (string, string, error) {
 	if err := s.auditClient.SendLogRequest(ctx, audit.LogItem{
 		Action:    audit.ACTION_LOGIN,
 		Entity:    audit.ENTITY_USER,
@@ -92,19 +256,42 @@ func (s *AuthService) SignIn(ctx context.Context, input domain.SingInInput) (str
 		}).Error("failed to send log request", err)
 	}
 
-	return s.generateTokens(ctx, user.ID)
+	// каждый вход открывает новое семейство refresh-токенов
+	family, err := s.NewRefreshToken()
+	if err != nil {
+		return "", "", fmt.Errorf("service: token family: %w", err)
+	}
+
+	return s.generateTokens(ctx, user, family, time.Now())
 }
 
-func (s *AuthService) generateTokens(ctx context.Context, userId int64) (string, string, error) {
+// generateTokens выпускает пару токенов; familyID и sessionStart переносятся при ротации,
+// IP и User-Agent берутся из текущего запроса
+func (s *AuthService) generateTokens(ctx context.Context, user domain.User, familyID string, sessionStart time.Time) (string, string, error) {
+	userId := user.ID
+	roles, err := s.roleRepo.GetUserRoles(ctx, userId)
+	if err != nil {
+		return "", "", fmt.Errorf("service: get user roles: %w", err)
+	}
+
+	permissions := domain.PermissionNames(roles)
+	// все права в системе — на запись, так что без них остаётся только чтение книг
+	if !user.EmailVerified() && s.cfg.UnverifiedPolicy == domain.UnverifiedReadOnly {
+		permissions = nil
+	}
+
 	// Используем RegisteredClaims — корректные имена полей и форматы
-	claims := &jwt.RegisteredClaims{
-		Subject:   strconv.Itoa(int(userId)),
-		IssuedAt:  jwt.NewNumericDate(time.Now()),
-		ExpiresAt: jwt.NewNumericDate(time.Now().Add(1 * time.Hour)),
+	claims := &domain.AccessClaims{
+		RegisteredClaims: jwt.RegisteredClaims{
+			Subject:   strconv.Itoa(int(userId)),
+			IssuedAt:  jwt.NewNumericDate(time.Now()),
+			ExpiresAt: jwt.NewNumericDate(time.Now().Add(1 * time.Hour)),
+		},
+		Roles:       domain.RoleNames(roles),
+		Permissions: permissions,
 	}
 
-	token := jwt.NewWithClaims(jwt.SigningMethodHS256, claims)
-	accessToken, err := token.SignedString(s.hmacSecret)
+	accessToken, err := s.cfg.Signer.Sign(claims)
 	if err != nil {
 		return "", "", fmt.Errorf("service: sign token: %w", err)
 	}
@@ -114,10 +301,16 @@ func (s *AuthService) generateTokens(ctx context.Context, userId int64) (string,
 		return "", "", fmt.Errorf("service: refresh token: %w", err)
 	}
 
+	client := domain.ClientInfoFromContext(ctx)
 	if err := s.sessionRepo.Create(ctx, domain.RefreshSession{
-		UserID:    userId,
-		Token:     refresh,
-		ExpiresAt: time.Now().Add(time.Hour * 24 * 30),
+		UserID:     userId,
+		TokenHash:  domain.HashToken(refresh),
+		FamilyID:   familyID,
+		UserAgent:  client.UserAgent,
+		IP:         client.IP,
+		CreatedAt:  sessionStart,
+		LastUsedAt: time.Now(),
+		ExpiresAt:  time.Now().Add(time.Hour * 24 * 30),
 	}); err != nil {
 		return "", "", fmt.Errorf("service: create refresh token: %w", err)
 	}
@@ -135,15 +328,215 @@ func (s *AuthService) NewRefreshToken() (string, error) {
 }
 
 func (s *AuthService) RefreshTokens(ctx context.Context, refreshToken string) (string, string, error) {
-	session, err := s.sessionRepo.Get(ctx, refreshToken)
+	session, err := s.sessionRepo.Get(ctx, domain.HashToken(refreshToken))
 	if err != nil {
 		return "", "", fmt.Errorf("service: refresh token: %w", err)
 	}
 
+	// повторное предъявление уже ротированного токена — признак кражи:
+	// отзываем всё семейство, и вору, и владельцу придётся войти заново
+	if session.RevokedAt != nil {
+		return "", "", s.revokeFamily(ctx, session)
+	}
+
 	if session.ExpiresAt.Unix() < time.Now().Unix() {
 		return "", "", domain.ErrRefreshTokenNotFound
 	}
 
-	return s.generateTokens(ctx, session.UserID)
+	// отзыв старого токена и запись нового — одна транзакция: если новый не сохранится,
+	// старый останется действующим и клиент не потеряет сессию
+	var access, refresh string
+	err = s.tx.WithinTx(ctx, func(ctx context.Context) error {
+		if err := s.sessionRepo.Revoke(ctx, session.ID); err != nil {
+			if errors.Is(err, domain.ErrRefreshTokenReused) {
+				return err
+			}
+			return fmt.Errorf("service: rotate refresh token: %w", err)
+		}
 
+		user, err := s.repo.GetByID(ctx, session.UserID)
+		if err != nil {
+			return fmt.Errorf("service: refresh token: %w", err)
+		}
+		if !user.EmailVerified() && s.cfg.UnverifiedPolicy == domain.UnverifiedDeny {
+			return domain.ErrEmailNotVerified
+		}
+
+		access, refresh, err = s.generateTokens(ctx, user, session.FamilyID, session.CreatedAt)
+		return err
+	})
+	if err != nil {
+		// токен успели ротировать параллельно; семейство отзывается уже вне откаченной транзакции
+		if errors.Is(err, domain.ErrRefreshTokenReused) {
+			return "", "", s.revokeFamily(ctx, session)
+		}
+		return "", "", err
+	}
+	return access, refresh, nil
+}
+
+// Logout удаляет refresh-токен текущей сессии. Неизвестный токен не считается ошибкой
+func (s *AuthService) Logout(ctx context.Context, refreshToken string) error {
+	tokenHash := domain.HashToken(refreshToken)
+	session, err := s.sessionRepo.Get(ctx, tokenHash)
+	if err != nil {
+		if errors.Is(err, domain.ErrRefreshTokenNotFound) {
+			return nil
+		}
+		return fmt.Errorf("service: logout: %w", err)
+	}
+
+	if err := s.sessionRepo.Delete(ctx, tokenHash); err != nil {
+		return fmt.Errorf("service: logout: %w", err)
+	}
+
+	if err := s.auditClient.SendLogRequest(ctx, audit.LogItem{
+		Action:    log_grpc.ACTION_LOGOUT,
+		Entity:    audit.ENTITY_USER,
+		EntityID:  session.UserID,
+		Timestamp: time.Now(),
+	}); err != nil {
+		logrus.WithFields(logrus.Fields{
+			"method": "Logout",
+		}).Error("failed to send log request", err)
+	}
+	return nil
+}
+
+// LogoutAll удаляет все refresh-токены пользователя; выданные access-токены живут до истечения срока
+func (s *AuthService) LogoutAll(ctx context.Context, userID int64) error {
+	if err := s.sessionRepo.DeleteByUser(ctx, userID); err != nil {
+		return fmt.Errorf("service: logout all: %w", err)
+	}
+
+	if err := s.auditClient.SendLogRequest(ctx, audit.LogItem{
+		Action:    log_grpc.ACTION_LOGOUT_ALL,
+		Entity:    audit.ENTITY_USER,
+		EntityID:  userID,
+		Timestamp: time.Now(),
+	}); err != nil {
+		logrus.WithFields(logrus.Fields{
+			"method": "LogoutAll",
+		}).Error("failed to send log request", err)
+	}
+	return nil
+}
+
+// ListSessions возвращает активные сессии пользователя; currentToken помечает сессию, из которой пришёл запрос
+func (s *AuthService) ListSessions(ctx context.Context, userID int64, currentToken string) ([]domain.Session, error) {
+	active, err := s.sessionRepo.ListActive(ctx, userID)
+	if err != nil {
+		return nil, fmt.Errorf("service: list sessions: %w", err)
+	}
+
+	var currentHash string
+	if currentToken != "" {
+		currentHash = domain.HashToken(currentToken)
+	}
+
+	sessions := make([]domain.Session, 0, len(active))
+	for _, a := range active {
+		sessions = append(sessions, domain.Session{
+			ID:         a.FamilyID,
+			UserAgent:  a.UserAgent,
+			IP:         a.IP,
+			CreatedAt:  a.CreatedAt,
+			LastUsedAt: a.LastUsedAt,
+			ExpiresAt:  a.ExpiresAt,
+			Current:    currentHash != "" && a.TokenHash == currentHash,
+		})
+	}
+	return sessions, nil
+}
+
+// RevokeSession завершает сессию (семейство токенов); искать сессию можно только среди своих
+func (s *AuthService) RevokeSession(ctx context.Context, userID int64, sessionID string) error {
+	active, err := s.sessionRepo.ListActive(ctx, userID)
+	if err != nil {
+		return fmt.Errorf("service: revoke session: %w", err)
+	}
+
+	found := false
+	for _, a := range active {
+		if a.FamilyID == sessionID {
+			found = true
+			break
+		}
+	}
+	if !found {
+		return domain.ErrSessionNotFound
+	}
+
+	if err := s.sessionRepo.RevokeFamily(ctx, userID, sessionID); err != nil {
+		return fmt.Errorf("service: revoke session: %w", err)
+	}
+
+	if err := s.auditClient.SendLogRequest(ctx, audit.LogItem{
+		Action:    log_grpc.ACTION_LOGOUT,
+		Entity:    audit.ENTITY_USER,
+		EntityID:  userID,
+		Timestamp: time.Now(),
+	}); err != nil {
+		logrus.WithFields(logrus.Fields{
+			"method": "RevokeSession",
+		}).Error("failed to send log request", err)
+	}
+	return nil
+}
+
+func (s *AuthService) revokeFamily(ctx context.Context, session domain.RefreshSession) error {
+	logrus.WithFields(logrus.Fields{
+		"method":  "RefreshTokens",
+		"user_id": session.UserID,
+	}).Warn("refresh token reuse detected, revoking token family")
+
+	if err := s.sessionRepo.RevokeFamily(ctx, session.UserID, session.FamilyID); err != nil {
+		return fmt.Errorf("service: revoke token family: %w", err)
+	}
+	return domain.ErrRefreshTokenReused
+}
+
+func (s *AuthService) GetUserRoles(ctx context.Context, userID int64) ([]domain.Role, error) {
+	roles, err := s.roleRepo.GetUserRoles(ctx, userID)
+	if err != nil {
+		return nil, fmt.Errorf("service: get user roles: %w", err)
+	}
+	return roles, nil
+}
+
+// AssignRole и RevokeRole вступают в силу со следующим access-токеном пользователя
+func (s *AuthService) AssignRole(ctx context.Context, userID int64, role string) error {
+	if err := s.roleRepo.AssignRole(ctx, userID, role); err != nil {
+		return fmt.Errorf("service: assign role: %w", err)
+	}
+
+	s.auditRoleChange(ctx, userID, "AssignRole")
+	return nil
+}
+
+func (s *AuthService) RevokeRole(ctx context.Context, userID int64, role string) error {
+	// администратор не может снять admin сам с себя и остаться без доступа к управлению ролями
+	if principal, ok := domain.PrincipalFromContext(ctx); ok && principal.UserID == userID && role == domain.RoleAdmin {
+		return fmt.Errorf("service: revoke role: %w", domain.ErrForbidden)
+	}
+
+	if err := s.roleRepo.RevokeRole(ctx, userID, role); err != nil {
+		return fmt.Errorf("service: revoke role: %w", err)
+	}
+
+	s.auditRoleChange(ctx, userID, "RevokeRole")
+	return nil
+}
+
+func (s *AuthService) auditRoleChange(ctx context.Context, userID int64, method string) {
+	if err := s.auditClient.SendLogRequest(ctx, audit.LogItem{
+		Action:    audit.ACTION_UPDATE,
+		Entity:    audit.ENTITY_USER,
+		EntityID:  userID,
+		Timestamp: time.Now(),
+	}); err != nil {
+		logrus.WithFields(logrus.Fields{
+			"method": method,
+		}).Error("failed to send log request", err)
+	}
 }
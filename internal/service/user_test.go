@@ -0,0 +1,326 @@
+package service
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	audit "github.com/CryptoGu1/books-grpc-log/pkg/domain"
+	"github.com/CryptoGu1/books-rest-clean-arch/internal/domain"
+	"github.com/CryptoGu1/books-rest-clean-arch/internal/repository"
+	"github.com/CryptoGu1/books-rest-clean-arch/mocks"
+	"github.com/CryptoGu1/books-rest-clean-arch/pkg/password"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+	"github.com/stretchr/testify/require"
+	"golang.org/x/crypto/bcrypt"
+	"google.golang.org/grpc/metadata"
+)
+
+type authMocks struct {
+	users    *mocks.UserRepository
+	roles    *mocks.RoleRepository
+	sessions *mocks.SessionRepository
+	resets   *mocks.PasswordResetRepository
+	attempts *repository.LoginAttemptMemoryRepo
+	apiKeys  *mocks.APIKeyRepository
+	mfa      *mocks.MFARepository
+	idents   *mocks.IdentityRepository
+	tx       *mocks.Transactor
+	mailer   *mocks.Mailer
+	audit    *mocks.AuditClient
+}
+
+func newTestAuthService(t *testing.T) (*AuthService, authMocks) {
+	m := authMocks{
+		users:    mocks.NewUserRepository(t),
+		roles:    mocks.NewRoleRepository(t),
+		sessions: mocks.NewSessionRepository(t),
+		resets:   mocks.NewPasswordResetRepository(t),
+		attempts: repository.NewLoginAttemptMemoryRepo(),
+		apiKeys:  mocks.NewAPIKeyRepository(t),
+		mfa:      mocks.NewMFARepository(t),
+		idents:   mocks.NewIdentityRepository(t),
+		tx:       inTx(t),
+		mailer:   mocks.NewMailer(t),
+		audit:    mocks.NewAuditClient(t),
+	}
+	return NewAuthService(m.users, m.roles, m.sessions, m.resets, m.attempts, m.apiKeys, m.mfa, m.idents, m.tx, m.mailer, m.audit, AuthConfig{
+		Secret: []byte("secret"),
+		// хеши в тестах считаются с bcrypt.MinCost; с ним же сравнивается «актуальность» хеша
+		Hasher: password.NewHasher(password.Bcrypt{Cost: bcrypt.MinCost}),
+	}), m
+}
+
+func TestAuthService_RefreshTokens(t *testing.T) {
+	revokedAt := time.Now().Add(-time.Minute)
+	createdAt := time.Now().Add(-24 * time.Hour)
+	active := domain.RefreshSession{
+		ID:        1,
+		UserID:    7,
+		TokenHash: domain.HashToken("old"),
+		FamilyID:  "family",
+		CreatedAt: createdAt,
+		ExpiresAt: time.Now().Add(time.Hour),
+	}
+
+	type mockBehavior func(m authMocks)
+
+	testTable := []struct {
+		name          string
+		mockBehavior  mockBehavior
+		expectedError error
+		expectFail    bool
+	}{
+		{
+			name: "rotates token within the family",
+			mockBehavior: func(m authMocks) {
+				m.sessions.On("Get", mock.Anything, domain.HashToken("old")).Return(active, nil)
+				m.sessions.On("Revoke", mock.Anything, int64(1)).Return(nil)
+				m.users.On("GetByID", mock.Anything, int64(7)).Return(domain.User{ID: 7, EmailVerifiedAt: &createdAt}, nil)
+				m.roles.On("GetUserRoles", mock.Anything, int64(7)).Return([]domain.Role{}, nil)
+				m.sessions.On("Create", mock.Anything, mock.MatchedBy(func(s domain.RefreshSession) bool {
+					return s.UserID == 7 && s.FamilyID == "family" && s.TokenHash != domain.HashToken("old") && len(s.TokenHash) == 64 &&
+						s.CreatedAt.Equal(createdAt) && s.IP == "10.0.0.1"
+				})).Return(nil)
+			},
+		},
+		{
+			name: "reuse of revoked token revokes the family",
+			mockBehavior: func(m authMocks) {
+				reused := active
+				reused.RevokedAt = &revokedAt
+				m.sessions.On("Get", mock.Anything, domain.HashToken("old")).Return(reused, nil)
+				m.sessions.On("RevokeFamily", mock.Anything, int64(7), "family").Return(nil)
+			},
+			expectedError: domain.ErrRefreshTokenReused,
+		},
+		{
+			name: "concurrent rotation is treated as reuse",
+			mockBehavior: func(m authMocks) {
+				m.sessions.On("Get", mock.Anything, domain.HashToken("old")).Return(active, nil)
+				m.sessions.On("Revoke", mock.Anything, int64(1)).Return(domain.ErrRefreshTokenReused)
+				m.sessions.On("RevokeFamily", mock.Anything, int64(7), "family").Return(nil)
+			},
+			expectedError: domain.ErrRefreshTokenReused,
+		},
+		{
+			name: "expired token",
+			mockBehavior: func(m authMocks) {
+				expired := active
+				expired.ExpiresAt = time.Now().Add(-time.Hour)
+				m.sessions.On("Get", mock.Anything, domain.HashToken("old")).Return(expired, nil)
+			},
+			expectedError: domain.ErrRefreshTokenNotFound,
+		},
+		{
+			name: "unknown token",
+			mockBehavior: func(m authMocks) {
+				m.sessions.On("Get", mock.Anything, domain.HashToken("old")).Return(domain.RefreshSession{}, domain.ErrRefreshTokenNotFound)
+			},
+			expectedError: domain.ErrRefreshTokenNotFound,
+		},
+		{
+			name: "revoke failure",
+			mockBehavior: func(m authMocks) {
+				m.sessions.On("Get", mock.Anything, domain.HashToken("old")).Return(active, nil)
+				m.sessions.On("Revoke", mock.Anything, int64(1)).Return(errors.New("db down"))
+			},
+			expectFail: true,
+		},
+	}
+
+	for _, testCase := range testTable {
+		t.Run(testCase.name, func(t *testing.T) {
+			s, m := newTestAuthService(t)
+			testCase.mockBehavior(m)
+
+			ctx := domain.WithClientInfo(context.Background(), domain.ClientInfo{IP: "10.0.0.1"})
+			access, refresh, err := s.RefreshTokens(ctx, "old")
+
+			if testCase.expectedError != nil || testCase.expectFail {
+				require.Error(t, err)
+				if testCase.expectedError != nil {
+					assert.ErrorIs(t, err, testCase.expectedError)
+				}
+				assert.Empty(t, access)
+				assert.Empty(t, refresh)
+				return
+			}
+
+			require.NoError(t, err)
+			assert.NotEmpty(t, access)
+			assert.NotEqual(t, "old", refresh)
+		})
+	}
+}
+
+func TestAuthService_RefreshTokens_InTx(t *testing.T) {
+	session := domain.RefreshSession{ID: 1, UserID: 7, FamilyID: "family", ExpiresAt: time.Now().Add(time.Hour)}
+
+	newService := func(t *testing.T) (*AuthService, authMocks, *bool) {
+		s, m := newTestAuthService(t)
+		inside := new(bool)
+		tx := mocks.NewTransactor(t)
+		tx.On("WithinTx", mock.Anything, mock.Anything).Return(func(ctx context.Context, fn func(context.Context) error) error {
+			*inside = true
+			defer func() { *inside = false }()
+			return fn(ctx)
+		}).Once()
+		s.tx = tx
+		m.sessions.On("Get", mock.Anything, domain.HashToken("old")).Return(session, nil)
+		return s, m, inside
+	}
+
+	t.Run("new token is not saved", func(t *testing.T) {
+		s, m, inside := newService(t)
+		m.sessions.On("Revoke", mock.Anything, int64(1)).Run(func(mock.Arguments) {
+			assert.True(t, *inside)
+		}).Return(nil)
+		m.users.On("GetByID", mock.Anything, int64(7)).Return(domain.User{ID: 7}, nil)
+		m.roles.On("GetUserRoles", mock.Anything, int64(7)).Return([]domain.Role{}, nil)
+		m.sessions.On("Create", mock.Anything, mock.Anything).Run(func(mock.Arguments) {
+			assert.True(t, *inside)
+		}).Return(errors.New("db down"))
+
+		// ошибка откатывает и отзыв: клиент может повторить запрос со старым токеном
+		_, _, err := s.RefreshTokens(context.Background(), "old")
+		assert.Error(t, err)
+		assert.NotErrorIs(t, err, domain.ErrRefreshTokenReused)
+	})
+
+	t.Run("family is revoked outside the rolled back tx", func(t *testing.T) {
+		s, m, inside := newService(t)
+		m.sessions.On("Revoke", mock.Anything, int64(1)).Return(domain.ErrRefreshTokenReused)
+		m.sessions.On("RevokeFamily", mock.Anything, int64(7), "family").Run(func(mock.Arguments) {
+			assert.False(t, *inside)
+		}).Return(nil)
+
+		_, _, err := s.RefreshTokens(context.Background(), "old")
+		assert.ErrorIs(t, err, domain.ErrRefreshTokenReused)
+	})
+}
+
+func TestAuthService_RevokeSession(t *testing.T) {
+	active := []domain.RefreshSession{
+		{ID: 1, UserID: 7, TokenHash: domain.HashToken("a"), FamilyID: "laptop"},
+		{ID: 2, UserID: 7, TokenHash: domain.HashToken("b"), FamilyID: "phone"},
+	}
+
+	t.Run("own session", func(t *testing.T) {
+		s, m := newTestAuthService(t)
+		m.sessions.On("ListActive", mock.Anything, int64(7)).Return(active, nil)
+		m.sessions.On("RevokeFamily", mock.Anything, int64(7), "phone").Return(nil)
+		m.audit.On("SendLogRequest", mock.Anything, mock.Anything).Return(nil)
+
+		assert.NoError(t, s.RevokeSession(context.Background(), 7, "phone"))
+	})
+
+	t.Run("unknown session", func(t *testing.T) {
+		s, m := newTestAuthService(t)
+		m.sessions.On("ListActive", mock.Anything, int64(7)).Return(active, nil)
+
+		assert.ErrorIs(t, s.RevokeSession(context.Background(), 7, "tablet"), domain.ErrSessionNotFound)
+	})
+}
+
+func TestAuthService_ListSessions_MarksCurrent(t *testing.T) {
+	s, m := newTestAuthService(t)
+	m.sessions.On("ListActive", mock.Anything, int64(7)).Return([]domain.RefreshSession{
+		{ID: 1, UserID: 7, TokenHash: domain.HashToken("a"), FamilyID: "laptop"},
+		{ID: 2, UserID: 7, TokenHash: domain.HashToken("b"), FamilyID: "phone"},
+	}, nil)
+
+	sessions, err := s.ListSessions(context.Background(), 7, "b")
+
+	require.NoError(t, err)
+	require.Len(t, sessions, 2)
+	assert.False(t, sessions[0].Current)
+	assert.True(t, sessions[1].Current)
+	assert.Equal(t, "phone", sessions[1].ID)
+}
+
+func TestAuthService_SignIn_Rehash(t *testing.T) {
+	legacy, err := password.Bcrypt{Cost: bcrypt.MinCost}.Hash("password")
+	require.NoError(t, err)
+	user := domain.User{ID: 7, Email: "leo@example.com", Password: legacy}
+	argon := password.Argon2id{Memory: 1024, Iterations: 1, Parallelism: 1, SaltLength: 16, KeyLength: 32}
+
+	testTable := []struct {
+		name         string
+		hasher       *password.Hasher
+		expectRehash bool
+	}{
+		{name: "legacy bcrypt to argon2id", hasher: password.NewHasher(argon, password.Bcrypt{Cost: bcrypt.MinCost}), expectRehash: true},
+		{name: "hash is current", hasher: password.NewHasher(password.Bcrypt{Cost: bcrypt.MinCost})},
+	}
+
+	for _, testCase := range testTable {
+		t.Run(testCase.name, func(t *testing.T) {
+			s, m := newTestAuthService(t)
+			s.cfg.Hasher = testCase.hasher
+
+			m.users.On("GetByCredentials", mock.Anything, user.Email).Return(user, nil)
+			if testCase.expectRehash {
+				m.users.On("UpdatePassword", mock.Anything, int64(7), mock.MatchedBy(func(hash string) bool {
+					ok, err := argon.Recognizes(hash), argon.Verify(hash, "password")
+					return ok && err == nil
+				})).Return(nil)
+			}
+			m.mfa.On("GetTOTP", mock.Anything, int64(7)).Return(domain.TOTP{}, domain.ErrMFANotEnabled)
+			m.audit.On("SendLogRequest", mock.Anything, mock.Anything).Return(nil)
+			m.roles.On("GetUserRoles", mock.Anything, int64(7)).Return([]domain.Role{}, nil)
+			m.sessions.On("Create", mock.Anything, mock.Anything).Return(nil)
+
+			_, err := s.SignIn(context.Background(), domain.SingInInput{Email: user.Email, Password: "password"})
+			require.NoError(t, err)
+		})
+	}
+}
+
+func TestAuthService_SignUp_InTx(t *testing.T) {
+	s, m := newTestAuthService(t)
+
+	inside := false
+	tx := mocks.NewTransactor(t)
+	tx.On("WithinTx", mock.Anything, mock.Anything).Return(func(ctx context.Context, fn func(context.Context) error) error {
+		inside = true
+		defer func() { inside = false }()
+		return fn(ctx)
+	}).Once()
+	s.tx = tx
+
+	m.users.On("CreateUser", mock.Anything, mock.Anything).Run(func(mock.Arguments) {
+		assert.True(t, inside)
+	}).Return(42, nil)
+	m.roles.On("AssignRole", mock.Anything, int64(42), domain.RoleReader).Run(func(mock.Arguments) {
+		assert.True(t, inside)
+	}).Return(errors.New("db is down"))
+
+	// роль не назначилась — транзакция откатит и пользователя; ни аудита, ни письма
+	_, err := s.SignUp(context.Background(), domain.SingUpInput{Name: "Leo", Email: "leo@example.com", Password: "password"})
+	assert.Error(t, err)
+}
+
+func TestAuthService_SignUp_Audit(t *testing.T) {
+	s, m := newTestAuthService(t)
+
+	m.users.On("CreateUser", mock.Anything, mock.Anything).Return(42, nil)
+	m.roles.On("AssignRole", mock.Anything, int64(42), domain.RoleReader).Return(nil)
+	m.mailer.On("Send", mock.Anything, mock.Anything).Return(nil)
+	// событие о регистрации несёт id созданного пользователя и сведения о запросе
+	m.audit.On("SendLogRequest", mock.MatchedBy(func(ctx context.Context) bool {
+		md, _ := metadata.FromOutgoingContext(ctx)
+		return assert.ObjectsAreEqual([]string{"req-1"}, md.Get("x-audit-request-id")) &&
+			assert.ObjectsAreEqual([]string{"10.0.0.1"}, md.Get("x-audit-client-ip")) &&
+			assert.ObjectsAreEqual([]string{"Читалка/1.0"}, md.Get("x-audit-user-agent-bin"))
+	}), mock.MatchedBy(func(item audit.LogItem) bool {
+		return item.Action == audit.ACTION_REGISTER && item.EntityID == 42
+	})).Return(nil).Once()
+
+	ctx := domain.WithClientInfo(context.Background(), domain.ClientInfo{IP: "10.0.0.1", UserAgent: "Читалка/1.0", RequestID: "req-1"})
+	id, err := s.SignUp(ctx, domain.SingUpInput{Name: "Leo", Email: "leo@example.com", Password: "password"})
+	require.NoError(t, err)
+	assert.Equal(t, 42, id)
+}
@@ -0,0 +1,143 @@
+package service
+
+import (
+	"context"
+	"crypto/hmac"
+	"crypto/sha256"
+	"database/sql"
+	"errors"
+	"fmt"
+	"net/url"
+	"strconv"
+	"time"
+
+	audit "github.com/CryptoGu1/books-grpc-log/pkg/domain"
+	"github.com/CryptoGu1/books-rest-clean-arch/internal/domain"
+	"github.com/CryptoGu1/books-rest-clean-arch/pkg/mailer"
+	"github.com/golang-jwt/jwt/v5"
+)
+
+// VerifyEmail подтверждает адрес по токену из письма, а ссылка смены адреса переключает email
+// на новый. Токен привязан к email: если адрес успел смениться, старая ссылка больше не работает
+func (s *AuthService) VerifyEmail(ctx context.Context, token string) error {
+	var claims domain.VerificationClaims
+	_, err := jwt.ParseWithClaims(token, &claims, func(t *jwt.Token) (interface{}, error) {
+		return s.purposeKey(domain.EmailVerificationAudience), nil
+	}, jwt.WithValidMethods([]string{jwt.SigningMethodHS256.Alg()}),
+		jwt.WithAudience(domain.EmailVerificationAudience),
+		jwt.WithExpirationRequired())
+	if err != nil {
+		return domain.ErrInvalidVerification
+	}
+
+	userID, err := strconv.ParseInt(claims.Subject, 10, 64)
+	if err != nil {
+		return domain.ErrInvalidVerification
+	}
+
+	user, err := s.repo.GetByID(ctx, userID)
+	if err != nil {
+		if errors.Is(err, domain.ErrUserNotFound) {
+			return domain.ErrInvalidVerification
+		}
+		return fmt.Errorf("service: verify email: %w", err)
+	}
+	if user.Email != claims.Email {
+		return domain.ErrInvalidVerification
+	}
+
+	if claims.NewEmail != "" {
+		if err := s.repo.ChangeEmail(ctx, userID, claims.Email, claims.NewEmail); err != nil {
+			if errors.Is(err, domain.ErrUserNotFound) {
+				return domain.ErrInvalidVerification
+			}
+			return fmt.Errorf("service: change email: %w", err)
+		}
+		s.auditUser(ctx, "ChangeEmail", audit.ACTION_UPDATE, userID)
+		return nil
+	}
+
+	if err := s.repo.MarkEmailVerified(ctx, userID); err != nil {
+		return fmt.Errorf("service: verify email: %w", err)
+	}
+	return nil
+}
+
+// ResendVerification повторно отправляет ссылку. Как и ForgotPassword, не сообщает,
+// существует ли адрес; для уже подтверждённого адреса письмо не отправляется
+func (s *AuthService) ResendVerification(ctx context.Context, input domain.ResendVerificationInput) error {
+	user, err := s.repo.GetByCredentials(ctx, input.Email)
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return nil
+		}
+		return fmt.Errorf("service: resend verification: %w", err)
+	}
+	if user.EmailVerified() {
+		return nil
+	}
+	return s.sendVerificationEmail(ctx, user)
+}
+
+func (s *AuthService) sendVerificationEmail(ctx context.Context, user domain.User) error {
+	link, err := s.verificationLink(user, "")
+	if err != nil {
+		return err
+	}
+
+	if err := s.mailer.Send(ctx, mailer.Message{
+		To:      user.Email,
+		Subject: "Confirm your email",
+		Body: fmt.Sprintf("Hello, %s!\n\nConfirm your email by following this link:\n%s\n"+
+			"The link expires in %s.\n", user.Name, link, domain.EmailVerificationTTL),
+	}); err != nil {
+		return fmt.Errorf("service: send verification email: %w", err)
+	}
+	return nil
+}
+
+// sendEmailChangeLink отправляет на новый адрес ссылку, по которой email будет изменён:
+// так адрес нельзя сменить на чужой или опечататься и потерять доступ к аккаунту
+func (s *AuthService) sendEmailChangeLink(ctx context.Context, user domain.User, newEmail string) error {
+	link, err := s.verificationLink(user, newEmail)
+	if err != nil {
+		return err
+	}
+
+	if err := s.mailer.Send(ctx, mailer.Message{
+		To:      newEmail,
+		Subject: "Confirm your new email",
+		Body: fmt.Sprintf("Hello, %s!\n\nFollow this link to use this address for your account:\n%s\n"+
+			"The link expires in %s. If you did not request the change, ignore this email.\n",
+			user.Name, link, domain.EmailVerificationTTL),
+	}); err != nil {
+		return fmt.Errorf("service: send email change link: %w", err)
+	}
+	return nil
+}
+
+func (s *AuthService) verificationLink(user domain.User, newEmail string) (string, error) {
+	claims := &domain.VerificationClaims{
+		RegisteredClaims: jwt.RegisteredClaims{
+			Subject:   strconv.FormatInt(user.ID, 10),
+			Audience:  jwt.ClaimStrings{domain.EmailVerificationAudience},
+			IssuedAt:  jwt.NewNumericDate(time.Now()),
+			ExpiresAt: jwt.NewNumericDate(time.Now().Add(domain.EmailVerificationTTL)),
+		},
+		Email:    user.Email,
+		NewEmail: newEmail,
+	}
+	token, err := jwt.NewWithClaims(jwt.SigningMethodHS256, claims).SignedString(s.purposeKey(domain.EmailVerificationAudience))
+	if err != nil {
+		return "", fmt.Errorf("service: sign verification token: %w", err)
+	}
+	return s.cfg.VerifyURL + "?token=" + url.QueryEscape(token), nil
+}
+
+// purposeKey выводит из общего секрета отдельный ключ для каждого назначения: иначе токен
+// из письма или MFA-токен прошёл бы проверку подписи в JWTMiddleware как access-токен
+func (s *AuthService) purposeKey(purpose string) []byte {
+	mac := hmac.New(sha256.New, s.hmacSecret)
+	mac.Write([]byte(purpose))
+	return mac.Sum(nil)
+}
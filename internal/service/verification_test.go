@@ -0,0 +1,108 @@
+package service
+
+import (
+	"context"
+	"net/url"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/CryptoGu1/books-rest-clean-arch/internal/domain"
+	"github.com/CryptoGu1/books-rest-clean-arch/pkg/mailer"
+	"github.com/golang-jwt/jwt/v5"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+	"github.com/stretchr/testify/require"
+	"golang.org/x/crypto/bcrypt"
+)
+
+func TestAuthService_VerifyEmail(t *testing.T) {
+	s, m := newTestAuthService(t)
+	mailPath := filepath.Join(t.TempDir(), "mail.jsonl")
+	s.mailer = mailer.NewFileMailer(mailPath)
+
+	user := domain.User{ID: 7, Name: "Leo", Email: "leo@example.com"}
+	require.NoError(t, s.sendVerificationEmail(context.Background(), user))
+
+	messages, err := mailer.ReadMessages(mailPath)
+	require.NoError(t, err)
+	require.Len(t, messages, 1)
+
+	_, rawLink, ok := strings.Cut(messages[0].Body, "/auth/verify?")
+	require.True(t, ok)
+	query, err := url.ParseQuery(strings.Fields(rawLink)[0])
+	require.NoError(t, err)
+	token := query.Get("token")
+
+	t.Run("valid link", func(t *testing.T) {
+		m.users.On("GetByID", mock.Anything, int64(7)).Return(user, nil).Once()
+		m.users.On("MarkEmailVerified", mock.Anything, int64(7)).Return(nil).Once()
+
+		assert.NoError(t, s.VerifyEmail(context.Background(), token))
+	})
+
+	t.Run("email changed since", func(t *testing.T) {
+		changed := user
+		changed.Email = "tolstoy@example.com"
+		m.users.On("GetByID", mock.Anything, int64(7)).Return(changed, nil).Once()
+
+		assert.ErrorIs(t, s.VerifyEmail(context.Background(), token), domain.ErrInvalidVerification)
+	})
+
+	t.Run("access token is not a verification link", func(t *testing.T) {
+		access, err := jwt.NewWithClaims(jwt.SigningMethodHS256, jwt.RegisteredClaims{
+			Subject:   "7",
+			ExpiresAt: jwt.NewNumericDate(time.Now().Add(time.Hour)),
+		}).SignedString(s.hmacSecret)
+		require.NoError(t, err)
+
+		assert.ErrorIs(t, s.VerifyEmail(context.Background(), access), domain.ErrInvalidVerification)
+	})
+}
+
+func TestAuthService_SignIn_UnverifiedPolicy(t *testing.T) {
+	hash, err := bcrypt.GenerateFromPassword([]byte("password"), bcrypt.MinCost)
+	require.NoError(t, err)
+	unverified := domain.User{ID: 7, Email: "leo@example.com", Password: string(hash)}
+	librarian := []domain.Role{{Name: domain.RoleLibrarian, Permissions: []string{domain.PermBooksWrite}}}
+
+	testTable := []struct {
+		name          string
+		policy        domain.UnverifiedPolicy
+		expectedError error
+		expectedPerms []string
+	}{
+		{name: "allow", policy: domain.UnverifiedAllow, expectedPerms: []string{domain.PermBooksWrite}},
+		{name: "read only", policy: domain.UnverifiedReadOnly},
+		{name: "deny", policy: domain.UnverifiedDeny, expectedError: domain.ErrEmailNotVerified},
+	}
+
+	for _, testCase := range testTable {
+		t.Run(testCase.name, func(t *testing.T) {
+			s, m := newTestAuthService(t)
+			s.cfg.UnverifiedPolicy = testCase.policy
+
+			m.users.On("GetByCredentials", mock.Anything, unverified.Email).Return(unverified, nil)
+			if testCase.expectedError == nil {
+				m.mfa.On("GetTOTP", mock.Anything, int64(7)).Return(domain.TOTP{}, domain.ErrMFANotEnabled)
+				m.audit.On("SendLogRequest", mock.Anything, mock.Anything).Return(nil)
+				m.roles.On("GetUserRoles", mock.Anything, int64(7)).Return(librarian, nil)
+				m.sessions.On("Create", mock.Anything, mock.Anything).Return(nil)
+			}
+
+			result, err := s.SignIn(context.Background(), domain.SingInInput{Email: unverified.Email, Password: "password"})
+			if testCase.expectedError != nil {
+				assert.ErrorIs(t, err, testCase.expectedError)
+				return
+			}
+			require.NoError(t, err)
+
+			var claims domain.AccessClaims
+			_, err = jwt.ParseWithClaims(result.AccessToken, &claims, func(*jwt.Token) (interface{}, error) { return s.hmacSecret, nil })
+			require.NoError(t, err)
+			assert.Equal(t, testCase.expectedPerms, claims.Permissions)
+			assert.Equal(t, []string{domain.RoleLibrarian}, claims.Roles)
+		})
+	}
+}
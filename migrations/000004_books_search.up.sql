@@ -0,0 +1,9 @@
+-- 'simple' не зависит от языка и не стеммит, поэтому подходит для смешанного каталога;
+-- язык из конфига применяется к поисковому запросу, а префиксный поиск (:*) покрывает словоформы
+ALTER TABLE books
+    ADD COLUMN search_vector tsvector GENERATED ALWAYS AS (
+        setweight(to_tsvector('simple', coalesce(title, '')), 'A') ||
+        setweight(to_tsvector('simple', coalesce(author, '')), 'B')
+    ) STORED;
+
+CREATE INDEX idx_books_search_vector ON books USING GIN (search_vector);
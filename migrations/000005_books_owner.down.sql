@@ -0,0 +1,2 @@
+DROP INDEX IF EXISTS idx_books_created_by;
+ALTER TABLE books DROP COLUMN IF EXISTS created_by;
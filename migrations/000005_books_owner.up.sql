@@ -0,0 +1,4 @@
+-- у существующих книг владельца нет: менять их может только администратор
+ALTER TABLE books ADD COLUMN created_by INT REFERENCES users(id) ON DELETE SET NULL;
+
+CREATE INDEX idx_books_created_by ON books(created_by);
@@ -0,0 +1,35 @@
+CREATE TABLE roles (
+    id   SERIAL PRIMARY KEY,
+    name VARCHAR(50) NOT NULL UNIQUE
+);
+
+CREATE TABLE permissions (
+    id   SERIAL PRIMARY KEY,
+    name VARCHAR(100) NOT NULL UNIQUE
+);
+
+CREATE TABLE role_permissions (
+    role_id       INT NOT NULL REFERENCES roles(id) ON DELETE CASCADE,
+    permission_id INT NOT NULL REFERENCES permissions(id) ON DELETE CASCADE,
+    PRIMARY KEY (role_id, permission_id)
+);
+
+CREATE TABLE user_roles (
+    user_id INT NOT NULL REFERENCES users(id) ON DELETE CASCADE,
+    role_id INT NOT NULL REFERENCES roles(id) ON DELETE CASCADE,
+    PRIMARY KEY (user_id, role_id)
+);
+
+INSERT INTO roles (name) VALUES ('reader'), ('librarian'), ('admin');
+INSERT INTO permissions (name) VALUES ('books:write'), ('roles:manage');
+
+INSERT INTO role_permissions (role_id, permission_id)
+SELECT r.id, p.id FROM roles r, permissions p
+WHERE (r.name = 'librarian' AND p.name = 'books:write')
+   OR r.name = 'admin';
+
+-- все существующие пользователи становятся читателями;
+-- первого администратора назначаем вручную:
+-- INSERT INTO user_roles (user_id, role_id) SELECT <id>, id FROM roles WHERE name = 'admin';
+INSERT INTO user_roles (user_id, role_id)
+SELECT u.id, r.id FROM users u, roles r WHERE r.name = 'reader';
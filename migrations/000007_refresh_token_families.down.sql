@@ -0,0 +1,5 @@
+DROP INDEX IF EXISTS idx_refresh_tokens_user_family;
+DROP INDEX IF EXISTS idx_refresh_tokens_token;
+ALTER TABLE refresh_tokens
+    DROP COLUMN IF EXISTS revoked_at,
+    DROP COLUMN IF EXISTS family_id;
@@ -0,0 +1,10 @@
+-- family_id связывает все токены, полученные ротацией из одного входа;
+-- revoked_at помечает использованные токены, чтобы распознать их повторное предъявление
+ALTER TABLE refresh_tokens
+    ADD COLUMN family_id VARCHAR(64) NOT NULL DEFAULT gen_random_uuid()::text,
+    ADD COLUMN revoked_at TIMESTAMP;
+
+ALTER TABLE refresh_tokens ALTER COLUMN family_id DROP DEFAULT;
+
+CREATE INDEX idx_refresh_tokens_token ON refresh_tokens(token);
+CREATE INDEX idx_refresh_tokens_user_family ON refresh_tokens(user_id, family_id);
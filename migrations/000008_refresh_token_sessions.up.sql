@@ -0,0 +1,6 @@
+-- created_at — начало сессии (сохраняется при ротации), last_used_at — последняя ротация
+ALTER TABLE refresh_tokens
+    ADD COLUMN user_agent   TEXT        NOT NULL DEFAULT '',
+    ADD COLUMN ip           VARCHAR(64) NOT NULL DEFAULT '',
+    ADD COLUMN created_at   TIMESTAMP   NOT NULL DEFAULT now(),
+    ADD COLUMN last_used_at TIMESTAMP   NOT NULL DEFAULT now();
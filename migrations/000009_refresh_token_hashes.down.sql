@@ -0,0 +1,9 @@
+-- хеш не обратить: откат инвалидирует все сессии
+DELETE FROM refresh_tokens;
+
+DROP INDEX IF EXISTS idx_refresh_tokens_token_hash;
+
+ALTER TABLE refresh_tokens ALTER COLUMN token_hash TYPE VARCHAR(255);
+ALTER TABLE refresh_tokens RENAME COLUMN token_hash TO token;
+
+CREATE INDEX idx_refresh_tokens_token ON refresh_tokens(token);
@@ -0,0 +1,10 @@
+-- refresh-токены хранятся только в виде SHA-256 (hex). Существующие строки перехешируются
+-- на месте, поэтому активные сессии переживают миграцию
+ALTER TABLE refresh_tokens RENAME COLUMN token TO token_hash;
+
+UPDATE refresh_tokens SET token_hash = encode(sha256(convert_to(token_hash, 'UTF8')), 'hex');
+
+ALTER TABLE refresh_tokens ALTER COLUMN token_hash TYPE CHAR(64);
+
+DROP INDEX IF EXISTS idx_refresh_tokens_token;
+CREATE UNIQUE INDEX idx_refresh_tokens_token_hash ON refresh_tokens(token_hash);
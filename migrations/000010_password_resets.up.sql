@@ -0,0 +1,11 @@
+-- одноразовые токены сброса пароля; как и refresh-токены, хранятся только в виде SHA-256
+CREATE TABLE password_resets (
+    id         SERIAL PRIMARY KEY,
+    user_id    INT       NOT NULL REFERENCES users(id) ON DELETE CASCADE,
+    token_hash CHAR(64)  NOT NULL UNIQUE,
+    created_at TIMESTAMP NOT NULL DEFAULT now(),
+    expires_at TIMESTAMP NOT NULL,
+    used_at    TIMESTAMP
+);
+
+CREATE INDEX idx_password_resets_user ON password_resets(user_id);
@@ -0,0 +1,4 @@
+-- уже зарегистрированные пользователи считаются подтверждёнными, чтобы не заблокировать их
+ALTER TABLE users ADD COLUMN email_verified_at TIMESTAMP;
+
+UPDATE users SET email_verified_at = COALESCE(registered_at, now());
@@ -0,0 +1,9 @@
+-- счётчики неудачных входов; key — "email:<адрес>" или "ip:<адрес>"
+CREATE TABLE login_attempts (
+    key              VARCHAR(320) PRIMARY KEY,
+    failures         INT       NOT NULL,
+    first_failure_at TIMESTAMP NOT NULL,
+    last_failure_at  TIMESTAMP NOT NULL
+);
+
+CREATE INDEX idx_login_attempts_last_failure ON login_attempts(last_failure_at);
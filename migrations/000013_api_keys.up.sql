@@ -0,0 +1,15 @@
+-- персональные API-ключи; сам ключ не хранится, только SHA-256 и короткий префикс для отображения
+CREATE TABLE api_keys (
+    id           SERIAL PRIMARY KEY,
+    user_id      INT          NOT NULL REFERENCES users(id) ON DELETE CASCADE,
+    name         VARCHAR(100) NOT NULL,
+    prefix       VARCHAR(16)  NOT NULL,
+    key_hash     CHAR(64)     NOT NULL UNIQUE,
+    scopes       TEXT[]       NOT NULL DEFAULT '{}',
+    created_at   TIMESTAMP    NOT NULL DEFAULT now(),
+    last_used_at TIMESTAMP,
+    expires_at   TIMESTAMP,
+    revoked_at   TIMESTAMP
+);
+
+CREATE INDEX idx_api_keys_user ON api_keys(user_id);
@@ -0,0 +1,18 @@
+-- TOTP-секрет пользователя; confirmed_at IS NULL — регистрация начата, но код ещё не подтверждён.
+-- last_used_step — последний принятый 30-секундный интервал, чтобы один код нельзя было использовать дважды
+CREATE TABLE user_totp (
+    user_id        INT       PRIMARY KEY REFERENCES users(id) ON DELETE CASCADE,
+    secret         TEXT      NOT NULL,
+    created_at     TIMESTAMP NOT NULL DEFAULT now(),
+    confirmed_at   TIMESTAMP,
+    last_used_step BIGINT    NOT NULL DEFAULT 0
+);
+
+-- резервные коды хранятся только в виде SHA-256
+CREATE TABLE mfa_recovery_codes (
+    id        SERIAL PRIMARY KEY,
+    user_id   INT       NOT NULL REFERENCES users(id) ON DELETE CASCADE,
+    code_hash CHAR(64)  NOT NULL,
+    used_at   TIMESTAMP,
+    UNIQUE (user_id, code_hash)
+);
@@ -0,0 +1,11 @@
+-- привязка аккаунта к внешнему OIDC-провайдеру: (provider, subject) однозначно определяют пользователя у провайдера
+CREATE TABLE user_identities (
+    provider   TEXT      NOT NULL,
+    subject    TEXT      NOT NULL,
+    user_id    INT       NOT NULL REFERENCES users(id) ON DELETE CASCADE,
+    email      TEXT      NOT NULL,
+    created_at TIMESTAMP NOT NULL DEFAULT now(),
+    PRIMARY KEY (provider, subject)
+);
+
+CREATE INDEX user_identities_user_id_idx ON user_identities (user_id);
@@ -0,0 +1,18 @@
+-- transactional outbox: событие пишется в одной транзакции с изменением, relay досылает его на сервер логов
+CREATE TABLE audit_outbox (
+    id              BIGSERIAL   PRIMARY KEY,
+    -- ключ идемпотентности, уходит на сервер логов вместе с событием
+    event_id        UUID        NOT NULL DEFAULT gen_random_uuid() UNIQUE,
+    entity          TEXT        NOT NULL,
+    action          TEXT        NOT NULL,
+    entity_id       BIGINT      NOT NULL,
+    metadata        JSONB       NOT NULL DEFAULT '{}',
+    occurred_at     TIMESTAMPTZ NOT NULL,
+    attempts        INT         NOT NULL DEFAULT 0,
+    next_attempt_at TIMESTAMPTZ NOT NULL DEFAULT now(),
+    last_error      TEXT,
+    delivered_at    TIMESTAMPTZ
+);
+
+CREATE INDEX audit_outbox_pending_idx ON audit_outbox (next_attempt_at, id) WHERE delivered_at IS NULL;
+CREATE INDEX audit_outbox_delivered_idx ON audit_outbox (delivered_at) WHERE delivered_at IS NOT NULL;
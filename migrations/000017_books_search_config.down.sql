@@ -0,0 +1,13 @@
+DROP TRIGGER IF EXISTS books_search_vector_update ON books;
+DROP FUNCTION IF EXISTS books_search_vector_update();
+DROP FUNCTION IF EXISTS books_search_vector(TEXT, TEXT);
+ALTER TABLE books DROP COLUMN IF EXISTS search_vector;
+DROP TABLE IF EXISTS search_settings;
+
+ALTER TABLE books
+    ADD COLUMN search_vector tsvector GENERATED ALWAYS AS (
+        setweight(to_tsvector('simple', coalesce(title, '')), 'A') ||
+        setweight(to_tsvector('simple', coalesce(author, '')), 'B')
+    ) STORED;
+
+CREATE INDEX idx_books_search_vector ON books USING GIN (search_vector);
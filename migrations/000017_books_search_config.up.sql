@@ -0,0 +1,34 @@
+-- конфигурация текстового поиска хранится в базе: вектор в books и поисковый запрос
+-- строятся одной и той же конфигурацией. Приложение при старте записывает сюда search.language
+CREATE TABLE search_settings (
+    singleton BOOLEAN   PRIMARY KEY DEFAULT TRUE CHECK (singleton),
+    config    REGCONFIG NOT NULL DEFAULT 'simple'
+);
+INSERT INTO search_settings DEFAULT VALUES;
+
+-- сгенерированная колонка не может читать другую таблицу, поэтому вектор считает триггер
+ALTER TABLE books DROP COLUMN search_vector;
+ALTER TABLE books ADD COLUMN search_vector tsvector;
+
+CREATE FUNCTION books_search_vector(title TEXT, author TEXT) RETURNS tsvector
+LANGUAGE sql STABLE AS $$
+    SELECT setweight(to_tsvector(s.config, coalesce(title, '')), 'A') ||
+           setweight(to_tsvector(s.config, coalesce(author, '')), 'B')
+    FROM search_settings s
+$$;
+
+CREATE FUNCTION books_search_vector_update() RETURNS trigger
+LANGUAGE plpgsql AS $$
+BEGIN
+    NEW.search_vector := books_search_vector(NEW.title, NEW.author);
+    RETURN NEW;
+END
+$$;
+
+CREATE TRIGGER books_search_vector_update
+    BEFORE INSERT OR UPDATE OF title, author ON books
+    FOR EACH ROW EXECUTE FUNCTION books_search_vector_update();
+
+UPDATE books SET search_vector = books_search_vector(title, author);
+
+CREATE INDEX idx_books_search_vector ON books USING GIN (search_vector);
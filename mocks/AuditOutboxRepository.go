@@ -0,0 +1,143 @@
+// Code generated by mockery v2.53.5. DO NOT EDIT.
+
+package mocks
+
+import (
+	context "context"
+
+	domain "github.com/CryptoGu1/books-rest-clean-arch/internal/domain"
+	mock "github.com/stretchr/testify/mock"
+
+	time "time"
+)
+
+// AuditOutboxRepository is an autogenerated mock type for the AuditOutboxRepository type
+type AuditOutboxRepository struct {
+	mock.Mock
+}
+
+// Add provides a mock function with given fields: ctx, event
+func (_m *AuditOutboxRepository) Add(ctx context.Context, event domain.AuditEvent) error {
+	ret := _m.Called(ctx, event)
+
+	if len(ret) == 0 {
+		panic("no return value specified for Add")
+	}
+
+	var r0 error
+	if rf, ok := ret.Get(0).(func(context.Context, domain.AuditEvent) error); ok {
+		r0 = rf(ctx, event)
+	} else {
+		r0 = ret.Error(0)
+	}
+
+	return r0
+}
+
+// ClaimPending provides a mock function with given fields: ctx, limit, leaseUntil
+func (_m *AuditOutboxRepository) ClaimPending(ctx context.Context, limit int, leaseUntil time.Time) ([]domain.AuditEvent, error) {
+	ret := _m.Called(ctx, limit, leaseUntil)
+
+	if len(ret) == 0 {
+		panic("no return value specified for ClaimPending")
+	}
+
+	var r0 []domain.AuditEvent
+	var r1 error
+	if rf, ok := ret.Get(0).(func(context.Context, int, time.Time) ([]domain.AuditEvent, error)); ok {
+		return rf(ctx, limit, leaseUntil)
+	}
+	if rf, ok := ret.Get(0).(func(context.Context, int, time.Time) []domain.AuditEvent); ok {
+		r0 = rf(ctx, limit, leaseUntil)
+	} else {
+		if ret.Get(0) != nil {
+			r0 = ret.Get(0).([]domain.AuditEvent)
+		}
+	}
+
+	if rf, ok := ret.Get(1).(func(context.Context, int, time.Time) error); ok {
+		r1 = rf(ctx, limit, leaseUntil)
+	} else {
+		r1 = ret.Error(1)
+	}
+
+	return r0, r1
+}
+
+// MarkDelivered provides a mock function with given fields: ctx, eventID
+func (_m *AuditOutboxRepository) MarkDelivered(ctx context.Context, eventID string) error {
+	ret := _m.Called(ctx, eventID)
+
+	if len(ret) == 0 {
+		panic("no return value specified for MarkDelivered")
+	}
+
+	var r0 error
+	if rf, ok := ret.Get(0).(func(context.Context, string) error); ok {
+		r0 = rf(ctx, eventID)
+	} else {
+		r0 = ret.Error(0)
+	}
+
+	return r0
+}
+
+// MarkFailed provides a mock function with given fields: ctx, eventID, nextAttempt, reason
+func (_m *AuditOutboxRepository) MarkFailed(ctx context.Context, eventID string, nextAttempt time.Time, reason string) error {
+	ret := _m.Called(ctx, eventID, nextAttempt, reason)
+
+	if len(ret) == 0 {
+		panic("no return value specified for MarkFailed")
+	}
+
+	var r0 error
+	if rf, ok := ret.Get(0).(func(context.Context, string, time.Time, string) error); ok {
+		r0 = rf(ctx, eventID, nextAttempt, reason)
+	} else {
+		r0 = ret.Error(0)
+	}
+
+	return r0
+}
+
+// PurgeDelivered provides a mock function with given fields: ctx, before
+func (_m *AuditOutboxRepository) PurgeDelivered(ctx context.Context, before time.Time) (int64, error) {
+	ret := _m.Called(ctx, before)
+
+	if len(ret) == 0 {
+		panic("no return value specified for PurgeDelivered")
+	}
+
+	var r0 int64
+	var r1 error
+	if rf, ok := ret.Get(0).(func(context.Context, time.Time) (int64, error)); ok {
+		return rf(ctx, before)
+	}
+	if rf, ok := ret.Get(0).(func(context.Context, time.Time) int64); ok {
+		r0 = rf(ctx, before)
+	} else {
+		r0 = ret.Get(0).(int64)
+	}
+
+	if rf, ok := ret.Get(1).(func(context.Context, time.Time) error); ok {
+		r1 = rf(ctx, before)
+	} else {
+		r1 = ret.Error(1)
+	}
+
+	return r0, r1
+}
+
+// NewAuditOutboxRepository creates a new instance of AuditOutboxRepository. It also registers a testing interface on the mock and a cleanup function to assert the mocks expectations.
+// The first argument is typically a *testing.T value.
+func NewAuditOutboxRepository(t interface {
+	mock.TestingT
+	Cleanup(func())
+}) *AuditOutboxRepository {
+	mock := &AuditOutboxRepository{}
+	mock.Mock.Test(t)
+
+	t.Cleanup(func() { mock.AssertExpectations(t) })
+
+	return mock
+}
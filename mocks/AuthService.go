@@ -15,6 +15,415 @@ type AuthService struct {
 	mock.Mock
 }
 
+// AssignRole provides a mock function with given fields: ctx, userID, role
+func (_m *AuthService) AssignRole(ctx context.Context, userID int64, role string) error {
+	ret := _m.Called(ctx, userID, role)
+
+	if len(ret) == 0 {
+		panic("no return value specified for AssignRole")
+	}
+
+	var r0 error
+	if rf, ok := ret.Get(0).(func(context.Context, int64, string) error); ok {
+		r0 = rf(ctx, userID, role)
+	} else {
+		r0 = ret.Error(0)
+	}
+
+	return r0
+}
+
+// AuthenticateAPIKey provides a mock function with given fields: ctx, rawKey
+func (_m *AuthService) AuthenticateAPIKey(ctx context.Context, rawKey string) (domain.Principal, error) {
+	ret := _m.Called(ctx, rawKey)
+
+	if len(ret) == 0 {
+		panic("no return value specified for AuthenticateAPIKey")
+	}
+
+	var r0 domain.Principal
+	var r1 error
+	if rf, ok := ret.Get(0).(func(context.Context, string) (domain.Principal, error)); ok {
+		return rf(ctx, rawKey)
+	}
+	if rf, ok := ret.Get(0).(func(context.Context, string) domain.Principal); ok {
+		r0 = rf(ctx, rawKey)
+	} else {
+		r0 = ret.Get(0).(domain.Principal)
+	}
+
+	if rf, ok := ret.Get(1).(func(context.Context, string) error); ok {
+		r1 = rf(ctx, rawKey)
+	} else {
+		r1 = ret.Error(1)
+	}
+
+	return r0, r1
+}
+
+// ChangePassword provides a mock function with given fields: ctx, userID, input
+func (_m *AuthService) ChangePassword(ctx context.Context, userID int64, input domain.ChangePasswordInput) error {
+	ret := _m.Called(ctx, userID, input)
+
+	if len(ret) == 0 {
+		panic("no return value specified for ChangePassword")
+	}
+
+	var r0 error
+	if rf, ok := ret.Get(0).(func(context.Context, int64, domain.ChangePasswordInput) error); ok {
+		r0 = rf(ctx, userID, input)
+	} else {
+		r0 = ret.Error(0)
+	}
+
+	return r0
+}
+
+// ConfirmTOTP provides a mock function with given fields: ctx, userID, code
+func (_m *AuthService) ConfirmTOTP(ctx context.Context, userID int64, code string) error {
+	ret := _m.Called(ctx, userID, code)
+
+	if len(ret) == 0 {
+		panic("no return value specified for ConfirmTOTP")
+	}
+
+	var r0 error
+	if rf, ok := ret.Get(0).(func(context.Context, int64, string) error); ok {
+		r0 = rf(ctx, userID, code)
+	} else {
+		r0 = ret.Error(0)
+	}
+
+	return r0
+}
+
+// CreateAPIKey provides a mock function with given fields: ctx, principal, input
+func (_m *AuthService) CreateAPIKey(ctx context.Context, principal domain.Principal, input domain.CreateAPIKeyInput) (domain.CreatedAPIKey, error) {
+	ret := _m.Called(ctx, principal, input)
+
+	if len(ret) == 0 {
+		panic("no return value specified for CreateAPIKey")
+	}
+
+	var r0 domain.CreatedAPIKey
+	var r1 error
+	if rf, ok := ret.Get(0).(func(context.Context, domain.Principal, domain.CreateAPIKeyInput) (domain.CreatedAPIKey, error)); ok {
+		return rf(ctx, principal, input)
+	}
+	if rf, ok := ret.Get(0).(func(context.Context, domain.Principal, domain.CreateAPIKeyInput) domain.CreatedAPIKey); ok {
+		r0 = rf(ctx, principal, input)
+	} else {
+		r0 = ret.Get(0).(domain.CreatedAPIKey)
+	}
+
+	if rf, ok := ret.Get(1).(func(context.Context, domain.Principal, domain.CreateAPIKeyInput) error); ok {
+		r1 = rf(ctx, principal, input)
+	} else {
+		r1 = ret.Error(1)
+	}
+
+	return r0, r1
+}
+
+// DeleteAccount provides a mock function with given fields: ctx, userID, input
+func (_m *AuthService) DeleteAccount(ctx context.Context, userID int64, input domain.DeleteAccountInput) error {
+	ret := _m.Called(ctx, userID, input)
+
+	if len(ret) == 0 {
+		panic("no return value specified for DeleteAccount")
+	}
+
+	var r0 error
+	if rf, ok := ret.Get(0).(func(context.Context, int64, domain.DeleteAccountInput) error); ok {
+		r0 = rf(ctx, userID, input)
+	} else {
+		r0 = ret.Error(0)
+	}
+
+	return r0
+}
+
+// DisableTOTP provides a mock function with given fields: ctx, userID, code
+func (_m *AuthService) DisableTOTP(ctx context.Context, userID int64, code string) error {
+	ret := _m.Called(ctx, userID, code)
+
+	if len(ret) == 0 {
+		panic("no return value specified for DisableTOTP")
+	}
+
+	var r0 error
+	if rf, ok := ret.Get(0).(func(context.Context, int64, string) error); ok {
+		r0 = rf(ctx, userID, code)
+	} else {
+		r0 = ret.Error(0)
+	}
+
+	return r0
+}
+
+// EnrollTOTP provides a mock function with given fields: ctx, userID
+func (_m *AuthService) EnrollTOTP(ctx context.Context, userID int64) (domain.TOTPEnrollment, error) {
+	ret := _m.Called(ctx, userID)
+
+	if len(ret) == 0 {
+		panic("no return value specified for EnrollTOTP")
+	}
+
+	var r0 domain.TOTPEnrollment
+	var r1 error
+	if rf, ok := ret.Get(0).(func(context.Context, int64) (domain.TOTPEnrollment, error)); ok {
+		return rf(ctx, userID)
+	}
+	if rf, ok := ret.Get(0).(func(context.Context, int64) domain.TOTPEnrollment); ok {
+		r0 = rf(ctx, userID)
+	} else {
+		r0 = ret.Get(0).(domain.TOTPEnrollment)
+	}
+
+	if rf, ok := ret.Get(1).(func(context.Context, int64) error); ok {
+		r1 = rf(ctx, userID)
+	} else {
+		r1 = ret.Error(1)
+	}
+
+	return r0, r1
+}
+
+// ForgotPassword provides a mock function with given fields: ctx, input
+func (_m *AuthService) ForgotPassword(ctx context.Context, input domain.ForgotPasswordInput) error {
+	ret := _m.Called(ctx, input)
+
+	if len(ret) == 0 {
+		panic("no return value specified for ForgotPassword")
+	}
+
+	var r0 error
+	if rf, ok := ret.Get(0).(func(context.Context, domain.ForgotPasswordInput) error); ok {
+		r0 = rf(ctx, input)
+	} else {
+		r0 = ret.Error(0)
+	}
+
+	return r0
+}
+
+// GetProfile provides a mock function with given fields: ctx, userID
+func (_m *AuthService) GetProfile(ctx context.Context, userID int64) (domain.User, error) {
+	ret := _m.Called(ctx, userID)
+
+	if len(ret) == 0 {
+		panic("no return value specified for GetProfile")
+	}
+
+	var r0 domain.User
+	var r1 error
+	if rf, ok := ret.Get(0).(func(context.Context, int64) (domain.User, error)); ok {
+		return rf(ctx, userID)
+	}
+	if rf, ok := ret.Get(0).(func(context.Context, int64) domain.User); ok {
+		r0 = rf(ctx, userID)
+	} else {
+		r0 = ret.Get(0).(domain.User)
+	}
+
+	if rf, ok := ret.Get(1).(func(context.Context, int64) error); ok {
+		r1 = rf(ctx, userID)
+	} else {
+		r1 = ret.Error(1)
+	}
+
+	return r0, r1
+}
+
+// GetUserRoles provides a mock function with given fields: ctx, userID
+func (_m *AuthService) GetUserRoles(ctx context.Context, userID int64) ([]domain.Role, error) {
+	ret := _m.Called(ctx, userID)
+
+	if len(ret) == 0 {
+		panic("no return value specified for GetUserRoles")
+	}
+
+	var r0 []domain.Role
+	var r1 error
+	if rf, ok := ret.Get(0).(func(context.Context, int64) ([]domain.Role, error)); ok {
+		return rf(ctx, userID)
+	}
+	if rf, ok := ret.Get(0).(func(context.Context, int64) []domain.Role); ok {
+		r0 = rf(ctx, userID)
+	} else {
+		if ret.Get(0) != nil {
+			r0 = ret.Get(0).([]domain.Role)
+		}
+	}
+
+	if rf, ok := ret.Get(1).(func(context.Context, int64) error); ok {
+		r1 = rf(ctx, userID)
+	} else {
+		r1 = ret.Error(1)
+	}
+
+	return r0, r1
+}
+
+// ListAPIKeys provides a mock function with given fields: ctx, userID
+func (_m *AuthService) ListAPIKeys(ctx context.Context, userID int64) ([]domain.APIKey, error) {
+	ret := _m.Called(ctx, userID)
+
+	if len(ret) == 0 {
+		panic("no return value specified for ListAPIKeys")
+	}
+
+	var r0 []domain.APIKey
+	var r1 error
+	if rf, ok := ret.Get(0).(func(context.Context, int64) ([]domain.APIKey, error)); ok {
+		return rf(ctx, userID)
+	}
+	if rf, ok := ret.Get(0).(func(context.Context, int64) []domain.APIKey); ok {
+		r0 = rf(ctx, userID)
+	} else {
+		if ret.Get(0) != nil {
+			r0 = ret.Get(0).([]domain.APIKey)
+		}
+	}
+
+	if rf, ok := ret.Get(1).(func(context.Context, int64) error); ok {
+		r1 = rf(ctx, userID)
+	} else {
+		r1 = ret.Error(1)
+	}
+
+	return r0, r1
+}
+
+// ListSessions provides a mock function with given fields: ctx, userID, currentToken
+func (_m *AuthService) ListSessions(ctx context.Context, userID int64, currentToken string) ([]domain.Session, error) {
+	ret := _m.Called(ctx, userID, currentToken)
+
+	if len(ret) == 0 {
+		panic("no return value specified for ListSessions")
+	}
+
+	var r0 []domain.Session
+	var r1 error
+	if rf, ok := ret.Get(0).(func(context.Context, int64, string) ([]domain.Session, error)); ok {
+		return rf(ctx, userID, currentToken)
+	}
+	if rf, ok := ret.Get(0).(func(context.Context, int64, string) []domain.Session); ok {
+		r0 = rf(ctx, userID, currentToken)
+	} else {
+		if ret.Get(0) != nil {
+			r0 = ret.Get(0).([]domain.Session)
+		}
+	}
+
+	if rf, ok := ret.Get(1).(func(context.Context, int64, string) error); ok {
+		r1 = rf(ctx, userID, currentToken)
+	} else {
+		r1 = ret.Error(1)
+	}
+
+	return r0, r1
+}
+
+// Logout provides a mock function with given fields: ctx, refreshToken
+func (_m *AuthService) Logout(ctx context.Context, refreshToken string) error {
+	ret := _m.Called(ctx, refreshToken)
+
+	if len(ret) == 0 {
+		panic("no return value specified for Logout")
+	}
+
+	var r0 error
+	if rf, ok := ret.Get(0).(func(context.Context, string) error); ok {
+		r0 = rf(ctx, refreshToken)
+	} else {
+		r0 = ret.Error(0)
+	}
+
+	return r0
+}
+
+// LogoutAll provides a mock function with given fields: ctx, userID
+func (_m *AuthService) LogoutAll(ctx context.Context, userID int64) error {
+	ret := _m.Called(ctx, userID)
+
+	if len(ret) == 0 {
+		panic("no return value specified for LogoutAll")
+	}
+
+	var r0 error
+	if rf, ok := ret.Get(0).(func(context.Context, int64) error); ok {
+		r0 = rf(ctx, userID)
+	} else {
+		r0 = ret.Error(0)
+	}
+
+	return r0
+}
+
+// OIDCCallback provides a mock function with given fields: ctx, input
+func (_m *AuthService) OIDCCallback(ctx context.Context, input domain.OIDCCallbackInput) (domain.SignInResult, error) {
+	ret := _m.Called(ctx, input)
+
+	if len(ret) == 0 {
+		panic("no return value specified for OIDCCallback")
+	}
+
+	var r0 domain.SignInResult
+	var r1 error
+	if rf, ok := ret.Get(0).(func(context.Context, domain.OIDCCallbackInput) (domain.SignInResult, error)); ok {
+		return rf(ctx, input)
+	}
+	if rf, ok := ret.Get(0).(func(context.Context, domain.OIDCCallbackInput) domain.SignInResult); ok {
+		r0 = rf(ctx, input)
+	} else {
+		r0 = ret.Get(0).(domain.SignInResult)
+	}
+
+	if rf, ok := ret.Get(1).(func(context.Context, domain.OIDCCallbackInput) error); ok {
+		r1 = rf(ctx, input)
+	} else {
+		r1 = ret.Error(1)
+	}
+
+	return r0, r1
+}
+
+// OIDCLogin provides a mock function with given fields: ctx, provider
+func (_m *AuthService) OIDCLogin(ctx context.Context, provider string) (string, string, error) {
+	ret := _m.Called(ctx, provider)
+
+	if len(ret) == 0 {
+		panic("no return value specified for OIDCLogin")
+	}
+
+	var r0 string
+	var r1 string
+	var r2 error
+	if rf, ok := ret.Get(0).(func(context.Context, string) (string, string, error)); ok {
+		return rf(ctx, provider)
+	}
+	if rf, ok := ret.Get(0).(func(context.Context, string) string); ok {
+		r0 = rf(ctx, provider)
+	} else {
+		r0 = ret.Get(0).(string)
+	}
+
+	if rf, ok := ret.Get(1).(func(context.Context, string) string); ok {
+		r1 = rf(ctx, provider)
+	} else {
+		r1 = ret.Get(1).(string)
+	}
+
+	if rf, ok := ret.Get(2).(func(context.Context, string) error); ok {
+		r2 = rf(ctx, provider)
+	} else {
+		r2 = ret.Error(2)
+	}
+
+	return r0, r1, r2
+}
+
 // RefreshTokens provides a mock function with given fields: ctx, refreshToken
 func (_m *AuthService) RefreshTokens(ctx context.Context, refreshToken string) (string, string, error) {
 	ret := _m.Called(ctx, refreshToken)
@@ -50,39 +459,122 @@ func (_m *AuthService) RefreshTokens(ctx context.Context, refreshToken string) (
 	return r0, r1, r2
 }
 
+// ResendVerification provides a mock function with given fields: ctx, input
+func (_m *AuthService) ResendVerification(ctx context.Context, input domain.ResendVerificationInput) error {
+	ret := _m.Called(ctx, input)
+
+	if len(ret) == 0 {
+		panic("no return value specified for ResendVerification")
+	}
+
+	var r0 error
+	if rf, ok := ret.Get(0).(func(context.Context, domain.ResendVerificationInput) error); ok {
+		r0 = rf(ctx, input)
+	} else {
+		r0 = ret.Error(0)
+	}
+
+	return r0
+}
+
+// ResetPassword provides a mock function with given fields: ctx, input
+func (_m *AuthService) ResetPassword(ctx context.Context, input domain.ResetPasswordInput) error {
+	ret := _m.Called(ctx, input)
+
+	if len(ret) == 0 {
+		panic("no return value specified for ResetPassword")
+	}
+
+	var r0 error
+	if rf, ok := ret.Get(0).(func(context.Context, domain.ResetPasswordInput) error); ok {
+		r0 = rf(ctx, input)
+	} else {
+		r0 = ret.Error(0)
+	}
+
+	return r0
+}
+
+// RevokeAPIKey provides a mock function with given fields: ctx, userID, keyID
+func (_m *AuthService) RevokeAPIKey(ctx context.Context, userID int64, keyID int64) error {
+	ret := _m.Called(ctx, userID, keyID)
+
+	if len(ret) == 0 {
+		panic("no return value specified for RevokeAPIKey")
+	}
+
+	var r0 error
+	if rf, ok := ret.Get(0).(func(context.Context, int64, int64) error); ok {
+		r0 = rf(ctx, userID, keyID)
+	} else {
+		r0 = ret.Error(0)
+	}
+
+	return r0
+}
+
+// RevokeRole provides a mock function with given fields: ctx, userID, role
+func (_m *AuthService) RevokeRole(ctx context.Context, userID int64, role string) error {
+	ret := _m.Called(ctx, userID, role)
+
+	if len(ret) == 0 {
+		panic("no return value specified for RevokeRole")
+	}
+
+	var r0 error
+	if rf, ok := ret.Get(0).(func(context.Context, int64, string) error); ok {
+		r0 = rf(ctx, userID, role)
+	} else {
+		r0 = ret.Error(0)
+	}
+
+	return r0
+}
+
+// RevokeSession provides a mock function with given fields: ctx, userID, sessionID
+func (_m *AuthService) RevokeSession(ctx context.Context, userID int64, sessionID string) error {
+	ret := _m.Called(ctx, userID, sessionID)
+
+	if len(ret) == 0 {
+		panic("no return value specified for RevokeSession")
+	}
+
+	var r0 error
+	if rf, ok := ret.Get(0).(func(context.Context, int64, string) error); ok {
+		r0 = rf(ctx, userID, sessionID)
+	} else {
+		r0 = ret.Error(0)
+	}
+
+	return r0
+}
+
 // SignIn provides a mock function with given fields: ctx, input
-func (_m *AuthService) SignIn(ctx context.Context, input domain.SingInInput) (string, string, error) {
+func (_m *AuthService) SignIn(ctx context.Context, input domain.SingInInput) (domain.SignInResult, error) {
 	ret := _m.Called(ctx, input)
 
 	if len(ret) == 0 {
 		panic("no return value specified for SignIn")
 	}
 
-	var r0 string
-	var r1 string
-	var r2 error
-	if rf, ok := ret.Get(0).(func(context.Context, domain.SingInInput) (string, string, error)); ok {
+	var r0 domain.SignInResult
+	var r1 error
+	if rf, ok := ret.Get(0).(func(context.Context, domain.SingInInput) (domain.SignInResult, error)); ok {
 		return rf(ctx, input)
 	}
-	if rf, ok := ret.Get(0).(func(context.Context, domain.SingInInput) string); ok {
+	if rf, ok := ret.Get(0).(func(context.Context, domain.SingInInput) domain.SignInResult); ok {
 		r0 = rf(ctx, input)
 	} else {
-		r0 = ret.Get(0).(string)
+		r0 = ret.Get(0).(domain.SignInResult)
 	}
 
-	if rf, ok := ret.Get(1).(func(context.Context, domain.SingInInput) string); ok {
+	if rf, ok := ret.Get(1).(func(context.Context, domain.SingInInput) error); ok {
 		r1 = rf(ctx, input)
 	} else {
-		r1 = ret.Get(1).(string)
-	}
-
-	if rf, ok := ret.Get(2).(func(context.Context, domain.SingInInput) error); ok {
-		r2 = rf(ctx, input)
-	} else {
-		r2 = ret.Error(2)
+		r1 = ret.Error(1)
 	}
 
-	return r0, r1, r2
+	return r0, r1
 }
 
 // SignUp provides a mock function with given fields: ctx, input
@@ -113,6 +605,87 @@ func (_m *AuthService) SignUp(ctx context.Context, input domain.SingUpInput) (in
 	return r0, r1
 }
 
+// UpdateProfile provides a mock function with given fields: ctx, userID, input
+func (_m *AuthService) UpdateProfile(ctx context.Context, userID int64, input domain.UpdateProfileInput) (domain.User, error) {
+	ret := _m.Called(ctx, userID, input)
+
+	if len(ret) == 0 {
+		panic("no return value specified for UpdateProfile")
+	}
+
+	var r0 domain.User
+	var r1 error
+	if rf, ok := ret.Get(0).(func(context.Context, int64, domain.UpdateProfileInput) (domain.User, error)); ok {
+		return rf(ctx, userID, input)
+	}
+	if rf, ok := ret.Get(0).(func(context.Context, int64, domain.UpdateProfileInput) domain.User); ok {
+		r0 = rf(ctx, userID, input)
+	} else {
+		r0 = ret.Get(0).(domain.User)
+	}
+
+	if rf, ok := ret.Get(1).(func(context.Context, int64, domain.UpdateProfileInput) error); ok {
+		r1 = rf(ctx, userID, input)
+	} else {
+		r1 = ret.Error(1)
+	}
+
+	return r0, r1
+}
+
+// VerifyEmail provides a mock function with given fields: ctx, token
+func (_m *AuthService) VerifyEmail(ctx context.Context, token string) error {
+	ret := _m.Called(ctx, token)
+
+	if len(ret) == 0 {
+		panic("no return value specified for VerifyEmail")
+	}
+
+	var r0 error
+	if rf, ok := ret.Get(0).(func(context.Context, string) error); ok {
+		r0 = rf(ctx, token)
+	} else {
+		r0 = ret.Error(0)
+	}
+
+	return r0
+}
+
+// VerifyMFA provides a mock function with given fields: ctx, input
+func (_m *AuthService) VerifyMFA(ctx context.Context, input domain.MFAVerifyInput) (string, string, error) {
+	ret := _m.Called(ctx, input)
+
+	if len(ret) == 0 {
+		panic("no return value specified for VerifyMFA")
+	}
+
+	var r0 string
+	var r1 string
+	var r2 error
+	if rf, ok := ret.Get(0).(func(context.Context, domain.MFAVerifyInput) (string, string, error)); ok {
+		return rf(ctx, input)
+	}
+	if rf, ok := ret.Get(0).(func(context.Context, domain.MFAVerifyInput) string); ok {
+		r0 = rf(ctx, input)
+	} else {
+		r0 = ret.Get(0).(string)
+	}
+
+	if rf, ok := ret.Get(1).(func(context.Context, domain.MFAVerifyInput) string); ok {
+		r1 = rf(ctx, input)
+	} else {
+		r1 = ret.Get(1).(string)
+	}
+
+	if rf, ok := ret.Get(2).(func(context.Context, domain.MFAVerifyInput) error); ok {
+		r2 = rf(ctx, input)
+	} else {
+		r2 = ret.Error(2)
+	}
+
+	return r0, r1, r2
+}
+
 // NewAuthService creates a new instance of AuthService. It also registers a testing interface on the mock and a cleanup function to assert the mocks expectations.
 // The first argument is typically a *testing.T value.
 func NewAuthService(t interface {
@@ -60,34 +60,41 @@ func (_m *BookRepository) Delete(ctx context.Context, id int) error {
 	return r0
 }
 
-// GetAllBooks provides a mock function with given fields: ctx
-func (_m *BookRepository) GetAllBooks(ctx context.Context) ([]*domain.Book, error) {
-	ret := _m.Called(ctx)
+// GetAllBooks provides a mock function with given fields: ctx, q
+func (_m *BookRepository) GetAllBooks(ctx context.Context, q domain.BookQuery) ([]*domain.Book, int, error) {
+	ret := _m.Called(ctx, q)
 
 	if len(ret) == 0 {
 		panic("no return value specified for GetAllBooks")
 	}
 
 	var r0 []*domain.Book
-	var r1 error
-	if rf, ok := ret.Get(0).(func(context.Context) ([]*domain.Book, error)); ok {
-		return rf(ctx)
+	var r1 int
+	var r2 error
+	if rf, ok := ret.Get(0).(func(context.Context, domain.BookQuery) ([]*domain.Book, int, error)); ok {
+		return rf(ctx, q)
 	}
-	if rf, ok := ret.Get(0).(func(context.Context) []*domain.Book); ok {
-		r0 = rf(ctx)
+	if rf, ok := ret.Get(0).(func(context.Context, domain.BookQuery) []*domain.Book); ok {
+		r0 = rf(ctx, q)
 	} else {
 		if ret.Get(0) != nil {
 			r0 = ret.Get(0).([]*domain.Book)
 		}
 	}
 
-	if rf, ok := ret.Get(1).(func(context.Context) error); ok {
-		r1 = rf(ctx)
+	if rf, ok := ret.Get(1).(func(context.Context, domain.BookQuery) int); ok {
+		r1 = rf(ctx, q)
 	} else {
-		r1 = ret.Error(1)
+		r1 = ret.Get(1).(int)
 	}
 
-	return r0, r1
+	if rf, ok := ret.Get(2).(func(context.Context, domain.BookQuery) error); ok {
+		r2 = rf(ctx, q)
+	} else {
+		r2 = ret.Error(2)
+	}
+
+	return r0, r1, r2
 }
 
 // GetBook provides a mock function with given fields: ctx, id
@@ -120,22 +127,131 @@ func (_m *BookRepository) GetBook(ctx context.Context, id int) (*domain.Book, er
 	return r0, r1
 }
 
-// Update provides a mock function with given fields: ctx, id, book
-func (_m *BookRepository) Update(ctx context.Context, id int, book *domain.Book) error {
-	ret := _m.Called(ctx, id, book)
+// GetBookForUpdate provides a mock function with given fields: ctx, id
+func (_m *BookRepository) GetBookForUpdate(ctx context.Context, id int) (*domain.Book, error) {
+	ret := _m.Called(ctx, id)
+
+	if len(ret) == 0 {
+		panic("no return value specified for GetBookForUpdate")
+	}
+
+	var r0 *domain.Book
+	var r1 error
+	if rf, ok := ret.Get(0).(func(context.Context, int) (*domain.Book, error)); ok {
+		return rf(ctx, id)
+	}
+	if rf, ok := ret.Get(0).(func(context.Context, int) *domain.Book); ok {
+		r0 = rf(ctx, id)
+	} else {
+		if ret.Get(0) != nil {
+			r0 = ret.Get(0).(*domain.Book)
+		}
+	}
+
+	if rf, ok := ret.Get(1).(func(context.Context, int) error); ok {
+		r1 = rf(ctx, id)
+	} else {
+		r1 = ret.Error(1)
+	}
+
+	return r0, r1
+}
+
+// GetBooksAfter provides a mock function with given fields: ctx, q, after
+func (_m *BookRepository) GetBooksAfter(ctx context.Context, q domain.BookQuery, after *domain.BookKeyset) ([]*domain.Book, error) {
+	ret := _m.Called(ctx, q, after)
+
+	if len(ret) == 0 {
+		panic("no return value specified for GetBooksAfter")
+	}
+
+	var r0 []*domain.Book
+	var r1 error
+	if rf, ok := ret.Get(0).(func(context.Context, domain.BookQuery, *domain.BookKeyset) ([]*domain.Book, error)); ok {
+		return rf(ctx, q, after)
+	}
+	if rf, ok := ret.Get(0).(func(context.Context, domain.BookQuery, *domain.BookKeyset) []*domain.Book); ok {
+		r0 = rf(ctx, q, after)
+	} else {
+		if ret.Get(0) != nil {
+			r0 = ret.Get(0).([]*domain.Book)
+		}
+	}
+
+	if rf, ok := ret.Get(1).(func(context.Context, domain.BookQuery, *domain.BookKeyset) error); ok {
+		r1 = rf(ctx, q, after)
+	} else {
+		r1 = ret.Error(1)
+	}
+
+	return r0, r1
+}
+
+// Search provides a mock function with given fields: ctx, q
+func (_m *BookRepository) Search(ctx context.Context, q domain.BookSearchQuery) ([]*domain.BookSearchResult, int, error) {
+	ret := _m.Called(ctx, q)
+
+	if len(ret) == 0 {
+		panic("no return value specified for Search")
+	}
+
+	var r0 []*domain.BookSearchResult
+	var r1 int
+	var r2 error
+	if rf, ok := ret.Get(0).(func(context.Context, domain.BookSearchQuery) ([]*domain.BookSearchResult, int, error)); ok {
+		return rf(ctx, q)
+	}
+	if rf, ok := ret.Get(0).(func(context.Context, domain.BookSearchQuery) []*domain.BookSearchResult); ok {
+		r0 = rf(ctx, q)
+	} else {
+		if ret.Get(0) != nil {
+			r0 = ret.Get(0).([]*domain.BookSearchResult)
+		}
+	}
+
+	if rf, ok := ret.Get(1).(func(context.Context, domain.BookSearchQuery) int); ok {
+		r1 = rf(ctx, q)
+	} else {
+		r1 = ret.Get(1).(int)
+	}
+
+	if rf, ok := ret.Get(2).(func(context.Context, domain.BookSearchQuery) error); ok {
+		r2 = rf(ctx, q)
+	} else {
+		r2 = ret.Error(2)
+	}
+
+	return r0, r1, r2
+}
+
+// Update provides a mock function with given fields: ctx, id, patch
+func (_m *BookRepository) Update(ctx context.Context, id int, patch domain.BookPatch) (*domain.Book, error) {
+	ret := _m.Called(ctx, id, patch)
 
 	if len(ret) == 0 {
 		panic("no return value specified for Update")
 	}
 
-	var r0 error
-	if rf, ok := ret.Get(0).(func(context.Context, int, *domain.Book) error); ok {
-		r0 = rf(ctx, id, book)
+	var r0 *domain.Book
+	var r1 error
+	if rf, ok := ret.Get(0).(func(context.Context, int, domain.BookPatch) (*domain.Book, error)); ok {
+		return rf(ctx, id, patch)
+	}
+	if rf, ok := ret.Get(0).(func(context.Context, int, domain.BookPatch) *domain.Book); ok {
+		r0 = rf(ctx, id, patch)
 	} else {
-		r0 = ret.Error(0)
+		if ret.Get(0) != nil {
+			r0 = ret.Get(0).(*domain.Book)
+		}
 	}
 
-	return r0
+	if rf, ok := ret.Get(1).(func(context.Context, int, domain.BookPatch) error); ok {
+		r1 = rf(ctx, id, patch)
+	} else {
+		r1 = ret.Error(1)
+	}
+
+	return r0, r1
 }
 
 // NewBookRepository creates a new instance of BookRepository. It also registers a testing interface on the mock and a cleanup function to assert the mocks expectations.
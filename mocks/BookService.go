@@ -15,6 +15,24 @@ type BookService struct {
 	mock.Mock
 }
 
+// ApplyJSONPatch provides a mock function with given fields: ctx, id, ops
+func (_m *BookService) ApplyJSONPatch(ctx context.Context, id int, ops []domain.JSONPatchOp) error {
+	ret := _m.Called(ctx, id, ops)
+
+	if len(ret) == 0 {
+		panic("no return value specified for ApplyJSONPatch")
+	}
+
+	var r0 error
+	if rf, ok := ret.Get(0).(func(context.Context, int, []domain.JSONPatchOp) error); ok {
+		r0 = rf(ctx, id, ops)
+	} else {
+		r0 = ret.Error(0)
+	}
+
+	return r0
+}
+
 // Create provides a mock function with given fields: ctx, input
 func (_m *BookService) Create(ctx context.Context, input *domain.CreateBookInput) (int, error) {
 	ret := _m.Called(ctx, input)
@@ -61,29 +79,59 @@ func (_m *BookService) Delete(ctx context.Context, id int) error {
 	return r0
 }
 
-// GetAll provides a mock function with given fields: ctx
-func (_m *BookService) GetAll(ctx context.Context) ([]*domain.Book, error) {
-	ret := _m.Called(ctx)
+// GetAll provides a mock function with given fields: ctx, query
+func (_m *BookService) GetAll(ctx context.Context, query domain.BookQuery) (*domain.BookPage, error) {
+	ret := _m.Called(ctx, query)
 
 	if len(ret) == 0 {
 		panic("no return value specified for GetAll")
 	}
 
-	var r0 []*domain.Book
+	var r0 *domain.BookPage
+	var r1 error
+	if rf, ok := ret.Get(0).(func(context.Context, domain.BookQuery) (*domain.BookPage, error)); ok {
+		return rf(ctx, query)
+	}
+	if rf, ok := ret.Get(0).(func(context.Context, domain.BookQuery) *domain.BookPage); ok {
+		r0 = rf(ctx, query)
+	} else {
+		if ret.Get(0) != nil {
+			r0 = ret.Get(0).(*domain.BookPage)
+		}
+	}
+
+	if rf, ok := ret.Get(1).(func(context.Context, domain.BookQuery) error); ok {
+		r1 = rf(ctx, query)
+	} else {
+		r1 = ret.Error(1)
+	}
+
+	return r0, r1
+}
+
+// GetAllAfter provides a mock function with given fields: ctx, query, cursor
+func (_m *BookService) GetAllAfter(ctx context.Context, query domain.BookQuery, cursor string) (*domain.BookCursorPage, error) {
+	ret := _m.Called(ctx, query, cursor)
+
+	if len(ret) == 0 {
+		panic("no return value specified for GetAllAfter")
+	}
+
+	var r0 *domain.BookCursorPage
 	var r1 error
-	if rf, ok := ret.Get(0).(func(context.Context) ([]*domain.Book, error)); ok {
-		return rf(ctx)
+	if rf, ok := ret.Get(0).(func(context.Context, domain.BookQuery, string) (*domain.BookCursorPage, error)); ok {
+		return rf(ctx, query, cursor)
 	}
-	if rf, ok := ret.Get(0).(func(context.Context) []*domain.Book); ok {
-		r0 = rf(ctx)
+	if rf, ok := ret.Get(0).(func(context.Context, domain.BookQuery, string) *domain.BookCursorPage); ok {
+		r0 = rf(ctx, query, cursor)
 	} else {
 		if ret.Get(0) != nil {
-			r0 = ret.Get(0).([]*domain.Book)
+			r0 = ret.Get(0).(*domain.BookCursorPage)
 		}
 	}
 
-	if rf, ok := ret.Get(1).(func(context.Context) error); ok {
-		r1 = rf(ctx)
+	if rf, ok := ret.Get(1).(func(context.Context, domain.BookQuery, string) error); ok {
+		r1 = rf(ctx, query, cursor)
 	} else {
 		r1 = ret.Error(1)
 	}
@@ -121,17 +169,47 @@ func (_m *BookService) GetById(ctx context.Context, id int) (*domain.Book, error
 	return r0, r1
 }
 
-// Update provides a mock function with given fields: ctx, id, book
-func (_m *BookService) Update(ctx context.Context, id int, book *domain.Book) error {
-	ret := _m.Called(ctx, id, book)
+// Search provides a mock function with given fields: ctx, query
+func (_m *BookService) Search(ctx context.Context, query domain.BookSearchQuery) (*domain.BookSearchPage, error) {
+	ret := _m.Called(ctx, query)
+
+	if len(ret) == 0 {
+		panic("no return value specified for Search")
+	}
+
+	var r0 *domain.BookSearchPage
+	var r1 error
+	if rf, ok := ret.Get(0).(func(context.Context, domain.BookSearchQuery) (*domain.BookSearchPage, error)); ok {
+		return rf(ctx, query)
+	}
+	if rf, ok := ret.Get(0).(func(context.Context, domain.BookSearchQuery) *domain.BookSearchPage); ok {
+		r0 = rf(ctx, query)
+	} else {
+		if ret.Get(0) != nil {
+			r0 = ret.Get(0).(*domain.BookSearchPage)
+		}
+	}
+
+	if rf, ok := ret.Get(1).(func(context.Context, domain.BookSearchQuery) error); ok {
+		r1 = rf(ctx, query)
+	} else {
+		r1 = ret.Error(1)
+	}
+
+	return r0, r1
+}
+
+// Update provides a mock function with given fields: ctx, id, patch
+func (_m *BookService) Update(ctx context.Context, id int, patch domain.BookPatch) error {
+	ret := _m.Called(ctx, id, patch)
 
 	if len(ret) == 0 {
 		panic("no return value specified for Update")
 	}
 
 	var r0 error
-	if rf, ok := ret.Get(0).(func(context.Context, int, *domain.Book) error); ok {
-		r0 = rf(ctx, id, book)
+	if rf, ok := ret.Get(0).(func(context.Context, int, domain.BookPatch) error); ok {
+		r0 = rf(ctx, id, patch)
 	} else {
 		r0 = ret.Error(0)
 	}
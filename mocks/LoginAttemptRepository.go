@@ -0,0 +1,105 @@
+// Code generated by mockery v2.53.5. DO NOT EDIT.
+
+package mocks
+
+import (
+	context "context"
+
+	domain "github.com/CryptoGu1/books-rest-clean-arch/internal/domain"
+	mock "github.com/stretchr/testify/mock"
+
+	time "time"
+)
+
+// LoginAttemptRepository is an autogenerated mock type for the LoginAttemptRepository type
+type LoginAttemptRepository struct {
+	mock.Mock
+}
+
+// Get provides a mock function with given fields: ctx, key
+func (_m *LoginAttemptRepository) Get(ctx context.Context, key string) (domain.LoginAttempts, error) {
+	ret := _m.Called(ctx, key)
+
+	if len(ret) == 0 {
+		panic("no return value specified for Get")
+	}
+
+	var r0 domain.LoginAttempts
+	var r1 error
+	if rf, ok := ret.Get(0).(func(context.Context, string) (domain.LoginAttempts, error)); ok {
+		return rf(ctx, key)
+	}
+	if rf, ok := ret.Get(0).(func(context.Context, string) domain.LoginAttempts); ok {
+		r0 = rf(ctx, key)
+	} else {
+		r0 = ret.Get(0).(domain.LoginAttempts)
+	}
+
+	if rf, ok := ret.Get(1).(func(context.Context, string) error); ok {
+		r1 = rf(ctx, key)
+	} else {
+		r1 = ret.Error(1)
+	}
+
+	return r0, r1
+}
+
+// RegisterFailure provides a mock function with given fields: ctx, key, now, window
+func (_m *LoginAttemptRepository) RegisterFailure(ctx context.Context, key string, now time.Time, window time.Duration) (domain.LoginAttempts, error) {
+	ret := _m.Called(ctx, key, now, window)
+
+	if len(ret) == 0 {
+		panic("no return value specified for RegisterFailure")
+	}
+
+	var r0 domain.LoginAttempts
+	var r1 error
+	if rf, ok := ret.Get(0).(func(context.Context, string, time.Time, time.Duration) (domain.LoginAttempts, error)); ok {
+		return rf(ctx, key, now, window)
+	}
+	if rf, ok := ret.Get(0).(func(context.Context, string, time.Time, time.Duration) domain.LoginAttempts); ok {
+		r0 = rf(ctx, key, now, window)
+	} else {
+		r0 = ret.Get(0).(domain.LoginAttempts)
+	}
+
+	if rf, ok := ret.Get(1).(func(context.Context, string, time.Time, time.Duration) error); ok {
+		r1 = rf(ctx, key, now, window)
+	} else {
+		r1 = ret.Error(1)
+	}
+
+	return r0, r1
+}
+
+// Reset provides a mock function with given fields: ctx, key
+func (_m *LoginAttemptRepository) Reset(ctx context.Context, key string) error {
+	ret := _m.Called(ctx, key)
+
+	if len(ret) == 0 {
+		panic("no return value specified for Reset")
+	}
+
+	var r0 error
+	if rf, ok := ret.Get(0).(func(context.Context, string) error); ok {
+		r0 = rf(ctx, key)
+	} else {
+		r0 = ret.Error(0)
+	}
+
+	return r0
+}
+
+// NewLoginAttemptRepository creates a new instance of LoginAttemptRepository. It also registers a testing interface on the mock and a cleanup function to assert the mocks expectations.
+// The first argument is typically a *testing.T value.
+func NewLoginAttemptRepository(t interface {
+	mock.TestingT
+	Cleanup(func())
+}) *LoginAttemptRepository {
+	mock := &LoginAttemptRepository{}
+	mock.Mock.Test(t)
+
+	t.Cleanup(func() { mock.AssertExpectations(t) })
+
+	return mock
+}
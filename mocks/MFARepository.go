@@ -0,0 +1,147 @@
+// Code generated by mockery v2.53.5. DO NOT EDIT.
+
+package mocks
+
+import (
+	context "context"
+
+	domain "github.com/CryptoGu1/books-rest-clean-arch/internal/domain"
+	mock "github.com/stretchr/testify/mock"
+)
+
+// MFARepository is an autogenerated mock type for the MFARepository type
+type MFARepository struct {
+	mock.Mock
+}
+
+// ConfirmTOTP provides a mock function with given fields: ctx, userID, step
+func (_m *MFARepository) ConfirmTOTP(ctx context.Context, userID int64, step int64) error {
+	ret := _m.Called(ctx, userID, step)
+
+	if len(ret) == 0 {
+		panic("no return value specified for ConfirmTOTP")
+	}
+
+	var r0 error
+	if rf, ok := ret.Get(0).(func(context.Context, int64, int64) error); ok {
+		r0 = rf(ctx, userID, step)
+	} else {
+		r0 = ret.Error(0)
+	}
+
+	return r0
+}
+
+// DeleteTOTP provides a mock function with given fields: ctx, userID
+func (_m *MFARepository) DeleteTOTP(ctx context.Context, userID int64) error {
+	ret := _m.Called(ctx, userID)
+
+	if len(ret) == 0 {
+		panic("no return value specified for DeleteTOTP")
+	}
+
+	var r0 error
+	if rf, ok := ret.Get(0).(func(context.Context, int64) error); ok {
+		r0 = rf(ctx, userID)
+	} else {
+		r0 = ret.Error(0)
+	}
+
+	return r0
+}
+
+// GetTOTP provides a mock function with given fields: ctx, userID
+func (_m *MFARepository) GetTOTP(ctx context.Context, userID int64) (domain.TOTP, error) {
+	ret := _m.Called(ctx, userID)
+
+	if len(ret) == 0 {
+		panic("no return value specified for GetTOTP")
+	}
+
+	var r0 domain.TOTP
+	var r1 error
+	if rf, ok := ret.Get(0).(func(context.Context, int64) (domain.TOTP, error)); ok {
+		return rf(ctx, userID)
+	}
+	if rf, ok := ret.Get(0).(func(context.Context, int64) domain.TOTP); ok {
+		r0 = rf(ctx, userID)
+	} else {
+		r0 = ret.Get(0).(domain.TOTP)
+	}
+
+	if rf, ok := ret.Get(1).(func(context.Context, int64) error); ok {
+		r1 = rf(ctx, userID)
+	} else {
+		r1 = ret.Error(1)
+	}
+
+	return r0, r1
+}
+
+// StartTOTP provides a mock function with given fields: ctx, userID, secret, recoveryHashes
+func (_m *MFARepository) StartTOTP(ctx context.Context, userID int64, secret string, recoveryHashes []string) error {
+	ret := _m.Called(ctx, userID, secret, recoveryHashes)
+
+	if len(ret) == 0 {
+		panic("no return value specified for StartTOTP")
+	}
+
+	var r0 error
+	if rf, ok := ret.Get(0).(func(context.Context, int64, string, []string) error); ok {
+		r0 = rf(ctx, userID, secret, recoveryHashes)
+	} else {
+		r0 = ret.Error(0)
+	}
+
+	return r0
+}
+
+// UseRecoveryCode provides a mock function with given fields: ctx, userID, codeHash
+func (_m *MFARepository) UseRecoveryCode(ctx context.Context, userID int64, codeHash string) error {
+	ret := _m.Called(ctx, userID, codeHash)
+
+	if len(ret) == 0 {
+		panic("no return value specified for UseRecoveryCode")
+	}
+
+	var r0 error
+	if rf, ok := ret.Get(0).(func(context.Context, int64, string) error); ok {
+		r0 = rf(ctx, userID, codeHash)
+	} else {
+		r0 = ret.Error(0)
+	}
+
+	return r0
+}
+
+// UseTOTPStep provides a mock function with given fields: ctx, userID, step
+func (_m *MFARepository) UseTOTPStep(ctx context.Context, userID int64, step int64) error {
+	ret := _m.Called(ctx, userID, step)
+
+	if len(ret) == 0 {
+		panic("no return value specified for UseTOTPStep")
+	}
+
+	var r0 error
+	if rf, ok := ret.Get(0).(func(context.Context, int64, int64) error); ok {
+		r0 = rf(ctx, userID, step)
+	} else {
+		r0 = ret.Error(0)
+	}
+
+	return r0
+}
+
+// NewMFARepository creates a new instance of MFARepository. It also registers a testing interface on the mock and a cleanup function to assert the mocks expectations.
+// The first argument is typically a *testing.T value.
+func NewMFARepository(t interface {
+	mock.TestingT
+	Cleanup(func())
+}) *MFARepository {
+	mock := &MFARepository{}
+	mock.Mock.Test(t)
+
+	t.Cleanup(func() { mock.AssertExpectations(t) })
+
+	return mock
+}
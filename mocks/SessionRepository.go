@@ -32,9 +32,45 @@ func (_m *SessionRepository) Create(ctx context.Context, token domain.RefreshSes
 	return r0
 }
 
-// Get provides a mock function with given fields: ctx, token
-func (_m *SessionRepository) Get(ctx context.Context, token string) (domain.RefreshSession, error) {
-	ret := _m.Called(ctx, token)
+// Delete provides a mock function with given fields: ctx, tokenHash
+func (_m *SessionRepository) Delete(ctx context.Context, tokenHash string) error {
+	ret := _m.Called(ctx, tokenHash)
+
+	if len(ret) == 0 {
+		panic("no return value specified for Delete")
+	}
+
+	var r0 error
+	if rf, ok := ret.Get(0).(func(context.Context, string) error); ok {
+		r0 = rf(ctx, tokenHash)
+	} else {
+		r0 = ret.Error(0)
+	}
+
+	return r0
+}
+
+// DeleteByUser provides a mock function with given fields: ctx, userID
+func (_m *SessionRepository) DeleteByUser(ctx context.Context, userID int64) error {
+	ret := _m.Called(ctx, userID)
+
+	if len(ret) == 0 {
+		panic("no return value specified for DeleteByUser")
+	}
+
+	var r0 error
+	if rf, ok := ret.Get(0).(func(context.Context, int64) error); ok {
+		r0 = rf(ctx, userID)
+	} else {
+		r0 = ret.Error(0)
+	}
+
+	return r0
+}
+
+// Get provides a mock function with given fields: ctx, tokenHash
+func (_m *SessionRepository) Get(ctx context.Context, tokenHash string) (domain.RefreshSession, error) {
+	ret := _m.Called(ctx, tokenHash)
 
 	if len(ret) == 0 {
 		panic("no return value specified for Get")
@@ -43,16 +79,46 @@ func (_m *SessionRepository) Get(ctx context.Context, token string) (domain.Refr
 	var r0 domain.RefreshSession
 	var r1 error
 	if rf, ok := ret.Get(0).(func(context.Context, string) (domain.RefreshSession, error)); ok {
-		return rf(ctx, token)
+		return rf(ctx, tokenHash)
 	}
 	if rf, ok := ret.Get(0).(func(context.Context, string) domain.RefreshSession); ok {
-		r0 = rf(ctx, token)
+		r0 = rf(ctx, tokenHash)
 	} else {
 		r0 = ret.Get(0).(domain.RefreshSession)
 	}
 
 	if rf, ok := ret.Get(1).(func(context.Context, string) error); ok {
-		r1 = rf(ctx, token)
+		r1 = rf(ctx, tokenHash)
+	} else {
+		r1 = ret.Error(1)
+	}
+
+	return r0, r1
+}
+
+// ListActive provides a mock function with given fields: ctx, userID
+func (_m *SessionRepository) ListActive(ctx context.Context, userID int64) ([]domain.RefreshSession, error) {
+	ret := _m.Called(ctx, userID)
+
+	if len(ret) == 0 {
+		panic("no return value specified for ListActive")
+	}
+
+	var r0 []domain.RefreshSession
+	var r1 error
+	if rf, ok := ret.Get(0).(func(context.Context, int64) ([]domain.RefreshSession, error)); ok {
+		return rf(ctx, userID)
+	}
+	if rf, ok := ret.Get(0).(func(context.Context, int64) []domain.RefreshSession); ok {
+		r0 = rf(ctx, userID)
+	} else {
+		if ret.Get(0) != nil {
+			r0 = ret.Get(0).([]domain.RefreshSession)
+		}
+	}
+
+	if rf, ok := ret.Get(1).(func(context.Context, int64) error); ok {
+		r1 = rf(ctx, userID)
 	} else {
 		r1 = ret.Error(1)
 	}
@@ -60,6 +126,42 @@ func (_m *SessionRepository) Get(ctx context.Context, token string) (domain.Refr
 	return r0, r1
 }
 
+// Revoke provides a mock function with given fields: ctx, id
+func (_m *SessionRepository) Revoke(ctx context.Context, id int64) error {
+	ret := _m.Called(ctx, id)
+
+	if len(ret) == 0 {
+		panic("no return value specified for Revoke")
+	}
+
+	var r0 error
+	if rf, ok := ret.Get(0).(func(context.Context, int64) error); ok {
+		r0 = rf(ctx, id)
+	} else {
+		r0 = ret.Error(0)
+	}
+
+	return r0
+}
+
+// RevokeFamily provides a mock function with given fields: ctx, userID, familyID
+func (_m *SessionRepository) RevokeFamily(ctx context.Context, userID int64, familyID string) error {
+	ret := _m.Called(ctx, userID, familyID)
+
+	if len(ret) == 0 {
+		panic("no return value specified for RevokeFamily")
+	}
+
+	var r0 error
+	if rf, ok := ret.Get(0).(func(context.Context, int64, string) error); ok {
+		r0 = rf(ctx, userID, familyID)
+	} else {
+		r0 = ret.Error(0)
+	}
+
+	return r0
+}
+
 // NewSessionRepository creates a new instance of SessionRepository. It also registers a testing interface on the mock and a cleanup function to assert the mocks expectations.
 // The first argument is typically a *testing.T value.
 func NewSessionRepository(t interface {
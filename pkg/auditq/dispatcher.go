@@ -0,0 +1,344 @@
+// Package auditq — асинхронная доставка аудит-событий: ограниченная очередь, воркеры, пачки,
+// повторы с экспоненциальной задержкой и долговременный outbox на случай переполнения
+// или недоступности сервера логов
+package auditq
+
+import (
+	"context"
+	"errors"
+	"sync"
+	"time"
+
+	audit "github.com/CryptoGu1/books-grpc-log/pkg/domain"
+	"github.com/CryptoGu1/books-rest-clean-arch/pkg/log_grpc"
+	"github.com/sirupsen/logrus"
+	"google.golang.org/grpc/metadata"
+)
+
+// Sender доставляет одно событие (log_grpc.Client)
+type Sender interface {
+	SendLogRequest(ctx context.Context, req audit.LogItem) error
+}
+
+// Event — событие в очереди и в outbox. Metadata — исходящая gRPC metadata запроса
+// (x-audit-action, изменённые поля): контекст запроса к моменту отправки уже завершён
+type Event struct {
+	Item     audit.LogItem       `json:"item"`
+	Metadata map[string][]string `json:"metadata,omitempty"`
+}
+
+type Config struct {
+	QueueSize int
+	Workers   int
+	// BatchSize — сколько событий воркер забирает из очереди за раз
+	BatchSize   int
+	MaxRetries  int
+	BaseBackoff time.Duration
+	MaxBackoff  time.Duration
+	SendTimeout time.Duration
+	// ReplayInterval — как часто пробовать доставить накопленное в outbox
+	ReplayInterval time.Duration
+}
+
+var DefaultConfig = Config{
+	QueueSize:      1024,
+	Workers:        2,
+	BatchSize:      50,
+	MaxRetries:     5,
+	BaseBackoff:    100 * time.Millisecond,
+	MaxBackoff:     5 * time.Second,
+	SendTimeout:    5 * time.Second,
+	ReplayInterval: 30 * time.Second,
+}
+
+var ErrClosed = errors.New("auditq: dispatcher closed")
+
+type Dispatcher struct {
+	sender Sender
+	outbox Outbox
+	cfg    Config
+
+	queue chan Event
+	// mu защищает закрытие очереди от одновременной записи в неё
+	mu     sync.RWMutex
+	closed bool
+
+	// stop прерывает повторы, когда на завершение не осталось времени
+	stop     context.Context
+	abort    context.CancelFunc
+	replayCh chan struct{}
+	wg       sync.WaitGroup
+	// workers — только воркеры очереди: последняя доставка из outbox ждёт, пока они закончат
+	workers sync.WaitGroup
+}
+
+// New; нулевые поля cfg берутся из DefaultConfig
+func New(sender Sender, outbox Outbox, cfg Config) *Dispatcher {
+	def := DefaultConfig
+	if cfg.QueueSize <= 0 {
+		cfg.QueueSize = def.QueueSize
+	}
+	if cfg.Workers <= 0 {
+		cfg.Workers = def.Workers
+	}
+	if cfg.BatchSize <= 0 {
+		cfg.BatchSize = def.BatchSize
+	}
+	if cfg.MaxRetries <= 0 {
+		cfg.MaxRetries = def.MaxRetries
+	}
+	if cfg.BaseBackoff <= 0 {
+		cfg.BaseBackoff = def.BaseBackoff
+	}
+	if cfg.MaxBackoff <= 0 {
+		cfg.MaxBackoff = def.MaxBackoff
+	}
+	if cfg.SendTimeout <= 0 {
+		cfg.SendTimeout = def.SendTimeout
+	}
+	if cfg.ReplayInterval <= 0 {
+		cfg.ReplayInterval = def.ReplayInterval
+	}
+
+	stop, abort := context.WithCancel(context.Background())
+	return &Dispatcher{
+		sender:   sender,
+		outbox:   outbox,
+		cfg:      cfg,
+		queue:    make(chan Event, cfg.QueueSize),
+		stop:     stop,
+		abort:    abort,
+		replayCh: make(chan struct{}),
+	}
+}
+
+// Start запускает воркеры и периодическую доставку из outbox
+func (d *Dispatcher) Start() {
+	for i := 0; i < d.cfg.Workers; i++ {
+		d.wg.Add(1)
+		d.workers.Add(1)
+		go d.worker()
+	}
+	d.wg.Add(1)
+	go d.replayLoop()
+}
+
+// SendLogRequest ставит событие в очередь и сразу возвращается. Если очередь заполнена
+// или диспетчер остановлен, событие пишется в outbox
+func (d *Dispatcher) SendLogRequest(ctx context.Context, req audit.LogItem) error {
+	ev := Event{Item: req}
+	if md, ok := metadata.FromOutgoingContext(ctx); ok {
+		ev.Metadata = md.Copy()
+	}
+
+	d.mu.RLock()
+	if !d.closed {
+		select {
+		case d.queue <- ev:
+			d.mu.RUnlock()
+			return nil
+		default:
+		}
+	}
+	d.mu.RUnlock()
+
+	return d.spill([]Event{ev}, "queue full or closed")
+}
+
+// Close перестаёт принимать события и дожидается отправки очереди. Когда ctx истекает,
+// повторы прерываются, а недоставленное сохраняется в outbox
+func (d *Dispatcher) Close(ctx context.Context) error {
+	d.mu.Lock()
+	if d.closed {
+		d.mu.Unlock()
+		return ErrClosed
+	}
+	d.closed = true
+	close(d.queue)
+	close(d.replayCh)
+	d.mu.Unlock()
+
+	done := make(chan struct{})
+	go func() {
+		d.wg.Wait()
+		close(done)
+	}()
+
+	select {
+	case <-done:
+		d.abort()
+		return nil
+	case <-ctx.Done():
+		d.abort()
+		<-done
+		return ctx.Err()
+	}
+}
+
+func (d *Dispatcher) worker() {
+	defer d.wg.Done()
+	defer d.workers.Done()
+
+	batch := make([]Event, 0, d.cfg.BatchSize)
+	for ev := range d.queue {
+		batch = append(batch[:0], ev)
+		// добираем то, что уже лежит в очереди, не дожидаясь новых событий
+	fill:
+		for len(batch) < d.cfg.BatchSize {
+			select {
+			case next, ok := <-d.queue:
+				if !ok {
+					break fill
+				}
+				batch = append(batch, next)
+			default:
+				break fill
+			}
+		}
+		d.deliver(batch)
+	}
+}
+
+// deliver отправляет пачку по порядку. Если событие не ушло и после повторов, сервер, скорее
+// всего, недоступен: остаток пачки сразу уходит в outbox
+func (d *Dispatcher) deliver(batch []Event) {
+	// при остановке без запаса времени не ждём сервер, а сразу сохраняем
+	if d.stop.Err() != nil {
+		_ = d.spill(batch, "shutdown")
+		return
+	}
+
+	for i, ev := range batch {
+		if err := d.sendWithRetry(ev); err != nil {
+			if log_grpc.Rejected(err) {
+				dropped(ev, err)
+				continue
+			}
+			_ = d.spill(batch[i:], err.Error())
+			return
+		}
+	}
+}
+
+func (d *Dispatcher) sendWithRetry(ev Event) error {
+	var err error
+	for attempt := 0; attempt < d.cfg.MaxRetries; attempt++ {
+		if attempt > 0 {
+			select {
+			case <-time.After(Backoff(d.cfg.BaseBackoff, d.cfg.MaxBackoff, attempt)):
+			case <-d.stop.Done():
+				return err
+			}
+		}
+
+		if err = d.send(ev); err == nil || log_grpc.Rejected(err) {
+			return err
+		}
+	}
+	return err
+}
+
+func (d *Dispatcher) send(ev Event) error {
+	ctx, cancel := context.WithTimeout(context.Background(), d.cfg.SendTimeout)
+	defer cancel()
+	if len(ev.Metadata) > 0 {
+		ctx = metadata.NewOutgoingContext(ctx, metadata.MD(ev.Metadata))
+	}
+	return d.sender.SendLogRequest(ctx, ev.Item)
+}
+
+// replayLoop периодически досылает события из outbox. При остановке, когда воркеры
+// разобрали очередь, — последняя попытка, если Close ещё не истёк
+func (d *Dispatcher) replayLoop() {
+	defer d.wg.Done()
+
+	ticker := time.NewTicker(d.cfg.ReplayInterval)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-ticker.C:
+			d.replay()
+		case <-d.replayCh:
+			d.workers.Wait()
+			d.replay()
+			return
+		}
+	}
+}
+
+// replay досылает outbox по порядку до первой ошибки. Событие, которое сервер отверг
+// (log_grpc.Rejected), повтор не исправит: оно логируется и убирается из outbox
+func (d *Dispatcher) replay() {
+	for d.stop.Err() == nil {
+		events, err := d.outbox.Peek(d.cfg.BatchSize)
+		if err != nil {
+			logrus.WithField("component", "auditq").Error("failed to read audit outbox", err)
+			return
+		}
+		if len(events) == 0 {
+			return
+		}
+
+		sent := 0
+		for _, ev := range events {
+			if d.stop.Err() != nil {
+				break
+			}
+			if err := d.send(ev); err != nil {
+				if !log_grpc.Rejected(err) {
+					break
+				}
+				dropped(ev, err)
+			}
+			sent++
+		}
+
+		if err := d.outbox.Remove(sent); err != nil {
+			logrus.WithField("component", "auditq").Error("failed to trim audit outbox", err)
+			return
+		}
+		if sent < len(events) {
+			return
+		}
+	}
+}
+
+func dropped(ev Event, err error) {
+	logrus.WithFields(logrus.Fields{
+		"component": "auditq",
+		"action":    ev.Item.Action,
+		"entity":    ev.Item.Entity,
+		"entity_id": ev.Item.EntityID,
+	}).Error("audit event dropped: ", err)
+}
+
+func (d *Dispatcher) spill(events []Event, reason string) error {
+	if err := d.outbox.Append(events); err != nil {
+		logrus.WithFields(logrus.Fields{
+			"component": "auditq",
+			"events":    len(events),
+		}).Error("audit events lost: ", err)
+		return err
+	}
+	logrus.WithFields(logrus.Fields{
+		"component": "auditq",
+		"events":    len(events),
+		"reason":    reason,
+	}).Warn("audit events saved to outbox")
+	return nil
+}
+
+// Backoff — задержка перед попыткой attempt (с 1): base, 2*base, 4*base... но не больше max
+func Backoff(base, max time.Duration, attempt int) time.Duration {
+	if attempt < 1 {
+		attempt = 1
+	}
+	if attempt > 32 {
+		return max
+	}
+	d := base << (attempt - 1)
+	if d <= 0 || d > max {
+		return max
+	}
+	return d
+}
@@ -0,0 +1,277 @@
+package auditq
+
+import (
+	"context"
+	"errors"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	audit "github.com/CryptoGu1/books-grpc-log/pkg/domain"
+	"github.com/CryptoGu1/books-rest-clean-arch/pkg/auditsink"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/metadata"
+	"google.golang.org/grpc/status"
+)
+
+type fakeSender struct {
+	mu   sync.Mutex
+	err  error
+	sent []Event
+}
+
+func (f *fakeSender) SendLogRequest(ctx context.Context, req audit.LogItem) error {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	if f.err != nil {
+		return f.err
+	}
+	md, _ := metadata.FromOutgoingContext(ctx)
+	f.sent = append(f.sent, Event{Item: req, Metadata: md})
+	return nil
+}
+
+func (f *fakeSender) setErr(err error) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	f.err = err
+}
+
+func (f *fakeSender) events() []Event {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	return append([]Event(nil), f.sent...)
+}
+
+var testConfig = Config{
+	QueueSize:      4,
+	Workers:        1,
+	BatchSize:      2,
+	MaxRetries:     2,
+	BaseBackoff:    time.Millisecond,
+	MaxBackoff:     time.Millisecond,
+	SendTimeout:    time.Second,
+	ReplayInterval: time.Hour,
+}
+
+func item(id int64) audit.LogItem {
+	return audit.LogItem{Entity: audit.ENTITY_BOOK, Action: audit.ACTION_CREATE, EntityID: id}
+}
+
+func TestDispatcher_Delivers(t *testing.T) {
+	sender := &fakeSender{}
+	d := New(sender, NewFileOutbox(filepath.Join(t.TempDir(), "outbox.jsonl")), testConfig)
+	d.Start()
+
+	ctx := metadata.AppendToOutgoingContext(context.Background(), "x-audit-action", "LOGIN")
+	require.NoError(t, d.SendLogRequest(ctx, item(1)))
+	require.NoError(t, d.SendLogRequest(context.Background(), item(2)))
+	require.NoError(t, d.Close(context.Background()))
+
+	sent := sender.events()
+	require.Len(t, sent, 2)
+	assert.Equal(t, int64(1), sent[0].Item.EntityID)
+	// metadata запроса доезжает до сервера, хотя отправка идёт в другой горутине
+	assert.Equal(t, []string{"LOGIN"}, sent[0].Metadata["x-audit-action"])
+}
+
+func TestDispatcher_SpillsAndReplays(t *testing.T) {
+	sender := &fakeSender{err: status.Error(codes.Unavailable, "connection refused")}
+	outbox := NewFileOutbox(filepath.Join(t.TempDir(), "outbox.jsonl"))
+	d := New(sender, outbox, testConfig)
+	d.Start()
+
+	for i := int64(1); i <= 3; i++ {
+		require.NoError(t, d.SendLogRequest(context.Background(), item(i)))
+	}
+	require.NoError(t, d.Close(context.Background()))
+
+	saved, err := outbox.Peek(10)
+	require.NoError(t, err)
+	require.Len(t, saved, 3)
+
+	// после восстановления сервера и перезапуска события уходят из outbox в исходном порядке
+	sender.setErr(nil)
+	New(sender, outbox, testConfig).replay()
+
+	sent := sender.events()
+	require.Len(t, sent, 3)
+	for i, ev := range sent {
+		assert.Equal(t, int64(i+1), ev.Item.EntityID)
+	}
+	saved, err = outbox.Peek(10)
+	require.NoError(t, err)
+	assert.Empty(t, saved)
+}
+
+func TestDispatcher_DropsPermanentErrors(t *testing.T) {
+	sender := &fakeSender{err: status.Error(codes.InvalidArgument, "unknown action")}
+	outbox := NewFileOutbox(filepath.Join(t.TempDir(), "outbox.jsonl"))
+	d := New(sender, outbox, testConfig)
+	d.Start()
+
+	require.NoError(t, d.SendLogRequest(context.Background(), item(1)))
+	require.NoError(t, d.Close(context.Background()))
+
+	saved, err := outbox.Peek(10)
+	require.NoError(t, err)
+	assert.Empty(t, saved)
+}
+
+func TestDispatcher_ReplaysOnClose(t *testing.T) {
+	outbox := NewFileOutbox(filepath.Join(t.TempDir(), "outbox.jsonl"))
+	require.NoError(t, outbox.Append([]Event{{Item: item(1)}, {Item: item(2)}}))
+
+	sender := &fakeSender{}
+	// до первого тика ReplayInterval дело не дойдёт: outbox досылается при остановке
+	d := New(sender, outbox, testConfig)
+	d.Start()
+	require.NoError(t, d.SendLogRequest(context.Background(), item(3)))
+	require.NoError(t, d.Close(context.Background()))
+
+	sent := sender.events()
+	require.Len(t, sent, 3)
+	assert.Equal(t, int64(3), sent[0].Item.EntityID)
+	assert.Equal(t, int64(1), sent[1].Item.EntityID)
+	saved, err := outbox.Peek(10)
+	require.NoError(t, err)
+	assert.Empty(t, saved)
+}
+
+func TestDispatcher_ReplayDropsPermanentErrors(t *testing.T) {
+	outbox := NewFileOutbox(filepath.Join(t.TempDir(), "outbox.jsonl"))
+	require.NoError(t, outbox.Append([]Event{{Item: item(1)}, {Item: item(2)}}))
+
+	sender := &fakeSender{err: status.Error(codes.InvalidArgument, "unknown action")}
+	New(sender, outbox, testConfig).replay()
+
+	// отвергнутые события не копятся в outbox и не блокируют следующие
+	saved, err := outbox.Peek(10)
+	require.NoError(t, err)
+	assert.Empty(t, saved)
+}
+
+func TestDispatcher_KeepsTransientErrors(t *testing.T) {
+	testTable := []struct {
+		name string
+		err  error
+	}{
+		{name: "internal", err: status.Error(codes.Internal, "panic in handler")},
+		{name: "unknown", err: status.Error(codes.Unknown, "unknown")},
+		{name: "not a status", err: errors.New("write audit.jsonl: no space left on device")},
+	}
+
+	for _, testCase := range testTable {
+		t.Run(testCase.name, func(t *testing.T) {
+			sender := &fakeSender{err: testCase.err}
+			outbox := NewFileOutbox(filepath.Join(t.TempDir(), "outbox.jsonl"))
+			d := New(sender, outbox, testConfig)
+			d.Start()
+
+			// не ушло после повторов — в outbox, а не в лог
+			require.NoError(t, d.SendLogRequest(context.Background(), item(1)))
+			require.NoError(t, d.Close(context.Background()))
+			saved, err := outbox.Peek(10)
+			require.NoError(t, err)
+			require.Len(t, saved, 1)
+
+			// и при досылке событие остаётся в outbox до следующей попытки
+			New(sender, outbox, testConfig).replay()
+			saved, err = outbox.Peek(10)
+			require.NoError(t, err)
+			assert.Len(t, saved, 1)
+		})
+	}
+}
+
+func TestDispatcher_FileSinkPrimary(t *testing.T) {
+	dir := t.TempDir()
+	primary, err := auditsink.NewFileSink(filepath.Join(dir, "primary.jsonl"), 0, 0)
+	require.NoError(t, err)
+	// закрытый файл — та же обычная ошибка ввода-вывода, что и переполненный диск
+	require.NoError(t, primary.Close())
+	secondary, err := auditsink.NewFileSink(filepath.Join(dir, "secondary.jsonl"), 0, 0)
+	require.NoError(t, err)
+
+	outbox := NewFileOutbox(filepath.Join(dir, "outbox.jsonl"))
+	d := New(auditsink.MultiSink{primary, secondary}, outbox, testConfig)
+	d.Start()
+	require.NoError(t, d.SendLogRequest(context.Background(), item(1)))
+	require.NoError(t, d.Close(context.Background()))
+
+	// событие не потеряно и не ушло в дополнительный получатель раньше основного
+	saved, err := outbox.Peek(10)
+	require.NoError(t, err)
+	require.Len(t, saved, 1)
+	require.NoError(t, secondary.Close())
+	assert.Empty(t, readLines(t, filepath.Join(dir, "secondary.jsonl")))
+
+	// основной снова пишет — outbox досылается в оба получателя по одному разу
+	primary, err = auditsink.NewFileSink(filepath.Join(dir, "primary.jsonl"), 0, 0)
+	require.NoError(t, err)
+	secondary, err = auditsink.NewFileSink(filepath.Join(dir, "secondary.jsonl"), 0, 0)
+	require.NoError(t, err)
+	New(auditsink.MultiSink{primary, secondary}, outbox, testConfig).replay()
+	require.NoError(t, primary.Close())
+	require.NoError(t, secondary.Close())
+
+	assert.Len(t, readLines(t, filepath.Join(dir, "primary.jsonl")), 1)
+	assert.Len(t, readLines(t, filepath.Join(dir, "secondary.jsonl")), 1)
+	saved, err = outbox.Peek(10)
+	require.NoError(t, err)
+	assert.Empty(t, saved)
+}
+
+func readLines(t *testing.T, path string) []string {
+	t.Helper()
+	b, err := os.ReadFile(path)
+	require.NoError(t, err)
+	return strings.Fields(string(b))
+}
+
+func TestDispatcher_QueueFull(t *testing.T) {
+	outbox := NewFileOutbox(filepath.Join(t.TempDir(), "outbox.jsonl"))
+	// воркеры не запущены: всё сверх ёмкости очереди уходит в outbox
+	d := New(&fakeSender{}, outbox, testConfig)
+
+	for i := int64(1); i <= 6; i++ {
+		require.NoError(t, d.SendLogRequest(context.Background(), item(i)))
+	}
+
+	saved, err := outbox.Peek(10)
+	require.NoError(t, err)
+	require.Len(t, saved, 2)
+	assert.Equal(t, int64(5), saved[0].Item.EntityID)
+}
+
+func TestDispatcher_CloseDeadline(t *testing.T) {
+	sender := &fakeSender{err: status.Error(codes.Unavailable, "connection refused")}
+	outbox := NewFileOutbox(filepath.Join(t.TempDir(), "outbox.jsonl"))
+	cfg := testConfig
+	cfg.MaxRetries = 100
+	cfg.BaseBackoff = time.Hour
+	cfg.MaxBackoff = time.Hour
+	d := New(sender, outbox, cfg)
+	d.Start()
+
+	require.NoError(t, d.SendLogRequest(context.Background(), item(1)))
+
+	ctx, cancel := context.WithTimeout(context.Background(), 50*time.Millisecond)
+	defer cancel()
+	assert.ErrorIs(t, d.Close(ctx), context.DeadlineExceeded)
+
+	saved, err := outbox.Peek(10)
+	require.NoError(t, err)
+	assert.Len(t, saved, 1)
+
+	// после остановки события не теряются
+	require.NoError(t, d.SendLogRequest(context.Background(), item(2)))
+	saved, err = outbox.Peek(10)
+	require.NoError(t, err)
+	assert.Len(t, saved, 2)
+}
@@ -0,0 +1,160 @@
+package auditq
+
+import (
+	"bufio"
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sync"
+)
+
+// Outbox — долговременное хранилище событий, которые не удалось доставить сразу
+type Outbox interface {
+	Append(events []Event) error
+	// Peek возвращает до max самых старых событий, не удаляя их
+	Peek(max int) ([]Event, error)
+	// Remove удаляет n самых старых событий — после того как они доставлены
+	Remove(n int) error
+}
+
+// FileOutbox хранит события в файле, по JSON на строку. Рассчитан на случай, когда сервер
+// логов недоступен минуты, а не дни: Remove перезаписывает файл целиком
+type FileOutbox struct {
+	path string
+	mu   sync.Mutex
+}
+
+func NewFileOutbox(path string) *FileOutbox {
+	return &FileOutbox{path: path}
+}
+
+func (o *FileOutbox) Append(events []Event) error {
+	if len(events) == 0 {
+		return nil
+	}
+
+	o.mu.Lock()
+	defer o.mu.Unlock()
+
+	f, err := os.OpenFile(o.path, os.O_CREATE|os.O_APPEND|os.O_WRONLY, 0o600)
+	if err != nil {
+		return fmt.Errorf("auditq: open outbox: %w", err)
+	}
+	defer f.Close()
+
+	w := bufio.NewWriter(f)
+	enc := json.NewEncoder(w)
+	for _, ev := range events {
+		if err := enc.Encode(ev); err != nil {
+			return fmt.Errorf("auditq: write outbox: %w", err)
+		}
+	}
+	if err := w.Flush(); err != nil {
+		return fmt.Errorf("auditq: write outbox: %w", err)
+	}
+	// событие считается сохранённым только после fsync
+	if err := f.Sync(); err != nil {
+		return fmt.Errorf("auditq: sync outbox: %w", err)
+	}
+	return nil
+}
+
+func (o *FileOutbox) Peek(max int) ([]Event, error) {
+	o.mu.Lock()
+	defer o.mu.Unlock()
+
+	all, err := o.read()
+	if err != nil {
+		return nil, err
+	}
+	if max < len(all) {
+		all = all[:max]
+	}
+	return all, nil
+}
+
+func (o *FileOutbox) Remove(n int) error {
+	if n <= 0 {
+		return nil
+	}
+
+	o.mu.Lock()
+	defer o.mu.Unlock()
+
+	all, err := o.read()
+	if err != nil {
+		return err
+	}
+	if n > len(all) {
+		n = len(all)
+	}
+	return o.rewrite(all[n:])
+}
+
+func (o *FileOutbox) read() ([]Event, error) {
+	f, err := os.Open(o.path)
+	if err != nil {
+		if os.IsNotExist(err) {
+			return nil, nil
+		}
+		return nil, fmt.Errorf("auditq: open outbox: %w", err)
+	}
+	defer f.Close()
+
+	var events []Event
+	scanner := bufio.NewScanner(f)
+	scanner.Buffer(make([]byte, 64*1024), 1024*1024)
+	for scanner.Scan() {
+		var ev Event
+		// оборванная при падении последняя строка не должна блокировать остальные события
+		if err := json.Unmarshal(scanner.Bytes(), &ev); err != nil {
+			continue
+		}
+		events = append(events, ev)
+	}
+	if err := scanner.Err(); err != nil {
+		return nil, fmt.Errorf("auditq: read outbox: %w", err)
+	}
+	return events, nil
+}
+
+// rewrite атомарно заменяет файл: временный файл + rename
+func (o *FileOutbox) rewrite(events []Event) error {
+	if len(events) == 0 {
+		if err := os.Remove(o.path); err != nil && !os.IsNotExist(err) {
+			return fmt.Errorf("auditq: truncate outbox: %w", err)
+		}
+		return nil
+	}
+
+	tmp, err := os.CreateTemp(filepath.Dir(o.path), filepath.Base(o.path)+".*")
+	if err != nil {
+		return fmt.Errorf("auditq: rewrite outbox: %w", err)
+	}
+	defer os.Remove(tmp.Name())
+
+	w := bufio.NewWriter(tmp)
+	enc := json.NewEncoder(w)
+	for _, ev := range events {
+		if err := enc.Encode(ev); err != nil {
+			tmp.Close()
+			return fmt.Errorf("auditq: rewrite outbox: %w", err)
+		}
+	}
+	if err := w.Flush(); err != nil {
+		tmp.Close()
+		return fmt.Errorf("auditq: rewrite outbox: %w", err)
+	}
+	if err := tmp.Sync(); err != nil {
+		tmp.Close()
+		return fmt.Errorf("auditq: rewrite outbox: %w", err)
+	}
+	if err := tmp.Close(); err != nil {
+		return fmt.Errorf("auditq: rewrite outbox: %w", err)
+	}
+	if err := os.Rename(tmp.Name(), o.path); err != nil {
+		return fmt.Errorf("auditq: rewrite outbox: %w", err)
+	}
+	return nil
+}
@@ -0,0 +1,125 @@
+package auditsink
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"os"
+	"sync"
+
+	audit "github.com/CryptoGu1/books-grpc-log/pkg/domain"
+)
+
+const (
+	defaultMaxSize    = 100 << 20
+	defaultMaxBackups = 5
+)
+
+// FileSink пишет события в JSONL-файл. Когда файл дорастает до MaxSize, он переименовывается
+// в path.1 (path.1 — в path.2 и т.д.), хранится не больше MaxBackups старых файлов
+type FileSink struct {
+	path       string
+	maxSize    int64
+	maxBackups int
+
+	mu   sync.Mutex
+	f    *os.File
+	size int64
+}
+
+// NewFileSink; maxSize <= 0 — 100 МБ, maxBackups <= 0 — 5
+func NewFileSink(path string, maxSize int64, maxBackups int) (*FileSink, error) {
+	if maxSize <= 0 {
+		maxSize = defaultMaxSize
+	}
+	if maxBackups <= 0 {
+		maxBackups = defaultMaxBackups
+	}
+
+	s := &FileSink{path: path, maxSize: maxSize, maxBackups: maxBackups}
+	if err := s.open(); err != nil {
+		return nil, err
+	}
+	return s, nil
+}
+
+func (s *FileSink) SendLogRequest(ctx context.Context, req audit.LogItem) error {
+	line, err := json.Marshal(newRecord(ctx, req))
+	if err != nil {
+		return err
+	}
+	line = append(line, '\n')
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if s.f == nil {
+		return fmt.Errorf("auditsink: %s is closed", s.path)
+	}
+	if s.size > 0 && s.size+int64(len(line)) > s.maxSize {
+		if err := s.rotate(); err != nil {
+			return err
+		}
+	}
+
+	n, err := s.f.Write(line)
+	s.size += int64(n)
+	if err != nil {
+		return fmt.Errorf("auditsink: write %s: %w", s.path, err)
+	}
+	return nil
+}
+
+func (s *FileSink) Close() error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if s.f == nil {
+		return nil
+	}
+	err := s.f.Close()
+	s.f = nil
+	return err
+}
+
+func (s *FileSink) open() error {
+	f, err := os.OpenFile(s.path, os.O_CREATE|os.O_APPEND|os.O_WRONLY, 0o600)
+	if err != nil {
+		return fmt.Errorf("auditsink: open %s: %w", s.path, err)
+	}
+	info, err := f.Stat()
+	if err != nil {
+		f.Close()
+		return fmt.Errorf("auditsink: stat %s: %w", s.path, err)
+	}
+	s.f, s.size = f, info.Size()
+	return nil
+}
+
+func (s *FileSink) rotate() error {
+	if err := s.f.Close(); err != nil {
+		return fmt.Errorf("auditsink: rotate %s: %w", s.path, err)
+	}
+	s.f = nil
+
+	// самый старый файл перезаписывается при сдвиге
+	var rotateErr error
+	for i := s.maxBackups - 1; i >= 1 && rotateErr == nil; i-- {
+		from := fmt.Sprintf("%s.%d", s.path, i)
+		if err := os.Rename(from, fmt.Sprintf("%s.%d", s.path, i+1)); err != nil && !os.IsNotExist(err) {
+			rotateErr = err
+		}
+	}
+	if rotateErr == nil {
+		rotateErr = os.Rename(s.path, s.path+".1")
+	}
+
+	// файл открывается снова, даже если сдвиг не удался: лучше писать дальше в старый, чем терять события
+	if err := s.open(); err != nil {
+		return err
+	}
+	if rotateErr != nil {
+		return fmt.Errorf("auditsink: rotate %s: %w", s.path, rotateErr)
+	}
+	return nil
+}
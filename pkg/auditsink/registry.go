@@ -0,0 +1,93 @@
+package auditsink
+
+import (
+	"fmt"
+	"sort"
+	"sync"
+
+	"github.com/CryptoGu1/books-rest-clean-arch/pkg/log_grpc"
+)
+
+// Config — настройки одного получателя; какие поля нужны, зависит от Type
+type Config struct {
+	// Type — имя в реестре: grpc, file, stdout, noop
+	Type string
+	GRPC log_grpc.Config
+	// file
+	Path       string
+	MaxSize    int64
+	MaxBackups int
+}
+
+// Factory создаёт получателя по настройкам
+type Factory func(cfg Config) (Sink, error)
+
+var (
+	mu        sync.RWMutex
+	factories = map[string]Factory{
+		"grpc":   newGRPC,
+		"file":   newFile,
+		"stdout": func(Config) (Sink, error) { return NewStdoutSink(), nil },
+		"noop":   func(Config) (Sink, error) { return NoopSink{}, nil },
+	}
+)
+
+// Register добавляет тип получателя или заменяет существующий
+func Register(name string, f Factory) {
+	mu.Lock()
+	defer mu.Unlock()
+	factories[name] = f
+}
+
+// New создаёт получателя; для нескольких настроек — MultiSink, для пустого списка — NoopSink.
+// Если один из получателей не создался, уже созданные закрываются
+func New(cfgs ...Config) (Sink, error) {
+	sinks := make(MultiSink, 0, len(cfgs))
+	for _, cfg := range cfgs {
+		mu.RLock()
+		factory, ok := factories[cfg.Type]
+		mu.RUnlock()
+		if !ok {
+			_ = sinks.Close()
+			return nil, fmt.Errorf("auditsink: unknown sink %q (known: %v)", cfg.Type, names())
+		}
+
+		sink, err := factory(cfg)
+		if err != nil {
+			_ = sinks.Close()
+			return nil, fmt.Errorf("auditsink: %s: %w", cfg.Type, err)
+		}
+		sinks = append(sinks, sink)
+	}
+
+	switch len(sinks) {
+	case 0:
+		return NoopSink{}, nil
+	case 1:
+		return sinks[0], nil
+	default:
+		return sinks, nil
+	}
+}
+
+func names() []string {
+	mu.RLock()
+	defer mu.RUnlock()
+	list := make([]string, 0, len(factories))
+	for name := range factories {
+		list = append(list, name)
+	}
+	sort.Strings(list)
+	return list
+}
+
+func newGRPC(cfg Config) (Sink, error) {
+	return log_grpc.NewClientWithConfig(cfg.GRPC)
+}
+
+func newFile(cfg Config) (Sink, error) {
+	if cfg.Path == "" {
+		return nil, fmt.Errorf("path is required")
+	}
+	return NewFileSink(cfg.Path, cfg.MaxSize, cfg.MaxBackups)
+}
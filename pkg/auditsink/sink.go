@@ -0,0 +1,144 @@
+// Package auditsink — получатели аудит-событий: сервис логов по gRPC, JSONL-файл с ротацией,
+// stdout, no-op и рассылка в несколько получателей. Все реализуют service.AuditClient
+package auditsink
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"io"
+	"os"
+	"sync"
+	"time"
+
+	audit "github.com/CryptoGu1/books-grpc-log/pkg/domain"
+	"github.com/sirupsen/logrus"
+	"google.golang.org/grpc/metadata"
+)
+
+type Sink interface {
+	SendLogRequest(ctx context.Context, req audit.LogItem) error
+	Close() error
+}
+
+// Checker — получатель, который умеет проверить доступность адресата (log_grpc.Client)
+type Checker interface {
+	Check(ctx context.Context) error
+}
+
+// Check проверяет получателя, если он это умеет; остальным проверять нечего
+func Check(ctx context.Context, s Sink) error {
+	if c, ok := s.(Checker); ok {
+		return c.Check(ctx)
+	}
+	return nil
+}
+
+// Record — строка JSONL для файла и stdout. Metadata — детали события (актор, запрос, diff),
+// которые сервису логов уходят как gRPC metadata
+type Record struct {
+	Entity    string              `json:"entity"`
+	Action    string              `json:"action"`
+	EntityID  int64               `json:"entity_id"`
+	Timestamp time.Time           `json:"timestamp"`
+	Metadata  map[string][]string `json:"metadata,omitempty"`
+}
+
+func newRecord(ctx context.Context, req audit.LogItem) Record {
+	r := Record{
+		Entity:    req.Entity,
+		Action:    req.Action,
+		EntityID:  req.EntityID,
+		Timestamp: req.Timestamp,
+	}
+	if md, ok := metadata.FromOutgoingContext(ctx); ok {
+		r.Metadata = md.Copy()
+	}
+	return r
+}
+
+// WriterSink пишет события в w по JSON на строку
+type WriterSink struct {
+	mu sync.Mutex
+	w  io.Writer
+}
+
+func NewWriterSink(w io.Writer) *WriterSink {
+	return &WriterSink{w: w}
+}
+
+func NewStdoutSink() *WriterSink {
+	return NewWriterSink(os.Stdout)
+}
+
+func (s *WriterSink) SendLogRequest(ctx context.Context, req audit.LogItem) error {
+	line, err := json.Marshal(newRecord(ctx, req))
+	if err != nil {
+		return err
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	_, err = s.w.Write(append(line, '\n'))
+	return err
+}
+
+func (s *WriterSink) Close() error {
+	return nil
+}
+
+// NoopSink отбрасывает события — для локальной разработки без сервиса логов
+type NoopSink struct{}
+
+func (NoopSink) SendLogRequest(context.Context, audit.LogItem) error { return nil }
+
+func (NoopSink) Close() error { return nil }
+
+// MultiSink отправляет событие во все получатели. Первый — основной: только его ошибка
+// возвращается вызывающему и ведёт к повтору, а остальные получают событие лишь после того,
+// как его принял основной, — повтор не продублирует его у них. Отказ дополнительного
+// получателя логируется, событие для него теряется. Ошибки ввода-вывода файла и stdout
+// возвращаются как есть: для auditq это временный сбой (не log_grpc.Rejected), событие уйдёт в outbox
+type MultiSink []Sink
+
+func (m MultiSink) SendLogRequest(ctx context.Context, req audit.LogItem) error {
+	if len(m) == 0 {
+		return nil
+	}
+	if err := m[0].SendLogRequest(ctx, req); err != nil {
+		return err
+	}
+
+	for i, s := range m[1:] {
+		if err := s.SendLogRequest(ctx, req); err != nil {
+			logrus.WithFields(logrus.Fields{
+				"component": "auditsink",
+				"sink":      i + 1,
+				"action":    req.Action,
+				"entity":    req.Entity,
+				"entity_id": req.EntityID,
+			}).Error("audit event not delivered to secondary sink: ", err)
+		}
+	}
+	return nil
+}
+
+func (m MultiSink) Check(ctx context.Context) error {
+	var errs []error
+	for _, s := range m {
+		if err := Check(ctx, s); err != nil {
+			errs = append(errs, err)
+		}
+	}
+	return errors.Join(errs...)
+}
+
+func (m MultiSink) Close() error {
+	var errs []error
+	for _, s := range m {
+		if err := s.Close(); err != nil {
+			errs = append(errs, err)
+		}
+	}
+	return errors.Join(errs...)
+}
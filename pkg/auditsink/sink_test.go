@@ -0,0 +1,164 @@
+package auditsink
+
+import (
+	"bufio"
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	audit "github.com/CryptoGu1/books-grpc-log/pkg/domain"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"google.golang.org/grpc/metadata"
+)
+
+func item(id int64) audit.LogItem {
+	return audit.LogItem{
+		Entity:    audit.ENTITY_BOOK,
+		Action:    audit.ACTION_DELETE,
+		EntityID:  id,
+		Timestamp: time.Date(2026, 10, 1, 12, 0, 0, 0, time.UTC),
+	}
+}
+
+func readRecords(t *testing.T, path string) []Record {
+	t.Helper()
+	f, err := os.Open(path)
+	require.NoError(t, err)
+	defer f.Close()
+
+	var records []Record
+	scanner := bufio.NewScanner(f)
+	for scanner.Scan() {
+		var r Record
+		require.NoError(t, json.Unmarshal(scanner.Bytes(), &r))
+		records = append(records, r)
+	}
+	require.NoError(t, scanner.Err())
+	return records
+}
+
+func TestWriterSink(t *testing.T) {
+	var buf bytes.Buffer
+	sink := NewWriterSink(&buf)
+
+	ctx := metadata.AppendToOutgoingContext(context.Background(), "x-audit-actor-id", "10")
+	require.NoError(t, sink.SendLogRequest(ctx, item(1)))
+
+	var r Record
+	require.NoError(t, json.Unmarshal(buf.Bytes(), &r))
+	assert.Equal(t, Record{
+		Entity:    audit.ENTITY_BOOK,
+		Action:    audit.ACTION_DELETE,
+		EntityID:  1,
+		Timestamp: item(1).Timestamp,
+		Metadata:  map[string][]string{"x-audit-actor-id": {"10"}},
+	}, r)
+}
+
+func TestFileSink_Rotate(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "audit.jsonl")
+	line, err := json.Marshal(newRecord(context.Background(), item(1)))
+	require.NoError(t, err)
+
+	// в файл помещается ровно две записи
+	sink, err := NewFileSink(path, int64(2*(len(line)+1)), 2)
+	require.NoError(t, err)
+
+	for id := int64(1); id <= 7; id++ {
+		require.NoError(t, sink.SendLogRequest(context.Background(), item(id)))
+	}
+	require.NoError(t, sink.Close())
+
+	ids := func(p string) []int64 {
+		var list []int64
+		for _, r := range readRecords(t, p) {
+			list = append(list, r.EntityID)
+		}
+		return list
+	}
+	assert.Equal(t, []int64{7}, ids(path))
+	assert.Equal(t, []int64{5, 6}, ids(path+".1"))
+	assert.Equal(t, []int64{3, 4}, ids(path+".2"))
+	// старше MaxBackups файлы не хранятся
+	assert.NoFileExists(t, path+".3")
+
+	assert.Error(t, sink.SendLogRequest(context.Background(), item(8)))
+}
+
+type failingSink struct{ err error }
+
+func (f failingSink) SendLogRequest(context.Context, audit.LogItem) error { return f.err }
+
+func (f failingSink) Close() error { return nil }
+
+type checkingSink struct {
+	NoopSink
+	err error
+}
+
+func (c checkingSink) Check(context.Context) error { return c.err }
+
+func TestCheck(t *testing.T) {
+	down := errors.New("down")
+
+	assert.NoError(t, Check(context.Background(), NewStdoutSink()))
+	assert.NoError(t, Check(context.Background(), MultiSink{NewStdoutSink(), checkingSink{}}))
+	assert.ErrorIs(t, Check(context.Background(), MultiSink{NewStdoutSink(), checkingSink{err: down}}), down)
+}
+
+func TestNew(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "audit.jsonl")
+	errDown := errors.New("logger is down")
+	Register("failing", func(Config) (Sink, error) { return failingSink{errDown}, nil })
+
+	testTable := []struct {
+		name          string
+		cfgs          []Config
+		expectedSink  Sink
+		expectedError bool
+	}{
+		{name: "no sinks", expectedSink: NoopSink{}},
+		{name: "single", cfgs: []Config{{Type: "noop"}}, expectedSink: NoopSink{}},
+		{name: "unknown type", cfgs: []Config{{Type: "noop"}, {Type: "kafka"}}, expectedError: true},
+		{name: "file without path", cfgs: []Config{{Type: "file"}}, expectedError: true},
+	}
+
+	for _, testCase := range testTable {
+		t.Run(testCase.name, func(t *testing.T) {
+			sink, err := New(testCase.cfgs...)
+			if testCase.expectedError {
+				assert.Error(t, err)
+				return
+			}
+			require.NoError(t, err)
+			assert.Equal(t, testCase.expectedSink, sink)
+		})
+	}
+
+	t.Run("fan-out", func(t *testing.T) {
+		sink, err := New(Config{Type: "file", Path: path}, Config{Type: "failing"})
+		require.NoError(t, err)
+		require.IsType(t, MultiSink{}, sink)
+
+		// отказ дополнительного получателя не мешает основному и не ведёт к повтору
+		require.NoError(t, sink.SendLogRequest(context.Background(), item(1)))
+		require.NoError(t, sink.Close())
+		assert.Len(t, readRecords(t, path), 1)
+	})
+
+	t.Run("primary failure", func(t *testing.T) {
+		sink, err := New(Config{Type: "failing"}, Config{Type: "file", Path: path + ".2"})
+		require.NoError(t, err)
+
+		// событие повторят целиком: дополнительные получатели получат его один раз, после основного
+		assert.ErrorIs(t, sink.SendLogRequest(context.Background(), item(1)), errDown)
+		require.NoError(t, sink.Close())
+		assert.Empty(t, readRecords(t, path+".2"))
+	})
+}
@@ -0,0 +1,60 @@
+package cursor
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+type payload struct {
+	Sort string `json:"s"`
+	ID   int    `json:"id"`
+}
+
+func TestCodec_RoundTrip(t *testing.T) {
+	codec := NewCodec([]byte("secret"))
+
+	token, err := codec.Encode(payload{Sort: "-rating", ID: 42})
+	require.NoError(t, err)
+
+	var got payload
+	require.NoError(t, codec.Decode(token, &got))
+	assert.Equal(t, payload{Sort: "-rating", ID: 42}, got)
+}
+
+func TestCodec_Rejects(t *testing.T) {
+	codec := NewCodec([]byte("secret"))
+	token, err := codec.Encode(payload{Sort: "id", ID: 1})
+	require.NoError(t, err)
+
+	forged, err := NewCodec([]byte("other")).Encode(payload{Sort: "id", ID: 1000})
+	require.NoError(t, err)
+
+	for name, input := range map[string]string{
+		"empty":          "",
+		"no signature":   "eyJpZCI6MX0",
+		"bad base64":     "!!!.???",
+		"foreign secret": forged,
+		"tampered":       "x" + token,
+	} {
+		t.Run(name, func(t *testing.T) {
+			var got payload
+			assert.ErrorIs(t, codec.Decode(input, &got), ErrInvalidCursor)
+		})
+	}
+}
+
+func TestDeriveSecret(t *testing.T) {
+	secret := []byte("jwt-secret")
+	derived := DeriveSecret(secret)
+
+	assert.NotEqual(t, secret, derived)
+	assert.Equal(t, derived, DeriveSecret(secret))
+
+	// курсор, подписанный самим общим секретом, производным ключом не принимается
+	s, err := NewCodec(secret).Encode(payload{Sort: "id", ID: 1})
+	require.NoError(t, err)
+	var got payload
+	assert.ErrorIs(t, NewCodec(derived).Decode(s, &got), ErrInvalidCursor)
+}
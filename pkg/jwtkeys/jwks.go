@@ -0,0 +1,111 @@
+package jwtkeys
+
+import (
+	"crypto/ed25519"
+	"crypto/rsa"
+	"encoding/base64"
+	"errors"
+	"fmt"
+	"math"
+	"math/big"
+	"sort"
+	"time"
+)
+
+// JWKS — публичная часть набора в формате RFC 7517
+type JWKS struct {
+	Keys []JWK `json:"keys"`
+}
+
+type JWK struct {
+	Kty string `json:"kty"`
+	Kid string `json:"kid"`
+	Use string `json:"use"`
+	Alg string `json:"alg"`
+	// RSA
+	N string `json:"n,omitempty"`
+	E string `json:"e,omitempty"`
+	// Ed25519 (RFC 8037)
+	Crv string `json:"crv,omitempty"`
+	X   string `json:"x,omitempty"`
+}
+
+// JWKS возвращает ключи, которые сейчас проверяют подписи. В HMAC-режиме список пуст:
+// симметричный секрет не публикуется
+func (s *KeySet) JWKS() JWKS {
+	set := JWKS{Keys: []JWK{}}
+	now := s.now()
+	for _, k := range s.keys {
+		if !k.VerifyUntil.IsZero() && now.After(k.VerifyUntil) {
+			continue
+		}
+
+		jwk := JWK{Kid: k.ID, Use: "sig", Alg: k.Method.Alg()}
+		enc := base64.RawURLEncoding
+		switch pub := k.public.(type) {
+		case *rsa.PublicKey:
+			jwk.Kty = "RSA"
+			jwk.N = enc.EncodeToString(pub.N.Bytes())
+			jwk.E = enc.EncodeToString(big.NewInt(int64(pub.E)).Bytes())
+		case ed25519.PublicKey:
+			jwk.Kty = "OKP"
+			jwk.Crv = "Ed25519"
+			jwk.X = enc.EncodeToString(pub)
+		default:
+			continue
+		}
+		set.Keys = append(set.Keys, jwk)
+	}
+
+	sort.Slice(set.Keys, func(i, j int) bool { return set.Keys[i].Kid < set.Keys[j].Kid })
+	return set
+}
+
+// PublicKey восстанавливает ключ из JWK; поддерживаются те же типы, что и для подписи
+func (j JWK) PublicKey() (*Key, error) {
+	enc := base64.RawURLEncoding
+	switch {
+	case j.Kty == "RSA":
+		n, err := enc.DecodeString(j.N)
+		if err != nil {
+			return nil, fmt.Errorf("jwtkeys: jwk %q: modulus: %w", j.Kid, err)
+		}
+		e, err := enc.DecodeString(j.E)
+		if err != nil {
+			return nil, fmt.Errorf("jwtkeys: jwk %q: exponent: %w", j.Kid, err)
+		}
+		exp := new(big.Int).SetBytes(e)
+		if !exp.IsInt64() || exp.Int64() > math.MaxInt32 {
+			return nil, fmt.Errorf("jwtkeys: jwk %q: exponent out of range", j.Kid)
+		}
+		return NewKey(j.Kid, &rsa.PublicKey{N: new(big.Int).SetBytes(n), E: int(exp.Int64())}, time.Time{})
+	case j.Kty == "OKP" && j.Crv == "Ed25519":
+		x, err := enc.DecodeString(j.X)
+		if err != nil || len(x) != ed25519.PublicKeySize {
+			return nil, fmt.Errorf("jwtkeys: jwk %q: invalid Ed25519 key", j.Kid)
+		}
+		return NewKey(j.Kid, ed25519.PublicKey(x), time.Time{})
+	default:
+		return nil, fmt.Errorf("jwtkeys: jwk %q: unsupported key type %s", j.Kid, j.Kty)
+	}
+}
+
+// NewVerifier собирает набор только для проверки подписей — например, из JWKS внешнего провайдера.
+// Ключи неподдерживаемых типов и ключи не для подписи пропускаются
+func NewVerifier(set JWKS) (*KeySet, error) {
+	s := &KeySet{keys: make(map[string]*Key, len(set.Keys)), now: time.Now}
+	for _, jwk := range set.Keys {
+		if jwk.Use != "" && jwk.Use != "sig" {
+			continue
+		}
+		k, err := jwk.PublicKey()
+		if err != nil {
+			continue
+		}
+		s.keys[k.ID] = k
+	}
+	if len(s.keys) == 0 {
+		return nil, errors.New("jwtkeys: no usable keys in JWKS")
+	}
+	return s, nil
+}
@@ -0,0 +1,180 @@
+// Package jwtkeys — набор ключей для подписи и проверки JWT. Подписывает один активный ключ,
+// проверяют все загруженные: так старый ключ продолжает работать в окне ротации
+package jwtkeys
+
+import (
+	"crypto"
+	"crypto/ed25519"
+	"crypto/rsa"
+	"crypto/x509"
+	"encoding/pem"
+	"errors"
+	"fmt"
+	"os"
+	"time"
+
+	"github.com/golang-jwt/jwt/v5"
+)
+
+var (
+	ErrUnknownKey = errors.New("jwtkeys: unknown key id")
+	ErrKeyRetired = errors.New("jwtkeys: key retired")
+)
+
+type Key struct {
+	ID     string
+	Method jwt.SigningMethod
+	// VerifyUntil — после этого момента ключ перестаёт проверять подписи; нулевое значение — бессрочно
+	VerifyUntil time.Time
+
+	private crypto.Signer
+	public  crypto.PublicKey
+}
+
+// LoadKey читает PEM-файл: приватный ключ (PKCS#8/PKCS#1) — ключ может подписывать,
+// публичный (PKIX) — только проверять
+func LoadKey(kid, path string, verifyUntil time.Time) (*Key, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, fmt.Errorf("jwtkeys: read %s: %w", path, err)
+	}
+	return ParseKey(kid, data, verifyUntil)
+}
+
+func ParseKey(kid string, pemData []byte, verifyUntil time.Time) (*Key, error) {
+	block, _ := pem.Decode(pemData)
+	if block == nil {
+		return nil, fmt.Errorf("jwtkeys: key %q: no PEM block", kid)
+	}
+
+	var parsed interface{}
+	var err error
+	switch block.Type {
+	case "PRIVATE KEY":
+		parsed, err = x509.ParsePKCS8PrivateKey(block.Bytes)
+	case "RSA PRIVATE KEY":
+		parsed, err = x509.ParsePKCS1PrivateKey(block.Bytes)
+	case "PUBLIC KEY":
+		parsed, err = x509.ParsePKIXPublicKey(block.Bytes)
+	default:
+		return nil, fmt.Errorf("jwtkeys: key %q: unsupported PEM type %q", kid, block.Type)
+	}
+	if err != nil {
+		return nil, fmt.Errorf("jwtkeys: key %q: %w", kid, err)
+	}
+
+	return NewKey(kid, parsed, verifyUntil)
+}
+
+// NewKey оборачивает RSA или Ed25519 ключ (приватный или публичный)
+func NewKey(kid string, raw interface{}, verifyUntil time.Time) (*Key, error) {
+	k := &Key{ID: kid, VerifyUntil: verifyUntil}
+
+	switch v := raw.(type) {
+	case *rsa.PrivateKey:
+		k.Method, k.private, k.public = jwt.SigningMethodRS256, v, &v.PublicKey
+	case *rsa.PublicKey:
+		k.Method, k.public = jwt.SigningMethodRS256, v
+	case ed25519.PrivateKey:
+		k.Method, k.private, k.public = jwt.SigningMethodEdDSA, v, v.Public()
+	case ed25519.PublicKey:
+		k.Method, k.public = jwt.SigningMethodEdDSA, v
+	default:
+		return nil, fmt.Errorf("jwtkeys: key %q: unsupported key type %T", kid, raw)
+	}
+	return k, nil
+}
+
+func (k *Key) CanSign() bool {
+	return k.private != nil
+}
+
+type KeySet struct {
+	signing *Key
+	keys    map[string]*Key
+	// hmac — режим совместимости: HS256 с общим секретом, без kid и без JWKS
+	hmac []byte
+	now  func() time.Time
+}
+
+// New собирает набор из асимметричных ключей; signingKID должен указывать на ключ с приватной частью
+func New(signingKID string, keys ...*Key) (*KeySet, error) {
+	s := &KeySet{keys: make(map[string]*Key, len(keys)), now: time.Now}
+	for _, k := range keys {
+		if _, dup := s.keys[k.ID]; dup {
+			return nil, fmt.Errorf("jwtkeys: duplicate key id %q", k.ID)
+		}
+		s.keys[k.ID] = k
+	}
+
+	signing, ok := s.keys[signingKID]
+	if !ok {
+		return nil, fmt.Errorf("jwtkeys: signing key %q: %w", signingKID, ErrUnknownKey)
+	}
+	if !signing.CanSign() {
+		return nil, fmt.Errorf("jwtkeys: signing key %q has no private part", signingKID)
+	}
+	s.signing = signing
+	return s, nil
+}
+
+// NewHMAC — набор для HS256 с общим секретом (локальная разработка и старые развёртывания)
+func NewHMAC(secret []byte) *KeySet {
+	return &KeySet{hmac: secret, now: time.Now}
+}
+
+// Sign подписывает claims активным ключом и проставляет kid в заголовок
+func (s *KeySet) Sign(claims jwt.Claims) (string, error) {
+	if s.hmac != nil {
+		return jwt.NewWithClaims(jwt.SigningMethodHS256, claims).SignedString(s.hmac)
+	}
+
+	if s.signing == nil {
+		return "", errors.New("jwtkeys: key set can only verify")
+	}
+
+	token := jwt.NewWithClaims(s.signing.Method, claims)
+	token.Header["kid"] = s.signing.ID
+	return token.SignedString(s.signing.private)
+}
+
+// Parse проверяет подпись и стандартные claims. Алгоритм берётся из ключа, а не из токена,
+// поэтому подделать HS256-токен публичным ключом не выйдет. opts добавляют проверки (iss, aud...)
+func (s *KeySet) Parse(tokenStr string, claims jwt.Claims, opts ...jwt.ParserOption) (*jwt.Token, error) {
+	opts = append([]jwt.ParserOption{jwt.WithValidMethods(s.methods())}, opts...)
+	return jwt.ParseWithClaims(tokenStr, claims, s.keyfunc, opts...)
+}
+
+func (s *KeySet) keyfunc(token *jwt.Token) (interface{}, error) {
+	if s.hmac != nil {
+		return s.hmac, nil
+	}
+
+	kid, _ := token.Header["kid"].(string)
+	k, ok := s.keys[kid]
+	if !ok {
+		return nil, ErrUnknownKey
+	}
+	if !k.VerifyUntil.IsZero() && s.now().After(k.VerifyUntil) {
+		return nil, ErrKeyRetired
+	}
+	if token.Method.Alg() != k.Method.Alg() {
+		return nil, fmt.Errorf("jwtkeys: key %q expects %s", kid, k.Method.Alg())
+	}
+	return k.public, nil
+}
+
+func (s *KeySet) methods() []string {
+	if s.hmac != nil {
+		return []string{jwt.SigningMethodHS256.Alg()}
+	}
+	seen := make(map[string]bool)
+	var methods []string
+	for _, k := range s.keys {
+		if alg := k.Method.Alg(); !seen[alg] {
+			seen[alg] = true
+			methods = append(methods, alg)
+		}
+	}
+	return methods
+}
@@ -0,0 +1,124 @@
+package jwtkeys
+
+import (
+	"crypto/ed25519"
+	"crypto/rand"
+	"crypto/rsa"
+	"testing"
+	"time"
+
+	"github.com/golang-jwt/jwt/v5"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func newEd25519(t *testing.T, kid string, verifyUntil time.Time) *Key {
+	_, priv, err := ed25519.GenerateKey(rand.Reader)
+	require.NoError(t, err)
+	k, err := NewKey(kid, priv, verifyUntil)
+	require.NoError(t, err)
+	return k
+}
+
+func claims() jwt.RegisteredClaims {
+	return jwt.RegisteredClaims{Subject: "7", ExpiresAt: jwt.NewNumericDate(time.Now().Add(time.Hour))}
+}
+
+func TestKeySet_Rotation(t *testing.T) {
+	old := newEd25519(t, "2026-07", time.Time{})
+	oldSet, err := New("2026-07", old)
+	require.NoError(t, err)
+	oldToken, err := oldSet.Sign(claims())
+	require.NoError(t, err)
+
+	rsaKey, err := rsa.GenerateKey(rand.Reader, 2048)
+	require.NoError(t, err)
+	current, err := NewKey("2026-10", rsaKey, time.Time{})
+	require.NoError(t, err)
+
+	// старый ключ ещё в окне ротации
+	old.VerifyUntil = time.Now().Add(time.Hour)
+	set, err := New("2026-10", current, old)
+	require.NoError(t, err)
+
+	newToken, err := set.Sign(claims())
+	require.NoError(t, err)
+
+	parsed, err := set.Parse(newToken, &jwt.RegisteredClaims{})
+	require.NoError(t, err)
+	assert.Equal(t, "2026-10", parsed.Header["kid"])
+	assert.Equal(t, "RS256", parsed.Method.Alg())
+
+	_, err = set.Parse(oldToken, &jwt.RegisteredClaims{})
+	assert.NoError(t, err)
+
+	jwks := set.JWKS()
+	require.Len(t, jwks.Keys, 2)
+	assert.Equal(t, "OKP", jwks.Keys[0].Kty)
+	assert.Equal(t, "RSA", jwks.Keys[1].Kty)
+
+	// окно закрылось — старые токены больше не проходят и ключ пропадает из JWKS
+	set.now = func() time.Time { return time.Now().Add(2 * time.Hour) }
+	_, err = set.Parse(oldToken, &jwt.RegisteredClaims{})
+	assert.ErrorIs(t, err, ErrKeyRetired)
+	assert.Len(t, set.JWKS().Keys, 1)
+}
+
+func TestKeySet_RejectsForeignTokens(t *testing.T) {
+	set, err := New("k1", newEd25519(t, "k1", time.Time{}))
+	require.NoError(t, err)
+
+	t.Run("hs256", func(t *testing.T) {
+		token, err := NewHMAC([]byte("secret")).Sign(claims())
+		require.NoError(t, err)
+		_, err = set.Parse(token, &jwt.RegisteredClaims{})
+		assert.Error(t, err)
+	})
+
+	t.Run("unknown kid", func(t *testing.T) {
+		other, err := New("k2", newEd25519(t, "k2", time.Time{}))
+		require.NoError(t, err)
+		token, err := other.Sign(claims())
+		require.NoError(t, err)
+		_, err = set.Parse(token, &jwt.RegisteredClaims{})
+		assert.ErrorIs(t, err, ErrUnknownKey)
+	})
+
+	t.Run("signing key without private part", func(t *testing.T) {
+		pub, _, err := ed25519.GenerateKey(rand.Reader)
+		require.NoError(t, err)
+		k, err := NewKey("pub", pub, time.Time{})
+		require.NoError(t, err)
+		_, err = New("pub", k)
+		assert.Error(t, err)
+	})
+}
+
+func TestNewVerifier_FromJWKS(t *testing.T) {
+	rsaKey, err := rsa.GenerateKey(rand.Reader, 2048)
+	require.NoError(t, err)
+	rsaSigner, err := NewKey("rsa", rsaKey, time.Time{})
+	require.NoError(t, err)
+	edSigner := newEd25519(t, "ed", time.Time{})
+
+	signers, err := New("rsa", rsaSigner, edSigner)
+	require.NoError(t, err)
+
+	// JWKS с чужого сервера: лишние ключи не мешают
+	published := signers.JWKS()
+	published.Keys = append(published.Keys, JWK{Kty: "EC", Kid: "ec", Use: "sig"}, JWK{Kty: "RSA", Kid: "enc", Use: "enc"})
+
+	verifier, err := NewVerifier(published)
+	require.NoError(t, err)
+
+	token, err := signers.Sign(claims())
+	require.NoError(t, err)
+	_, err = verifier.Parse(token, &jwt.RegisteredClaims{})
+	assert.NoError(t, err)
+
+	_, err = verifier.Sign(claims())
+	assert.Error(t, err)
+
+	_, err = NewVerifier(JWKS{Keys: []JWK{{Kty: "EC", Kid: "ec"}}})
+	assert.Error(t, err)
+}
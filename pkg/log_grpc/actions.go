@@ -0,0 +1,23 @@
+package log_grpc
+
+import audit "github.com/CryptoGu1/books-grpc-log/pkg/domain"
+
+// Действия, которых нет в протоколе сервера логов. На сервер они уходят как ближайшее
+// базовое действие, а настоящее имя передаётся в metadata (actionKey)
+const (
+	ACTION_LOGOUT     = "LOGOUT"
+	ACTION_LOGOUT_ALL = "LOGOUT_ALL"
+
+	ACTION_PASSWORD_RESET = "PASSWORD_RESET"
+	ACTION_LOCKOUT        = "LOCKOUT"
+)
+
+const actionKey = "x-audit-action"
+
+var baseActions = map[string]string{
+	ACTION_LOGOUT:     audit.ACTION_LOGIN,
+	ACTION_LOGOUT_ALL: audit.ACTION_LOGIN,
+
+	ACTION_PASSWORD_RESET: audit.ACTION_UPDATE,
+	ACTION_LOCKOUT:        audit.ACTION_LOGIN,
+}
@@ -0,0 +1,104 @@
+package log_grpc
+
+import (
+	"sync"
+	"time"
+
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+)
+
+// ErrCircuitOpen — сервер логов признан нездоровым, запрос не отправлялся. Код Unavailable,
+// чтобы вызывающая сторона обработала его как обычную временную недоступность
+var ErrCircuitOpen = status.Error(codes.Unavailable, "log_grpc: circuit breaker is open")
+
+type breakerState int
+
+const (
+	breakerClosed breakerState = iota
+	breakerOpen
+	breakerHalfOpen
+)
+
+// breaker — предохранитель: после threshold подряд неудачных вызовов запросы не отправляются
+// в течение openTimeout, затем один пробный запрос решает, закрыть его или снова открыть
+type breaker struct {
+	threshold   int
+	openTimeout time.Duration
+	now         func() time.Time
+
+	mu       sync.Mutex
+	state    breakerState
+	failures int
+	openedAt time.Time
+}
+
+func newBreaker(threshold int, openTimeout time.Duration) *breaker {
+	return &breaker{threshold: threshold, openTimeout: openTimeout, now: time.Now}
+}
+
+// allow сообщает, можно ли отправлять запрос. В полуоткрытом состоянии пропускается один
+func (b *breaker) allow() bool {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	switch b.state {
+	case breakerOpen:
+		if b.now().Sub(b.openedAt) < b.openTimeout {
+			return false
+		}
+		b.state = breakerHalfOpen
+		return true
+	case breakerHalfOpen:
+		return false
+	default:
+		return true
+	}
+}
+
+// record учитывает результат вызова. Ошибки самого запроса (неизвестное действие и т.п.)
+// о здоровье сервера не говорят и считаются успехом. Отменённый вызывающей стороной запрос
+// не говорит ничего: состояние не меняется, а отменённая проба уступает место следующей
+func (b *breaker) record(err error) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	if status.Code(err) == codes.Canceled {
+		if b.state == breakerHalfOpen {
+			// openedAt прежний, поэтому следующий allow сразу пропустит новую пробу
+			b.state = breakerOpen
+		}
+		return
+	}
+
+	if !unhealthy(err) {
+		b.state, b.failures = breakerClosed, 0
+		return
+	}
+
+	b.failures++
+	if b.state == breakerHalfOpen || b.failures >= b.threshold {
+		b.state, b.openedAt = breakerOpen, b.now()
+	}
+}
+
+// unhealthy — любая ошибка, кроме отказа в самом запросе (Rejected): сбой сервера,
+// транспорта или ошибка без gRPC-статуса
+func unhealthy(err error) bool {
+	return err != nil && !Rejected(err)
+}
+
+// Rejected — сервер логов разобрал запрос и отказал в нём (неизвестное действие, нет прав и т.п.).
+// Повтор такого запроса не поможет. Всё остальное, включая ошибки без gRPC-статуса, считается
+// временным: событие стоит сохранить и отправить ещё раз
+func Rejected(err error) bool {
+	if err == nil {
+		return false
+	}
+	switch status.Code(err) {
+	case codes.InvalidArgument, codes.NotFound, codes.AlreadyExists, codes.PermissionDenied, codes.Unimplemented:
+		return true
+	default:
+		return false
+	}
+}
@@ -2,25 +2,123 @@ package log_grpc
 
 import (
 	"context"
+	"crypto/tls"
+	"crypto/x509"
+	"encoding/json"
 	"fmt"
+	"os"
+	"strconv"
+	"time"
 
 	audit "github.com/CryptoGu1/books-grpc-log/pkg/domain"
 	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/credentials"
 	"google.golang.org/grpc/credentials/insecure"
+	_ "google.golang.org/grpc/health" // регистрирует клиентский health-check для healthCheckConfig
+	healthpb "google.golang.org/grpc/health/grpc_health_v1"
+	"google.golang.org/grpc/keepalive"
+	"google.golang.org/grpc/metadata"
+	"google.golang.org/grpc/status"
 	"google.golang.org/protobuf/types/known/timestamppb"
 )
 
 type Client struct {
 	conn        *grpc.ClientConn
 	auditClient audit.AuditServiceClient
+	health      healthpb.HealthClient
+	timeout     time.Duration
+	breaker     *breaker
+}
+
+// Config — подключение к серверу логов. Нулевые значения заменяются умолчаниями
+type Config struct {
+	Host string
+	Port int
+	// Timeout — дедлайн одного вызова, если у контекста нет более раннего
+	Timeout time.Duration
+	TLS     TLSConfig
+	Retry   RetryConfig
+	// KeepaliveTime — как часто пинговать соединение с активными вызовами; 0 — не пинговать.
+	// Не меньше, чем разрешает сервер (в grpc-go по умолчанию 5 минут), иначе он разорвёт соединение
+	KeepaliveTime    time.Duration
+	KeepaliveTimeout time.Duration
+	Breaker          BreakerConfig
+	// HealthCheck — маршрутизировать вызовы только на SERVING по стандартному gRPC health-протоколу.
+	// Сервер без health-сервиса считается здоровым
+	HealthCheck bool
+}
+
+// TLSConfig; без CAFile используются системные корневые сертификаты. CertFile и KeyFile — для mTLS
+type TLSConfig struct {
+	Enabled    bool
+	CAFile     string
+	CertFile   string
+	KeyFile    string
+	ServerName string
+}
+
+// RetryConfig — политика повторов gRPC (service config): повторяются только UNAVAILABLE
+// и RESOURCE_EXHAUSTED, то есть вызовы, не дошедшие до обработки
+type RetryConfig struct {
+	MaxAttempts    int
+	InitialBackoff time.Duration
+	MaxBackoff     time.Duration
+}
+
+// BreakerConfig — после FailureThreshold подряд неудачных вызовов запросы отклоняются
+// сразу (ErrCircuitOpen) в течение OpenTimeout
+type BreakerConfig struct {
+	FailureThreshold int
+	OpenTimeout      time.Duration
+}
+
+var DefaultConfig = Config{
+	Host:             "localhost",
+	Port:             9000,
+	Timeout:          3 * time.Second,
+	Retry:            RetryConfig{MaxAttempts: 3, InitialBackoff: 100 * time.Millisecond, MaxBackoff: time.Second},
+	KeepaliveTime:    5 * time.Minute,
+	KeepaliveTimeout: 10 * time.Second,
+	Breaker:          BreakerConfig{FailureThreshold: 5, OpenTimeout: 10 * time.Second},
 }
 
 func NewClient(host string, port int) (*Client, error) {
-	var conn *grpc.ClientConn
+	cfg := DefaultConfig
+	cfg.Host, cfg.Port = host, port
+	return NewClientWithConfig(cfg)
+}
+
+func NewClientWithConfig(cfg Config) (*Client, error) {
+	cfg = withDefaults(cfg)
 
-	addr := fmt.Sprintf("%s:%d", host, port)
+	creds := insecure.NewCredentials()
+	if cfg.TLS.Enabled {
+		tlsCfg, err := cfg.TLS.load()
+		if err != nil {
+			return nil, err
+		}
+		creds = credentials.NewTLS(tlsCfg)
+	}
 
-	conn, err := grpc.NewClient(addr, grpc.WithTransportCredentials(insecure.NewCredentials()))
+	serviceConfig, err := cfg.serviceConfig()
+	if err != nil {
+		return nil, err
+	}
+
+	opts := []grpc.DialOption{
+		grpc.WithTransportCredentials(creds),
+		grpc.WithDefaultServiceConfig(serviceConfig),
+	}
+	if cfg.KeepaliveTime > 0 {
+		opts = append(opts, grpc.WithKeepaliveParams(keepalive.ClientParameters{
+			Time:    cfg.KeepaliveTime,
+			Timeout: cfg.KeepaliveTimeout,
+		}))
+	}
+
+	addr := fmt.Sprintf("%s:%d", cfg.Host, cfg.Port)
+	conn, err := grpc.NewClient(addr, opts...)
 	if err != nil {
 		return nil, err
 	}
@@ -28,18 +126,157 @@ func NewClient(host string, port int) (*Client, error) {
 	return &Client{
 		conn:        conn,
 		auditClient: audit.NewAuditServiceClient(conn),
+		health:      healthpb.NewHealthClient(conn),
+		timeout:     cfg.Timeout,
+		breaker:     newBreaker(cfg.Breaker.FailureThreshold, cfg.Breaker.OpenTimeout),
 	}, nil
+}
+
+func withDefaults(cfg Config) Config {
+	def := DefaultConfig
+	if cfg.Host == "" {
+		cfg.Host = def.Host
+	}
+	if cfg.Port == 0 {
+		cfg.Port = def.Port
+	}
+	if cfg.Timeout <= 0 {
+		cfg.Timeout = def.Timeout
+	}
+	if cfg.Retry.MaxAttempts <= 0 {
+		cfg.Retry.MaxAttempts = def.Retry.MaxAttempts
+	}
+	if cfg.Retry.InitialBackoff <= 0 {
+		cfg.Retry.InitialBackoff = def.Retry.InitialBackoff
+	}
+	if cfg.Retry.MaxBackoff <= 0 {
+		cfg.Retry.MaxBackoff = def.Retry.MaxBackoff
+	}
+	if cfg.KeepaliveTimeout <= 0 {
+		cfg.KeepaliveTimeout = def.KeepaliveTimeout
+	}
+	if cfg.Breaker.FailureThreshold <= 0 {
+		cfg.Breaker.FailureThreshold = def.Breaker.FailureThreshold
+	}
+	if cfg.Breaker.OpenTimeout <= 0 {
+		cfg.Breaker.OpenTimeout = def.Breaker.OpenTimeout
+	}
+	return cfg
+}
+
+func (c TLSConfig) load() (*tls.Config, error) {
+	tlsCfg := &tls.Config{
+		MinVersion: tls.VersionTLS12,
+		ServerName: c.ServerName,
+	}
+
+	if c.CAFile != "" {
+		pem, err := os.ReadFile(c.CAFile)
+		if err != nil {
+			return nil, fmt.Errorf("log_grpc: read CA: %w", err)
+		}
+		pool := x509.NewCertPool()
+		if !pool.AppendCertsFromPEM(pem) {
+			return nil, fmt.Errorf("log_grpc: no certificates in %s", c.CAFile)
+		}
+		tlsCfg.RootCAs = pool
+	}
+
+	if c.CertFile != "" || c.KeyFile != "" {
+		cert, err := tls.LoadX509KeyPair(c.CertFile, c.KeyFile)
+		if err != nil {
+			return nil, fmt.Errorf("log_grpc: load client certificate: %w", err)
+		}
+		tlsCfg.Certificates = []tls.Certificate{cert}
+	}
+	return tlsCfg, nil
+}
 
+// serviceConfig собирает JSON service config: политика повторов для AuditService и, если нужно,
+// health-check. Клиентский health-check работает только с round_robin
+func (c Config) serviceConfig() (string, error) {
+	type retryPolicy struct {
+		MaxAttempts          int      `json:"maxAttempts"`
+		InitialBackoff       string   `json:"initialBackoff"`
+		MaxBackoff           string   `json:"maxBackoff"`
+		BackoffMultiplier    float64  `json:"backoffMultiplier"`
+		RetryableStatusCodes []string `json:"retryableStatusCodes"`
+	}
+	type methodConfig struct {
+		Name        []map[string]string `json:"name"`
+		RetryPolicy *retryPolicy        `json:"retryPolicy,omitempty"`
+	}
+	sc := struct {
+		LoadBalancingConfig []map[string]struct{} `json:"loadBalancingConfig,omitempty"`
+		MethodConfig        []methodConfig        `json:"methodConfig"`
+		HealthCheckConfig   *struct {
+			ServiceName string `json:"serviceName"`
+		} `json:"healthCheckConfig,omitempty"`
+	}{
+		MethodConfig: []methodConfig{{
+			Name: []map[string]string{{"service": audit.AuditService_ServiceDesc.ServiceName}},
+		}},
+	}
+	// одна попытка — повторы выключены; gRPC требует maxAttempts от 2 и больше 5 всё равно не делает
+	if c.Retry.MaxAttempts > 1 {
+		sc.MethodConfig[0].RetryPolicy = &retryPolicy{
+			MaxAttempts:          min(c.Retry.MaxAttempts, 5),
+			InitialBackoff:       seconds(c.Retry.InitialBackoff),
+			MaxBackoff:           seconds(c.Retry.MaxBackoff),
+			BackoffMultiplier:    2,
+			RetryableStatusCodes: []string{"UNAVAILABLE", "RESOURCE_EXHAUSTED"},
+		}
+	}
+	if c.HealthCheck {
+		sc.LoadBalancingConfig = []map[string]struct{}{{"round_robin": {}}}
+		sc.HealthCheckConfig = &struct {
+			ServiceName string `json:"serviceName"`
+		}{}
+	}
+
+	raw, err := json.Marshal(sc)
+	if err != nil {
+		return "", fmt.Errorf("log_grpc: service config: %w", err)
+	}
+	return string(raw), nil
+}
+
+// seconds — длительность в формате protobuf Duration для service config
+func seconds(d time.Duration) string {
+	return strconv.FormatFloat(d.Seconds(), 'f', -1, 64) + "s"
 }
 
 func (c *Client) CloseConnection() error {
 	return c.conn.Close()
 }
 
+// Check спрашивает у сервера логов его состояние по стандартному gRPC health-протоколу.
+// Если сервер health-протокол не поддерживает, возвращается ошибка Unimplemented
+func (c *Client) Check(ctx context.Context) error {
+	ctx, cancel := context.WithTimeout(ctx, c.timeout)
+	defer cancel()
+
+	resp, err := c.health.Check(ctx, &healthpb.HealthCheckRequest{})
+	if err != nil {
+		return err
+	}
+	if resp.GetStatus() != healthpb.HealthCheckResponse_SERVING {
+		return status.Errorf(codes.Unavailable, "log_grpc: server is %s", resp.GetStatus())
+	}
+	return nil
+}
+
 func (c *Client) SendLogRequest(ctx context.Context, req audit.LogItem) error {
 	action, err := audit.ToPbAction(req.Action)
 	if err != nil {
-		return err
+		base, ok := baseActions[req.Action]
+		if !ok {
+			return err
+		}
+		if action, err = audit.ToPbAction(base); err != nil {
+			return err
+		}
+		ctx = metadata.AppendToOutgoingContext(ctx, actionKey, req.Action)
 	}
 
 	entity, err := audit.ToPbEntity(req.Entity)
@@ -47,12 +284,25 @@ func (c *Client) SendLogRequest(ctx context.Context, req audit.LogItem) error {
 		return err
 	}
 
+	if !c.breaker.allow() {
+		return ErrCircuitOpen
+	}
+
+	ctx, cancel := context.WithTimeout(ctx, c.timeout)
+	defer cancel()
+
 	_, err = c.auditClient.Log(ctx, &audit.LogRequest{
 		Action:    action,
 		Entity:    entity,
 		IntityId:  req.EntityID,
 		Timestamp: timestamppb.New(req.Timestamp),
 	})
+	c.breaker.record(err)
 
 	return err
 }
+
+// Close — то же, что CloseConnection; нужен, чтобы клиент подходил как auditsink.Sink
+func (c *Client) Close() error {
+	return c.CloseConnection()
+}
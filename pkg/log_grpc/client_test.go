@@ -0,0 +1,180 @@
+package log_grpc
+
+import (
+	"context"
+	"errors"
+	"net"
+	"sync"
+	"testing"
+	"time"
+
+	audit "github.com/CryptoGu1/books-grpc-log/pkg/domain"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/health"
+	healthpb "google.golang.org/grpc/health/grpc_health_v1"
+	"google.golang.org/grpc/status"
+)
+
+type auditServer struct {
+	audit.UnimplementedAuditServiceServer
+
+	mu sync.Mutex
+	// failures — сколько ближайших вызовов завершить с Unavailable; -1 — все
+	failures int
+	calls    int
+}
+
+func (s *auditServer) Log(context.Context, *audit.LogRequest) (*audit.Empty, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	s.calls++
+	if s.failures != 0 {
+		s.failures--
+		return nil, status.Error(codes.Unavailable, "overloaded")
+	}
+	return &audit.Empty{}, nil
+}
+
+func (s *auditServer) callCount() int {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return s.calls
+}
+
+func startServer(t *testing.T, srv *auditServer) (*health.Server, Config) {
+	t.Helper()
+
+	lis, err := net.Listen("tcp", "127.0.0.1:0")
+	require.NoError(t, err)
+
+	s := grpc.NewServer()
+	hs := health.NewServer()
+	audit.RegisterAuditServiceServer(s, srv)
+	healthpb.RegisterHealthServer(s, hs)
+	go s.Serve(lis)
+	t.Cleanup(s.Stop)
+
+	return hs, Config{
+		Host:  "127.0.0.1",
+		Port:  lis.Addr().(*net.TCPAddr).Port,
+		Retry: RetryConfig{MaxAttempts: 3, InitialBackoff: time.Millisecond, MaxBackoff: time.Millisecond},
+	}
+}
+
+func newTestClient(t *testing.T, cfg Config) *Client {
+	t.Helper()
+	c, err := NewClientWithConfig(cfg)
+	require.NoError(t, err)
+	t.Cleanup(func() { c.Close() })
+	return c
+}
+
+var logItem = audit.LogItem{Entity: audit.ENTITY_BOOK, Action: audit.ACTION_CREATE, EntityID: 1, Timestamp: time.Now()}
+
+func TestClient_Retry(t *testing.T) {
+	srv := &auditServer{failures: 2}
+	_, cfg := startServer(t, srv)
+	c := newTestClient(t, cfg)
+
+	// две временные ошибки перекрываются политикой повторов из service config
+	require.NoError(t, c.SendLogRequest(context.Background(), logItem))
+	assert.Equal(t, 3, srv.callCount())
+}
+
+func TestClient_NoRetry(t *testing.T) {
+	srv := &auditServer{failures: 1}
+	_, cfg := startServer(t, srv)
+	cfg.Retry.MaxAttempts = 1
+	c := newTestClient(t, cfg)
+
+	assert.Equal(t, codes.Unavailable, status.Code(c.SendLogRequest(context.Background(), logItem)))
+	assert.Equal(t, 1, srv.callCount())
+}
+
+func TestClient_CircuitBreaker(t *testing.T) {
+	srv := &auditServer{failures: -1}
+	_, cfg := startServer(t, srv)
+	cfg.Retry.MaxAttempts = 2
+	cfg.Breaker = BreakerConfig{FailureThreshold: 2, OpenTimeout: time.Hour}
+	c := newTestClient(t, cfg)
+
+	for i := 0; i < 2; i++ {
+		err := c.SendLogRequest(context.Background(), logItem)
+		assert.Equal(t, codes.Unavailable, status.Code(err))
+	}
+	calls := srv.callCount()
+
+	// предохранитель открыт: запрос до сервера не доходит
+	assert.ErrorIs(t, c.SendLogRequest(context.Background(), logItem), ErrCircuitOpen)
+	assert.Equal(t, calls, srv.callCount())
+}
+
+func TestClient_Check(t *testing.T) {
+	hs, cfg := startServer(t, &auditServer{})
+	cfg.HealthCheck = true
+	c := newTestClient(t, cfg)
+
+	require.NoError(t, c.Check(context.Background()))
+
+	hs.SetServingStatus("", healthpb.HealthCheckResponse_NOT_SERVING)
+	assert.Equal(t, codes.Unavailable, status.Code(c.Check(context.Background())))
+}
+
+func TestBreaker_HalfOpen(t *testing.T) {
+	now := time.Now()
+	b := newBreaker(1, time.Minute)
+	b.now = func() time.Time { return now }
+	unavailable := status.Error(codes.Unavailable, "down")
+
+	require.True(t, b.allow())
+	b.record(unavailable)
+	assert.False(t, b.allow())
+
+	// по истечении OpenTimeout пропускается ровно один пробный запрос
+	now = now.Add(time.Minute)
+	assert.True(t, b.allow())
+	assert.False(t, b.allow())
+
+	// проба не удалась — снова открыт
+	b.record(unavailable)
+	assert.False(t, b.allow())
+
+	now = now.Add(time.Minute)
+	require.True(t, b.allow())
+	// проба отменена вызывающей стороной — о сервере ничего не известно, пропускается следующая
+	b.record(status.Error(codes.Canceled, "context canceled"))
+	require.True(t, b.allow())
+	assert.False(t, b.allow())
+
+	// ошибка запроса, а не сервера — предохранитель закрывается
+	b.record(status.Error(codes.InvalidArgument, "unknown action"))
+	assert.True(t, b.allow())
+	assert.True(t, b.allow())
+}
+
+func TestRejected(t *testing.T) {
+	testTable := []struct {
+		err      error
+		rejected bool
+	}{
+		{err: status.Error(codes.InvalidArgument, "unknown action"), rejected: true},
+		{err: status.Error(codes.PermissionDenied, "denied"), rejected: true},
+		{err: status.Error(codes.Unimplemented, "unimplemented"), rejected: true},
+		{err: status.Error(codes.Internal, "internal"), rejected: false},
+		{err: status.Error(codes.Unknown, "unknown"), rejected: false},
+		{err: status.Error(codes.Unavailable, "down"), rejected: false},
+		// ошибка без статуса — не ответ сервера, а сбой по дороге
+		{err: errors.New("disk full"), rejected: false},
+		{err: nil, rejected: false},
+	}
+
+	for _, testCase := range testTable {
+		assert.Equal(t, testCase.rejected, Rejected(testCase.err), "%v", testCase.err)
+		// то, что сервер отверг, не делает его нездоровым, остальное — делает
+		assert.Equal(t, testCase.err != nil && !testCase.rejected, unhealthy(testCase.err), "%v", testCase.err)
+	}
+}
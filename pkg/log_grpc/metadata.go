@@ -0,0 +1,90 @@
+package log_grpc
+
+import (
+	"context"
+	"strconv"
+	"strings"
+
+	"google.golang.org/grpc/metadata"
+)
+
+// в audit.LogItem нет места для деталей события, поэтому они уходят на сервер логов как gRPC metadata
+const (
+	changedFieldsKey = "x-audit-changed-fields"
+	eventIDKey       = "x-audit-event-id"
+	actorIDKey       = "x-audit-actor-id"
+	requestIDKey     = "x-audit-request-id"
+	clientIPKey      = "x-audit-client-ip"
+	userAgentKey     = "x-audit-user-agent"
+	// diff — JSON, в нём может быть что угодно, поэтому он всегда бинарный
+	diffKey = "x-audit-diff-bin"
+)
+
+// RequestDetails — кто и откуда выполнил действие. Пустые поля не отправляются
+type RequestDetails struct {
+	ActorID   int64
+	RequestID string
+	ClientIP  string
+	UserAgent string
+}
+
+// WithChangedFields прикладывает к аудит-событию список изменённых полей сущности
+func WithChangedFields(ctx context.Context, fields []string) context.Context {
+	if len(fields) == 0 {
+		return ctx
+	}
+	return metadata.AppendToOutgoingContext(ctx, changedFieldsKey, strings.Join(fields, ","))
+}
+
+// WithEventID прикладывает ключ идемпотентности: по нему сервер логов отбрасывает повторную доставку
+func WithEventID(ctx context.Context, id string) context.Context {
+	if id == "" {
+		return ctx
+	}
+	return metadata.AppendToOutgoingContext(ctx, eventIDKey, id)
+}
+
+// WithRequestDetails прикладывает к аудит-событию актора и сведения о запросе
+func WithRequestDetails(ctx context.Context, d RequestDetails) context.Context {
+	var kv []string
+	if d.ActorID != 0 {
+		kv = append(kv, actorIDKey, strconv.FormatInt(d.ActorID, 10))
+	}
+	kv = appendText(kv, requestIDKey, d.RequestID)
+	kv = appendText(kv, clientIPKey, d.ClientIP)
+	kv = appendText(kv, userAgentKey, d.UserAgent)
+
+	if len(kv) == 0 {
+		return ctx
+	}
+	return metadata.AppendToOutgoingContext(ctx, kv...)
+}
+
+// WithDiff прикладывает JSON со значениями изменённых полей до и после
+func WithDiff(ctx context.Context, diff []byte) context.Context {
+	if len(diff) == 0 {
+		return ctx
+	}
+	return metadata.AppendToOutgoingContext(ctx, diffKey, string(diff))
+}
+
+// appendText: в текстовой metadata допустим только печатный ASCII. Остальное (например, User-Agent
+// с кириллицей) уходит под ключом с суффиксом -bin, который gRPC передаёт в base64
+func appendText(kv []string, key, value string) []string {
+	if value == "" {
+		return kv
+	}
+	if !printableASCII(value) {
+		key += "-bin"
+	}
+	return append(kv, key, value)
+}
+
+func printableASCII(s string) bool {
+	for i := 0; i < len(s); i++ {
+		if s[i] < 0x20 || s[i] > 0x7e {
+			return false
+		}
+	}
+	return true
+}
@@ -0,0 +1,96 @@
+package mailer
+
+import (
+	"context"
+	"sync"
+
+	log "github.com/sirupsen/logrus"
+)
+
+// AsyncMailer отправляет письма в фоне. Запрос не ждёт SMTP, поэтому по времени ответа
+// (например, на «забыли пароль») нельзя понять, было ли письмо отправлено
+type AsyncMailer struct {
+	next  Sender
+	queue chan Message
+	done  chan struct{}
+	once  sync.Once
+	wg    sync.WaitGroup
+}
+
+// NewAsync запускает workers отправителей с очередью на size писем
+func NewAsync(next Sender, size, workers int) *AsyncMailer {
+	if size <= 0 {
+		size = 100
+	}
+	if workers <= 0 {
+		workers = 1
+	}
+
+	m := &AsyncMailer{
+		next:  next,
+		queue: make(chan Message, size),
+		done:  make(chan struct{}),
+	}
+	m.wg.Add(workers)
+	for i := 0; i < workers; i++ {
+		go m.worker()
+	}
+	return m
+}
+
+// Send ставит письмо в очередь. Ошибки отправки только логируются: вызывающий
+// всё равно не должен сообщать о них клиенту
+func (m *AsyncMailer) Send(_ context.Context, msg Message) error {
+	select {
+	case <-m.done:
+		log.WithFields(log.Fields{"to": msg.To, "subject": msg.Subject}).Warn("mailer: closed, message dropped")
+	case m.queue <- msg:
+	default:
+		log.WithFields(log.Fields{"to": msg.To, "subject": msg.Subject}).Warn("mailer: queue is full, message dropped")
+	}
+	return nil
+}
+
+func (m *AsyncMailer) worker() {
+	defer m.wg.Done()
+	for {
+		select {
+		case msg := <-m.queue:
+			m.send(msg)
+		case <-m.done:
+			// дописываем то, что успели поставить в очередь
+			for {
+				select {
+				case msg := <-m.queue:
+					m.send(msg)
+				default:
+					return
+				}
+			}
+		}
+	}
+}
+
+func (m *AsyncMailer) send(msg Message) {
+	if err := m.next.Send(context.Background(), msg); err != nil {
+		log.WithFields(log.Fields{"to": msg.To, "subject": msg.Subject}).Error(err)
+	}
+}
+
+// Close отправляет оставшиеся письма и ждёт workers, но не дольше ctx
+func (m *AsyncMailer) Close(ctx context.Context) error {
+	m.once.Do(func() { close(m.done) })
+
+	finished := make(chan struct{})
+	go func() {
+		m.wg.Wait()
+		close(finished)
+	}()
+
+	select {
+	case <-finished:
+		return nil
+	case <-ctx.Done():
+		return ctx.Err()
+	}
+}
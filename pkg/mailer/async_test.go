@@ -0,0 +1,26 @@
+package mailer
+
+import (
+	"context"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestAsyncMailer(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "mail.jsonl")
+	m := NewAsync(NewFileMailer(path), 10, 2)
+
+	require.NoError(t, m.Send(context.Background(), Message{To: "a@example.com", Subject: "first"}))
+	require.NoError(t, m.Send(context.Background(), Message{To: "b@example.com", Subject: "second"}))
+	require.NoError(t, m.Close(context.Background()))
+
+	messages, err := ReadMessages(path)
+	require.NoError(t, err)
+	assert.Len(t, messages, 2)
+
+	// после Close письма не принимаются, но и не паникуют
+	assert.NoError(t, m.Send(context.Background(), Message{To: "c@example.com"}))
+}
@@ -0,0 +1,80 @@
+package mailer
+
+import (
+	"bufio"
+	"context"
+	"encoding/json"
+	"fmt"
+	"os"
+	"sync"
+
+	log "github.com/sirupsen/logrus"
+)
+
+// FileMailer дописывает письма в файл по одному JSON на строку
+type FileMailer struct {
+	path string
+	mu   sync.Mutex
+}
+
+func NewFileMailer(path string) *FileMailer {
+	return &FileMailer{path: path}
+}
+
+func (m *FileMailer) Send(_ context.Context, msg Message) error {
+	line, err := json.Marshal(msg)
+	if err != nil {
+		return fmt.Errorf("mailer: encode message: %w", err)
+	}
+
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	f, err := os.OpenFile(m.path, os.O_CREATE|os.O_APPEND|os.O_WRONLY, 0o600)
+	if err != nil {
+		return fmt.Errorf("mailer: open file: %w", err)
+	}
+	defer f.Close()
+
+	if _, err := f.Write(append(line, '\n')); err != nil {
+		return fmt.Errorf("mailer: write file: %w", err)
+	}
+	return nil
+}
+
+// ReadMessages читает письма, записанные FileMailer
+func ReadMessages(path string) ([]Message, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+
+	var messages []Message
+	scanner := bufio.NewScanner(f)
+	for scanner.Scan() {
+		var msg Message
+		if err := json.Unmarshal(scanner.Bytes(), &msg); err != nil {
+			return nil, fmt.Errorf("mailer: decode message: %w", err)
+		}
+		messages = append(messages, msg)
+	}
+	return messages, scanner.Err()
+}
+
+// LogMailer ничего не отправляет, а пишет в лог получателя и тему.
+// Тело не пишется: в нём токены сброса пароля и ссылки подтверждения
+type LogMailer struct{}
+
+func NewLogMailer() *LogMailer {
+	return &LogMailer{}
+}
+
+func (m *LogMailer) Send(_ context.Context, msg Message) error {
+	log.WithFields(log.Fields{
+		"mailer":  "log",
+		"to":      msg.To,
+		"subject": msg.Subject,
+	}).Info("mail not sent: log driver")
+	return nil
+}
@@ -0,0 +1,25 @@
+package mailer
+
+import (
+	"context"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestFileMailer(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "mail.jsonl")
+	m := NewFileMailer(path)
+
+	require.NoError(t, m.Send(context.Background(), Message{To: "a@example.com", Subject: "first", Body: "line1\nline2"}))
+	require.NoError(t, m.Send(context.Background(), Message{To: "b@example.com", Subject: "second"}))
+
+	messages, err := ReadMessages(path)
+	require.NoError(t, err)
+	require.Len(t, messages, 2)
+	assert.Equal(t, "a@example.com", messages[0].To)
+	assert.Equal(t, "line1\nline2", messages[0].Body)
+	assert.Equal(t, "second", messages[1].Subject)
+}
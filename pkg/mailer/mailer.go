@@ -0,0 +1,16 @@
+// Package mailer — отправка писем. SMTPMailer для боевого окружения,
+// FileMailer и LogMailer — для локального запуска и тестов
+package mailer
+
+import "context"
+
+type Message struct {
+	To      string `json:"to"`
+	Subject string `json:"subject"`
+	Body    string `json:"body"`
+}
+
+// Sender — любой способ отправки писем
+type Sender interface {
+	Send(ctx context.Context, msg Message) error
+}
@@ -0,0 +1,91 @@
+package oidc_test
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"testing"
+
+	"github.com/CryptoGu1/books-rest-clean-arch/pkg/oidc"
+	"github.com/CryptoGu1/books-rest-clean-arch/pkg/oidc/oidctest"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+const redirectURL = "http://app.local/auth/oidc/local/callback"
+
+// authorize проходит авторизацию у провайдера и возвращает code из редиректа
+func authorize(t *testing.T, p *oidc.Provider, state, nonce, verifier string) string {
+	authURL, err := p.AuthCodeURL(context.Background(), state, nonce, verifier)
+	require.NoError(t, err)
+
+	client := &http.Client{CheckRedirect: func(*http.Request, []*http.Request) error { return http.ErrUseLastResponse }}
+	resp, err := client.Get(authURL)
+	require.NoError(t, err)
+	resp.Body.Close()
+	require.Equal(t, http.StatusFound, resp.StatusCode)
+
+	back, err := url.Parse(resp.Header.Get("Location"))
+	require.NoError(t, err)
+	assert.Equal(t, state, back.Query().Get("state"))
+	return back.Query().Get("code")
+}
+
+func TestProvider_AuthorizationCodeFlow(t *testing.T) {
+	mock, srv, err := oidctest.NewServer("books", "s3cret")
+	require.NoError(t, err)
+	defer srv.Close()
+	mock.SetUser(oidctest.User{Subject: "42", Email: "leo@example.com", EmailVerified: true, Name: "Leo"})
+
+	p := oidc.NewProvider(oidc.Config{Issuer: srv.URL + "/", ClientID: "books", ClientSecret: "s3cret", RedirectURL: redirectURL}, nil)
+
+	verifier, err := oidc.NewVerifier()
+	require.NoError(t, err)
+
+	t.Run("ok", func(t *testing.T) {
+		code := authorize(t, p, "state", "nonce", verifier)
+		claims, err := p.Exchange(context.Background(), code, verifier, "nonce")
+		require.NoError(t, err)
+		assert.Equal(t, "42", claims.Subject)
+		assert.Equal(t, "leo@example.com", claims.Email)
+		assert.True(t, claims.EmailVerified)
+
+		// код одноразовый
+		_, err = p.Exchange(context.Background(), code, verifier, "nonce")
+		assert.Error(t, err)
+	})
+
+	t.Run("wrong verifier", func(t *testing.T) {
+		code := authorize(t, p, "state", "nonce", verifier)
+		other, err := oidc.NewVerifier()
+		require.NoError(t, err)
+		_, err = p.Exchange(context.Background(), code, other, "nonce")
+		assert.Error(t, err)
+	})
+
+	t.Run("nonce mismatch", func(t *testing.T) {
+		code := authorize(t, p, "state", "nonce", verifier)
+		_, err := p.Exchange(context.Background(), code, verifier, "other-nonce")
+		assert.ErrorIs(t, err, oidc.ErrInvalidIDToken)
+	})
+
+	t.Run("wrong client secret", func(t *testing.T) {
+		bad := oidc.NewProvider(oidc.Config{Issuer: srv.URL, ClientID: "books", ClientSecret: "wrong", RedirectURL: redirectURL}, nil)
+		code := authorize(t, bad, "state", "nonce", verifier)
+		_, err := bad.Exchange(context.Background(), code, verifier, "nonce")
+		assert.Error(t, err)
+	})
+}
+
+func TestProvider_IssuerMismatch(t *testing.T) {
+	// провайдер представляется другим issuer, чем тот, у кого запросили метаданные
+	mock, err := oidctest.New("http://issuer.example", "books", "")
+	require.NoError(t, err)
+	srv := httptest.NewServer(mock)
+	defer srv.Close()
+
+	p := oidc.NewProvider(oidc.Config{Issuer: srv.URL, ClientID: "books"}, nil)
+	_, err = p.AuthCodeURL(context.Background(), "s", "n", "v")
+	assert.ErrorContains(t, err, "does not match")
+}
@@ -0,0 +1,120 @@
+package password
+
+import (
+	"crypto/rand"
+	"crypto/subtle"
+	"encoding/base64"
+	"fmt"
+	"strings"
+
+	"golang.org/x/crypto/argon2"
+)
+
+const argon2Prefix = "$argon2id$"
+
+// Argon2id — хеши в формате PHC: $argon2id$v=19$m=65536,t=3,p=4$<salt>$<hash>
+type Argon2id struct {
+	// Memory — в KiB
+	Memory      uint32
+	Iterations  uint32
+	Parallelism uint8
+	SaltLength  uint32
+	KeyLength   uint32
+}
+
+// DefaultArgon2id — второй рекомендуемый набор RFC 9106 §4 (64 MiB)
+var DefaultArgon2id = Argon2id{Memory: 64 * 1024, Iterations: 3, Parallelism: 4, SaltLength: 16, KeyLength: 32}
+
+// Validate проверяет параметры: с нулевыми t или p argon2.IDKey паникует,
+// а с пустой солью или коротким ключом хеш бесполезен
+func (a Argon2id) Validate() error {
+	switch {
+	case a.Iterations < 1:
+		return fmt.Errorf("argon2id: iterations must be at least 1")
+	case a.Parallelism < 1:
+		return fmt.Errorf("argon2id: parallelism must be at least 1")
+	case a.Memory < 8*uint32(a.Parallelism):
+		return fmt.Errorf("argon2id: memory must be at least 8 KiB per lane (%d KiB)", 8*uint32(a.Parallelism))
+	case a.SaltLength < 8:
+		return fmt.Errorf("argon2id: salt length must be at least 8 bytes")
+	case a.KeyLength < 16:
+		return fmt.Errorf("argon2id: key length must be at least 16 bytes")
+	}
+	return nil
+}
+
+type argon2Params struct {
+	version            int
+	memory, iterations uint32
+	parallelism        uint8
+	salt, key          []byte
+}
+
+func (a Argon2id) Hash(password string) (string, error) {
+	salt := make([]byte, a.SaltLength)
+	if _, err := rand.Read(salt); err != nil {
+		return "", err
+	}
+	key := argon2.IDKey([]byte(password), salt, a.Iterations, a.Memory, a.Parallelism, a.KeyLength)
+
+	enc := base64.RawStdEncoding
+	return fmt.Sprintf("%sv=%d$m=%d,t=%d,p=%d$%s$%s", argon2Prefix, argon2.Version,
+		a.Memory, a.Iterations, a.Parallelism, enc.EncodeToString(salt), enc.EncodeToString(key)), nil
+}
+
+func (a Argon2id) Verify(encoded, password string) error {
+	p, err := parseArgon2(encoded)
+	if err != nil {
+		return err
+	}
+	key := argon2.IDKey([]byte(password), p.salt, p.iterations, p.memory, p.parallelism, uint32(len(p.key)))
+	if subtle.ConstantTimeCompare(key, p.key) != 1 {
+		return ErrMismatch
+	}
+	return nil
+}
+
+func (a Argon2id) Recognizes(encoded string) bool {
+	return strings.HasPrefix(encoded, argon2Prefix)
+}
+
+func (a Argon2id) Outdated(encoded string) bool {
+	p, err := parseArgon2(encoded)
+	if err != nil {
+		return true
+	}
+	return p.version != argon2.Version || p.memory != a.Memory || p.iterations != a.Iterations ||
+		p.parallelism != a.Parallelism || uint32(len(p.salt)) != a.SaltLength || uint32(len(p.key)) != a.KeyLength
+}
+
+func parseArgon2(encoded string) (argon2Params, error) {
+	// "", "argon2id", "v=19", "m=...,t=...,p=...", salt, key
+	parts := strings.Split(encoded, "$")
+	if len(parts) != 6 || parts[1] != "argon2id" {
+		return argon2Params{}, fmt.Errorf("%w: malformed argon2id hash", ErrUnknownFormat)
+	}
+
+	var p argon2Params
+	if _, err := fmt.Sscanf(parts[2], "v=%d", &p.version); err != nil {
+		return argon2Params{}, fmt.Errorf("%w: argon2id version: %v", ErrUnknownFormat, err)
+	}
+	if p.version != argon2.Version {
+		return argon2Params{}, fmt.Errorf("%w: argon2id version %d", ErrUnknownFormat, p.version)
+	}
+	if _, err := fmt.Sscanf(parts[3], "m=%d,t=%d,p=%d", &p.memory, &p.iterations, &p.parallelism); err != nil {
+		return argon2Params{}, fmt.Errorf("%w: argon2id params: %v", ErrUnknownFormat, err)
+	}
+	// испорченный хеш из базы не должен ронять процесс внутри argon2.IDKey
+	if p.iterations < 1 || p.parallelism < 1 {
+		return argon2Params{}, fmt.Errorf("%w: argon2id params out of range", ErrUnknownFormat)
+	}
+
+	var err error
+	if p.salt, err = base64.RawStdEncoding.DecodeString(parts[4]); err != nil {
+		return argon2Params{}, fmt.Errorf("%w: argon2id salt: %v", ErrUnknownFormat, err)
+	}
+	if p.key, err = base64.RawStdEncoding.DecodeString(parts[5]); err != nil || len(p.key) == 0 {
+		return argon2Params{}, fmt.Errorf("%w: argon2id key", ErrUnknownFormat)
+	}
+	return p, nil
+}
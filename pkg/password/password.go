@@ -0,0 +1,75 @@
+// Package password — хеширование паролей. Новые хеши считаются текущим алгоритмом,
+// проверяются хеши всех известных: так можно сменить алгоритм или его параметры без сброса паролей
+package password
+
+import (
+	"errors"
+	"fmt"
+)
+
+var (
+	ErrMismatch      = errors.New("password: mismatch")
+	ErrUnknownFormat = errors.New("password: unknown hash format")
+)
+
+// Algorithm — один способ хеширования
+type Algorithm interface {
+	Hash(password string) (string, error)
+	// Verify возвращает ErrMismatch, если пароль не подходит
+	Verify(encoded, password string) error
+	// Recognizes — хеш записан в формате этого алгоритма
+	Recognizes(encoded string) bool
+	// Outdated — хеш этого алгоритма, но с другими параметрами
+	Outdated(encoded string) bool
+}
+
+type Hasher struct {
+	current Algorithm
+	known   []Algorithm
+	// sem ограничивает число одновременных вычислений хеша (см. Limit)
+	sem chan struct{}
+}
+
+// NewHasher: current хеширует новые пароли, legacy — только проверяют старые хеши
+func NewHasher(current Algorithm, legacy ...Algorithm) *Hasher {
+	return &Hasher{current: current, known: append([]Algorithm{current}, legacy...)}
+}
+
+// Limit ограничивает число одновременных вычислений хеша. Argon2id берёт десятки мегабайт
+// на каждый вызов, и без лимита параллельные входы исчерпают память; лишние ждут очереди
+func (h *Hasher) Limit(n int) *Hasher {
+	if n > 0 {
+		h.sem = make(chan struct{}, n)
+	}
+	return h
+}
+
+func (h *Hasher) acquire() func() {
+	if h.sem == nil {
+		return func() {}
+	}
+	h.sem <- struct{}{}
+	return func() { <-h.sem }
+}
+
+func (h *Hasher) Hash(password string) (string, error) {
+	defer h.acquire()()
+	return h.current.Hash(password)
+}
+
+// Verify проверяет пароль; rehash — хеш верный, но его стоит пересчитать текущим алгоритмом
+func (h *Hasher) Verify(encoded, password string) (rehash bool, err error) {
+	for _, alg := range h.known {
+		if !alg.Recognizes(encoded) {
+			continue
+		}
+		release := h.acquire()
+		err := alg.Verify(encoded, password)
+		release()
+		if err != nil {
+			return false, err
+		}
+		return alg != h.current || alg.Outdated(encoded), nil
+	}
+	return false, fmt.Errorf("%w: %.10q", ErrUnknownFormat, encoded)
+}
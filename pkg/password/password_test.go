@@ -0,0 +1,157 @@
+package password
+
+import (
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"golang.org/x/crypto/bcrypt"
+)
+
+// дешёвые параметры, чтобы тесты не тратили 64 MiB на каждый хеш
+var testArgon2 = Argon2id{Memory: 1024, Iterations: 1, Parallelism: 1, SaltLength: 16, KeyLength: 32}
+
+func TestArgon2id_PHCFormat(t *testing.T) {
+	encoded, err := testArgon2.Hash("password")
+	require.NoError(t, err)
+	assert.True(t, strings.HasPrefix(encoded, "$argon2id$v=19$m=1024,t=1,p=1$"), encoded)
+
+	assert.NoError(t, testArgon2.Verify(encoded, "password"))
+	assert.ErrorIs(t, testArgon2.Verify(encoded, "wrong"), ErrMismatch)
+	assert.False(t, testArgon2.Outdated(encoded))
+
+	// соль случайная — одинаковые пароли дают разные хеши
+	other, err := testArgon2.Hash("password")
+	require.NoError(t, err)
+	assert.NotEqual(t, encoded, other)
+
+	// хеш проверяется по своим параметрам, даже если текущие уже другие
+	stronger := testArgon2
+	stronger.Iterations = 2
+	assert.NoError(t, stronger.Verify(encoded, "password"))
+	assert.True(t, stronger.Outdated(encoded))
+
+	_, err = parseArgon2("$argon2id$v=19$m=1024,t=1$c2FsdA$a2V5")
+	assert.ErrorIs(t, err, ErrUnknownFormat)
+}
+
+func TestHasher_Verify(t *testing.T) {
+	legacyBcrypt, err := Bcrypt{Cost: bcrypt.MinCost}.Hash("password")
+	require.NoError(t, err)
+	currentArgon, err := testArgon2.Hash("password")
+	require.NoError(t, err)
+	weakArgon, err := Argon2id{Memory: 512, Iterations: 1, Parallelism: 1, SaltLength: 16, KeyLength: 32}.Hash("password")
+	require.NoError(t, err)
+
+	h := NewHasher(testArgon2, Bcrypt{Cost: bcrypt.MinCost})
+
+	testTable := []struct {
+		name           string
+		encoded        string
+		password       string
+		expectedRehash bool
+		expectedError  error
+	}{
+		{name: "current", encoded: currentArgon, password: "password"},
+		{name: "legacy algorithm", encoded: legacyBcrypt, password: "password", expectedRehash: true},
+		{name: "outdated params", encoded: weakArgon, password: "password", expectedRehash: true},
+		{name: "wrong password", encoded: legacyBcrypt, password: "wrong", expectedError: ErrMismatch},
+		{name: "unknown format", encoded: "plain-text", password: "plain-text", expectedError: ErrUnknownFormat},
+	}
+
+	for _, testCase := range testTable {
+		t.Run(testCase.name, func(t *testing.T) {
+			rehash, err := h.Verify(testCase.encoded, testCase.password)
+			if testCase.expectedError != nil {
+				assert.ErrorIs(t, err, testCase.expectedError)
+				assert.False(t, rehash)
+				return
+			}
+			require.NoError(t, err)
+			assert.Equal(t, testCase.expectedRehash, rehash)
+		})
+	}
+
+	t.Run("bcrypt cost change", func(t *testing.T) {
+		h := NewHasher(Bcrypt{Cost: bcrypt.MinCost + 1})
+		rehash, err := h.Verify(legacyBcrypt, "password")
+		require.NoError(t, err)
+		assert.True(t, rehash)
+	})
+}
+
+func TestArgon2id_Validate(t *testing.T) {
+	testTable := []struct {
+		name      string
+		modify    func(a *Argon2id)
+		expectErr bool
+	}{
+		{name: "default", modify: func(*Argon2id) {}},
+		{name: "zero iterations", modify: func(a *Argon2id) { a.Iterations = 0 }, expectErr: true},
+		{name: "zero parallelism", modify: func(a *Argon2id) { a.Parallelism = 0 }, expectErr: true},
+		{name: "too little memory", modify: func(a *Argon2id) { a.Memory = 16 }, expectErr: true},
+		{name: "empty salt", modify: func(a *Argon2id) { a.SaltLength = 0 }, expectErr: true},
+		{name: "short key", modify: func(a *Argon2id) { a.KeyLength = 4 }, expectErr: true},
+	}
+
+	for _, testCase := range testTable {
+		t.Run(testCase.name, func(t *testing.T) {
+			a := DefaultArgon2id
+			testCase.modify(&a)
+			if testCase.expectErr {
+				assert.Error(t, a.Validate())
+			} else {
+				assert.NoError(t, a.Validate())
+			}
+		})
+	}
+}
+
+func TestArgon2id_Verify_BrokenParams(t *testing.T) {
+	// t=0 в сохранённом хеше — ошибка формата, а не паника в argon2.IDKey
+	_, err := NewHasher(DefaultArgon2id).Verify("$argon2id$v=19$m=65536,t=0,p=4$c2FsdHNhbHQ$a2V5a2V5a2V5a2V5", "password")
+	assert.ErrorIs(t, err, ErrUnknownFormat)
+}
+
+type slowAlgorithm struct {
+	Bcrypt
+	mu      *sync.Mutex
+	running *int
+	peak    *int
+}
+
+func (s slowAlgorithm) Hash(string) (string, error) {
+	s.mu.Lock()
+	*s.running++
+	if *s.running > *s.peak {
+		*s.peak = *s.running
+	}
+	s.mu.Unlock()
+
+	time.Sleep(10 * time.Millisecond)
+
+	s.mu.Lock()
+	*s.running--
+	s.mu.Unlock()
+	return "", nil
+}
+
+func TestHasher_Limit(t *testing.T) {
+	var running, peak int
+	h := NewHasher(slowAlgorithm{mu: &sync.Mutex{}, running: &running, peak: &peak}).Limit(2)
+
+	var wg sync.WaitGroup
+	for i := 0; i < 8; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			_, _ = h.Hash("password")
+		}()
+	}
+	wg.Wait()
+
+	assert.Equal(t, 2, peak)
+}